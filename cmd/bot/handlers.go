@@ -1,7 +1,9 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
+	"strings"
 
 	"go.uber.org/zap"
 	tele "gopkg.in/telebot.v3"
@@ -11,19 +13,23 @@ import (
 )
 
 // registerCommands регистрирует все команды бота.
-// Хендлеры для базовых команд: /start, /help, /version.
+// Хендлеры для базовых команд: /start, /help, /version, /modules, /enable, /disable.
 func registerCommands(
 	bot *tele.Bot,
+	db *sql.DB,
 	chatRepo *repositories.ChatRepository,
 	eventRepo *repositories.EventRepository,
+	settingsRepo *repositories.SettingsRepository,
+	registry *core.Registry,
+	moduleStates *core.ModuleStates,
 	logger *zap.Logger,
 	botVersion string,
 ) {
 	// /version — информация о версии бота
 	bot.Handle("/version", handleVersion(botVersion))
 
-	// OnUserJoined — приветствие новых пользователей и бота
-	bot.Handle(tele.OnUserJoined, handleUserJoined(chatRepo, logger))
+	// OnUserJoined — модули (капча), затем приветствие новых пользователей и бота
+	bot.Handle(tele.OnUserJoined, handleUserJoined(db, chatRepo, registry, logger))
 
 	// /start — приветствие
 	bot.Handle("/start", handleStart(chatRepo, eventRepo, logger))
@@ -31,6 +37,11 @@ func registerCommands(
 	// /help — помощь
 	bot.Handle("/help", handleHelp(logger))
 
+	// /modules, /enable, /disable — управление модулями чата (только админы)
+	bot.Handle("/modules", handleModules(db, registry, moduleStates, settingsRepo, logger))
+	bot.Handle("/enable", handleSetModuleEnabled(true, db, registry, moduleStates, settingsRepo, eventRepo, logger))
+	bot.Handle("/disable", handleSetModuleEnabled(false, db, registry, moduleStates, settingsRepo, eventRepo, logger))
+
 	// Универсальный обработчик для всех типов сообщений.
 	// Хендлеры нужны для активации middleware (bot.Use).
 	// Сами хендлеры ничего не делают — вся логика в middleware pipeline.
@@ -48,6 +59,10 @@ func registerCommands(
 	bot.Handle(tele.OnLocation, noOpHandler)
 	bot.Handle(tele.OnContact, noOpHandler)
 	bot.Handle(tele.OnPoll, noOpHandler)
+
+	// Отредактированные сообщения проходят тот же pipeline (ctx.IsEdit = true):
+	// иначе можно отправить чистый текст и дописать мат правкой
+	bot.Handle(tele.OnEdited, noOpHandler)
 }
 
 // handleVersion возвращает хендлер для команды /version
@@ -63,8 +78,10 @@ func handleVersion(botVersion string) func(tele.Context) error {
 	}
 }
 
-// handleUserJoined возвращает хендлер для события OnUserJoined
-func handleUserJoined(chatRepo *repositories.ChatRepository, logger *zap.Logger) func(tele.Context) error {
+// handleUserJoined возвращает хендлер для события OnUserJoined.
+// telebot допускает один хендлер на событие, поэтому модули (core.JoinHandler)
+// вызываются отсюда — до приветствия, которое они могут подавить.
+func handleUserJoined(db *sql.DB, chatRepo *repositories.ChatRepository, registry *core.Registry, logger *zap.Logger) func(tele.Context) error {
 	return func(c tele.Context) error {
 		newMember := c.Message().UserJoined
 
@@ -86,13 +103,36 @@ func handleUserJoined(chatRepo *repositories.ChatRepository, logger *zap.Logger)
 				"🔹 Автоматическая статистика активности\n" +
 				"🔹 Лимиты на контент (фото, видео, стикеры)\n" +
 				"🔹 Автоответы, фильтры и модерация контента\n" +
-				"🔹 Запланированные задачи по расписанию\n\n" +
+				"🔹 Запланированные задачи по расписанию\n" +
+				"🔹 Капча для новых участников\n\n" +
 				"Используйте /help для списка всех команд.\n" +
 				"Администраторы могут настраивать модули самостоятельно.\n\n" +
 				"💬 Автор бота: @FlyBasist"
 			return c.Send(answer)
 		}
 
+		joinCtx := &core.JoinContext{
+			Bot:      c.Bot(),
+			Chat:     c.Chat(),
+			User:     newMember,
+			Message:  c.Message(),
+			ThreadID: core.GetThreadID(db, c),
+		}
+		for _, h := range registry.JoinHandlers() {
+			if err := h.OnUserJoined(joinCtx); err != nil {
+				logger.Error("join handler failed",
+					zap.Int64("chat_id", c.Chat().ID),
+					zap.Int64("user_id", newMember.ID),
+					zap.Error(err))
+			}
+			if joinCtx.Handled {
+				break
+			}
+		}
+		if joinCtx.SuppressGreeting {
+			return nil
+		}
+
 		// Приветствие обычного пользователя
 		username := newMember.Username
 		var answer string
@@ -100,9 +140,7 @@ func handleUserJoined(chatRepo *repositories.ChatRepository, logger *zap.Logger)
 		if username != "" {
 			// Есть никнейм - стандартное приветствие
 			answer = fmt.Sprintf(
-				"👋 Привет, @%s! Добро пожаловать в наш чат!\n\n"+
-					"Капча для новых пользователей в разработке, "+
-					"поэтому если ты спамер то удались сам пожалуйста 😊",
+				"👋 Привет, @%s! Добро пожаловать в наш чат!",
 				username,
 			)
 		} else {
@@ -171,6 +209,8 @@ func handleHelp(logger *zap.Logger) func(tele.Context) error {
 /start — приветствие и инициализация
 /help — эта справка
 /version — информация о версии бота
+🔒 /modules — модули чата и их состояние
+🔒 /enable, 🔒 /disable — включить/выключить модуль
 
 🤖 Модули бота (работают автоматически):
 
@@ -181,26 +221,202 @@ func handleHelp(logger *zap.Logger) func(tele.Context) error {
 
 🔹 limiter — контроль лимитов контента
    Ограничивает фото, видео, стикеры и т.д.
-   📌 /limiter, /mystats, /getlimit
+   📌 /limiter, /mystats, /getlimit, /forwardpolicy
    📌 🔒 /setlimit, 🔒 /setvip, 🔒 /removevip, 🔒 /listvips
+   📌 🔒 /setforward, 🔒 /allowforward, 🔒 /removeforward
 
 🔹 reactions — реакции, фильтры и модерация
-   Автоответы, фильтрация слов и мата
+   Автоответы, фильтрация слов, мата и ссылок
    📌 /reactions — автоответы на ключевые слова
       🔒 /addreaction, 🔒 /listreactions, 🔒 /removereaction
    📌 /textfilter — фильтр запрещённых слов
-      🔒 /addban, 🔒 /listbans, 🔒 /removeban
+      🔒 /addban, 🔒 /listbans, 🔒 /removeban, 🔒 /banshadow
    📌 /profanity — фильтр ненормативной лексики
       🔒 /setprofanity, 🔒 /profanitystatus, 🔒 /removeprofanity
+      🔒 /profanityallow, 🔒 /profanityadd, 🔒 /profanityremove, 🔒 /profanitylist
+   📌 /linkfilter — фильтр ссылок и приглашений
+      🔒 /setlinks, 🔒 /allowdomain, 🔒 /denydomain, 🔒 /removedomain
+      🔒 /linkstatus, 🔒 /removelinks
+   📌 🔒 /testfilter — какое правило сработает на текст (без удаления)
+   📌 🔒 /shadowreport — что сработало бы в теневом режиме
 
 🔹 scheduler — запланированные задачи
-   Выполняет задачи по расписанию (cron)
+   Выполняет задачи по расписанию (cron), в том числе ночной режим
    📌 /scheduler
    📌 🔒 /addtask, 🔒 /listtasks, 🔒 /deltask, 🔒 /runtask
 
+🔹 captcha — проверка новых участников
+   Новичок не может писать, пока не решит капчу
+   📌 /captcha
+   📌 🔒 /setcaptcha
+
+🔹 moderation — предупреждения, наказания и жалобы
+   3 предупреждения → мут, 5 → бан (настраивается)
+   📌 /moderation, /warns, /warnpolicy
+   📌 /report — пожаловаться админам (ответом на сообщение)
+   📌 🔒 /reports, 🔒 /setreport
+   📌 🔒 /warn, 🔒 /unwarn, 🔒 /resetwarns, 🔒 /setwarnpolicy, 🔒 /setautowarn
+   📌 🔒 /mute, 🔒 /tmute, 🔒 /unmute, 🔒 /ban, 🔒 /tban, 🔒 /unban, 🔒 /kick
+
+🔹 antiflood — защита от флуда
+   Больше N сообщений за M секунд → удаление, мут или предупреждение
+   📌 /antiflood
+   📌 🔒 /setflood
+
+🔹 antiraid — защита от рейдов
+   Всплеск вступлений → новички ограничиваются или кикаются
+   📌 /antiraid
+   📌 🔒 /raidmode
+
+🔹 probation — испытательный срок новичков
+   Первое время новым участникам можно только текст без ссылок
+   📌 /probation
+   📌 🔒 /setprobation, 🔒 /trust
+
 🔒 = команда доступна только администраторам чата
 💡 Используйте команду модуля (например /reactions) для подробной справки.`
 
 		return c.Send(helpMsg)
 	}
 }
+
+// handleModules возвращает хендлер для команды /modules — состояние модулей в чате/топике.
+func handleModules(
+	db *sql.DB,
+	registry *core.Registry,
+	moduleStates *core.ModuleStates,
+	settingsRepo *repositories.SettingsRepository,
+	logger *zap.Logger,
+) func(tele.Context) error {
+	return func(c tele.Context) error {
+		chatID := c.Chat().ID
+		threadID := core.GetThreadID(db, c)
+
+		logger.Info("handling /modules command",
+			zap.Int64("chat_id", chatID),
+			zap.Int("thread_id", threadID),
+			zap.Int64("user_id", c.Sender().ID),
+		)
+
+		available, err := settingsRepo.GetAvailableModules()
+		if err != nil {
+			logger.Error("failed to get available modules", zap.Error(err))
+			return c.Send("❌ Не удалось получить список модулей")
+		}
+
+		var sb strings.Builder
+		if threadID != 0 {
+			sb.WriteString("🧩 <b>Модули (для этого топика):</b>\n\n")
+		} else {
+			sb.WriteString("🧩 <b>Модули (для всего чата):</b>\n\n")
+		}
+
+		for _, m := range registry.Pipeline() {
+			status := "✅"
+			if !moduleStates.IsEnabled(chatID, threadID, m.Name()) {
+				status = "❌"
+			}
+			note := ""
+			if !containsString(available, m.Name()) {
+				note = " <i>(управление недоступно)</i>"
+			}
+			sb.WriteString(fmt.Sprintf("%s <code>%s</code>%s\n", status, m.Name(), note))
+		}
+
+		sb.WriteString("\n💡 <code>/enable &lt;модуль&gt;</code>, <code>/disable &lt;модуль&gt;</code>\n")
+		sb.WriteString("Команда в топике меняет настройку только для топика.")
+
+		return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeHTML})
+	}
+}
+
+// handleSetModuleEnabled возвращает хендлер для /enable (enabled=true) и /disable (enabled=false).
+// Управлять можно только модулями pipeline, перечисленными в bot_settings.available_modules.
+func handleSetModuleEnabled(
+	enabled bool,
+	db *sql.DB,
+	registry *core.Registry,
+	moduleStates *core.ModuleStates,
+	settingsRepo *repositories.SettingsRepository,
+	eventRepo *repositories.EventRepository,
+	logger *zap.Logger,
+) func(tele.Context) error {
+	command := "/disable"
+	eventType := "disable_module"
+	if enabled {
+		command = "/enable"
+		eventType = "enable_module"
+	}
+
+	return func(c tele.Context) error {
+		chatID := c.Chat().ID
+		threadID := core.GetThreadID(db, c)
+
+		logger.Info("handling module toggle command",
+			zap.String("command", command),
+			zap.Int64("chat_id", chatID),
+			zap.Int("thread_id", threadID),
+			zap.Int64("user_id", c.Sender().ID),
+		)
+
+		args := c.Args()
+		if len(args) != 1 {
+			return c.Send(fmt.Sprintf("Использование: %s <модуль>\nСписок модулей: /modules", command))
+		}
+		moduleName := strings.ToLower(args[0])
+
+		m := registry.Get(moduleName)
+		if m == nil || m.Priority() == core.PriorityNone {
+			return c.Send("❌ Неизвестный модуль: " + moduleName + "\nСписок модулей: /modules")
+		}
+
+		available, err := settingsRepo.GetAvailableModules()
+		if err != nil {
+			logger.Error("failed to get available modules", zap.Error(err))
+			return c.Send("❌ Не удалось получить список модулей")
+		}
+		if !containsString(available, moduleName) {
+			return c.Send("❌ Управление модулем " + moduleName + " недоступно")
+		}
+
+		// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+		_, _ = db.Exec(`
+			INSERT INTO chats (chat_id, chat_type, title)
+			VALUES ($1, 'unknown', 'unknown')
+			ON CONFLICT (chat_id) DO NOTHING
+		`, chatID)
+
+		if err := moduleStates.SetEnabled(chatID, threadID, moduleName, enabled, c.Sender().ID); err != nil {
+			logger.Error("failed to set module state", zap.Error(err))
+			return c.Send("❌ Не удалось изменить состояние модуля")
+		}
+
+		_ = eventRepo.Log(chatID, c.Sender().ID, "core", eventType,
+			fmt.Sprintf("%s module %s (chat=%d, thread=%d)", command, moduleName, chatID, threadID))
+
+		scope := "этого топика"
+		if threadID == 0 {
+			scope = "всего чата"
+		}
+
+		if enabled {
+			return c.Send(fmt.Sprintf("✅ Модуль %s включён для %s", moduleName, scope))
+		}
+
+		msg := fmt.Sprintf("✅ Модуль %s выключен для %s", moduleName, scope)
+		if moduleName == "statistics" {
+			msg += "\n\n⚠️ Limiter и фильтр мата считают сообщения по статистике — без неё лимиты не срабатывают."
+		}
+		return c.Send(msg)
+	}
+}
+
+// containsString проверяет наличие строки в срезе.
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
@@ -18,6 +17,7 @@ import (
 	"github.com/flybasist/bmft/internal/config"
 	"github.com/flybasist/bmft/internal/core"
 	"github.com/flybasist/bmft/internal/logx"
+	"github.com/flybasist/bmft/internal/metrics"
 	"github.com/flybasist/bmft/internal/migrations"
 	"github.com/flybasist/bmft/internal/postgresql"
 	"github.com/flybasist/bmft/internal/postgresql/repositories"
@@ -33,7 +33,7 @@ func main() {
 	// 2. Инициализируем логгер
 	// 3. Подключаемся к PostgreSQL
 	// 4. Автоматически применяем миграции
-	// 5. Создаём telebot.v3 бота с Long Polling
+	// 5. Создаём telebot.v3 бота (Long Polling или webhook)
 	// 6. Создаём и инициализируем модули
 	// 7. Регистрируем команды модулей
 	// 8. Регистрируем pipeline обработки сообщений
@@ -81,6 +81,7 @@ func run() error {
 		zap.String("timezone", tz),
 		zap.Duration("shutdown_timeout", cfg.ShutdownTimeout),
 		zap.Int("polling_timeout", cfg.PollingTimeout),
+		zap.Bool("webhook", cfg.WebhookEnabled()),
 		zap.Int("log_max_size_mb", cfg.LogMaxSizeMB),
 		zap.Int("log_max_backups", cfg.LogMaxBackups),
 		zap.Int("log_max_age_days", cfg.LogMaxAgeDays),
@@ -88,7 +89,8 @@ func run() error {
 	)
 
 	// Подключаемся к PostgreSQL
-	db, err := sql.Open("postgres", cfg.PostgresDSN)
+	// Пул соединений с метриками длительности запросов (bmft_db_query_duration_seconds)
+	db, err := postgresql.Open(cfg.PostgresDSN)
 	if err != nil {
 		return fmt.Errorf("failed to connect to postgres: %w", err)
 	}
@@ -120,16 +122,33 @@ func run() error {
 		logger.Warn("failed to load profanity dictionary", zap.Error(err))
 	}
 
-	// Создаём telebot.v3 бота с Long Polling
+	// Создаём telebot.v3 бота: Long Polling по умолчанию, webhook при заданном WEBHOOK_URL
+	poller, webhook := newPoller(cfg, logger)
+	// MiddlewarePoller видит все обновления (в т.ч. без хендлера) — считаем их по типу.
+	// Транспорт HTTP-клиента считает ошибки Bot API (bmft_telegram_api_errors_total).
 	pref := tele.Settings{
-		Token:  cfg.TelegramBotToken,
-		Poller: &tele.LongPoller{Timeout: time.Duration(cfg.PollingTimeout) * time.Second},
+		Token: cfg.TelegramBotToken,
+		Poller: tele.NewMiddlewarePoller(poller, func(u *tele.Update) bool {
+			metrics.ObserveUpdate(u)
+			return true
+		}),
+		Client: &http.Client{
+			Timeout:   time.Minute,
+			Transport: metrics.NewTelegramTransport(http.DefaultTransport),
+		},
 	}
 	bot, err := tele.NewBot(pref)
 	if err != nil {
 		return fmt.Errorf("failed to create bot: %w", err)
 	}
 
+	if !cfg.WebhookEnabled() {
+		// После работы в webhook-режиме getUpdates возвращает 409, пока webhook не снят
+		if err := bot.RemoveWebhook(); err != nil {
+			logger.Warn("failed to remove webhook before long polling", zap.Error(err))
+		}
+	}
+
 	logger.Info("bot created successfully",
 		zap.String("bot_username", bot.Me.Username),
 		zap.Int64("bot_id", bot.Me.ID),
@@ -143,9 +162,17 @@ func run() error {
 	bot.Use(core.LoggerMiddleware(logger))
 	bot.Use(core.PanicRecoveryMiddleware(logger))
 
+	// Кэш per-chat состояний модулей (/enable, /disable)
+	moduleStates := core.NewModuleStates(repositories.NewModuleStateRepository(db), 60*time.Second, logger)
+
+	// Соединение LISTEN для сброса кэшей модулей по уведомлениям PostgreSQL (antiflood, reactions).
+	// Одно на все модули; закрывается после остановки модулей, а не в их Shutdown
+	listener := postgresql.NewListener(cfg.PostgresDSN, logger)
+
 	// Создаём все модули
-	modules, err := initModules(db, bot, logger, cfg)
+	registry, err := initModules(db, bot, logger, cfg, moduleStates, listener)
 	if err != nil {
+		_ = listener.Close()
 		return fmt.Errorf("failed to init modules: %w", err)
 	}
 
@@ -164,7 +191,7 @@ func run() error {
 	}
 
 	// Регистрируем базовые команды
-	registerCommands(bot, chatRepo, eventRepo, logger, botVersion)
+	registerCommands(bot, db, chatRepo, eventRepo, settingsRepo, registry, moduleStates, logger, botVersion)
 
 	// Создаём контекст для graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -174,23 +201,42 @@ func run() error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Запускаем HTTP health-сервер для Docker healthcheck
+	// Запускаем HTTP сервер: /healthz для Docker healthcheck (процесс жив),
+	// /readyz для оркестратора (БД, миграции, Telegram, cron), /metrics для Prometheus
 	healthMux := http.NewServeMux()
 	healthMux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("ok"))
 	})
+	healthMux.Handle("/readyz", readyzHandler(db, registry, cfg, webhook, logger))
+	healthMux.Handle("/metrics", metrics.Handler())
 	healthServer := &http.Server{Addr: cfg.MetricsAddr, Handler: healthMux}
-	go func() {
-		logger.Info("health server started", zap.String("addr", cfg.MetricsAddr))
-		if err := healthServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("health server failed", zap.Error(err))
+
+	// Webhook монтируется на тот же сервер рядом с /healthz, если адреса совпадают,
+	// иначе поднимается отдельный сервер (TLS — только на отдельном, см. config.validateWebhook).
+	// До запуска bot.Start() обработчик webhook'а отвечает 503 — Telegram повторит доставку
+	var webhookServer *http.Server
+	if cfg.WebhookEnabled() {
+		if cfg.WebhookListen == cfg.MetricsAddr {
+			healthMux.Handle(cfg.WebhookPath, webhook)
+		} else {
+			webhookMux := http.NewServeMux()
+			webhookMux.Handle(cfg.WebhookPath, webhook)
+			webhookServer = &http.Server{Addr: cfg.WebhookListen, Handler: webhookMux}
+			startHTTPServer(webhookServer, cfg.WebhookTLSCert, cfg.WebhookTLSKey, "webhook", logger)
 		}
-	}()
+	}
+	startHTTPServer(healthServer, "", "", "health", logger)
 
 	// Запускаем бота в отдельной горутине
 	go func() {
-		logger.Info("bot started, polling for updates...")
+		if cfg.WebhookEnabled() {
+			logger.Info("bot started, receiving updates via webhook...",
+				zap.String("listen", cfg.WebhookListen),
+				zap.String("path", cfg.WebhookPath))
+		} else {
+			logger.Info("bot started, polling for updates...")
+		}
 		bot.Start()
 	}()
 
@@ -209,14 +255,23 @@ func run() error {
 		if err := healthServer.Shutdown(shutdownCtx); err != nil {
 			logger.Error("failed to shutdown health server", zap.Error(err))
 		}
+		if webhookServer != nil {
+			logger.Info("shutting down webhook server...")
+			if err := webhookServer.Shutdown(shutdownCtx); err != nil {
+				logger.Error("failed to shutdown webhook server", zap.Error(err))
+			}
+		}
 
 		logger.Info("shutting down bot...")
 		bot.Stop()
 
 		logger.Info("shutting down modules...")
-		if err := modules.shutdownModules(logger); err != nil {
+		if err := registry.ShutdownAll(); err != nil {
 			logger.Error("failed to shutdown modules", zap.Error(err))
 		}
+		if err := listener.Close(); err != nil {
+			logger.Error("failed to close postgres listener", zap.Error(err))
+		}
 
 		// db.Close() вызывается через defer в run() — дублировать не нужно
 
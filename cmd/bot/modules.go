@@ -3,138 +3,132 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/flybasist/bmft/internal/config"
 	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/metrics"
+	"github.com/flybasist/bmft/internal/modules/antiflood"
+	"github.com/flybasist/bmft/internal/modules/antiraid"
+	"github.com/flybasist/bmft/internal/modules/captcha"
 	"github.com/flybasist/bmft/internal/modules/limiter"
 	"github.com/flybasist/bmft/internal/modules/maintenance"
+	"github.com/flybasist/bmft/internal/modules/moderation"
+	"github.com/flybasist/bmft/internal/modules/probation"
 	"github.com/flybasist/bmft/internal/modules/reactions"
 	"github.com/flybasist/bmft/internal/modules/scheduler"
 	"github.com/flybasist/bmft/internal/modules/statistics"
+	"github.com/flybasist/bmft/internal/postgresql"
 	"github.com/flybasist/bmft/internal/postgresql/repositories"
 	"go.uber.org/zap"
 	tele "gopkg.in/telebot.v3"
 )
 
-// Modules содержит все модули бота.
-// Явная структура со всеми модулями.
-// Нет магии registry, все модули видны напрямую.
+// builtinModules создаёт встроенные модули бота.
+// Порядок в pipeline задаёт Priority() модуля, а не порядок в этом списке.
+// Собственный модуль команды подключается одной строкой здесь —
+// достаточно реализовать core.Module (и опционально core.AdminCommandsRegistrar).
 // TextFilter и ProfanityFilter объединены в Reactions (v1.1).
-type Modules struct {
-	Limiter     *limiter.LimiterModule
-	Statistics  *statistics.StatisticsModule
-	Reactions   *reactions.ReactionsModule
-	Scheduler   *scheduler.SchedulerModule
-	Maintenance *maintenance.MaintenanceModule
-}
-
-// initModules создаёт и инициализирует все модули бота.
-// Централизованная инициализация всех модулей.
-// Возвращает структуру Modules со всеми готовыми к работе модулями.
-func initModules(db *sql.DB, bot *tele.Bot, logger *zap.Logger, cfg *config.Config) (*Modules, error) {
-	logger.Info("initializing modules")
-
+// listener — общее соединение LISTEN, им владеет run: модули только подписываются.
+func builtinModules(db *sql.DB, bot *tele.Bot, logger *zap.Logger, cfg *config.Config, listener *postgresql.Listener) []core.Module {
 	// Создаём репозитории
 	eventRepo := repositories.NewEventRepository(db)
 	vipRepo := repositories.NewVIPRepository(db)
 	contentLimitsRepo := repositories.NewContentLimitsRepository(db)
 	schedulerRepo := repositories.NewSchedulerRepository(db)
 	messageRepo := repositories.NewMessageRepository(db, logger)
+	captchaRepo := repositories.NewCaptchaRepository(db)
+	warnRepo := repositories.NewWarningRepository(db)
+	punishRepo := repositories.NewPunishmentRepository(db)
+	reportRepo := repositories.NewReportRepository(db)
+	antifloodRepo := repositories.NewAntifloodRepository(db)
+	raidRepo := repositories.NewRaidRepository(db)
+	linkFilterRepo := repositories.NewLinkFilterRepository(db)
+	forwardRepo := repositories.NewForwardRepository(db)
+	probationRepo := repositories.NewProbationRepository(db)
+	chatStateRepo := repositories.NewChatStateRepository(db)
+	profanityRepo := repositories.NewProfanityRepository(db)
+	shadowRepo := repositories.NewShadowRepository(db)
+
+	// moderation выдаёт предупреждения за нарушения, найденные limiter, reactions и antiflood (core.Warner),
+	// и наказывает флудеров, нарушителей лимитов и участников рейда (core.Punisher);
+	// captcha возвращает права через него же, не снимая наказаний
+	moderationModule := moderation.New(db, warnRepo, punishRepo, eventRepo, reportRepo, logger, bot)
+
+	// antiflood и reactions сбрасывают кэши (настройки антифлуда, правила keyword_reactions)
+	// по уведомлениям PostgreSQL через listener — несколько экземпляров бота видят
+	// изменения /setflood, slow mode, /addreaction и /addban сразу.
+	// antiflood включает slow mode по задачам scheduler (core.SlowModer)
+	antifloodModule := antiflood.New(db, antifloodRepo, vipRepo, eventRepo, chatStateRepo, listener, moderationModule, moderationModule, logger, bot)
 
-	// Создаём модули
 	// messageRepo — единый экземпляр для всех модулей (statistics, limiter, reactions).
 	// Раньше каждый модуль создавал свой NewMessageRepository — 3 одинаковых объекта на одну БД.
-	modules := &Modules{
-		Statistics:  statistics.New(db, eventRepo, messageRepo, logger, bot),
-		Limiter:     limiter.New(db, vipRepo, contentLimitsRepo, messageRepo, eventRepo, logger, bot),
-		Scheduler:   scheduler.New(db, schedulerRepo, eventRepo, logger, bot),
-		Reactions:   reactions.New(db, vipRepo, contentLimitsRepo, messageRepo, eventRepo, logger, bot),
-		Maintenance: maintenance.New(db, logger, cfg.DBRetentionMonths),
+	return []core.Module{
+		statistics.New(db, eventRepo, messageRepo, logger, bot),
+		antifloodModule,
+		// probation — первый JoinHandler: время вступления записывается и для участников рейда
+		probation.New(db, probationRepo, vipRepo, eventRepo, logger, bot),
+		limiter.New(db, vipRepo, contentLimitsRepo, forwardRepo, messageRepo, eventRepo, shadowRepo, moderationModule, moderationModule, logger, bot),
+		scheduler.New(db, schedulerRepo, chatStateRepo, eventRepo, antifloodModule, logger, bot),
+		reactions.New(db, vipRepo, contentLimitsRepo, messageRepo, eventRepo, linkFilterRepo, profanityRepo, shadowRepo, listener, moderationModule, logger, bot),
+		maintenance.New(db, logger, cfg.DBRetentionMonths),
+		// antiraid — до captcha: JoinHandler вызываются в порядке регистрации,
+		// участник рейда помечается Handled и капчу не получает
+		antiraid.New(db, raidRepo, eventRepo, moderationModule, logger, bot),
+		captcha.New(db, captchaRepo, eventRepo, moderationModule, logger, bot),
+		moderationModule,
 	}
+}
+
+// initModules создаёт реестр модулей, запускает их, регистрирует команды и pipeline.
+// Возвращает реестр — через него main выполняет graceful shutdown.
+// moduleStates — per-chat включение/выключение модулей (проверяется в wrapModuleMiddleware).
+func initModules(db *sql.DB, bot *tele.Bot, logger *zap.Logger, cfg *config.Config, moduleStates *core.ModuleStates, listener *postgresql.Listener) (*core.Registry, error) {
+	logger.Info("initializing modules")
 
-	// Запускаем scheduler (явный старт жизненного цикла)
-	logger.Info("starting scheduler module")
-	if err := modules.Scheduler.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start scheduler: %w", err)
+	registry := core.NewRegistry(logger)
+	for _, m := range builtinModules(db, bot, logger, cfg, listener) {
+		if err := registry.Register(m); err != nil {
+			return nil, fmt.Errorf("failed to register module: %w", err)
+		}
 	}
 
-	// Запускаем maintenance (автоматическая ротация данных)
-	logger.Info("starting maintenance module")
-	if err := modules.Maintenance.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start maintenance: %w", err)
+	// Явный старт жизненного цикла (scheduler и maintenance запускают cron)
+	if err := registry.StartAll(); err != nil {
+		return nil, err
 	}
 
 	// Регистрируем команды всех модулей
 	logger.Info("registering module commands")
-
-	// Statistics
-	modules.Statistics.RegisterCommands(bot)
-	modules.Statistics.RegisterAdminCommands(bot)
-
-	// Limiter
-	modules.Limiter.RegisterCommands(bot)
-	modules.Limiter.RegisterAdminCommands(bot)
-
-	// Scheduler
-	modules.Scheduler.RegisterCommands(bot)
-	modules.Scheduler.RegisterAdminCommands(bot)
-
-	// Reactions (включает фильтры и мат)
-	modules.Reactions.RegisterCommands(bot)
-	modules.Reactions.RegisterAdminCommands(bot)
+	registry.RegisterCommands(bot)
 
 	logger.Info("all modules initialized successfully")
 
 	// Регистрируем pipeline обработки сообщений
 	logger.Info("registering message pipeline")
-	registerPipeline(bot, modules, db, logger)
+	registerPipeline(bot, registry, moduleStates, db, logger)
 
-	return modules, nil
+	return registry, nil
 }
 
-// shutdownModules выполняет graceful shutdown всех модулей.
-// Scheduler и Maintenance требуют явного shutdown (остановка cron).
-// Остальные модули stateless и не требуют очистки ресурсов.
-func (m *Modules) shutdownModules(logger *zap.Logger) error {
-	logger.Info("shutting down modules")
-
-	// Останавливаем все модули последовательно.
-	// НЕ прерываемся при ошибке — каждый модуль должен получить шанс на shutdown.
-	// Раньше ошибка Scheduler.Shutdown() блокировала остановку Maintenance.
-	var firstErr error
-
-	if err := m.Scheduler.Shutdown(); err != nil {
-		logger.Error("failed to shutdown scheduler", zap.Error(err))
-		firstErr = err
-	}
-
-	if err := m.Maintenance.Shutdown(); err != nil {
-		logger.Error("failed to shutdown maintenance", zap.Error(err))
-		if firstErr == nil {
-			firstErr = err
-		}
-	}
-
-	logger.Info("all modules shutdown complete")
-	return firstErr
-}
-
-// registerPipeline регистрирует явный pipeline обработки сообщений через bot.Use().
-// Каждый модуль регистрируется как middleware в правильном порядке.
-// ВАЖНО: Порядок модулей критичен!
+// registerPipeline регистрирует pipeline обработки сообщений через bot.Use().
+// Модули встраиваются в порядке Priority (см. core.Registry.Pipeline).
+// ВАЖНО: Порядок встроенных модулей критичен!
 // 1. Statistics — записывает все сообщения в таблицу messages
-// 2. Limiter — проверяет лимиты контента, может удалить сообщение
-// 3. Reactions — фильтры (мат, бан-слова) + автоответы на ключевые слова
+// 2. Antiflood — частота сообщений (окна в памяти), может удалить сообщение
+// 3. Limiter — проверяет лимиты контента, может удалить сообщение
+// 4. Reactions — фильтры (мат, бан-слова) + автоответы на ключевые слова
 //
 // ThreadID вычисляется один раз в первом middleware и кешируется через c.Set (−2 SQL-запроса).
 // MessageDeleted пропагируется через c.Set: если Limiter удалил сообщение,
 // Reactions видит MessageDeleted=true и считает мат без повторного удаления.
-func registerPipeline(bot *tele.Bot, modules *Modules, db *sql.DB, logger *zap.Logger) {
-	bot.Use(wrapModuleMiddleware(modules.Statistics.OnMessage, "statistics", db, logger))
-	bot.Use(wrapModuleMiddleware(modules.Limiter.OnMessage, "limiter", db, logger))
-	bot.Use(wrapModuleMiddleware(modules.Reactions.OnMessage, "reactions", db, logger))
+func registerPipeline(bot *tele.Bot, registry *core.Registry, moduleStates *core.ModuleStates, db *sql.DB, logger *zap.Logger) {
+	pipeline := registry.Pipeline()
+	for _, m := range pipeline {
+		bot.Use(wrapModuleMiddleware(m.OnMessage, m.Name(), moduleStates, db, logger))
+	}
 
-	logger.Info("message pipeline registered", zap.Int("modules", 3))
+	logger.Info("message pipeline registered", zap.Int("modules", len(pipeline)))
 }
 
 // wrapModuleMiddleware конвертирует функцию Module.OnMessage в telebot.MiddlewareFunc.
@@ -144,15 +138,25 @@ func registerPipeline(bot *tele.Bot, modules *Modules, db *sql.DB, logger *zap.L
 // 1. ThreadID вычисляется один раз (первый модуль), кешируется для остальных
 // 2. MessageDeleted пропагируется между модулями через c.Set/c.Get
 // 3. ВСЕГДА вызывает next(c) — каждый модуль получает шанс обработать сообщение
+// 4. Модуль, выключенный в чате/топике (/disable), пропускается
+// 5. Время OnMessage и ошибки модуля попадают в метрики (bmft_module_on_message_*)
+// 6. Новые и отредактированные сообщения (ctx.IsEdit) идут по одному pipeline, callback-и — нет
 func wrapModuleMiddleware(
 	onMessage func(*core.MessageContext) error,
 	moduleName string,
+	moduleStates *core.ModuleStates,
 	db *sql.DB,
 	logger *zap.Logger,
 ) tele.MiddlewareFunc {
 	return func(next tele.HandlerFunc) tele.HandlerFunc {
 		return func(c tele.Context) error {
-			msg := c.Message()
+			// c.Message() для callback-а вернёт сообщение бота с кнопкой — берём только
+			// сообщения пользователей: новые и отредактированные
+			update := c.Update()
+			msg, isEdit := update.Message, false
+			if msg == nil && update.EditedMessage != nil {
+				msg, isEdit = update.EditedMessage, true
+			}
 			if msg == nil {
 				return next(c) // пропускаем не-сообщения
 			}
@@ -167,6 +171,12 @@ func wrapModuleMiddleware(
 				c.Set("pipelineThreadID", threadID)
 			}
 
+			// Модуль выключен в этом чате/топике через /disable — пропускаем.
+			// В ЛС настройки не задаются, проверка не нужна.
+			if !msg.Private() && !moduleStates.IsEnabled(msg.Chat.ID, threadID, moduleName) {
+				return next(c)
+			}
+
 			// MessageDeleted пропагируется между модулями.
 			// Если Limiter удалил сообщение, Reactions увидит и скорректирует поведение.
 			messageDeleted := false
@@ -181,9 +191,14 @@ func wrapModuleMiddleware(
 				Bot:            c.Bot(),
 				ThreadID:       threadID,
 				MessageDeleted: messageDeleted,
+				IsEdit:         isEdit,
 			}
 
-			if err := onMessage(ctx); err != nil {
+			start := time.Now()
+			err := onMessage(ctx)
+			metrics.ModuleDuration.WithLabelValues(moduleName).Observe(time.Since(start).Seconds())
+			if err != nil {
+				metrics.ModuleErrorsTotal.WithLabelValues(moduleName).Inc()
 				logger.Error("module failed to process message",
 					zap.String("module", moduleName),
 					zap.Int64("chat_id", msg.Chat.ID),
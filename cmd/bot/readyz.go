@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/flybasist/bmft/internal/config"
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/metrics"
+	"github.com/flybasist/bmft/internal/migrations"
+)
+
+// readyzTimeout — общий таймаут проверок БД, чтобы /readyz не зависал при недоступном PostgreSQL.
+const readyzTimeout = 3 * time.Second
+
+// componentStatus — состояние одного компонента в ответе /readyz.
+type componentStatus struct {
+	Status string `json:"status"`           // ok | fail
+	Detail string `json:"detail,omitempty"` // пояснение (ошибка, версия, возраст последнего getUpdates)
+}
+
+// readyzResponse — тело ответа /readyz.
+type readyzResponse struct {
+	Status     string                     `json:"status"` // ok, если все компоненты ok
+	Components map[string]componentStatus `json:"components"`
+}
+
+// readyzHandler возвращает обработчик /readyz.
+// В отличие от /healthz (процесс жив) проверяет, что инстанс реально может работать:
+// БД отвечает, схема на LatestSchemaVersion, Telegram отдаёт обновления, cron модулей запущен.
+// 200 — готов, 503 — оркестратор должен убрать инстанс из балансировки.
+// webhook — nil в режиме Long Polling.
+func readyzHandler(db *sql.DB, registry *core.Registry, cfg *config.Config, webhook *webhookPoller, logger *zap.Logger) http.HandlerFunc {
+	// Long Polling отвечает не реже чем раз в PollingTimeout; двойной запас на сетевые задержки
+	pollStaleAfter := 2 * time.Duration(cfg.PollingTimeout) * time.Second
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyzTimeout)
+		defer cancel()
+
+		resp := readyzResponse{Status: "ok", Components: make(map[string]componentStatus)}
+		set := func(name string, err error, detail string) {
+			if err != nil {
+				resp.Status = "fail"
+				resp.Components[name] = componentStatus{Status: "fail", Detail: err.Error()}
+				return
+			}
+			resp.Components[name] = componentStatus{Status: "ok", Detail: detail}
+		}
+
+		// PostgreSQL
+		dbErr := db.PingContext(ctx)
+		set("database", dbErr, "")
+
+		// Миграции (без БД проверять нечего)
+		if dbErr != nil {
+			set("migrations", fmt.Errorf("database unavailable"), "")
+		} else if version, err := migrations.CurrentSchemaVersion(ctx, db); err != nil {
+			set("migrations", err, "")
+		} else if version != migrations.LatestSchemaVersion {
+			set("migrations", fmt.Errorf("schema version %d, expected %d", version, migrations.LatestSchemaVersion), "")
+		} else {
+			set("migrations", nil, fmt.Sprintf("version %d", version))
+		}
+
+		// Telegram: в webhook-режиме getUpdates не вызывается — проверяем регистрацию
+		// webhook'а (getWebhookInfo раз в webhookProbeInterval, без запроса на каждый /readyz)
+		if webhook != nil {
+			detail, err := webhook.Status()
+			set("poller", err, detail)
+		} else if last := metrics.LastGetUpdates(); last.IsZero() {
+			set("poller", fmt.Errorf("no successful getUpdates response yet"), "")
+		} else if age := time.Since(last); age > pollStaleAfter {
+			set("poller", fmt.Errorf("last getUpdates response %s ago", age.Round(time.Second)), "")
+		} else {
+			set("poller", nil, fmt.Sprintf("last getUpdates %s ago", age.Round(time.Second)))
+		}
+
+		// Фоновые задачи модулей (scheduler, maintenance)
+		for name, err := range registry.HealthChecks() {
+			set(name, err, "")
+		}
+
+		code := http.StatusOK
+		if resp.Status != "ok" {
+			code = http.StatusServiceUnavailable
+			logger.Warn("readiness check failed", zap.Any("components", resp.Components))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
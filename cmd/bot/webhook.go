@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/config"
+)
+
+// webhookProbeInterval — как часто webhook-режим проверяет регистрацию через getWebhookInfo.
+const webhookProbeInterval = 30 * time.Second
+
+// newPoller создаёт источник обновлений: webhook (если задан WEBHOOK_URL) или Long Polling.
+// Для webhook'а возвращается также сам webhookPoller: его HTTP-обработчик нужно смонтировать
+// на WebhookPath, а Status() использует /readyz.
+func newPoller(cfg *config.Config, logger *zap.Logger) (tele.Poller, *webhookPoller) {
+	if !cfg.WebhookEnabled() {
+		return &tele.LongPoller{Timeout: time.Duration(cfg.PollingTimeout) * time.Second}, nil
+	}
+
+	// Listen пустой: telebot не поднимает свой сервер, обновления принимает наш HTTP сервер.
+	// Endpoint задан всегда — Telegram должен знать публичный URL, а не адрес за прокси.
+	webhook := &tele.Webhook{
+		SecretToken: cfg.WebhookSecret,
+		Endpoint:    &tele.WebhookEndpoint{PublicURL: cfg.WebhookURL},
+	}
+	if cfg.WebhookTLSEnabled() {
+		// Сертификат загружается в Telegram (нужно для самоподписанных сертификатов)
+		webhook.Endpoint.Cert = cfg.WebhookTLSCert
+	}
+
+	poller := &webhookPoller{webhook: webhook, url: cfg.WebhookURL, secret: cfg.WebhookSecret, logger: logger}
+	return poller, poller
+}
+
+// webhookPoller регистрирует webhook и передаёт принятые обновления боту.
+// HTTP сервер поднимается раньше bot.Start(), а после рестарта Telegram шлёт
+// обновления сразу — до запуска Poll канала обновлений ещё нет, и запросы
+// получают 503 (Telegram повторит доставку). tele.Webhook в этом случае
+// блокируется на nil-канале навсегда.
+type webhookPoller struct {
+	webhook *tele.Webhook
+	url     string
+	secret  string
+	logger  *zap.Logger
+
+	dest atomic.Pointer[chan tele.Update] // nil — Poll не запущен или остановлен
+
+	mu          sync.Mutex
+	probeErr    error     // результат последней проверки getWebhookInfo
+	probeDetail string    // пояснение для /readyz
+	probedAt    time.Time // время последней проверки (zero — ещё не было)
+}
+
+// Poll регистрирует webhook в Telegram и принимает обновления до остановки бота.
+func (p *webhookPoller) Poll(b *tele.Bot, dest chan tele.Update, stop chan struct{}) {
+	if err := b.SetWebhook(p.webhook); err != nil {
+		// Без webhook'а обновления не придут — /readyz сообщит об ошибке
+		p.logger.Error("failed to set webhook", zap.Error(err))
+		p.setStatus(fmt.Errorf("setWebhook: %w", err), "")
+		return
+	}
+
+	p.dest.Store(&dest)
+	go p.probeLoop(b, stop)
+	<-stop
+	p.dest.Store(nil)
+}
+
+// probeLoop периодически проверяет через getWebhookInfo, что токен действителен
+// и webhook зарегистрирован на наш URL (его могли снять или переключить на другой).
+func (p *webhookPoller) probeLoop(b *tele.Bot, stop chan struct{}) {
+	ticker := time.NewTicker(webhookProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		p.probe(b)
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe выполняет одну проверку getWebhookInfo.
+func (p *webhookPoller) probe(b *tele.Bot) {
+	info, err := b.Webhook()
+	switch {
+	case err != nil:
+		p.setStatus(fmt.Errorf("getWebhookInfo: %w", err), "")
+	case info.Listen == "":
+		p.setStatus(errors.New("webhook is not set"), "")
+	case info.Listen != p.url:
+		p.setStatus(fmt.Errorf("webhook is set to another URL: %s", info.Listen), "")
+	default:
+		detail := fmt.Sprintf("webhook set, %d pending updates", info.PendingUpdates)
+		if info.ErrorMessage != "" {
+			// Ошибки доставки бывают временными (прокси перезапускался) — не повод снимать инстанс
+			detail += fmt.Sprintf(", last delivery error at %s: %s",
+				time.Unix(info.ErrorUnixtime, 0).Format(time.RFC3339), info.ErrorMessage)
+		}
+		p.setStatus(nil, detail)
+	}
+}
+
+func (p *webhookPoller) setStatus(err error, detail string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.probeErr, p.probeDetail, p.probedAt = err, detail, time.Now()
+}
+
+// Status возвращает результат последней проверки webhook'а для /readyz.
+func (p *webhookPoller) Status() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case p.probedAt.IsZero():
+		return "", errors.New("webhook is not registered yet")
+	case p.probeErr != nil:
+		return "", p.probeErr
+	}
+	if age := time.Since(p.probedAt); age > 3*webhookProbeInterval {
+		return "", fmt.Errorf("last getWebhookInfo check %s ago", age.Round(time.Second))
+	}
+	return p.probeDetail, nil
+}
+
+// ServeHTTP проверяет метод и secret token до разбора обновления.
+// Сам telebot на неверный токен отвечает 200 — для прокси и мониторинга
+// отказ должен быть виден как 401.
+func (p *webhookPoller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(p.secret)) != 1 {
+		p.logger.Warn("webhook request with invalid secret token",
+			zap.String("remote_addr", r.RemoteAddr))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	dest := p.dest.Load()
+	if dest == nil {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	var update tele.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		p.logger.Warn("failed to decode webhook update", zap.Error(err))
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case *dest <- update:
+	case <-r.Context().Done():
+		// Клиент отключился, пока бот разбирал очередь — Telegram повторит доставку
+	}
+}
+
+// startHTTPServer запускает HTTP сервер в отдельной горутине (с TLS, если переданы cert и key).
+func startHTTPServer(server *http.Server, certFile, keyFile, name string, logger *zap.Logger) {
+	go func() {
+		logger.Info(name+" server started",
+			zap.String("addr", server.Addr),
+			zap.Bool("tls", certFile != ""))
+
+		var err error
+		if certFile != "" {
+			err = server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error(name+" server failed", zap.Error(err))
+		}
+	}()
+}
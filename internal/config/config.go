@@ -3,7 +3,9 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -23,6 +25,14 @@ type Config struct {
 	MetricsAddr      string        // Адрес HTTP сервера метрик /healthz /metrics
 	PollingTimeout   int           // Таймаут Long Polling в секундах (default: 60)
 
+	// Параметры webhook-режима (включается, если задан WEBHOOK_URL)
+	WebhookURL     string // Публичный URL, который регистрируется в Telegram (https://...)
+	WebhookListen  string // Адрес HTTP сервера для приёма обновлений (default: MetricsAddr)
+	WebhookPath    string // Путь обработчика на HTTP сервере (default: путь из WEBHOOK_URL)
+	WebhookSecret  string // Секрет для заголовка X-Telegram-Bot-Api-Secret-Token
+	WebhookTLSCert string // Путь к сертификату (опционально, если TLS не терминируется прокси)
+	WebhookTLSKey  string // Путь к приватному ключу
+
 	// Параметры ротации логов
 	LogMaxSizeMB  int // Максимальный размер файла лога в MB (default: 100)
 	LogMaxBackups int // Количество старых файлов логов (default: 3)
@@ -69,6 +79,14 @@ func Load() (*Config, error) {
 		cfg.PollingTimeout = 60 // default 60 секунд
 	}
 
+	// Webhook-режим
+	cfg.WebhookURL = strings.TrimSpace(os.Getenv("WEBHOOK_URL"))
+	cfg.WebhookListen = firstNonEmpty(strings.TrimSpace(os.Getenv("WEBHOOK_LISTEN_ADDR")), cfg.MetricsAddr)
+	cfg.WebhookPath = strings.TrimSpace(os.Getenv("WEBHOOK_PATH"))
+	cfg.WebhookSecret = strings.TrimSpace(os.Getenv("WEBHOOK_SECRET_TOKEN"))
+	cfg.WebhookTLSCert = strings.TrimSpace(os.Getenv("WEBHOOK_TLS_CERT"))
+	cfg.WebhookTLSKey = strings.TrimSpace(os.Getenv("WEBHOOK_TLS_KEY"))
+
 	// Параметры ротации логов
 	cfg.LogMaxSizeMB = getEnvInt("LOG_MAX_SIZE_MB", 100)
 	cfg.LogMaxBackups = getEnvInt("LOG_MAX_BACKUPS", 3)
@@ -94,6 +112,61 @@ func (c *Config) validate() error {
 	if len(missing) > 0 {
 		return errors.New("missing required env vars: " + strings.Join(missing, ", "))
 	}
+	if c.WebhookEnabled() {
+		return c.validateWebhook()
+	}
+	return nil
+}
+
+// WebhookEnabled — true, если обновления доставляются через webhook, а не Long Polling.
+func (c *Config) WebhookEnabled() bool {
+	return c.WebhookURL != ""
+}
+
+// WebhookTLSEnabled — true, если HTTP сервер webhook'а сам терминирует TLS.
+func (c *Config) WebhookTLSEnabled() bool {
+	return c.WebhookTLSCert != ""
+}
+
+// secretTokenPattern — допустимые символы secret_token по документации Bot API.
+var secretTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// validateWebhook проверяет параметры webhook-режима и вычисляет WebhookPath.
+func (c *Config) validateWebhook() error {
+	u, err := url.Parse(c.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("invalid WEBHOOK_URL: %w", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.New("invalid WEBHOOK_URL: must be an absolute https:// URL")
+	}
+
+	// Без секрета любой, кто знает URL, может слать боту поддельные обновления
+	if c.WebhookSecret == "" {
+		return errors.New("WEBHOOK_SECRET_TOKEN is required when WEBHOOK_URL is set")
+	}
+	if !secretTokenPattern.MatchString(c.WebhookSecret) {
+		return errors.New("invalid WEBHOOK_SECRET_TOKEN: only A-Z, a-z, 0-9, _ and - are allowed (1-256 chars)")
+	}
+
+	if (c.WebhookTLSCert == "") != (c.WebhookTLSKey == "") {
+		return errors.New("WEBHOOK_TLS_CERT and WEBHOOK_TLS_KEY must be set together")
+	}
+	// TLS на общем сервере перевёл бы /healthz на https и сломал бы проверки по http
+	if c.WebhookTLSEnabled() && c.WebhookListen == c.MetricsAddr {
+		return errors.New("WEBHOOK_TLS_CERT requires a separate WEBHOOK_LISTEN_ADDR (METRICS_ADDR must stay plain HTTP)")
+	}
+
+	if c.WebhookPath == "" {
+		c.WebhookPath = u.Path
+	}
+	if !strings.HasPrefix(c.WebhookPath, "/") {
+		c.WebhookPath = "/" + c.WebhookPath
+	}
+	switch c.WebhookPath {
+	case "/healthz", "/readyz", "/metrics":
+		return fmt.Errorf("invalid WEBHOOK_PATH: %s is reserved", c.WebhookPath)
+	}
 	return nil
 }
 
@@ -203,3 +203,149 @@ func TestPollingTimeoutParsing(t *testing.T) {
 		})
 	}
 }
+
+// TestWebhookConfig проверяет параметры webhook-режима
+func TestWebhookConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		expectError   bool
+		errorContains string
+		expectedPath  string
+		expectedAddr  string
+	}{
+		{
+			name: "Webhook disabled by default",
+			env:  map[string]string{},
+		},
+		{
+			name: "Path from URL, listen defaults to METRICS_ADDR",
+			env: map[string]string{
+				"WEBHOOK_URL":          "https://bots.example.com/bmft/hook",
+				"WEBHOOK_SECRET_TOKEN": "s3cret_token-1",
+			},
+			expectedPath: "/bmft/hook",
+			expectedAddr: ":9090",
+		},
+		{
+			name: "Explicit path and listen address",
+			env: map[string]string{
+				"WEBHOOK_URL":          "https://bots.example.com/bmft",
+				"WEBHOOK_SECRET_TOKEN": "secret",
+				"WEBHOOK_PATH":         "telegram",
+				"WEBHOOK_LISTEN_ADDR":  ":8443",
+			},
+			expectedPath: "/telegram",
+			expectedAddr: ":8443",
+		},
+		{
+			name: "Plain HTTP URL",
+			env: map[string]string{
+				"WEBHOOK_URL":          "http://bots.example.com/hook",
+				"WEBHOOK_SECRET_TOKEN": "secret",
+			},
+			expectError:   true,
+			errorContains: "https://",
+		},
+		{
+			name: "Missing secret",
+			env: map[string]string{
+				"WEBHOOK_URL": "https://bots.example.com/hook",
+			},
+			expectError:   true,
+			errorContains: "WEBHOOK_SECRET_TOKEN",
+		},
+		{
+			name: "Invalid secret characters",
+			env: map[string]string{
+				"WEBHOOK_URL":          "https://bots.example.com/hook",
+				"WEBHOOK_SECRET_TOKEN": "bad secret!",
+			},
+			expectError:   true,
+			errorContains: "WEBHOOK_SECRET_TOKEN",
+		},
+		{
+			name: "Cert without key",
+			env: map[string]string{
+				"WEBHOOK_URL":          "https://bots.example.com/hook",
+				"WEBHOOK_SECRET_TOKEN": "secret",
+				"WEBHOOK_TLS_CERT":     "/etc/bmft/cert.pem",
+			},
+			expectError:   true,
+			errorContains: "WEBHOOK_TLS_KEY",
+		},
+		{
+			name: "TLS on METRICS_ADDR",
+			env: map[string]string{
+				"WEBHOOK_URL":          "https://bots.example.com/hook",
+				"WEBHOOK_SECRET_TOKEN": "secret",
+				"WEBHOOK_TLS_CERT":     "/etc/bmft/cert.pem",
+				"WEBHOOK_TLS_KEY":      "/etc/bmft/key.pem",
+			},
+			expectError:   true,
+			errorContains: "WEBHOOK_LISTEN_ADDR",
+		},
+		{
+			name: "TLS on separate listen address",
+			env: map[string]string{
+				"WEBHOOK_URL":          "https://bots.example.com/hook",
+				"WEBHOOK_SECRET_TOKEN": "secret",
+				"WEBHOOK_LISTEN_ADDR":  ":8443",
+				"WEBHOOK_TLS_CERT":     "/etc/bmft/cert.pem",
+				"WEBHOOK_TLS_KEY":      "/etc/bmft/key.pem",
+			},
+			expectedPath: "/hook",
+			expectedAddr: ":8443",
+		},
+		{
+			name: "Reserved path",
+			env: map[string]string{
+				"WEBHOOK_URL":          "https://bots.example.com/healthz",
+				"WEBHOOK_SECRET_TOKEN": "secret",
+			},
+			expectError:   true,
+			errorContains: "/healthz",
+		},
+	}
+
+	webhookVars := []string{"WEBHOOK_URL", "WEBHOOK_SECRET_TOKEN", "WEBHOOK_PATH", "WEBHOOK_LISTEN_ADDR", "WEBHOOK_TLS_CERT", "WEBHOOK_TLS_KEY"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("TELEGRAM_BOT_TOKEN", "test")
+			os.Setenv("POSTGRES_DSN", "postgres://localhost/test")
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+			}
+
+			cfg, err := Load()
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				} else if !strings.Contains(err.Error(), tt.errorContains) {
+					t.Errorf("Expected error containing '%s', got '%v'", tt.errorContains, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Load() failed: %v", err)
+				}
+				if cfg.WebhookEnabled() != (tt.expectedPath != "") {
+					t.Errorf("Expected WebhookEnabled()=%v, got %v", tt.expectedPath != "", cfg.WebhookEnabled())
+				}
+				if tt.expectedPath != "" && cfg.WebhookPath != tt.expectedPath {
+					t.Errorf("Expected WebhookPath='%s', got '%s'", tt.expectedPath, cfg.WebhookPath)
+				}
+				if tt.expectedAddr != "" && cfg.WebhookListen != tt.expectedAddr {
+					t.Errorf("Expected WebhookListen='%s', got '%s'", tt.expectedAddr, cfg.WebhookListen)
+				}
+			}
+
+			os.Unsetenv("TELEGRAM_BOT_TOKEN")
+			os.Unsetenv("POSTGRES_DSN")
+			for _, k := range webhookVars {
+				os.Unsetenv(k)
+			}
+		})
+	}
+}
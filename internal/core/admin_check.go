@@ -95,11 +95,18 @@ func (ac *AdminChecker) IsAdmin(chat *tele.Chat, userID int64) (bool, error) {
 // adminCommands — список команд, требующих прав администратора.
 // Если команда в этом списке и вызвана не-админом — middleware молча удаляет сообщение.
 var adminCommands = map[string]bool{
+	// core
+	"/modules": true,
+	"/enable":  true,
+	"/disable": true,
 	// limiter
-	"/setlimit":  true,
-	"/setvip":    true,
-	"/removevip": true,
-	"/listvips":  true,
+	"/setlimit":      true,
+	"/setvip":        true,
+	"/removevip":     true,
+	"/listvips":      true,
+	"/setforward":    true,
+	"/allowforward":  true,
+	"/removeforward": true,
 	// statistics
 	"/chatstats": true,
 	"/topchat":   true,
@@ -110,14 +117,51 @@ var adminCommands = map[string]bool{
 	"/addban":          true,
 	"/listbans":        true,
 	"/removeban":       true,
+	"/banshadow":       true,
 	"/setprofanity":    true,
 	"/removeprofanity": true,
 	"/profanitystatus": true,
+	"/profanityallow":  true,
+	"/profanityadd":    true,
+	"/profanityremove": true,
+	"/profanitylist":   true,
+	"/setlinks":        true,
+	"/removelinks":     true,
+	"/allowdomain":     true,
+	"/denydomain":      true,
+	"/removedomain":    true,
+	"/linkstatus":      true,
+	"/testfilter":      true,
+	"/shadowreport":    true,
 	// scheduler
 	"/listtasks": true,
 	"/addtask":   true,
 	"/deltask":   true,
 	"/runtask":   true,
+	// captcha
+	"/setcaptcha": true,
+	// moderation
+	"/warn":          true,
+	"/unwarn":        true,
+	"/resetwarns":    true,
+	"/setwarnpolicy": true,
+	"/setautowarn":   true,
+	"/mute":          true,
+	"/tmute":         true,
+	"/unmute":        true,
+	"/ban":           true,
+	"/tban":          true,
+	"/unban":         true,
+	"/kick":          true,
+	"/reports":       true,
+	"/setreport":     true,
+	// antiflood
+	"/setflood": true,
+	// antiraid
+	"/raidmode": true,
+	// probation
+	"/setprobation": true,
+	"/trust":        true,
 }
 
 // AdminOnlyMiddleware блокирует вызов админских команд не-админами.
@@ -126,8 +170,9 @@ var adminCommands = map[string]bool{
 func AdminOnlyMiddleware(ac *AdminChecker, logger *zap.Logger) tele.MiddlewareFunc {
 	return func(next tele.HandlerFunc) tele.HandlerFunc {
 		return func(c tele.Context) error {
+			// Отредактированная команда не выполняется (OnEdited), удалять её незачем
 			msg := c.Message()
-			if msg == nil {
+			if msg == nil || c.Update().EditedMessage != nil {
 				return next(c)
 			}
 
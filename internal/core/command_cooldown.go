@@ -45,8 +45,9 @@ func CommandCooldownMiddleware(cooldown time.Duration, logger *zap.Logger) tele.
 
 	return func(next tele.HandlerFunc) tele.HandlerFunc {
 		return func(c tele.Context) error {
+			// Редактирование команды — не повторный вызов (хендлер OnEdited её не выполняет)
 			msg := c.Message()
-			if msg == nil {
+			if msg == nil || c.Update().EditedMessage != nil {
 				return next(c)
 			}
 
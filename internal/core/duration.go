@@ -0,0 +1,74 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationUnits — суффиксы длительности в командах: 30m, 1h, 2d, 1w.
+var durationUnits = map[byte]time.Duration{
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// maxDuration — максимальная длительность наказания.
+const maxDuration = 365 * 24 * time.Hour
+
+// ParseDuration разбирает длительность наказания: число + m/h/d/w.
+// Telegram считает ограничения короче 30 сек и длиннее 366 дней бессрочными,
+// поэтому допустимый диапазон — от 1 минуты до 365 дней.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return 0, fmt.Errorf("неверная длительность %q (примеры: 30m, 1h, 2d, 1w)", s)
+	}
+
+	unit, ok := durationUnits[s[len(s)-1]]
+	if !ok {
+		return 0, fmt.Errorf("неверная единица в %q (m, h, d, w)", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("неверная длительность %q (примеры: 30m, 1h, 2d, 1w)", s)
+	}
+
+	// Проверка до умножения: time.Duration(n) * unit переполняется для больших n
+	// (15251w — отрицательная длительность, а она означает «навсегда»)
+	if n > int(maxDuration/unit) {
+		return 0, fmt.Errorf("длительность больше 365 дней")
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// FormatDuration форматирует длительность для сообщений бота: «2 д.», «1 ч. 30 мин.».
+// 0 = навсегда.
+func FormatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "навсегда"
+	}
+
+	days := int(d / (24 * time.Hour))
+	d -= time.Duration(days) * 24 * time.Hour
+	hours := int(d / time.Hour)
+	d -= time.Duration(hours) * time.Hour
+	minutes := int(d / time.Minute)
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%d д.", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%d ч.", hours))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%d мин.", minutes))
+	}
+	if len(parts) == 0 {
+		return "меньше минуты"
+	}
+	return strings.Join(parts, " ")
+}
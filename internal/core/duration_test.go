@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseDuration проверяет разбор длительности наказаний
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  time.Duration
+		expectErr bool
+	}{
+		{"30m", 30 * time.Minute, false},
+		{"1h", time.Hour, false},
+		{"2d", 48 * time.Hour, false},
+		{"1w", 7 * 24 * time.Hour, false},
+		{" 2D ", 48 * time.Hour, false},
+		{"365d", 365 * 24 * time.Hour, false},
+		{"52w", 52 * 7 * 24 * time.Hour, false},
+		{"366d", 0, true},
+		{"53w", 0, true},
+		{"525601m", 0, true},
+		{"15251w", 0, true},               // переполнение time.Duration в отрицательное
+		{"9223372036854775807m", 0, true}, // переполнение в ноль и дальше
+		{"0m", 0, true},
+		{"-5m", 0, true},
+		{"m", 0, true},
+		{"", 0, true},
+		{"10", 0, true},
+		{"10s", 0, true},
+		{"1.5h", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := ParseDuration(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("ParseDuration(%q) = %v, expected error", tt.input, d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDuration(%q) failed: %v", tt.input, err)
+			}
+			if d != tt.expected {
+				t.Errorf("ParseDuration(%q) = %v, expected %v", tt.input, d, tt.expected)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"database/sql"
 	"encoding/json"
+	"regexp"
 	"strconv"
 
 	"gopkg.in/telebot.v3"
@@ -76,6 +77,34 @@ func GetThreadIDFromMessage(db *sql.DB, msg *telebot.Message) int {
 	return msg.ThreadID
 }
 
+// PlainLinkRe — ссылки в тексте, для которых Telegram мог не создать entity:
+// со схемой, с www. и ссылки t.me без схемы.
+var PlainLinkRe = regexp.MustCompile(`(?i)(?:https?://|tg://|www\.)[^\s<>"']+|\b(?:t\.me|telegram\.me|telegram\.dog)/[^\s<>"']+`)
+
+// HasLink сообщает, есть ли в тексте или подписи сообщения ссылка
+// (entity url, text_link или ссылка по PlainLinkRe).
+func HasLink(msg *telebot.Message) bool {
+	text, entities := msg.Text, msg.Entities
+	if msg.Caption != "" {
+		text, entities = msg.Caption, msg.CaptionEntities
+	}
+	for _, e := range entities {
+		if e.Type == telebot.EntityURL || e.Type == telebot.EntityTextLink {
+			return true
+		}
+	}
+	return PlainLinkRe.MatchString(text)
+}
+
+// IsForward сообщает, что сообщение переслано из другого чата или от другого пользователя.
+// Автопересылка поста из привязанного канала в группу обсуждения пересылкой не считается.
+func IsForward(msg *telebot.Message) bool {
+	if msg.AutomaticForward {
+		return false
+	}
+	return msg.Origin != nil || msg.IsForwarded() || msg.OriginalSenderName != ""
+}
+
 // DetectContentType определяет тип контента сообщения.
 // Общая функция для определения типа контента.
 // Используется в модулях limiter и statistics.
@@ -1,18 +1,24 @@
 package core
 
 import (
+	"time"
+
 	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/metrics"
 )
 
 // MessageContext — контекст входящего сообщения для модулей pipeline.
 // Передаётся модулям в явном pipeline:
 //
-//	statistics → limiter → reactions
+//	statistics → antiflood → limiter → reactions
 //
 // Reactions включает: фильтр мата, фильтр запрещённых слов, автоответы.
 // ThreadID вычисляется один раз в middleware и кешируется для всех модулей (−2 SQL-запроса).
 // MessageDeleted пропагируется между модулями: если Limiter удалил сообщение,
 // Reactions увидит MessageDeleted=true и скорректирует поведение (подсчёт мата без delete/warn).
+// IsEdit = true для отредактированного сообщения (edited_message): оно уже учтено
+// при отправке, поэтому модули не должны считать его повторно.
 type MessageContext struct {
 	Message        *tele.Message // Оригинальное сообщение от telebot.v3
 	Bot            *tele.Bot     // Инстанс бота
@@ -20,6 +26,7 @@ type MessageContext struct {
 	Sender         *tele.User    // Пользователь, отправивший сообщение
 	ThreadID       int           // ID топика (0 = основной чат, вычислен в middleware)
 	MessageDeleted bool          // Сообщение удалено (пропагируется через pipeline)
+	IsEdit         bool          // Сообщение отредактировано (edited_message), а не новое
 }
 
 // SendReply отправляет ответ на сообщение с автоматическим ThreadID для форумов.
@@ -59,8 +66,44 @@ func (ctx *MessageContext) SendOptions() *tele.SendOptions {
 // DeleteMessage удаляет текущее сообщение и помечает контекст.
 // MessageDeleted = true всегда: даже при ошибке удаления (message not found, no permission)
 // не рискуем обрабатывать потенциально удалённое сообщение в следующих модулях.
-func (ctx *MessageContext) DeleteMessage() error {
+// reason — причина удаления для метрики bmft_message_deletions_total (limit_exceeded, profanity, ...).
+func (ctx *MessageContext) DeleteMessage(reason string) error {
 	err := ctx.Bot.Delete(ctx.Message)
 	ctx.MessageDeleted = true
+	if err == nil {
+		metrics.DeletionsTotal.WithLabelValues(reason).Inc()
+	}
 	return err
 }
+
+// Warner выдаёт предупреждения от имени бота (реализует модуль moderation).
+// Limiter и Reactions вызывают AutoWarn при нарушении; выдавать ли предупреждение
+// за данный source (limiter, profanity, banned_words), решает настройка чата.
+// Antiflood вызывает AutoWarn только если действием выбрано warn — его source
+// настройкой чата не отключается.
+// Ошибки логируются внутри — нарушение уже обработано, pipeline не прерывается.
+type Warner interface {
+	AutoWarn(ctx *MessageContext, source, reason string)
+}
+
+// Punisher применяет наказания от имени бота (реализует модуль moderation).
+// Наказание записывается в punishments и снимается воркером по истечении срока.
+type Punisher interface {
+	// Mute запрещает пользователю писать на d (0 = навсегда).
+	Mute(chat *tele.Chat, user *tele.User, d time.Duration, reason string) error
+	// RestrictContent запрещает пользователю отправлять contentType (photo, sticker, ...) на d.
+	RestrictContent(chat *tele.Chat, user *tele.User, contentType string, d time.Duration, reason string) error
+	// Kick удаляет пользователя из чата (он может вернуться по ссылке).
+	Kick(chat *tele.Chat, user *tele.User, reason string) error
+	// Unrestrict возвращает пользователю права base (например, права участников чата
+	// по умолчанию), урезанные действующими мутами и запретами контента.
+	Unrestrict(chat *tele.Chat, user *tele.User, base tele.Rights) error
+}
+
+// SlowModer включает и выключает slow mode в чате или топике (реализует модуль antiflood).
+// Bot API не позволяет ботам менять slow mode чата, поэтому он эмулируется
+// антифлудом: одно сообщение за delay на пользователя, лишние удаляются.
+type SlowModer interface {
+	// SetSlowMode включает slow mode (delay > 0) или возвращает прежнюю настройку антифлуда (delay = 0).
+	SetSlowMode(chatID int64, threadID int, delay time.Duration) error
+}
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+)
+
+// Приоритеты встроенных модулей в pipeline.
+// Меньшее значение = раньше в цепочке. Шаг 100 оставляет место для
+// собственных модулей между встроенными (например, 250 — после limiter, до reactions).
+const (
+	PriorityStatistics = 100 // всегда первый — записывает сообщение в messages
+	PriorityAntiFlood  = 150 // частота сообщений (окна в памяти), может удалить сообщение
+	PriorityProbation  = 175 // испытательный срок новичков, может удалить сообщение
+	PriorityLimiter    = 200 // лимиты контента, может удалить сообщение
+	PriorityReactions  = 300 // мат → бан-слова → автоответы
+
+	// PriorityNone — модуль работает в фоне и не участвует в pipeline (scheduler, maintenance).
+	PriorityNone = -1
+)
+
+// Module — контракт модуля бота.
+// Реестр (Registry) регистрирует команды, встраивает OnMessage в pipeline
+// в порядке Priority и управляет жизненным циклом через Start/Shutdown.
+type Module interface {
+	// Name — уникальное имя модуля (используется в логах и event_log).
+	Name() string
+	// Priority — позиция в pipeline; PriorityNone = модуль не получает сообщения.
+	Priority() int
+	// OnMessage обрабатывает входящее сообщение (вызывается только при Priority >= 0).
+	OnMessage(ctx *MessageContext) error
+	// RegisterCommands регистрирует пользовательские команды модуля.
+	RegisterCommands(bot *tele.Bot)
+	// Start запускает фоновые задачи модуля (cron, воркеры). Stateless-модули возвращают nil.
+	Start() error
+	// Shutdown останавливает фоновые задачи модуля.
+	Shutdown() error
+}
+
+// AdminCommandsRegistrar — опциональный интерфейс для модулей с админскими командами.
+// Права на сами команды проверяет AdminOnlyMiddleware (см. adminCommands).
+type AdminCommandsRegistrar interface {
+	RegisterAdminCommands(bot *tele.Bot)
+}
+
+// HealthChecker — опциональный интерфейс для модулей с фоновыми задачами.
+// Результат HealthCheck попадает в /readyz; nil = модуль работает.
+type HealthChecker interface {
+	HealthCheck() error
+}
+
+// JoinContext — контекст вступления пользователя в чат для модулей-обработчиков JoinHandler.
+// SuppressGreeting пропагируется между обработчиками: если модуль сам написал новичку
+// (например, капча), стандартное приветствие не отправляется.
+// Handled останавливает цепочку: пользователь уже обработан (например, кикнут антирейдом)
+// и следующим обработчикам (капче) делать с ним нечего.
+type JoinContext struct {
+	Bot              *tele.Bot
+	Chat             *tele.Chat
+	User             *tele.User    // Вступивший пользователь (не бот-модератор)
+	Message          *tele.Message // Сервисное сообщение о вступлении
+	ThreadID         int           // ID топика (0 = основной чат)
+	SuppressGreeting bool          // Не отправлять стандартное приветствие
+	Handled          bool          // Не вызывать следующие обработчики
+}
+
+// JoinHandler — опциональный интерфейс для модулей, реагирующих на вступление в чат.
+// Обработчики вызываются в порядке регистрации модулей, пока один из них не выставит Handled.
+type JoinHandler interface {
+	OnUserJoined(ctx *JoinContext) error
+}
+
+// Registry хранит модули бота и управляет их порядком и жизненным циклом.
+// Не потокобезопасен: все модули регистрируются при старте, до bot.Start().
+type Registry struct {
+	modules []Module
+	started []Module // успешно запущенные модули (для корректного shutdown)
+	logger  *zap.Logger
+}
+
+// NewRegistry создаёт пустой реестр модулей.
+func NewRegistry(logger *zap.Logger) *Registry {
+	return &Registry{logger: logger}
+}
+
+// Register добавляет модуль в реестр. Имя модуля должно быть уникальным.
+func (r *Registry) Register(m Module) error {
+	if m.Name() == "" {
+		return fmt.Errorf("module name must not be empty")
+	}
+	if r.Get(m.Name()) != nil {
+		return fmt.Errorf("module %q already registered", m.Name())
+	}
+	r.modules = append(r.modules, m)
+	r.logger.Info("module registered",
+		zap.String("module", m.Name()),
+		zap.Int("priority", m.Priority()))
+	return nil
+}
+
+// Get возвращает модуль по имени или nil, если такого модуля нет.
+func (r *Registry) Get(name string) Module {
+	for _, m := range r.modules {
+		if m.Name() == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Modules возвращает все модули в порядке регистрации.
+func (r *Registry) Modules() []Module {
+	return append([]Module(nil), r.modules...)
+}
+
+// Pipeline возвращает модули, участвующие в обработке сообщений, отсортированные по Priority.
+// При равном приоритете сохраняется порядок регистрации (sort.SliceStable).
+func (r *Registry) Pipeline() []Module {
+	var pipeline []Module
+	for _, m := range r.modules {
+		if m.Priority() >= 0 {
+			pipeline = append(pipeline, m)
+		}
+	}
+	sort.SliceStable(pipeline, func(i, j int) bool {
+		return pipeline[i].Priority() < pipeline[j].Priority()
+	})
+	return pipeline
+}
+
+// RegisterCommands регистрирует пользовательские и админские команды всех модулей.
+func (r *Registry) RegisterCommands(bot *tele.Bot) {
+	for _, m := range r.modules {
+		m.RegisterCommands(bot)
+		if admin, ok := m.(AdminCommandsRegistrar); ok {
+			admin.RegisterAdminCommands(bot)
+		}
+		r.logger.Debug("module commands registered", zap.String("module", m.Name()))
+	}
+}
+
+// HealthChecks возвращает результат HealthCheck модулей, реализующих HealthChecker.
+func (r *Registry) HealthChecks() map[string]error {
+	checks := make(map[string]error)
+	for _, m := range r.modules {
+		if checker, ok := m.(HealthChecker); ok {
+			checks[m.Name()] = checker.HealthCheck()
+		}
+	}
+	return checks
+}
+
+// JoinHandlers возвращает модули, реализующие JoinHandler, в порядке регистрации.
+func (r *Registry) JoinHandlers() []JoinHandler {
+	var handlers []JoinHandler
+	for _, m := range r.modules {
+		if h, ok := m.(JoinHandler); ok {
+			handlers = append(handlers, h)
+		}
+	}
+	return handlers
+}
+
+// StartAll запускает модули в порядке регистрации.
+// При ошибке уже запущенные модули останавливаются — бот не стартует в полусобранном состоянии.
+func (r *Registry) StartAll() error {
+	for _, m := range r.modules {
+		r.logger.Info("starting module", zap.String("module", m.Name()))
+		if err := m.Start(); err != nil {
+			_ = r.ShutdownAll()
+			return fmt.Errorf("failed to start %s: %w", m.Name(), err)
+		}
+		r.started = append(r.started, m)
+	}
+	return nil
+}
+
+// ShutdownAll останавливает запущенные модули в обратном порядке.
+// НЕ прерывается при ошибке — каждый модуль должен получить шанс на shutdown.
+// Возвращает первую ошибку.
+func (r *Registry) ShutdownAll() error {
+	var firstErr error
+	for i := len(r.started) - 1; i >= 0; i-- {
+		m := r.started[i]
+		if err := m.Shutdown(); err != nil {
+			r.logger.Error("failed to shutdown module", zap.String("module", m.Name()), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	r.started = nil
+	return firstErr
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// moduleStateEntry — кэш состояний модулей одного чата.
+type moduleStateEntry struct {
+	states    map[int]map[string]bool // thread_id → module_name → is_enabled
+	fetchedAt time.Time
+}
+
+// ModuleStates отвечает на вопрос «включён ли модуль в этом чате/топике».
+// Вызывается для каждого модуля pipeline на каждое сообщение, поэтому
+// состояния чата кэшируются целиком (один SQL-запрос на чат за TTL).
+// Потокобезопасен (sync.RWMutex).
+type ModuleStates struct {
+	repo     *repositories.ModuleStateRepository
+	cache    map[int64]*moduleStateEntry // key = chatID
+	mu       sync.RWMutex
+	cacheTTL time.Duration
+	logger   *zap.Logger
+}
+
+// NewModuleStates создаёт кэш состояний модулей с заданным TTL.
+func NewModuleStates(repo *repositories.ModuleStateRepository, cacheTTL time.Duration, logger *zap.Logger) *ModuleStates {
+	return &ModuleStates{
+		repo:     repo,
+		cache:    make(map[int64]*moduleStateEntry),
+		cacheTTL: cacheTTL,
+		logger:   logger,
+	}
+}
+
+// IsEnabled проверяет, включён ли модуль в чате/топике.
+// Fallback: настройка топика → настройка чата → включён по умолчанию.
+// При ошибке БД модуль считается включённым — модерация не должна
+// молча отключаться из-за сбоя чтения настроек.
+func (s *ModuleStates) IsEnabled(chatID int64, threadID int, moduleName string) bool {
+	states, err := s.chatStates(chatID)
+	if err != nil {
+		s.logger.Warn("failed to load module states, assuming enabled",
+			zap.Int64("chat_id", chatID),
+			zap.String("module", moduleName),
+			zap.Error(err))
+		return true
+	}
+
+	if threadID != 0 {
+		if enabled, ok := states[threadID][moduleName]; ok {
+			return enabled
+		}
+	}
+	if enabled, ok := states[0][moduleName]; ok {
+		return enabled
+	}
+	return true
+}
+
+// SetEnabled сохраняет состояние модуля и сбрасывает кэш чата.
+func (s *ModuleStates) SetEnabled(chatID int64, threadID int, moduleName string, enabled bool, updatedBy int64) error {
+	if err := s.repo.SetEnabled(chatID, threadID, moduleName, enabled, updatedBy); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.cache, chatID)
+	s.mu.Unlock()
+	return nil
+}
+
+// chatStates возвращает состояния модулей чата из кэша или из БД.
+func (s *ModuleStates) chatStates(chatID int64) (map[int]map[string]bool, error) {
+	s.mu.RLock()
+	entry, exists := s.cache[chatID]
+	if exists && time.Since(entry.fetchedAt) < s.cacheTTL {
+		states := entry.states
+		s.mu.RUnlock()
+		return states, nil
+	}
+	s.mu.RUnlock()
+
+	rows, err := s.repo.GetChatStates(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[int]map[string]bool)
+	for _, row := range rows {
+		if states[row.ThreadID] == nil {
+			states[row.ThreadID] = make(map[string]bool)
+		}
+		states[row.ThreadID][row.ModuleName] = row.IsEnabled
+	}
+
+	s.mu.Lock()
+	s.cache[chatID] = &moduleStateEntry{states: states, fetchedAt: time.Now()}
+	s.mu.Unlock()
+
+	return states, nil
+}
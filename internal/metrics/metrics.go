@@ -0,0 +1,100 @@
+// Package metrics содержит Prometheus-метрики бота.
+// Метрики регистрируются в глобальном реестре при импорте пакета
+// и отдаются HTTP сервером на METRICS_ADDR/metrics (см. Handler).
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bmft"
+
+var (
+	// UpdatesTotal — входящие обновления Telegram по типу (message, edited_message, callback_query, ...).
+	UpdatesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "updates_total",
+		Help:      "Telegram updates received, by update type.",
+	}, []string{"type"})
+
+	// ModuleDuration — время выполнения OnMessage модуля pipeline.
+	ModuleDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "module_on_message_duration_seconds",
+		Help:      "Duration of module OnMessage calls in the message pipeline.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"module"})
+
+	// ModuleErrorsTotal — ошибки, возвращённые OnMessage модуля.
+	ModuleErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "module_on_message_errors_total",
+		Help:      "Errors returned by module OnMessage calls.",
+	}, []string{"module"})
+
+	// DeletionsTotal — сообщения, удалённые ботом, по причине.
+	DeletionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "message_deletions_total",
+		Help:      "Messages deleted by the bot, by reason.",
+	}, []string{"reason"})
+
+	// BansTotal — пользователи, забаненные ботом, по причине.
+	BansTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bans_total",
+		Help:      "Users banned by the bot, by reason.",
+	}, []string{"reason"})
+
+	// ReactionTriggersTotal — сработавшие правила reactions (автоответ, фильтр, мат).
+	ReactionTriggersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reaction_triggers_total",
+		Help:      "Triggered reactions rules, by kind.",
+	}, []string{"kind"})
+
+	// SchedulerExecutionsTotal — запуски задач планировщика по типу задачи.
+	SchedulerExecutionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scheduler_executions_total",
+		Help:      "Scheduled task executions, by task type.",
+	}, []string{"task_type"})
+
+	// SchedulerFailuresTotal — неудачные запуски задач планировщика.
+	SchedulerFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "scheduler_failures_total",
+		Help:      "Failed scheduled task executions, by task type.",
+	}, []string{"task_type"})
+
+	// TelegramAPIErrorsTotal — ошибки запросов к Bot API по методу и HTTP-коду (0 = сетевая ошибка).
+	TelegramAPIErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_api_errors_total",
+		Help:      "Failed Telegram Bot API requests, by method and HTTP status code.",
+	}, []string{"method", "code"})
+
+	// LastGetUpdatesTimestamp — Unix-время последнего успешного ответа getUpdates (Long Polling).
+	LastGetUpdatesTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "telegram_last_get_updates_timestamp_seconds",
+		Help:      "Unix time of the last successful getUpdates response.",
+	})
+
+	// DBQueryDuration — время выполнения SQL-запросов по операции (select, insert, ...).
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of PostgreSQL queries, by statement type.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "status"})
+)
+
+// Handler возвращает HTTP-обработчик /metrics в формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
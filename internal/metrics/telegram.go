@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// lastGetUpdates — время последнего успешного ответа getUpdates (UnixNano, 0 = ещё не было).
+var lastGetUpdates atomic.Int64
+
+// LastGetUpdates возвращает время последнего успешного getUpdates (zero time, если не было).
+// Используется /readyz: при отозванном токене или недоступном API время перестаёт обновляться.
+func LastGetUpdates() time.Time {
+	ns := lastGetUpdates.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// telegramTransport считает неудачные запросы к Bot API.
+// Telegram возвращает ошибки (400, 403, 429, ...) HTTP-кодом ответа,
+// поэтому тело ответа разбирать не нужно.
+type telegramTransport struct {
+	base http.RoundTripper
+}
+
+// NewTelegramTransport оборачивает транспорт HTTP-клиента бота метриками ошибок API.
+func NewTelegramTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &telegramTransport{base: base}
+}
+
+// RoundTrip выполняет запрос и учитывает сетевые ошибки и не-2xx ответы.
+func (t *telegramTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	method := apiMethod(req.URL.Path)
+	if err != nil {
+		TelegramAPIErrorsTotal.WithLabelValues(method, "0").Inc()
+		return resp, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		TelegramAPIErrorsTotal.WithLabelValues(method, strconv.Itoa(resp.StatusCode)).Inc()
+		return resp, nil
+	}
+	if method == "getUpdates" {
+		now := time.Now()
+		lastGetUpdates.Store(now.UnixNano())
+		LastGetUpdatesTimestamp.Set(float64(now.Unix()))
+	}
+	return resp, nil
+}
+
+// apiMethod извлекает имя метода из пути /bot<token>/<method>.
+// Токен в метку не попадает; скачивание файлов (/file/bot<token>/...) — метод "file".
+func apiMethod(path string) string {
+	if !strings.HasPrefix(path, "/bot") {
+		return "file"
+	}
+	if i := strings.LastIndex(path, "/"); i >= 0 && i < len(path)-1 {
+		return path[i+1:]
+	}
+	return "unknown"
+}
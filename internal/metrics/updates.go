@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	tele "gopkg.in/telebot.v3"
+)
+
+// ObserveUpdate учитывает входящее обновление в bmft_updates_total.
+// Вызывается из MiddlewarePoller для всех обновлений, включая те, у которых нет хендлера.
+func ObserveUpdate(u *tele.Update) {
+	UpdatesTotal.WithLabelValues(updateType(u)).Inc()
+}
+
+// updateType возвращает тип обновления в терминах Bot API.
+func updateType(u *tele.Update) string {
+	switch {
+	case u.Message != nil:
+		return "message"
+	case u.EditedMessage != nil:
+		return "edited_message"
+	case u.ChannelPost != nil:
+		return "channel_post"
+	case u.EditedChannelPost != nil:
+		return "edited_channel_post"
+	case u.MessageReaction != nil:
+		return "message_reaction"
+	case u.MessageReactionCount != nil:
+		return "message_reaction_count"
+	case u.Callback != nil:
+		return "callback_query"
+	case u.Query != nil:
+		return "inline_query"
+	case u.InlineResult != nil:
+		return "chosen_inline_result"
+	case u.ShippingQuery != nil:
+		return "shipping_query"
+	case u.PreCheckoutQuery != nil:
+		return "pre_checkout_query"
+	case u.Poll != nil:
+		return "poll"
+	case u.PollAnswer != nil:
+		return "poll_answer"
+	case u.MyChatMember != nil:
+		return "my_chat_member"
+	case u.ChatMember != nil:
+		return "chat_member"
+	case u.ChatJoinRequest != nil:
+		return "chat_join_request"
+	case u.Boost != nil:
+		return "chat_boost"
+	case u.BoostRemoved != nil:
+		return "removed_chat_boost"
+	default:
+		return "unknown"
+	}
+}
@@ -28,21 +28,54 @@ var ExpectedSchema = []ExpectedTable{
 	{Name: "chats", Columns: []string{"chat_id", "chat_type", "title", "is_forum", "is_active"}},
 	{Name: "chat_vips", Columns: []string{"id", "chat_id", "thread_id", "user_id", "granted_at"}},
 	{Name: "messages", Columns: []string{"id", "chat_id", "thread_id", "user_id", "message_id", "content_type", "chat_name", "metadata"}},
+	{Name: "chat_modules", Columns: []string{"chat_id", "thread_id", "module_name", "is_enabled"}},
+	{Name: "message_edits", Columns: []string{"id", "chat_id", "message_id", "text", "caption", "edited_at"}},
 
 	// Limiter Module
-	{Name: "content_limits", Columns: []string{"id", "chat_id", "thread_id", "limit_text", "limit_photo", "limit_banned_words"}},
+	{Name: "content_limits", Columns: []string{"id", "chat_id", "thread_id", "limit_text", "limit_photo", "limit_banned_words", "limit_forward", "penalty", "shadow"}},
+	{Name: "content_limit_windows", Columns: []string{"id", "chat_id", "thread_id", "user_id", "content_type", "window_type", "window_seconds", "limit_value"}},
+	{Name: "forward_settings", Columns: []string{"chat_id", "thread_id", "policy"}},
+	{Name: "forward_whitelist", Columns: []string{"chat_id", "thread_id", "source_id", "title"}},
 
 	// Reactions Module (включая бывшие textfilter и profanityfilter)
-	{Name: "keyword_reactions", Columns: []string{"id", "chat_id", "thread_id", "pattern", "response_type", "response_content", "action", "is_active"}},
+	{Name: "keyword_reactions", Columns: []string{"id", "chat_id", "thread_id", "pattern", "response_type", "response_content", "action", "is_active", "shadow"}},
 	{Name: "reaction_triggers", Columns: []string{"chat_id", "reaction_id", "user_id", "last_triggered_at", "trigger_count"}},
 	{Name: "reaction_daily_counters", Columns: []string{"chat_id", "reaction_id", "user_id", "counter_date", "count"}},
 
 	// Profanity (глобальный словарь + per-chat настройки)
 	{Name: "profanity_dictionary", Columns: []string{"id", "pattern", "is_regex", "severity"}},
-	{Name: "profanity_settings", Columns: []string{"chat_id", "thread_id", "action"}},
+	{Name: "profanity_settings", Columns: []string{"chat_id", "thread_id", "action", "min_severity", "action_mild", "action_moderate", "action_severe", "shadow"}},
+	{Name: "profanity_chat_words", Columns: []string{"chat_id", "word", "kind", "severity"}},
+
+	// Link filter (часть модуля Reactions)
+	{Name: "link_filter_settings", Columns: []string{"chat_id", "thread_id", "action", "mode", "block_invites", "block_mentions"}},
+	{Name: "link_filter_domains", Columns: []string{"id", "chat_id", "thread_id", "domain", "list_type"}},
 
 	// Scheduler Module
 	{Name: "scheduled_tasks", Columns: []string{"id", "chat_id", "cron_expression", "action_type", "is_active"}},
+	{Name: "chat_state_snapshots", Columns: []string{"chat_id", "thread_id", "kind", "data"}},
+
+	// Captcha Module
+	{Name: "captcha_settings", Columns: []string{"chat_id", "enabled", "timeout_seconds", "challenge_type", "fail_action"}},
+	{Name: "captcha_challenges", Columns: []string{"chat_id", "user_id", "answer", "attempts", "expires_at"}},
+
+	// Moderation Module (предупреждения, наказания, жалобы)
+	{Name: "warnings", Columns: []string{"id", "chat_id", "user_id", "issued_by", "source", "revoked_at"}},
+	{Name: "warn_settings", Columns: []string{"chat_id", "auto_limiter", "auto_profanity", "auto_banned_words", "auto_links"}},
+	{Name: "warn_escalation", Columns: []string{"chat_id", "warn_count", "action", "duration_seconds"}},
+	{Name: "punishments", Columns: []string{"id", "chat_id", "user_id", "action", "content_type", "expires_at", "lifted_at"}},
+	{Name: "reports", Columns: []string{"id", "chat_id", "thread_id", "message_id", "reporter_id", "reported_user_id", "status", "notifications", "resolved_by"}},
+	{Name: "report_settings", Columns: []string{"chat_id", "enabled", "notify", "log_chat_id"}},
+
+	// Antiflood Module (порог флуда)
+	{Name: "antiflood_settings", Columns: []string{"chat_id", "thread_id", "enabled", "max_messages", "window_seconds", "action", "weights"}},
+
+	// Antiraid Module (детектор рейдов)
+	{Name: "raid_settings", Columns: []string{"chat_id", "auto_enabled", "join_threshold", "window_seconds", "action", "cooldown_seconds", "raid_until"}},
+
+	// Probation Module (испытательный срок новичков)
+	{Name: "chat_members", Columns: []string{"chat_id", "user_id", "joined_at", "message_count", "trusted_at", "trusted_by"}},
+	{Name: "probation_settings", Columns: []string{"chat_id", "duration_seconds", "min_messages"}},
 
 	// System tables
 	{Name: "schema_migrations", Columns: []string{"version", "description", "applied_at"}},
@@ -53,7 +86,7 @@ var ExpectedSchema = []ExpectedTable{
 // LatestSchemaVersion - текущая версия схемы базы данных
 // Увеличивайте эту константу при добавлении новых миграций.
 // Каждая новая версия = один файл NNN_migration.sql в папке migrations/.
-const LatestSchemaVersion = 3
+const LatestSchemaVersion = 23
 
 // RunMigrationsIfNeeded проверяет схему БД и выполняет миграции если требуется
 // Возвращает ошибку если схема несовместима или миграция не удалась
@@ -316,6 +349,12 @@ func checkColumnExists(ctx context.Context, db *sql.DB, tableName, columnName st
 	return exists, err
 }
 
+// CurrentSchemaVersion возвращает версию схемы, записанную в schema_migrations.
+// Используется /readyz для сравнения с LatestSchemaVersion.
+func CurrentSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	return getCurrentSchemaVersion(ctx, db)
+}
+
 // getCurrentSchemaVersion возвращает текущую версию схемы из таблицы schema_migrations
 func getCurrentSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
 	query := `SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1`
@@ -0,0 +1,430 @@
+package antiflood
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+const (
+	// settingsChannel — канал NOTIFY об изменении antiflood_settings, payload — chat_id.
+	settingsChannel = "bmft_antiflood_settings"
+	// settingsCacheTTL — сколько живут настройки чата в кэше (как у core.ModuleStates).
+	// Страховка на случай потерянного уведомления.
+	settingsCacheTTL = time.Minute
+	// exemptTTL — сколько помнится, что пользователь админ или VIP.
+	exemptTTL = 10 * time.Minute
+	// staleAfter — состояние пользователя без сообщений дольше этого удаляется очисткой.
+	staleAfter = 10 * time.Minute
+)
+
+// defaultWeights — веса типов контента, если чат не задал свои.
+// Стикер или гифка заметнее в ленте, чем строка текста.
+var defaultWeights = map[string]int{
+	"sticker":   2,
+	"animation": 2,
+}
+
+// floodKey — счётчик флуда одного пользователя в области настройки
+// (топик со своей настройкой или весь чат).
+type floodKey struct {
+	chatID   int64
+	threadID int
+	userID   int64
+}
+
+// hit — сообщение в скользящем окне.
+type hit struct {
+	at     time.Time
+	weight int
+}
+
+// floodState — скользящее окно пользователя.
+type floodState struct {
+	hits          []hit
+	lastSeen      time.Time
+	lastAction    time.Time // последнее уведомление/мут/предупреждение
+	exempt        bool      // админ или VIP
+	exemptChecked time.Time
+}
+
+// settingsEntry — кэш настроек антифлуда одного чата.
+type settingsEntry struct {
+	settings  map[int]*repositories.AntifloodSettings // thread_id → настройки
+	fetchedAt time.Time
+}
+
+// AntifloodModule ограничивает частоту сообщений: не больше N «весовых» сообщений
+// за M секунд на пользователя. Окна считаются в памяти, настройки чата кэшируются —
+// на обычное сообщение модуль не делает ни одного SQL-запроса. Кэш сбрасывается
+// /setflood и slow mode планировщика на всех экземплярах бота через PostgreSQL NOTIFY.
+// Проверка админа и VIP выполняется только при срабатывании порога.
+type AntifloodModule struct {
+	db            *sql.DB
+	bot           *tele.Bot
+	logger        *zap.Logger
+	antifloodRepo *repositories.AntifloodRepository
+	vipRepo       *repositories.VIPRepository
+	eventRepo     *repositories.EventRepository
+	chatStateRepo *repositories.ChatStateRepository // настройка до slow mode
+	listener      *postgresql.Listener              // уведомления об изменении настроек; nil — только TTL
+	warner        core.Warner                       // действие warn
+	punisher      core.Punisher                     // действие mute
+
+	mu          sync.Mutex
+	states      map[floodKey]*floodState
+	cacheMu     sync.RWMutex
+	settings    map[int64]*settingsEntry // key = chatID
+	settingsGen uint64                   // растёт при каждом сбросе кэша
+
+	cron    *cron.Cron
+	running atomic.Bool // очистка состояний запущена (для /readyz)
+}
+
+// New создаёт новый инстанс модуля антифлуда.
+func New(db *sql.DB, antifloodRepo *repositories.AntifloodRepository, vipRepo *repositories.VIPRepository, eventRepo *repositories.EventRepository, chatStateRepo *repositories.ChatStateRepository, listener *postgresql.Listener, warner core.Warner, punisher core.Punisher, logger *zap.Logger, bot *tele.Bot) *AntifloodModule {
+	m := &AntifloodModule{
+		db:            db,
+		bot:           bot,
+		logger:        logger,
+		antifloodRepo: antifloodRepo,
+		vipRepo:       vipRepo,
+		eventRepo:     eventRepo,
+		chatStateRepo: chatStateRepo,
+		listener:      listener,
+		warner:        warner,
+		punisher:      punisher,
+		states:        make(map[floodKey]*floodState),
+		settings:      make(map[int64]*settingsEntry),
+		cron:          cron.New(),
+	}
+
+	logger.Info("antiflood module created")
+	return m
+}
+
+// Name возвращает имя модуля.
+func (m *AntifloodModule) Name() string { return "antiflood" }
+
+// Priority — после statistics (флуд тоже попадает в статистику), до limiter.
+func (m *AntifloodModule) Priority() int { return core.PriorityAntiFlood }
+
+// Start подписывает кэш настроек на уведомления и запускает очистку устаревших окон и кэша.
+func (m *AntifloodModule) Start() error {
+	m.logger.Info("starting antiflood module")
+	m.subscribeSettings()
+
+	if _, err := m.cron.AddFunc("@every 1m", m.cleanup); err != nil {
+		return fmt.Errorf("failed to schedule antiflood cleanup: %w", err)
+	}
+
+	m.cron.Start()
+	m.running.Store(true)
+	m.logger.Info("antiflood cleanup started")
+	return nil
+}
+
+// Shutdown выполняет graceful shutdown модуля.
+func (m *AntifloodModule) Shutdown() error {
+	m.logger.Info("shutting down antiflood module")
+	m.running.Store(false)
+	ctx := m.cron.Stop()
+	<-ctx.Done()
+	m.logger.Info("antiflood cleanup stopped")
+	return nil
+}
+
+// HealthCheck сообщает /readyz, запущена ли очистка состояний.
+func (m *AntifloodModule) HealthCheck() error {
+	if !m.running.Load() {
+		return errors.New("antiflood cleanup is not running")
+	}
+	return nil
+}
+
+// OnMessage добавляет сообщение в окно пользователя и применяет действие при флуде.
+func (m *AntifloodModule) OnMessage(ctx *core.MessageContext) error {
+	// Команды не считаются: админ должен иметь возможность управлять ботом.
+	// Правка не новое сообщение — в окно не добавляется.
+	if ctx.Message.Private() || ctx.IsEdit || strings.HasPrefix(ctx.Message.Text, "/") {
+		return nil
+	}
+
+	settings, err := m.settingsFor(ctx.Chat.ID, ctx.ThreadID)
+	if err != nil {
+		m.logger.Error("failed to get antiflood settings", zap.Error(err))
+		return nil
+	}
+	if settings == nil {
+		return nil
+	}
+
+	contentType := core.DetectContentType(ctx.Message)
+	if contentType == "unknown" {
+		return nil
+	}
+	weight := weightOf(settings, contentType)
+	if weight == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	key := floodKey{chatID: ctx.Chat.ID, threadID: settings.ThreadID, userID: ctx.Sender.ID}
+	if !m.record(key, weight, settings, now) {
+		return nil
+	}
+	if m.isExempt(ctx, key) {
+		return nil
+	}
+
+	if err := ctx.DeleteMessage("flood"); err != nil {
+		m.logger.Error("failed to delete flood message", zap.Error(err))
+	}
+
+	// Действие и уведомление — один раз на окно, остальные сообщения флуда только удаляются
+	if !m.markAction(key, settings, now) {
+		return nil
+	}
+
+	m.logger.Info("flood detected",
+		zap.Int64("chat_id", ctx.Chat.ID),
+		zap.Int("thread_id", ctx.ThreadID),
+		zap.Int64("user_id", ctx.Sender.ID),
+		zap.String("action", settings.Action))
+	_ = m.eventRepo.Log(ctx.Chat.ID, ctx.Sender.ID, "antiflood", "flood",
+		fmt.Sprintf("Flood: more than %d messages in %s, action=%s", settings.MaxMessages, settings.Window, settings.Action))
+
+	name := core.DisplayName(ctx.Sender)
+	switch settings.Action {
+	case "mute":
+		if err := m.punisher.Mute(ctx.Chat, ctx.Sender, settings.MuteDuration, "флуд"); err != nil {
+			m.logger.Error("failed to mute flooder", zap.Error(err))
+			return nil
+		}
+		m.reset(key)
+		return ctx.Send(fmt.Sprintf("🔇 %s в муте за флуд: %s", name, core.FormatDuration(settings.MuteDuration)))
+	case "warn":
+		m.warner.AutoWarn(ctx, "antiflood", "флуд")
+		return nil
+	default:
+		return ctx.Send(fmt.Sprintf("🌊 %s, не флудите: не больше %d сообщений за %d сек.",
+			name, settings.MaxMessages, int(settings.Window/time.Second)))
+	}
+}
+
+// record добавляет сообщение, отправленное в now, в окно и возвращает true, если порог превышен.
+func (m *AntifloodModule) record(key floodKey, weight int, settings *repositories.AntifloodSettings, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.states[key]
+	if st == nil {
+		st = &floodState{}
+		m.states[key] = st
+	}
+	st.lastSeen = now
+
+	// Сдвигаем окно: отбрасываем сообщения старше window
+	cutoff := now.Add(-settings.Window)
+	i := 0
+	for i < len(st.hits) && !st.hits[i].at.After(cutoff) {
+		i++
+	}
+	st.hits = append(st.hits[i:], hit{at: now, weight: weight})
+
+	total := 0
+	for _, h := range st.hits {
+		total += h.weight
+	}
+	return total > settings.MaxMessages
+}
+
+// markAction отмечает применение действия в now. false — действие уже применялось в этом окне.
+func (m *AntifloodModule) markAction(key floodKey, settings *repositories.AntifloodSettings, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.states[key]
+	if st == nil || now.Sub(st.lastAction) < settings.Window {
+		return false
+	}
+	st.lastAction = now
+	return true
+}
+
+// reset очищает окно пользователя (после мута счёт начинается заново).
+func (m *AntifloodModule) reset(key floodKey) {
+	m.mu.Lock()
+	if st := m.states[key]; st != nil {
+		st.hits = nil
+	}
+	m.mu.Unlock()
+}
+
+// isExempt проверяет, что пользователь админ или VIP. Результат кэшируется
+// в состоянии пользователя на exemptTTL, чтобы флуд админа не порождал запрос на каждое сообщение.
+func (m *AntifloodModule) isExempt(ctx *core.MessageContext, key floodKey) bool {
+	m.mu.Lock()
+	st := m.states[key]
+	if st != nil && time.Since(st.exemptChecked) < exemptTTL {
+		exempt := st.exempt
+		m.mu.Unlock()
+		return exempt
+	}
+	m.mu.Unlock()
+
+	exempt := false
+	if member, err := m.bot.ChatMemberOf(ctx.Chat, ctx.Sender); err == nil {
+		exempt = member.Role == tele.Administrator || member.Role == tele.Creator
+	}
+	if !exempt {
+		isVIP, err := m.vipRepo.IsVIP(ctx.Chat.ID, ctx.ThreadID, ctx.Sender.ID)
+		if err != nil {
+			m.logger.Error("failed to check VIP status", zap.Error(err))
+		}
+		exempt = isVIP
+	}
+
+	m.mu.Lock()
+	if st := m.states[key]; st != nil {
+		st.exempt = exempt
+		st.exemptChecked = time.Now()
+	}
+	m.mu.Unlock()
+	return exempt
+}
+
+// settingsFor возвращает действующие настройки для чата/топика или nil, если антифлуд выключен.
+// Fallback: настройка топика → настройка чата.
+func (m *AntifloodModule) settingsFor(chatID int64, threadID int) (*repositories.AntifloodSettings, error) {
+	all, err := m.chatSettings(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	s := all[threadID]
+	if s == nil {
+		s = all[0]
+	}
+	if s == nil || !s.Enabled {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// chatSettings возвращает настройки антифлуда чата из кэша или из БД.
+func (m *AntifloodModule) chatSettings(chatID int64) (map[int]*repositories.AntifloodSettings, error) {
+	m.cacheMu.RLock()
+	entry, exists := m.settings[chatID]
+	gen := m.settingsGen
+	if exists && time.Since(entry.fetchedAt) < settingsCacheTTL {
+		settings := entry.settings
+		m.cacheMu.RUnlock()
+		return settings, nil
+	}
+	m.cacheMu.RUnlock()
+
+	rows, err := m.antifloodRepo.GetChatSettings(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[int]*repositories.AntifloodSettings, len(rows))
+	for i := range rows {
+		settings[rows[i].ThreadID] = &rows[i]
+	}
+
+	// Сброс во время чтения — прочитанные настройки могли устареть, не кэшируем
+	m.cacheMu.Lock()
+	if gen == m.settingsGen {
+		m.settings[chatID] = &settingsEntry{settings: settings, fetchedAt: time.Now()}
+	}
+	m.cacheMu.Unlock()
+
+	return settings, nil
+}
+
+// subscribeSettings подписывает кэш на уведомления об изменении настроек.
+func (m *AntifloodModule) subscribeSettings() {
+	if m.listener == nil {
+		return
+	}
+	m.listener.Subscribe(settingsChannel, func(payload string) {
+		if payload == "" {
+			// Соединение восстановлено — уведомления могли потеряться
+			m.cacheMu.Lock()
+			m.settings = make(map[int64]*settingsEntry)
+			m.settingsGen++
+			m.cacheMu.Unlock()
+			return
+		}
+		chatID, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			m.logger.Warn("invalid antiflood_settings notification", zap.String("payload", payload))
+			return
+		}
+		m.dropSettings(chatID)
+	})
+}
+
+// invalidate сбрасывает кэш настроек чата на этом и остальных экземплярах бота.
+// Вызывается после /setflood и slow mode.
+func (m *AntifloodModule) invalidate(chatID int64) {
+	m.dropSettings(chatID)
+	if err := postgresql.Notify(m.db, settingsChannel, strconv.FormatInt(chatID, 10)); err != nil {
+		m.logger.Error("failed to notify antiflood_settings change", zap.Int64("chat_id", chatID), zap.Error(err))
+	}
+}
+
+// dropSettings удаляет настройки чата из кэша этого экземпляра.
+func (m *AntifloodModule) dropSettings(chatID int64) {
+	m.cacheMu.Lock()
+	delete(m.settings, chatID)
+	m.settingsGen++
+	m.cacheMu.Unlock()
+}
+
+// cleanup удаляет окна неактивных пользователей и устаревший кэш настроек (cron).
+func (m *AntifloodModule) cleanup() {
+	now := time.Now()
+
+	m.mu.Lock()
+	for key, st := range m.states {
+		if now.Sub(st.lastSeen) > staleAfter {
+			delete(m.states, key)
+		}
+	}
+	m.mu.Unlock()
+
+	m.cacheMu.Lock()
+	for chatID, entry := range m.settings {
+		if now.Sub(entry.fetchedAt) > settingsCacheTTL {
+			delete(m.settings, chatID)
+		}
+	}
+	m.cacheMu.Unlock()
+}
+
+// weightOf возвращает вес типа контента: настройка чата → вес по умолчанию → 1.
+func weightOf(settings *repositories.AntifloodSettings, contentType string) int {
+	if w, ok := settings.Weights[contentType]; ok {
+		return w
+	}
+	if w, ok := defaultWeights[contentType]; ok {
+		return w
+	}
+	return 1
+}
@@ -0,0 +1,132 @@
+package antiflood
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// newTestModule — модуль только с состояниями окон, без БД и бота.
+func newTestModule() *AntifloodModule {
+	return &AntifloodModule{states: make(map[floodKey]*floodState)}
+}
+
+// floodHit — сообщение через sec секунд от начала и ожидаемый результат record.
+type floodHit struct {
+	sec    int
+	weight int
+	flood  bool
+}
+
+// TestRecordWindow проверяет скользящее окно: порог, веса и выход старых сообщений
+func TestRecordWindow(t *testing.T) {
+	settings := &repositories.AntifloodSettings{MaxMessages: 3, Window: 10 * time.Second}
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return start.Add(time.Duration(sec) * time.Second) }
+
+	tests := []struct {
+		name string
+		hits []floodHit
+	}{
+		{
+			name: "Порог превышает четвёртое сообщение",
+			hits: []floodHit{{0, 1, false}, {1, 1, false}, {2, 1, false}, {3, 1, true}},
+		},
+		{
+			name: "Старые сообщения выходят из окна",
+			hits: []floodHit{{0, 1, false}, {1, 1, false}, {2, 1, false}, {11, 1, false}, {12, 1, false}, {12, 1, false}, {12, 1, true}},
+		},
+		{
+			name: "Сообщение ровно на границе окна уже не считается",
+			hits: []floodHit{{0, 1, false}, {0, 1, false}, {0, 1, false}, {10, 1, false}},
+		},
+		{
+			name: "Стикеры весят больше текста",
+			hits: []floodHit{{0, 2, false}, {1, 2, true}},
+		},
+	}
+	for _, tc := range tests {
+		m := newTestModule()
+		key := floodKey{chatID: 1, userID: 42}
+		for i, h := range tc.hits {
+			if got := m.record(key, h.weight, settings, at(h.sec)); got != h.flood {
+				t.Errorf("%s: сообщение %d (t=%ds): record = %t, want %t", tc.name, i+1, h.sec, got, h.flood)
+			}
+		}
+	}
+}
+
+// TestRecordSeparateKeys проверяет, что окна пользователей и топиков независимы
+func TestRecordSeparateKeys(t *testing.T) {
+	settings := &repositories.AntifloodSettings{MaxMessages: 1, Window: time.Minute}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	m := newTestModule()
+
+	keys := []floodKey{
+		{chatID: 1, userID: 42},
+		{chatID: 1, userID: 43},
+		{chatID: 1, threadID: 5, userID: 42},
+		{chatID: 2, userID: 42},
+	}
+	for _, key := range keys {
+		if m.record(key, 1, settings, now) {
+			t.Errorf("record(%+v): первое сообщение считается флудом", key)
+		}
+	}
+	if !m.record(keys[0], 1, settings, now) {
+		t.Errorf("record(%+v): второе сообщение не считается флудом", keys[0])
+	}
+}
+
+// TestMarkActionOncePerWindow проверяет, что действие применяется один раз на окно,
+// а reset начинает счёт заново
+func TestMarkActionOncePerWindow(t *testing.T) {
+	settings := &repositories.AntifloodSettings{MaxMessages: 1, Window: 10 * time.Second}
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	m := newTestModule()
+	key := floodKey{chatID: 1, userID: 42}
+
+	if m.markAction(key, settings, start) {
+		t.Error("markAction без состояния пользователя = true, want false")
+	}
+
+	m.record(key, 1, settings, start)
+	m.record(key, 1, settings, start)
+	steps := []struct {
+		after time.Duration
+		want  bool
+	}{
+		{0, true},
+		{5 * time.Second, false},
+		{9 * time.Second, false},
+		{10 * time.Second, true},
+		{11 * time.Second, false},
+	}
+	for _, s := range steps {
+		if got := m.markAction(key, settings, start.Add(s.after)); got != s.want {
+			t.Errorf("markAction(+%s) = %t, want %t", s.after, got, s.want)
+		}
+	}
+
+	m.reset(key)
+	if m.record(key, 1, settings, start.Add(12*time.Second)) {
+		t.Error("record после reset: первое сообщение считается флудом")
+	}
+}
+
+// TestWeightOf проверяет вес типа контента: настройка чата → по умолчанию → 1
+func TestWeightOf(t *testing.T) {
+	settings := &repositories.AntifloodSettings{Weights: map[string]int{"photo": 3, "sticker": 0}}
+	tests := map[string]int{
+		"photo":     3, // настройка чата
+		"sticker":   0, // настройка чата: не считается
+		"animation": 2, // по умолчанию
+		"text":      1,
+	}
+	for contentType, want := range tests {
+		if got := weightOf(settings, contentType); got != want {
+			t.Errorf("weightOf(%q) = %d, want %d", contentType, got, want)
+		}
+	}
+}
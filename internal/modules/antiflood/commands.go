@@ -0,0 +1,217 @@
+package antiflood
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+const (
+	// minMessages, maxMessages — допустимый порог сообщений в окне.
+	minMessages = 2
+	maxMessages = 100
+	// maxWindow — максимальная длина окна (секунды): флуд — это всплеск, а не дневной лимит.
+	maxWindow = 300
+	// maxWeight — максимальный вес типа контента.
+	maxWeight = 10
+)
+
+// weightTypes — типы контента, для которых можно задать вес.
+var weightTypes = []string{
+	"text", "photo", "video", "sticker", "animation", "voice",
+	"video_note", "audio", "document", "location", "contact",
+}
+
+// RegisterCommands регистрирует пользовательские команды.
+func (m *AntifloodModule) RegisterCommands(bot *tele.Bot) {
+	bot.Handle("/antiflood", m.handleHelp)
+}
+
+// RegisterAdminCommands регистрирует админские команды.
+func (m *AntifloodModule) RegisterAdminCommands(bot *tele.Bot) {
+	bot.Handle("/setflood", m.handleSetFlood)
+}
+
+// handleHelp — /antiflood: справка и текущая настройка чата/топика.
+func (m *AntifloodModule) handleHelp(c tele.Context) error {
+	msg := "🌊 <b>Модуль Antiflood</b> — Защита от флуда\n\n"
+	msg += "Удаляет сообщения пользователя, который пишет слишком часто: больше N сообщений за M секунд. "
+	msg += "Стикеры и гифки по умолчанию весят 2 сообщения. Админы и VIP не ограничиваются.\n\n"
+	msg += "<b>Команды:</b>\n\n"
+	msg += "🔹 <code>/setflood &lt;N&gt; &lt;сек&gt; [действие]</code> — Включить антифлуд (только админы)\n"
+	msg += "   Действия: <code>delete</code> (по умолчанию), <code>mute &lt;длительность&gt;</code>, <code>warn</code>\n"
+	msg += "   📌 <code>/setflood 5 10</code> — больше 5 сообщений за 10 сек удаляются\n"
+	msg += "   📌 <code>/setflood 5 10 mute 30m</code> — флудер получает мут на 30 минут\n"
+	msg += "   📌 <code>/setflood 5 10 warn</code> — флудер получает предупреждение (/moderation)\n\n"
+	msg += "🔹 <code>/setflood weight &lt;тип&gt; &lt;вес&gt;</code> — Вес типа контента (0 = не считать)\n"
+	msg += "   📌 <code>/setflood weight sticker 3</code>\n\n"
+	msg += "🔹 <code>/setflood off</code> — Выключить антифлуд\n\n"
+	msg += "⚙️ <b>Работа с топиками:</b>\n"
+	msg += "• Команда в <b>топике</b> настраивает антифлуд только для этого топика\n"
+	msg += "• Команда в <b>основном чате</b> — для всего чата\n\n"
+
+	threadID := core.GetThreadID(m.db, c)
+	settings, err := m.settingsFor(c.Chat().ID, threadID)
+	if err != nil {
+		m.logger.Error("failed to get antiflood settings", zap.Error(err))
+		return c.Send("❌ Не удалось получить настройки антифлуда")
+	}
+	if settings == nil {
+		msg += "📊 <b>Сейчас:</b> выключен"
+	} else {
+		msg += "📊 <b>Сейчас:</b> " + describe(settings)
+	}
+
+	return c.Send(msg, &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// handleSetFlood — /setflood <N> <сек> [delete|mute <дл.>|warn] | weight <тип> <вес> | off
+func (m *AntifloodModule) handleSetFlood(c tele.Context) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+	args := c.Args()
+	if len(args) == 0 {
+		return c.Send(setFloodUsage)
+	}
+
+	// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, chatID)
+
+	// Изменяем собственную запись области (топика или чата), а не унаследованную
+	all, err := m.chatSettings(chatID)
+	if err != nil {
+		m.logger.Error("failed to get antiflood settings", zap.Error(err))
+		return c.Send("❌ Не удалось получить настройки антифлуда")
+	}
+	own := all[threadID]
+
+	var settings repositories.AntifloodSettings
+	var text string
+	switch strings.ToLower(args[0]) {
+	case "off":
+		if own != nil {
+			settings = *own
+		} else {
+			settings = *repositories.DefaultAntifloodSettings()
+		}
+		settings.Enabled = false
+		text = "✅ Антифлуд выключен"
+
+	case "weight":
+		if own == nil {
+			return c.Send("❌ Сначала включите антифлуд здесь: /setflood <N> <сек>")
+		}
+		if len(args) != 3 || !slices.Contains(weightTypes, args[1]) {
+			return c.Send("Использование: /setflood weight <тип> <вес>\nТипы: " + strings.Join(weightTypes, ", "))
+		}
+		weight, err := strconv.Atoi(args[2])
+		if err != nil || weight < 0 || weight > maxWeight {
+			return c.Send(fmt.Sprintf("❌ Вес — число от 0 до %d", maxWeight))
+		}
+		settings = *own
+		settings.Weights = maps.Clone(own.Weights)
+		settings.Weights[args[1]] = weight
+		text = fmt.Sprintf("✅ Вес %s: %d", args[1], weight)
+
+	default:
+		if len(args) < 2 {
+			return c.Send(setFloodUsage)
+		}
+		limit, err := strconv.Atoi(args[0])
+		if err != nil || limit < minMessages || limit > maxMessages {
+			return c.Send(fmt.Sprintf("❌ N — число от %d до %d", minMessages, maxMessages))
+		}
+		window, err := strconv.Atoi(args[1])
+		if err != nil || window < 1 || window > maxWindow {
+			return c.Send(fmt.Sprintf("❌ Окно — от 1 до %d секунд", maxWindow))
+		}
+
+		if own != nil {
+			settings = *own
+		} else {
+			settings = *repositories.DefaultAntifloodSettings()
+		}
+		settings.ThreadID = threadID
+		settings.Enabled = true
+		settings.MaxMessages = limit
+		settings.Window = time.Duration(window) * time.Second
+		settings.Action = "delete"
+
+		if len(args) > 2 {
+			switch strings.ToLower(args[2]) {
+			case "delete":
+			case "warn":
+				settings.Action = "warn"
+			case "mute":
+				settings.Action = "mute"
+				if len(args) > 3 {
+					d, err := core.ParseDuration(args[3])
+					if err != nil {
+						return c.Send("❌ " + err.Error())
+					}
+					settings.MuteDuration = d
+				}
+			default:
+				return c.Send(setFloodUsage)
+			}
+		}
+		text = "✅ Антифлуд включён: " + describe(&settings)
+	}
+	settings.ThreadID = threadID
+
+	if err := m.antifloodRepo.Set(chatID, &settings, c.Sender().ID); err != nil {
+		m.logger.Error("failed to set antiflood settings", zap.Error(err))
+		return c.Send("❌ Не удалось сохранить настройки антифлуда")
+	}
+	m.invalidate(chatID)
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "antiflood", "set_flood",
+		fmt.Sprintf("Set antiflood: enabled=%t max=%d window=%s action=%s (chat=%d, thread=%d)",
+			settings.Enabled, settings.MaxMessages, settings.Window, settings.Action, chatID, threadID))
+
+	if threadID != 0 {
+		text += "\n\n💡 Настройка действует только в этом топике"
+	}
+	return c.Send(text)
+}
+
+// setFloodUsage — подсказка по формату /setflood.
+const setFloodUsage = "Использование:\n" +
+	"/setflood <N> <сек> [delete|mute <длительность>|warn]\n" +
+	"/setflood weight <тип> <вес>\n" +
+	"/setflood off"
+
+// describe — текстовое описание настройки для ответов бота.
+func describe(s *repositories.AntifloodSettings) string {
+	text := fmt.Sprintf("больше %d сообщений за %d сек. → ", s.MaxMessages, int(s.Window/time.Second))
+	switch s.Action {
+	case "mute":
+		text += "мут " + core.FormatDuration(s.MuteDuration)
+	case "warn":
+		text += "удаление и предупреждение"
+	default:
+		text += "удаление"
+	}
+
+	if len(s.Weights) > 0 {
+		var weights []string
+		for _, t := range slices.Sorted(maps.Keys(s.Weights)) {
+			weights = append(weights, fmt.Sprintf("%s=%d", t, s.Weights[t]))
+		}
+		text += "\nВеса: " + strings.Join(weights, ", ")
+	}
+	return text
+}
@@ -0,0 +1,101 @@
+package antiflood
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// SetSlowMode включает slow mode в чате/топике: одно сообщение любого типа
+// за delay на пользователя, лишние удаляются. Собственная настройка антифлуда
+// области сохраняется в chat_state_snapshots и возвращается при delay = 0.
+// Реализует core.SlowModer (действие slowmode планировщика).
+func (m *AntifloodModule) SetSlowMode(chatID int64, threadID int, delay time.Duration) error {
+	// Снимок и восстановление — по свежим данным, а не по кэшу.
+	// Новая настройка применяется на всех экземплярах бота сразу, не через TTL кэша
+	m.dropSettings(chatID)
+	defer m.invalidate(chatID)
+
+	if delay == 0 {
+		return m.restoreSlowMode(chatID, threadID)
+	}
+
+	all, err := m.chatSettings(chatID)
+	if err != nil {
+		return err
+	}
+	var snapshot []byte
+	if own := all[threadID]; own != nil {
+		if snapshot, err = json.Marshal(own); err != nil {
+			return fmt.Errorf("marshal antiflood settings: %w", err)
+		}
+	}
+	// false — slow mode уже включён, исходная настройка сохранена раньше
+	if _, err := m.chatStateRepo.SaveSnapshot(chatID, threadID, repositories.SnapshotSlowMode, snapshot); err != nil {
+		return err
+	}
+
+	settings := repositories.DefaultAntifloodSettings()
+	settings.ThreadID = threadID
+	settings.Enabled = true
+	settings.MaxMessages = 1
+	settings.Window = delay
+	settings.Action = "delete"
+	// Стикер или гифка — тоже одно сообщение
+	for _, contentType := range weightTypes {
+		settings.Weights[contentType] = 1
+	}
+	if err := m.antifloodRepo.Set(chatID, settings, 0); err != nil {
+		return err
+	}
+
+	_ = m.eventRepo.Log(chatID, 0, "antiflood", "slowmode_on",
+		fmt.Sprintf("Slow mode %s enabled (thread=%d)", delay, threadID))
+	m.logger.Info("slow mode enabled",
+		zap.Int64("chat_id", chatID),
+		zap.Int("thread_id", threadID),
+		zap.Duration("delay", delay))
+	return nil
+}
+
+// restoreSlowMode возвращает настройку антифлуда, действовавшую до slow mode.
+// Без снимка ничего не меняется: настройку, которую задал не slow mode, не трогаем.
+func (m *AntifloodModule) restoreSlowMode(chatID int64, threadID int) error {
+	snapshot, found, err := m.chatStateRepo.GetSnapshot(chatID, threadID, repositories.SnapshotSlowMode)
+	if err != nil {
+		return err
+	}
+	if !found {
+		m.logger.Info("slow mode is not enabled, nothing to restore",
+			zap.Int64("chat_id", chatID),
+			zap.Int("thread_id", threadID))
+		return nil
+	}
+
+	if snapshot == nil {
+		err = m.antifloodRepo.Delete(chatID, threadID)
+	} else {
+		var previous repositories.AntifloodSettings
+		if err := json.Unmarshal(snapshot, &previous); err != nil {
+			return fmt.Errorf("unmarshal antiflood snapshot: %w", err)
+		}
+		err = m.antifloodRepo.Set(chatID, &previous, 0)
+	}
+	if err != nil {
+		return err
+	}
+	if err := m.chatStateRepo.DeleteSnapshot(chatID, threadID, repositories.SnapshotSlowMode); err != nil {
+		return err
+	}
+
+	_ = m.eventRepo.Log(chatID, 0, "antiflood", "slowmode_off",
+		fmt.Sprintf("Slow mode disabled, antiflood settings restored (thread=%d)", threadID))
+	m.logger.Info("slow mode disabled",
+		zap.Int64("chat_id", chatID),
+		zap.Int("thread_id", threadID))
+	return nil
+}
@@ -0,0 +1,308 @@
+package antiraid
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+const (
+	// restrictDuration — на сколько ограничиваются участники, вступившие во время рейда.
+	// Настоящих участников админы освобождают через /unmute.
+	restrictDuration = 24 * time.Hour
+	// extendStep — режим рейда продлевается в БД не чаще, чем на этот шаг,
+	// чтобы не писать в raid_settings на каждое вступление во время рейда.
+	extendStep = time.Minute
+	// staleAfter — окно вступлений чата без новых вступлений дольше этого удаляется.
+	staleAfter = time.Hour
+)
+
+// joinState — скользящее окно вступлений чата.
+type joinState struct {
+	joins     []time.Time
+	raidUntil time.Time // режим рейда, включённый автодетектором этого процесса
+}
+
+// AntiraidModule обнаруживает рейды — всплески вступлений в чат (больше N за M секунд).
+// При рейде включается режим рейда: новые участники ограничиваются или кикаются,
+// приветствия и капча для них не отправляются, админы получают оповещение.
+// Режим снимается автоматически через cooldown или вручную командой /raidmode off.
+// Окна вступлений считаются в памяти, конец режима рейда хранится в PostgreSQL.
+type AntiraidModule struct {
+	db        *sql.DB
+	bot       *tele.Bot
+	logger    *zap.Logger
+	raidRepo  *repositories.RaidRepository
+	eventRepo *repositories.EventRepository
+	punisher  core.Punisher // restrict и kick участников рейда
+
+	mu     sync.Mutex
+	states map[int64]*joinState // key = chatID
+
+	cron    *cron.Cron
+	running atomic.Bool // воркер снятия режима рейда запущен (для /readyz)
+}
+
+// New создаёт новый инстанс модуля антирейда.
+func New(db *sql.DB, raidRepo *repositories.RaidRepository, eventRepo *repositories.EventRepository, punisher core.Punisher, logger *zap.Logger, bot *tele.Bot) *AntiraidModule {
+	m := &AntiraidModule{
+		db:        db,
+		bot:       bot,
+		logger:    logger,
+		raidRepo:  raidRepo,
+		eventRepo: eventRepo,
+		punisher:  punisher,
+		states:    make(map[int64]*joinState),
+		cron:      cron.New(),
+	}
+
+	logger.Info("antiraid module created")
+	return m
+}
+
+// Name возвращает имя модуля.
+func (m *AntiraidModule) Name() string { return "antiraid" }
+
+// Priority — антирейд реагирует на вступление (JoinHandler), а не на сообщения.
+func (m *AntiraidModule) Priority() int { return core.PriorityNone }
+
+// OnMessage не вызывается (Priority = PriorityNone), нужен для core.Module.
+func (m *AntiraidModule) OnMessage(ctx *core.MessageContext) error { return nil }
+
+// Start запускает воркер, который снимает истёкший режим рейда.
+// Режим, истёкший пока бот был выключен, снимается первым же проходом.
+func (m *AntiraidModule) Start() error {
+	m.logger.Info("starting antiraid module")
+
+	if _, err := m.cron.AddFunc("@every 30s", m.liftExpired); err != nil {
+		return fmt.Errorf("failed to schedule raid expiry worker: %w", err)
+	}
+
+	m.cron.Start()
+	m.running.Store(true)
+	m.logger.Info("raid expiry worker started")
+	return nil
+}
+
+// Shutdown выполняет graceful shutdown модуля.
+func (m *AntiraidModule) Shutdown() error {
+	m.logger.Info("shutting down antiraid module")
+	m.running.Store(false)
+	ctx := m.cron.Stop()
+	<-ctx.Done()
+	m.logger.Info("raid expiry worker stopped")
+	return nil
+}
+
+// HealthCheck сообщает /readyz, запущен ли воркер снятия режима рейда.
+func (m *AntiraidModule) HealthCheck() error {
+	if !m.running.Load() {
+		return errors.New("raid expiry worker is not running")
+	}
+	return nil
+}
+
+// OnUserJoined учитывает вступление и, если чат в режиме рейда, ограничивает
+// или кикает нового участника. Модуль регистрируется до капчи: участник рейда
+// помечается Handled, и капча ему не отправляется.
+func (m *AntiraidModule) OnUserJoined(ctx *core.JoinContext) error {
+	if ctx.User.IsBot {
+		return nil
+	}
+	chatID := ctx.Chat.ID
+
+	settings, err := m.raidRepo.GetSettings(chatID)
+	if err != nil {
+		return fmt.Errorf("get raid settings: %w", err)
+	}
+
+	now := time.Now()
+	active := settings.Active(now)
+
+	if settings.AutoEnabled {
+		count, start, until := m.recordJoin(chatID, now, settings)
+		if start || (until.After(now) && (settings.RaidUntil == nil || until.Sub(*settings.RaidUntil) >= extendStep)) {
+			// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+			_, _ = m.db.Exec(`
+				INSERT INTO chats (chat_id, chat_type, title)
+				VALUES ($1, 'unknown', 'unknown')
+				ON CONFLICT (chat_id) DO NOTHING
+			`, chatID)
+
+			if err := m.raidRepo.SetRaidUntil(chatID, &until); err != nil {
+				m.logger.Error("failed to save raid mode", zap.Int64("chat_id", chatID), zap.Error(err))
+			}
+		}
+		if start {
+			m.startRaid(ctx.Chat, settings, count, until)
+		}
+		active = active || until.After(now)
+	}
+
+	if !active {
+		return nil
+	}
+
+	// Новичок не увидит ни приветствия, ни капчи
+	ctx.SuppressGreeting = true
+	ctx.Handled = true
+
+	switch settings.Action {
+	case "kick":
+		err = m.punisher.Kick(ctx.Chat, ctx.User, "Режим рейда")
+	default:
+		err = m.punisher.Mute(ctx.Chat, ctx.User, restrictDuration, "Режим рейда")
+	}
+	if err != nil {
+		return fmt.Errorf("raid action %s: %w", settings.Action, err)
+	}
+
+	// Сервисные сообщения о вступлении рейдеров только засоряют чат
+	if err := ctx.Bot.Delete(ctx.Message); err != nil {
+		m.logger.Debug("failed to delete join message", zap.Error(err))
+	}
+
+	m.logger.Info("raid member handled",
+		zap.Int64("chat_id", chatID),
+		zap.Int64("user_id", ctx.User.ID),
+		zap.String("action", settings.Action))
+	return nil
+}
+
+// recordJoin добавляет вступление в окно чата и решает, начался ли рейд.
+// Возвращает число вступлений в окне, start = рейд только что обнаружен
+// и until — конец режима рейда по автодетектору (нулевое время, если порог не превышен).
+func (m *AntiraidModule) recordJoin(chatID int64, now time.Time, settings *repositories.RaidSettings) (count int, start bool, until time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.states[chatID]
+	if st == nil {
+		st = &joinState{}
+		m.states[chatID] = st
+	}
+
+	cutoff := now.Add(-settings.Window)
+	kept := st.joins[:0]
+	for _, t := range st.joins {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	st.joins = append(kept, now)
+	count = len(st.joins)
+
+	if count <= settings.JoinThreshold {
+		return count, false, time.Time{}
+	}
+
+	// Пока вступления идут выше порога, режим рейда продлевается на cooldown
+	start = !settings.Active(now) && !now.Before(st.raidUntil)
+	st.raidUntil = now.Add(settings.Cooldown)
+	return count, start, st.raidUntil
+}
+
+// startRaid оповещает чат и админов о начале рейда.
+func (m *AntiraidModule) startRaid(chat *tele.Chat, settings *repositories.RaidSettings, count int, until time.Time) {
+	m.logger.Warn("raid detected",
+		zap.Int64("chat_id", chat.ID),
+		zap.Int("joins", count),
+		zap.Duration("window", settings.Window))
+
+	_ = m.eventRepo.Log(chat.ID, 0, "antiraid", "raid_start",
+		fmt.Sprintf("Raid detected: %d joins in %s, raid mode until %s (action=%s)",
+			count, settings.Window, until.Format(time.RFC3339), settings.Action))
+
+	text := fmt.Sprintf("🚨 Обнаружен рейд: %d вступлений за %d сек.\n\n"+
+		"Режим рейда включён на %s: новые участники %s, приветствия отключены.\n"+
+		"Снять досрочно: /raidmode off",
+		count, int(settings.Window/time.Second), core.FormatDuration(settings.Cooldown), actionDescription(settings.Action))
+
+	if _, err := m.bot.Send(chat, text); err != nil {
+		m.logger.Error("failed to send raid alert", zap.Int64("chat_id", chat.ID), zap.Error(err))
+	}
+	m.alertAdmins(chat, text)
+}
+
+// alertAdmins дублирует оповещение админам в личку.
+// Админ, который не запускал бота в личке, сообщение не получит — это не ошибка.
+func (m *AntiraidModule) alertAdmins(chat *tele.Chat, text string) {
+	admins, err := m.bot.AdminsOf(chat)
+	if err != nil {
+		m.logger.Error("failed to get chat admins", zap.Int64("chat_id", chat.ID), zap.Error(err))
+		return
+	}
+
+	title := chat.Title
+	if title == "" {
+		title = fmt.Sprintf("%d", chat.ID)
+	}
+	for _, admin := range admins {
+		if admin.User == nil || admin.User.IsBot {
+			continue
+		}
+		if _, err := m.bot.Send(admin.User, fmt.Sprintf("Чат «%s»\n\n%s", title, text)); err != nil {
+			m.logger.Debug("failed to alert admin", zap.Int64("admin_id", admin.User.ID), zap.Error(err))
+		}
+	}
+}
+
+// liftExpired снимает истёкший режим рейда и чистит устаревшие окна вступлений.
+func (m *AntiraidModule) liftExpired() {
+	now := time.Now()
+	chatIDs, err := m.raidRepo.GetExpiredRaids(now)
+	if err != nil {
+		m.logger.Error("failed to get expired raids", zap.Error(err))
+		return
+	}
+
+	for _, chatID := range chatIDs {
+		if err := m.raidRepo.SetRaidUntil(chatID, nil); err != nil {
+			m.logger.Error("failed to lift raid mode", zap.Int64("chat_id", chatID), zap.Error(err))
+			continue
+		}
+		m.reset(chatID)
+
+		_ = m.eventRepo.Log(chatID, 0, "antiraid", "raid_end", "Raid mode lifted after cooldown")
+		if _, err := m.bot.Send(&tele.Chat{ID: chatID}, "✅ Режим рейда снят: новые участники снова могут писать"); err != nil {
+			m.logger.Error("failed to send raid end notice", zap.Int64("chat_id", chatID), zap.Error(err))
+		}
+		m.logger.Info("raid mode lifted", zap.Int64("chat_id", chatID))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for chatID, st := range m.states {
+		if now.Before(st.raidUntil) {
+			continue
+		}
+		if len(st.joins) == 0 || now.Sub(st.joins[len(st.joins)-1]) > staleAfter {
+			delete(m.states, chatID)
+		}
+	}
+}
+
+// reset забывает окно вступлений чата (после снятия режима рейда).
+func (m *AntiraidModule) reset(chatID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.states, chatID)
+}
+
+// actionDescription — действие с новыми участниками в тексте бота.
+func actionDescription(action string) string {
+	if action == "kick" {
+		return "удаляются из чата"
+	}
+	return "не могут писать " + core.FormatDuration(restrictDuration)
+}
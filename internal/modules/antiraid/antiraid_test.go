@@ -0,0 +1,63 @@
+package antiraid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// TestRecordJoin проверяет окно вступлений: порог, выход старых вступлений,
+// однократное обнаружение рейда и продление режима на cooldown
+func TestRecordJoin(t *testing.T) {
+	settings := &repositories.RaidSettings{JoinThreshold: 3, Window: 10 * time.Second, Cooldown: 5 * time.Minute}
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return start.Add(time.Duration(sec) * time.Second) }
+
+	m := &AntiraidModule{states: make(map[int64]*joinState)}
+	steps := []struct {
+		sec       int
+		wantCount int
+		wantStart bool
+		wantUntil time.Time
+	}{
+		{0, 1, false, time.Time{}},
+		{1, 2, false, time.Time{}},
+		{2, 3, false, time.Time{}},
+		{3, 4, true, at(3).Add(settings.Cooldown)},  // порог превышен — рейд начался
+		{4, 5, false, at(4).Add(settings.Cooldown)}, // рейд продолжается и продлевается
+		{30, 1, false, time.Time{}},                 // старые вступления вышли из окна
+	}
+	for _, s := range steps {
+		count, started, until := m.recordJoin(1, at(s.sec), settings)
+		if count != s.wantCount || started != s.wantStart || !until.Equal(s.wantUntil) {
+			t.Errorf("recordJoin(t=%ds) = (%d, %t, %s), want (%d, %t, %s)",
+				s.sec, count, started, until, s.wantCount, s.wantStart, s.wantUntil)
+		}
+	}
+
+	// Окна чатов независимы
+	if count, _, _ := m.recordJoin(2, at(30), settings); count != 1 {
+		t.Errorf("recordJoin(other chat) count = %d, want 1", count)
+	}
+}
+
+// TestRecordJoinActiveRaid проверяет, что рейд не «начинается» повторно,
+// пока режим уже включён (вручную через /raidmode on или другим экземпляром)
+func TestRecordJoinActiveRaid(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	until := now.Add(time.Hour)
+	settings := &repositories.RaidSettings{JoinThreshold: 1, Window: time.Minute, Cooldown: time.Minute, RaidUntil: &until}
+
+	m := &AntiraidModule{states: make(map[int64]*joinState)}
+	m.recordJoin(1, now, settings)
+	if _, started, _ := m.recordJoin(1, now, settings); started {
+		t.Error("recordJoin при включённом режиме рейда: start = true, want false")
+	}
+
+	// После reset счёт начинается заново
+	m.reset(1)
+	if count, _, _ := m.recordJoin(1, now, settings); count != 1 {
+		t.Errorf("recordJoin после reset: count = %d, want 1", count)
+	}
+}
@@ -0,0 +1,217 @@
+package antiraid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+const (
+	// minThreshold, maxThreshold — допустимый порог вступлений в окне.
+	minThreshold = 2
+	maxThreshold = 1000
+	// minWindow, maxWindow — допустимая длина окна вступлений (секунды).
+	minWindow = 5
+	maxWindow = 3600
+	// maxRaidDuration — максимальная длительность режима рейда и cooldown.
+	maxRaidDuration = 7 * 24 * time.Hour
+)
+
+// RegisterCommands регистрирует пользовательские команды.
+func (m *AntiraidModule) RegisterCommands(bot *tele.Bot) {
+	bot.Handle("/antiraid", m.handleHelp)
+}
+
+// RegisterAdminCommands регистрирует админские команды.
+func (m *AntiraidModule) RegisterAdminCommands(bot *tele.Bot) {
+	bot.Handle("/raidmode", m.handleRaidMode)
+}
+
+// handleHelp — /antiraid: справка и текущее состояние антирейда.
+func (m *AntiraidModule) handleHelp(c tele.Context) error {
+	msg := "🛡 <b>Модуль Antiraid</b> — Защита от рейдов\n\n"
+	msg += "Следит за всплесками вступлений в чат. Если за короткое время вступает слишком много людей, "
+	msg += "включается режим рейда: новые участники ограничиваются или удаляются, приветствия и капча им не отправляются, "
+	msg += "админы получают оповещение. Режим снимается сам через cooldown.\n\n"
+	msg += "<b>Команды (только админы):</b>\n\n"
+	msg += "🔹 <code>/raidmode</code> — Текущее состояние\n"
+	msg += "🔹 <code>/raidmode on [длительность]</code> — Включить режим рейда вручную\n"
+	msg += "   📌 <code>/raidmode on 1h</code>\n"
+	msg += "🔹 <code>/raidmode off</code> — Снять режим рейда\n"
+	msg += "🔹 <code>/raidmode auto &lt;N&gt; &lt;сек&gt; [cooldown]</code> — Порог автодетектора\n"
+	msg += "   📌 <code>/raidmode auto 10 60 15m</code> — больше 10 вступлений за 60 сек → рейд на 15 минут\n"
+	msg += "🔹 <code>/raidmode auto on|off</code> — Включить/выключить автодетектор\n"
+	msg += "🔹 <code>/raidmode action restrict|kick</code> — Что делать с новыми участниками в режиме рейда\n\n"
+
+	settings, err := m.raidRepo.GetSettings(c.Chat().ID)
+	if err != nil {
+		m.logger.Error("failed to get raid settings", zap.Error(err))
+		return c.Send("❌ Не удалось получить настройки антирейда")
+	}
+	msg += "📊 <b>Сейчас:</b>\n" + describe(settings, time.Now())
+
+	return c.Send(msg, &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// handleRaidMode — /raidmode [on [дл.] | off | auto <N> <сек> [cooldown] | auto on|off | action restrict|kick]
+func (m *AntiraidModule) handleRaidMode(c tele.Context) error {
+	chatID := c.Chat().ID
+	args := c.Args()
+
+	settings, err := m.raidRepo.GetSettings(chatID)
+	if err != nil {
+		m.logger.Error("failed to get raid settings", zap.Error(err))
+		return c.Send("❌ Не удалось получить настройки антирейда")
+	}
+
+	if len(args) == 0 {
+		return c.Send("🛡 Антирейд\n\n" + describe(settings, time.Now()) + "\n\n" + raidModeUsage)
+	}
+
+	// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, chatID)
+
+	switch strings.ToLower(args[0]) {
+	case "on":
+		d := settings.Cooldown
+		if len(args) > 1 {
+			if d, err = core.ParseDuration(args[1]); err != nil {
+				return c.Send("❌ " + err.Error())
+			}
+			if d > maxRaidDuration {
+				return c.Send("❌ Режим рейда — не дольше " + core.FormatDuration(maxRaidDuration))
+			}
+		}
+		until := time.Now().Add(d)
+		if err := m.raidRepo.SetRaidUntil(chatID, &until); err != nil {
+			m.logger.Error("failed to enable raid mode", zap.Error(err))
+			return c.Send("❌ Не удалось включить режим рейда")
+		}
+
+		_ = m.eventRepo.Log(chatID, c.Sender().ID, "antiraid", "raid_mode_on",
+			fmt.Sprintf("Raid mode enabled manually until %s", until.Format(time.RFC3339)))
+		return c.Send(fmt.Sprintf("🚨 Режим рейда включён на %s: новые участники %s, приветствия отключены.\nСнять: /raidmode off",
+			core.FormatDuration(d), actionDescription(settings.Action)))
+
+	case "off":
+		if err := m.raidRepo.SetRaidUntil(chatID, nil); err != nil {
+			m.logger.Error("failed to disable raid mode", zap.Error(err))
+			return c.Send("❌ Не удалось снять режим рейда")
+		}
+		m.reset(chatID)
+
+		_ = m.eventRepo.Log(chatID, c.Sender().ID, "antiraid", "raid_mode_off", "Raid mode disabled manually")
+		return c.Send("✅ Режим рейда снят")
+
+	case "action":
+		if len(args) != 2 || (args[1] != "restrict" && args[1] != "kick") {
+			return c.Send("Использование: /raidmode action restrict|kick")
+		}
+		settings.Action = args[1]
+		if err := m.saveSettings(c, settings); err != nil {
+			return c.Send("❌ Не удалось сохранить настройки антирейда")
+		}
+		return c.Send("✅ В режиме рейда новые участники " + actionDescription(settings.Action))
+
+	case "auto":
+		if len(args) == 2 && (args[1] == "on" || args[1] == "off") {
+			settings.AutoEnabled = args[1] == "on"
+			if err := m.saveSettings(c, settings); err != nil {
+				return c.Send("❌ Не удалось сохранить настройки антирейда")
+			}
+			if !settings.AutoEnabled {
+				return c.Send("✅ Автодетектор рейдов выключен. Режим рейда можно включить вручную: /raidmode on")
+			}
+			return c.Send("✅ Автодетектор рейдов включён: " + describeThreshold(settings))
+		}
+
+		if len(args) < 3 || len(args) > 4 {
+			return c.Send(raidModeUsage)
+		}
+		threshold, err := strconv.Atoi(args[1])
+		if err != nil || threshold < minThreshold || threshold > maxThreshold {
+			return c.Send(fmt.Sprintf("❌ N — число от %d до %d", minThreshold, maxThreshold))
+		}
+		window, err := strconv.Atoi(args[2])
+		if err != nil || window < minWindow || window > maxWindow {
+			return c.Send(fmt.Sprintf("❌ Окно — от %d до %d секунд", minWindow, maxWindow))
+		}
+		if len(args) == 4 {
+			cooldown, err := core.ParseDuration(args[3])
+			if err != nil {
+				return c.Send("❌ " + err.Error())
+			}
+			if cooldown > maxRaidDuration {
+				return c.Send("❌ Cooldown — не дольше " + core.FormatDuration(maxRaidDuration))
+			}
+			settings.Cooldown = cooldown
+		}
+
+		settings.AutoEnabled = true
+		settings.JoinThreshold = threshold
+		settings.Window = time.Duration(window) * time.Second
+		if err := m.saveSettings(c, settings); err != nil {
+			return c.Send("❌ Не удалось сохранить настройки антирейда")
+		}
+		return c.Send("✅ Автодетектор рейдов: " + describeThreshold(settings))
+
+	default:
+		return c.Send(raidModeUsage)
+	}
+}
+
+// saveSettings сохраняет порог и действие антирейда и пишет событие в event_log.
+func (m *AntiraidModule) saveSettings(c tele.Context, settings *repositories.RaidSettings) error {
+	chatID := c.Chat().ID
+	if err := m.raidRepo.SetSettings(chatID, settings, c.Sender().ID); err != nil {
+		m.logger.Error("failed to set raid settings", zap.Error(err))
+		return err
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "antiraid", "set_raid",
+		fmt.Sprintf("Set antiraid: auto=%t threshold=%d window=%s cooldown=%s action=%s",
+			settings.AutoEnabled, settings.JoinThreshold, settings.Window, settings.Cooldown, settings.Action))
+	return nil
+}
+
+// raidModeUsage — подсказка по формату /raidmode.
+const raidModeUsage = "Использование:\n" +
+	"/raidmode on [длительность]\n" +
+	"/raidmode off\n" +
+	"/raidmode auto <N> <сек> [cooldown]\n" +
+	"/raidmode auto on|off\n" +
+	"/raidmode action restrict|kick"
+
+// describeThreshold — порог автодетектора в тексте бота.
+func describeThreshold(s *repositories.RaidSettings) string {
+	return fmt.Sprintf("больше %d вступлений за %d сек. → режим рейда на %s",
+		s.JoinThreshold, int(s.Window/time.Second), core.FormatDuration(s.Cooldown))
+}
+
+// describe — состояние антирейда чата для ответов бота.
+func describe(s *repositories.RaidSettings, now time.Time) string {
+	var text string
+	if s.Active(now) {
+		text = "🚨 Режим рейда включён, осталось " + core.FormatDuration(s.RaidUntil.Sub(now)) + "\n"
+	} else {
+		text = "Режим рейда выключен\n"
+	}
+	if s.AutoEnabled {
+		text += "Автодетектор: " + describeThreshold(s) + "\n"
+	} else {
+		text += "Автодетектор: выключен\n"
+	}
+	text += "Новые участники в режиме рейда " + actionDescription(s.Action)
+	return text
+}
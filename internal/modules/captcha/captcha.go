@@ -0,0 +1,501 @@
+package captcha
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/metrics"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+const (
+	// callbackUnique — идентификатор inline-кнопок капчи (telebot: "\fcaptcha|<data>").
+	callbackUnique = "captcha"
+	// maxAttempts — число неверных ответов на math-капчу до применения fail_action.
+	// После каждого неверного ответа пример и варианты меняются, поэтому бот,
+	// нажимающий кнопки наугад, проходит с вероятностью 1 - (7/8)^2 ≈ 23%.
+	maxAttempts = 2
+	// mathOptions — число вариантов ответа math-капчи (два ряда по 4 кнопки).
+	mathOptions = 8
+	// mathMin, mathMax — диапазон суммы двух слагаемых от 1 до 9; из него же берутся
+	// неверные варианты, чтобы правильный ответ не выделялся среди них.
+	mathMin = 2
+	mathMax = 18
+	// minTimeout, maxTimeout — допустимый диапазон таймаута капчи (секунды).
+	minTimeout = 30
+	maxTimeout = 3600
+)
+
+// CaptchaModule проверяет новых участников чата.
+// При вступлении пользователь ограничивается (не может писать) и получает капчу
+// с inline-кнопками. Решил — ограничения снимаются, не успел за timeout — кик или бан.
+// Ожидающие капчи хранятся в PostgreSQL и переживают рестарт бота.
+type CaptchaModule struct {
+	db          *sql.DB
+	bot         *tele.Bot
+	logger      *zap.Logger
+	captchaRepo *repositories.CaptchaRepository
+	eventRepo   *repositories.EventRepository
+	punisher    core.Punisher // возврат прав с учётом наказаний, выданных во время капчи
+	cron        *cron.Cron
+	running     atomic.Bool // воркер истёкших капч запущен (для /readyz)
+}
+
+// New создаёт новый инстанс модуля капчи.
+func New(db *sql.DB, captchaRepo *repositories.CaptchaRepository, eventRepo *repositories.EventRepository, punisher core.Punisher, logger *zap.Logger, bot *tele.Bot) *CaptchaModule {
+	m := &CaptchaModule{
+		db:          db,
+		bot:         bot,
+		logger:      logger,
+		captchaRepo: captchaRepo,
+		eventRepo:   eventRepo,
+		punisher:    punisher,
+		cron:        cron.New(),
+	}
+
+	logger.Info("captcha module created")
+	return m
+}
+
+// Name возвращает имя модуля.
+func (m *CaptchaModule) Name() string { return "captcha" }
+
+// Priority — капча реагирует на вступление (JoinHandler), а не на сообщения.
+func (m *CaptchaModule) Priority() int { return core.PriorityNone }
+
+// OnMessage не вызывается (Priority = PriorityNone), нужен для core.Module.
+func (m *CaptchaModule) OnMessage(ctx *core.MessageContext) error { return nil }
+
+// Start запускает воркер, который обрабатывает истёкшие капчи.
+// Капчи, истёкшие пока бот был выключен, обрабатываются первым же проходом.
+func (m *CaptchaModule) Start() error {
+	m.logger.Info("starting captcha module")
+
+	if _, err := m.cron.AddFunc("@every 10s", m.processExpired); err != nil {
+		return fmt.Errorf("failed to schedule captcha expiry worker: %w", err)
+	}
+
+	m.cron.Start()
+	m.running.Store(true)
+	m.logger.Info("captcha expiry worker started")
+	return nil
+}
+
+// Shutdown выполняет graceful shutdown модуля.
+func (m *CaptchaModule) Shutdown() error {
+	m.logger.Info("shutting down captcha module")
+	m.running.Store(false)
+	ctx := m.cron.Stop()
+	<-ctx.Done()
+	m.logger.Info("captcha expiry worker stopped")
+	return nil
+}
+
+// HealthCheck сообщает /readyz, запущен ли воркер истёкших капч.
+func (m *CaptchaModule) HealthCheck() error {
+	if !m.running.Load() {
+		return errors.New("captcha expiry worker is not running")
+	}
+	return nil
+}
+
+// RegisterCommands регистрирует пользовательские команды и обработчик кнопок капчи.
+func (m *CaptchaModule) RegisterCommands(bot *tele.Bot) {
+	bot.Handle("/captcha", m.handleCaptchaInfo)
+	bot.Handle(&tele.Btn{Unique: callbackUnique}, m.handleCallback)
+}
+
+// RegisterAdminCommands регистрирует админские команды.
+func (m *CaptchaModule) RegisterAdminCommands(bot *tele.Bot) {
+	bot.Handle("/setcaptcha", m.handleSetCaptcha)
+}
+
+// OnUserJoined ограничивает новичка и отправляет ему капчу.
+// Если капча выключена в чате — ничего не делает, приветствие отправляется как обычно.
+func (m *CaptchaModule) OnUserJoined(ctx *core.JoinContext) error {
+	if ctx.User.IsBot {
+		return nil
+	}
+
+	chatID := ctx.Chat.ID
+	settings, err := m.captchaRepo.GetSettings(chatID)
+	if err != nil {
+		return err
+	}
+	if !settings.Enabled {
+		return nil
+	}
+
+	// Сначала ограничиваем: без прав администратора капча бессмысленна
+	if err := ctx.Bot.Restrict(ctx.Chat, &tele.ChatMember{
+		User:            ctx.User,
+		Rights:          tele.NoRights(),
+		RestrictedUntil: tele.Forever(),
+	}); err != nil {
+		return fmt.Errorf("restrict new member: %w", err)
+	}
+
+	answer, problem := newAnswer(settings.ChallengeType)
+	text, markup := buildChallenge(ctx.User, settings.ChallengeType, problem, settings.TimeoutSeconds, maxAttempts, false)
+
+	challenge := &repositories.CaptchaChallenge{
+		ChatID:        chatID,
+		UserID:        ctx.User.ID,
+		ThreadID:      ctx.ThreadID,
+		ChallengeType: settings.ChallengeType,
+		Answer:        answer,
+		FailAction:    settings.FailAction,
+		ExpiresAt:     time.Now().Add(time.Duration(settings.TimeoutSeconds) * time.Second),
+	}
+	// Запись создаётся до отправки: пользователь может нажать кнопку раньше,
+	// чем мы получим ID сообщения
+	if err := m.captchaRepo.CreateChallenge(challenge); err != nil {
+		return err
+	}
+
+	sendOpts := &tele.SendOptions{ReplyMarkup: markup, ParseMode: tele.ModeHTML}
+	if ctx.ThreadID != 0 {
+		sendOpts.ThreadID = ctx.ThreadID
+	}
+	sent, err := ctx.Bot.Send(ctx.Chat, text, sendOpts)
+	if err != nil {
+		// Капча не дошла — воркер всё равно применит fail_action по таймауту,
+		// но лучше сразу вернуть пользователю права, чем наказать за сбой бота
+		m.logger.Error("failed to send captcha, lifting restriction",
+			zap.Int64("chat_id", chatID), zap.Int64("user_id", ctx.User.ID), zap.Error(err))
+		_, _ = m.captchaRepo.DeleteChallenge(chatID, ctx.User.ID)
+		m.unrestrict(ctx.Chat, ctx.User)
+		return fmt.Errorf("send captcha: %w", err)
+	}
+
+	if err := m.captchaRepo.SetChallengeMessage(chatID, ctx.User.ID, sent.ID); err != nil {
+		m.logger.Error("failed to save captcha message id", zap.Error(err))
+	}
+
+	ctx.SuppressGreeting = true
+
+	_ = m.eventRepo.Log(chatID, ctx.User.ID, "captcha", "captcha_sent",
+		fmt.Sprintf("Captcha %s sent, timeout %ds", settings.ChallengeType, settings.TimeoutSeconds))
+
+	m.logger.Info("captcha sent",
+		zap.Int64("chat_id", chatID),
+		zap.Int64("user_id", ctx.User.ID),
+		zap.String("type", settings.ChallengeType))
+	return nil
+}
+
+// mathChallenge — пример math-капчи: a + b и варианты ответа.
+type mathChallenge struct {
+	a, b    int
+	options []int // правильный ответ и неверные, перемешанные
+}
+
+// newMathChallenge генерирует пример и mathOptions уникальных вариантов ответа.
+// Неверные варианты берутся из всего диапазона сумм, а не рядом с ответом:
+// иначе ответ угадывался бы как середина вариантов.
+func newMathChallenge() mathChallenge {
+	a, b := rand.IntN(9)+1, rand.IntN(9)+1
+	answer := a + b
+
+	options := []int{answer}
+	for _, candidate := range rand.Perm(mathMax - mathMin + 1) {
+		if len(options) == mathOptions {
+			break
+		}
+		if candidate += mathMin; candidate != answer {
+			options = append(options, candidate)
+		}
+	}
+	rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+	return mathChallenge{a: a, b: b, options: options}
+}
+
+// newAnswer генерирует правильный ответ капчи (и пример для math).
+func newAnswer(challengeType string) (string, mathChallenge) {
+	if challengeType != "math" {
+		return "ok", mathChallenge{}
+	}
+	problem := newMathChallenge()
+	return strconv.Itoa(problem.a + problem.b), problem
+}
+
+// buildChallenge формирует текст и кнопки капчи. retry — пример выдан заново после неверного ответа.
+// Данные кнопки: "<user_id>|<ответ>" — нажать может только вступивший пользователь.
+func buildChallenge(user *tele.User, challengeType string, problem mathChallenge, secondsLeft, attemptsLeft int, retry bool) (string, *tele.ReplyMarkup) {
+	markup := &tele.ReplyMarkup{}
+	userID := strconv.FormatInt(user.ID, 10)
+	name := html.EscapeString(core.DisplayName(user))
+
+	if challengeType == "math" {
+		buttons := make([]tele.Btn, 0, len(problem.options))
+		for _, opt := range problem.options {
+			value := strconv.Itoa(opt)
+			buttons = append(buttons, markup.Data(value, callbackUnique, userID, value))
+		}
+		markup.Inline(markup.Split(4, buttons)...)
+
+		header := fmt.Sprintf("👋 %s, добро пожаловать!", name)
+		if retry {
+			header = fmt.Sprintf("❌ %s, неверно — вот новый пример.", name)
+		}
+		text := fmt.Sprintf("%s\n\n"+
+			"Чтобы писать в чат, решите пример: <b>%d + %d = ?</b>\n"+
+			"⏱ Время: %d сек. Попыток: %d.", header, problem.a, problem.b, secondsLeft, attemptsLeft)
+		return text, markup
+	}
+
+	markup.Inline(markup.Row(markup.Data("✅ Я не бот", callbackUnique, userID, "ok")))
+	text := fmt.Sprintf("👋 %s, добро пожаловать!\n\n"+
+		"Чтобы писать в чат, нажмите кнопку ниже.\n"+
+		"⏱ Время: %d сек.", name, secondsLeft)
+	return text, markup
+}
+
+// handleCallback обрабатывает нажатие кнопки капчи.
+func (m *CaptchaModule) handleCallback(c tele.Context) error {
+	cb := c.Callback()
+	parts := strings.SplitN(cb.Data, "|", 2)
+	if len(parts) != 2 {
+		return c.Respond()
+	}
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return c.Respond()
+	}
+
+	if c.Sender().ID != userID {
+		return c.Respond(&tele.CallbackResponse{Text: "Это капча для другого участника", ShowAlert: true})
+	}
+
+	chat := c.Chat()
+	challenge, err := m.captchaRepo.GetChallenge(chat.ID, userID)
+	if err != nil {
+		m.logger.Error("failed to get captcha challenge", zap.Error(err))
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка, попробуйте ещё раз"})
+	}
+	if challenge == nil {
+		return c.Respond(&tele.CallbackResponse{Text: "Капча уже неактуальна"})
+	}
+
+	if parts[1] == challenge.Answer {
+		// DeleteChallenge = false: капчу одновременно обработал воркер по таймауту
+		if deleted, err := m.captchaRepo.DeleteChallenge(chat.ID, userID); err != nil || !deleted {
+			return c.Respond(&tele.CallbackResponse{Text: "Капча уже неактуальна"})
+		}
+		m.unrestrict(chat, c.Sender())
+		_ = c.Delete()
+
+		_ = m.eventRepo.Log(chat.ID, userID, "captcha", "captcha_passed", "Captcha passed")
+		m.logger.Info("captcha passed", zap.Int64("chat_id", chat.ID), zap.Int64("user_id", userID))
+		return c.Respond(&tele.CallbackResponse{Text: "✅ Проверка пройдена, добро пожаловать!"})
+	}
+
+	// Неверный ответ: новый пример с другими вариантами — перебор кнопок по очереди не помогает
+	answer, problem := newAnswer(challenge.ChallengeType)
+	attempts, ok, err := m.captchaRepo.NextAttempt(chat.ID, userID, challenge.Answer, answer)
+	if err != nil {
+		m.logger.Error("failed to record captcha attempt", zap.Error(err))
+		return c.Respond()
+	}
+	if !ok {
+		// Пример уже сменился (двойное нажатие) — это нажатие по старым кнопкам не считается
+		return c.Respond(&tele.CallbackResponse{Text: "Пример обновлён, попробуйте ещё раз"})
+	}
+	if attempts >= maxAttempts {
+		m.fail(challenge, "too many wrong answers")
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Проверка не пройдена"})
+	}
+
+	secondsLeft := max(int(time.Until(challenge.ExpiresAt).Seconds()), 0)
+	text, markup := buildChallenge(c.Sender(), challenge.ChallengeType, problem, secondsLeft, maxAttempts-attempts, true)
+	if err := c.Edit(text, &tele.SendOptions{ReplyMarkup: markup, ParseMode: tele.ModeHTML}); err != nil {
+		m.logger.Error("failed to update captcha", zap.Int64("chat_id", chat.ID), zap.Int64("user_id", userID), zap.Error(err))
+	}
+	return c.Respond(&tele.CallbackResponse{
+		Text: fmt.Sprintf("❌ Неверно. Новый пример, осталось попыток: %d", maxAttempts-attempts),
+	})
+}
+
+// processExpired применяет fail_action к капчам с истёкшим сроком.
+func (m *CaptchaModule) processExpired() {
+	challenges, err := m.captchaRepo.GetExpiredChallenges(time.Now())
+	if err != nil {
+		m.logger.Error("failed to get expired captchas", zap.Error(err))
+		return
+	}
+
+	for i := range challenges {
+		m.fail(&challenges[i], "timeout")
+	}
+}
+
+// fail удаляет капчу и применяет к пользователю fail_action (kick или ban).
+// Кик = бан + немедленный разбан: пользователь удаляется, но может вернуться.
+func (m *CaptchaModule) fail(challenge *repositories.CaptchaChallenge, reason string) {
+	deleted, err := m.captchaRepo.DeleteChallenge(challenge.ChatID, challenge.UserID)
+	if err != nil {
+		m.logger.Error("failed to delete captcha challenge", zap.Error(err))
+		return
+	}
+	if !deleted {
+		// Пользователь успел решить капчу, либо её обработал параллельный вызов
+		return
+	}
+
+	chat := &tele.Chat{ID: challenge.ChatID}
+	user := &tele.User{ID: challenge.UserID}
+
+	if challenge.MessageID != 0 {
+		_ = m.bot.Delete(&tele.Message{ID: challenge.MessageID, Chat: chat})
+	}
+
+	member := &tele.ChatMember{User: user, RestrictedUntil: tele.Forever()}
+	if err := m.bot.Ban(chat, member); err != nil {
+		m.logger.Error("failed to remove user after captcha failure",
+			zap.Int64("chat_id", challenge.ChatID),
+			zap.Int64("user_id", challenge.UserID),
+			zap.Error(err))
+		return
+	}
+	if challenge.FailAction == "ban" {
+		metrics.BansTotal.WithLabelValues("captcha").Inc()
+	} else if err := m.bot.Unban(chat, user); err != nil {
+		m.logger.Error("failed to unban kicked user", zap.Error(err))
+	}
+
+	_ = m.eventRepo.Log(challenge.ChatID, challenge.UserID, "captcha", "captcha_failed",
+		fmt.Sprintf("Captcha failed (%s), action: %s", reason, challenge.FailAction))
+
+	m.logger.Info("captcha failed",
+		zap.Int64("chat_id", challenge.ChatID),
+		zap.Int64("user_id", challenge.UserID),
+		zap.String("reason", reason),
+		zap.String("action", challenge.FailAction))
+}
+
+// unrestrict снимает ограничения, наложенные при вступлении: возвращает права
+// участников чата по умолчанию. Мут или запрет, выданные, пока капча ждала ответа,
+// остаются в силе — права пересчитывает moderation.
+func (m *CaptchaModule) unrestrict(chat *tele.Chat, user *tele.User) {
+	base := tele.NoRestrictions()
+	if full, err := m.bot.ChatByID(chat.ID); err != nil {
+		m.logger.Warn("failed to get chat permissions, lifting all restrictions",
+			zap.Int64("chat_id", chat.ID), zap.Error(err))
+	} else if full.Permissions != nil {
+		base = *full.Permissions
+	}
+
+	if err := m.punisher.Unrestrict(chat, user, base); err != nil {
+		m.logger.Error("failed to lift captcha restriction",
+			zap.Int64("chat_id", chat.ID), zap.Int64("user_id", user.ID), zap.Error(err))
+	}
+}
+
+// handleCaptchaInfo — /captcha: справка и текущие настройки чата.
+func (m *CaptchaModule) handleCaptchaInfo(c tele.Context) error {
+	settings, err := m.captchaRepo.GetSettings(c.Chat().ID)
+	if err != nil {
+		m.logger.Error("failed to get captcha settings", zap.Error(err))
+		return c.Send("❌ Ошибка при получении настроек капчи")
+	}
+
+	status := "❌ выключена"
+	if settings.Enabled {
+		status = "✅ включена"
+	}
+
+	msg := "🛡 <b>Модуль Captcha</b> — Проверка новых участников\n\n"
+	msg += "Новичок не может писать, пока не решит капчу. Не успел — удаляется из чата.\n\n"
+	msg += fmt.Sprintf("<b>Статус:</b> %s\n", status)
+	msg += fmt.Sprintf("<b>Тип:</b> %s\n", settings.ChallengeType)
+	msg += fmt.Sprintf("<b>Таймаут:</b> %d сек.\n", settings.TimeoutSeconds)
+	msg += fmt.Sprintf("<b>При провале:</b> %s\n\n", settings.FailAction)
+
+	msg += "<b>Настройка (только админы):</b>\n"
+	msg += "<code>/setcaptcha on|off [button|math] [секунды] [kick|ban]</code>\n"
+	msg += "📌 Пример: <code>/setcaptcha on math 180 kick</code>\n\n"
+	msg += "• <b>button</b> — нажать кнопку «Я не бот»\n"
+	msg += fmt.Sprintf("• <b>math</b> — решить пример, %d попытки (после ошибки — новый пример)\n", maxAttempts)
+	msg += "• <b>kick</b> — удалить (может вернуться), <b>ban</b> — забанить\n\n"
+	msg += "⚠️ Боту нужны права администратора на ограничение и бан участников."
+
+	return c.Send(msg, &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// handleSetCaptcha — /setcaptcha on|off [button|math] [timeout_sec] [kick|ban].
+// Параметры после on/off — в любом порядке; не указанные сохраняют текущее значение.
+func (m *CaptchaModule) handleSetCaptcha(c tele.Context) error {
+	chatID := c.Chat().ID
+	args := c.Args()
+	if len(args) == 0 {
+		return c.Send("Использование: /setcaptcha on|off [button|math] [секунды] [kick|ban]\nПример: /setcaptcha on math 180 kick")
+	}
+
+	settings, err := m.captchaRepo.GetSettings(chatID)
+	if err != nil {
+		m.logger.Error("failed to get captcha settings", zap.Error(err))
+		return c.Send("❌ Ошибка при получении настроек капчи")
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on":
+		settings.Enabled = true
+	case "off":
+		settings.Enabled = false
+	default:
+		return c.Send("❌ Первый параметр: on или off")
+	}
+
+	for _, arg := range args[1:] {
+		arg = strings.ToLower(arg)
+		switch arg {
+		case "button", "math":
+			settings.ChallengeType = arg
+		case "kick", "ban":
+			settings.FailAction = arg
+		default:
+			timeout, err := strconv.Atoi(arg)
+			if err != nil {
+				return c.Send(fmt.Sprintf("❌ Неизвестный параметр: %s", arg))
+			}
+			if timeout < minTimeout || timeout > maxTimeout {
+				return c.Send(fmt.Sprintf("❌ Таймаут должен быть от %d до %d секунд", minTimeout, maxTimeout))
+			}
+			settings.TimeoutSeconds = timeout
+		}
+	}
+
+	// captcha_settings имеет REFERENCES chats(chat_id) — без записи в chats INSERT упадёт
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, chatID)
+
+	if err := m.captchaRepo.SetSettings(chatID, settings, c.Sender().ID); err != nil {
+		m.logger.Error("failed to save captcha settings", zap.Error(err))
+		return c.Send("❌ Ошибка при сохранении настроек капчи")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "captcha", "set_captcha",
+		fmt.Sprintf("enabled=%t type=%s timeout=%d action=%s",
+			settings.Enabled, settings.ChallengeType, settings.TimeoutSeconds, settings.FailAction))
+
+	if !settings.Enabled {
+		return c.Send("✅ Капча выключена")
+	}
+	return c.Send(fmt.Sprintf("✅ Капча включена\nТип: %s\nТаймаут: %d сек.\nПри провале: %s",
+		settings.ChallengeType, settings.TimeoutSeconds, settings.FailAction))
+}
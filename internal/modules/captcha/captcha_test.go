@@ -0,0 +1,98 @@
+package captcha
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// TestNewMathChallenge проверяет варианты ответа math-капчи
+func TestNewMathChallenge(t *testing.T) {
+	positions := make([]int, mathOptions)
+	for range 1000 {
+		ch := newMathChallenge()
+		answer := ch.a + ch.b
+
+		if ch.a < 1 || ch.a > 9 || ch.b < 1 || ch.b > 9 {
+			t.Fatalf("Addends out of range: %d + %d", ch.a, ch.b)
+		}
+		if len(ch.options) != mathOptions {
+			t.Fatalf("Expected %d options, got %d", mathOptions, len(ch.options))
+		}
+		seen := make(map[int]bool)
+		for _, opt := range ch.options {
+			if opt < mathMin || opt > mathMax {
+				t.Fatalf("Option %d out of range [%d, %d]", opt, mathMin, mathMax)
+			}
+			if seen[opt] {
+				t.Fatalf("Duplicate option %d in %v", opt, ch.options)
+			}
+			seen[opt] = true
+		}
+		i := slices.Index(ch.options, answer)
+		if i < 0 {
+			t.Fatalf("Answer %d missing from %v", answer, ch.options)
+		}
+		positions[i]++
+	}
+
+	// Ответ перемешан, а не стоит на фиксированном месте
+	for i, n := range positions {
+		if n == 0 {
+			t.Errorf("Answer never placed at position %d: %v", i, positions)
+		}
+	}
+}
+
+// TestNewAnswer проверяет правильный ответ для обоих типов капчи
+func TestNewAnswer(t *testing.T) {
+	answer, problem := newAnswer("math")
+	if answer != strconv.Itoa(problem.a+problem.b) {
+		t.Errorf("Expected answer %d, got %s", problem.a+problem.b, answer)
+	}
+
+	if answer, _ := newAnswer("button"); answer != "ok" {
+		t.Errorf("Expected button answer 'ok', got %s", answer)
+	}
+}
+
+// TestBuildChallenge проверяет кнопки и текст капчи
+func TestBuildChallenge(t *testing.T) {
+	user := &tele.User{ID: 42, FirstName: "<Test>"}
+	problem := newMathChallenge()
+
+	text, markup := buildChallenge(user, "math", problem, 120, maxAttempts, false)
+	var data []string
+	for _, row := range markup.InlineKeyboard {
+		if len(row) > 4 {
+			t.Errorf("Expected at most 4 buttons per row, got %d", len(row))
+		}
+		for _, btn := range row {
+			data = append(data, btn.Data)
+		}
+	}
+	if len(data) != mathOptions {
+		t.Fatalf("Expected %d buttons, got %d", mathOptions, len(data))
+	}
+	for i, d := range data {
+		if want := "42|" + strconv.Itoa(problem.options[i]); d != want {
+			t.Errorf("Button %d: expected data %q, got %q", i, want, d)
+		}
+	}
+	if !strings.Contains(text, "&lt;Test&gt;") {
+		t.Errorf("Expected escaped name in text: %s", text)
+	}
+
+	retry, _ := buildChallenge(user, "math", problem, 60, 1, true)
+	if !strings.Contains(retry, "новый пример") || !strings.Contains(retry, "Попыток: 1") {
+		t.Errorf("Unexpected retry text: %s", retry)
+	}
+
+	_, markup = buildChallenge(user, "button", problem, 120, maxAttempts, false)
+	if len(markup.InlineKeyboard) != 1 || len(markup.InlineKeyboard[0]) != 1 {
+		t.Errorf("Expected single button, got %v", markup.InlineKeyboard)
+	}
+}
@@ -0,0 +1,315 @@
+package limiter
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// forwardPolicyNames — политики пересылок в тексте бота.
+var forwardPolicyNames = map[string]string{
+	repositories.ForwardAllow:        "пересылки разрешены",
+	repositories.ForwardDeny:         "пересылки запрещены",
+	repositories.ForwardDenyChannels: "запрещены пересылки из каналов",
+	repositories.ForwardWhitelist:    "разрешены пересылки только из whitelist",
+}
+
+// forwardNoticeInterval — не чаще одного уведомления о запрещённой пересылке
+// на пользователя за это время: серия пересылок удаляется молча.
+const forwardNoticeInterval = 10 * time.Minute
+
+// forwardNoticeKey — пользователь в чате для уведомлений о запрещённых пересылках.
+type forwardNoticeKey struct {
+	chatID int64
+	userID int64
+}
+
+// forwardOrigin — откуда переслано сообщение.
+type forwardOrigin struct {
+	id      int64  // ID канала, группы или пользователя; 0 — скрытый отправитель
+	title   string // название или имя для whitelist
+	channel bool   // переслано из канала
+}
+
+// forwardSource определяет источник пересылки. Сначала смотрим forward_origin
+// (Bot API 7.0+), затем устаревшие поля forward_from / forward_from_chat.
+func forwardSource(msg *tele.Message) forwardOrigin {
+	if o := msg.Origin; o != nil {
+		switch {
+		case o.Chat != nil:
+			return forwardOrigin{id: o.Chat.ID, title: chatTitle(o.Chat), channel: o.Chat.Type == tele.ChatChannel}
+		case o.SenderChat != nil:
+			return forwardOrigin{id: o.SenderChat.ID, title: chatTitle(o.SenderChat), channel: o.SenderChat.Type == tele.ChatChannel}
+		case o.Sender != nil:
+			return forwardOrigin{id: o.Sender.ID, title: core.DisplayName(o.Sender)}
+		default:
+			// hidden_user: пользователь скрыл аккаунт в пересылках
+			return forwardOrigin{title: o.SenderUsername, channel: o.Type == "channel"}
+		}
+	}
+	if msg.OriginalChat != nil {
+		return forwardOrigin{id: msg.OriginalChat.ID, title: chatTitle(msg.OriginalChat), channel: msg.OriginalChat.Type == tele.ChatChannel}
+	}
+	if msg.OriginalSender != nil {
+		return forwardOrigin{id: msg.OriginalSender.ID, title: core.DisplayName(msg.OriginalSender)}
+	}
+	return forwardOrigin{title: msg.OriginalSenderName}
+}
+
+// chatTitle — название чата или @username, если названия нет.
+func chatTitle(chat *tele.Chat) string {
+	if chat.Title != "" {
+		return chat.Title
+	}
+	if chat.Username != "" {
+		return "@" + chat.Username
+	}
+	return strconv.FormatInt(chat.ID, 10)
+}
+
+// checkForwardPolicy отмечает пересылку и применяет к ней политику пересылок.
+// forward — сообщение переслано: тип контента у него остаётся своим (photo, text...),
+// а для лимита forward оно помечается в metadata (metadata->'forward').
+// denied — пересылка запрещена и сообщение удалено.
+// Админы чата политикой пересылок не ограничиваются.
+func (m *LimiterModule) checkForwardPolicy(ctx *core.MessageContext) (forward, denied bool) {
+	if !core.IsForward(ctx.Message) {
+		return false, false
+	}
+
+	chatID := ctx.Chat.ID
+	source := forwardSource(ctx.Message)
+	forwardMeta := repositories.ForwardMetadata{Detected: true, SourceID: source.id}
+	if err := m.messageRepo.UpdateMessageMetadata(chatID, ctx.Message.ID, "forward", forwardMeta); err != nil {
+		m.logger.Error("failed to update forward metadata", zap.Error(err))
+	}
+
+	policy, err := m.forwardRepo.GetPolicy(chatID, ctx.ThreadID)
+	if err != nil {
+		m.logger.Error("failed to get forward policy", zap.Error(err))
+		return true, false
+	}
+	if policy == repositories.ForwardAllow {
+		return true, false
+	}
+
+	switch policy {
+	case repositories.ForwardDeny:
+		denied = true
+	case repositories.ForwardDenyChannels:
+		denied = source.channel
+	case repositories.ForwardWhitelist:
+		allowed := false
+		if source.id != 0 {
+			if allowed, err = m.forwardRepo.IsWhitelisted(chatID, ctx.ThreadID, source.id); err != nil {
+				m.logger.Error("failed to check forward whitelist", zap.Error(err))
+				return true, false
+			}
+		}
+		denied = !allowed
+	}
+	if !denied {
+		return true, false
+	}
+
+	if member, err := m.bot.ChatMemberOf(ctx.Chat, ctx.Sender); err == nil &&
+		(member.Role == tele.Administrator || member.Role == tele.Creator) {
+		return true, false
+	}
+
+	if err := ctx.DeleteMessage("forward_policy"); err != nil {
+		m.logger.Error("failed to delete forward", zap.Error(err))
+	}
+
+	_ = m.eventRepo.Log(chatID, ctx.Sender.ID, "limiter", "forward_denied",
+		fmt.Sprintf("Forward from %d (%s) denied by policy %s", source.id, source.title, policy))
+
+	if m.markForwardNotice(forwardNoticeKey{chatID: chatID, userID: ctx.Sender.ID}, time.Now()) {
+		if err := ctx.Send(fmt.Sprintf("❌ %s, %s в этом чате", core.DisplayName(ctx.Sender), forwardPolicyNames[policy])); err != nil {
+			m.logger.Error("failed to send forward notice", zap.Error(err))
+		}
+	}
+	return true, true
+}
+
+// markForwardNotice отмечает уведомление о запрещённой пересылке в now.
+// false — пользователь уже получал уведомление за последние forwardNoticeInterval.
+// Устаревшие отметки удаляются здесь же: фоновой очистки у модуля нет.
+func (m *LimiterModule) markForwardNotice(key forwardNoticeKey, now time.Time) bool {
+	m.noticeMu.Lock()
+	defer m.noticeMu.Unlock()
+
+	if last, ok := m.forwardNotices[key]; ok && now.Sub(last) < forwardNoticeInterval {
+		return false
+	}
+	for k, last := range m.forwardNotices {
+		if now.Sub(last) >= forwardNoticeInterval {
+			delete(m.forwardNotices, k)
+		}
+	}
+	m.forwardNotices[key] = now
+	return true
+}
+
+// handleForwardPolicy — /forwardpolicy: текущая политика пересылок и whitelist.
+func (m *LimiterModule) handleForwardPolicy(c tele.Context) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+
+	policy, err := m.forwardRepo.GetPolicy(chatID, threadID)
+	if err != nil {
+		m.logger.Error("failed to get forward policy", zap.Error(err))
+		return c.Send("❌ Не удалось получить политику пересылок")
+	}
+	sources, err := m.forwardRepo.GetWhitelist(chatID, threadID)
+	if err != nil {
+		m.logger.Error("failed to get forward whitelist", zap.Error(err))
+		return c.Send("❌ Не удалось получить whitelist пересылок")
+	}
+
+	msg := "↪️ <b>Политика пересылок</b>\n\n"
+	msg += fmt.Sprintf("Сейчас: <code>%s</code> — %s\n\n", policy, forwardPolicyNames[policy])
+	if len(sources) > 0 {
+		msg += "<b>Whitelist:</b>\n"
+		for _, s := range sources {
+			scope := ""
+			if s.ThreadID != 0 {
+				scope = " (топик)"
+			}
+			msg += fmt.Sprintf("• <code>%d</code> %s%s\n", s.SourceID, html.EscapeString(s.Title), scope)
+		}
+		msg += "\n"
+	}
+	msg += "<b>Команды (только админы):</b>\n"
+	msg += "🔹 <code>/setforward allow|deny|deny_channels|whitelist</code> — Политика\n"
+	msg += "🔹 <code>/allowforward</code> — В ответ на пересланное сообщение: разрешить источник\n"
+	msg += "🔹 <code>/allowforward &lt;id|@канал&gt;</code> — Разрешить источник по ID или username\n"
+	msg += "🔹 <code>/removeforward &lt;id&gt;</code> — Убрать источник из whitelist\n\n"
+	msg += "ℹ️ Лимит на количество пересылок: <code>/setlimit forward &lt;N&gt;</code>"
+
+	return c.Send(msg, &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// handleSetForward — /setforward allow|deny|deny_channels|whitelist
+func (m *LimiterModule) handleSetForward(c tele.Context) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+	args := c.Args()
+
+	if len(args) != 1 {
+		return c.Send("Использование: /setforward allow|deny|deny_channels|whitelist")
+	}
+	policy := strings.ToLower(args[0])
+	if _, ok := forwardPolicyNames[policy]; !ok {
+		return c.Send("❌ Неизвестная политика: " + args[0] + "\n\nДопустимые: allow, deny, deny_channels, whitelist")
+	}
+
+	// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, chatID)
+
+	if err := m.forwardRepo.SetPolicy(chatID, threadID, policy, c.Sender().ID); err != nil {
+		m.logger.Error("failed to set forward policy", zap.Error(err))
+		return c.Send("❌ Не удалось сохранить политику пересылок")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "limiter", "set_forward",
+		fmt.Sprintf("Set forward policy %s (thread=%d)", policy, threadID))
+
+	msg := "✅ Политика пересылок: " + forwardPolicyNames[policy]
+	if threadID != 0 {
+		msg += " (для этого топика)"
+	}
+	if policy == repositories.ForwardWhitelist {
+		msg += "\n\nДобавить источник: /allowforward в ответ на пересланное сообщение"
+	}
+	return c.Send(msg)
+}
+
+// handleAllowForward — /allowforward [id|@канал]: добавить источник в whitelist.
+// Без аргументов — в ответ на пересланное сообщение.
+func (m *LimiterModule) handleAllowForward(c tele.Context) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+	args := c.Args()
+
+	var source forwardOrigin
+	switch {
+	case len(args) == 1 && strings.HasPrefix(args[0], "@"):
+		chat, err := m.bot.ChatByUsername(args[0])
+		if err != nil {
+			return c.Send("❌ Не удалось найти " + args[0])
+		}
+		source = forwardOrigin{id: chat.ID, title: chatTitle(chat)}
+	case len(args) == 1:
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return c.Send("❌ Укажите числовой ID или @username источника")
+		}
+		source = forwardOrigin{id: id, title: args[0]}
+	case len(args) == 0 && c.Message().ReplyTo != nil && core.IsForward(c.Message().ReplyTo):
+		source = forwardSource(c.Message().ReplyTo)
+		if source.id == 0 {
+			return c.Send("❌ Отправитель скрыл аккаунт в пересылках — его нельзя добавить в whitelist")
+		}
+	default:
+		return c.Send("Использование: /allowforward в ответ на пересланное сообщение или /allowforward <id|@канал>")
+	}
+
+	// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, chatID)
+
+	if err := m.forwardRepo.AddSource(chatID, threadID, source.id, source.title, c.Sender().ID); err != nil {
+		m.logger.Error("failed to add forward source", zap.Error(err))
+		return c.Send("❌ Не удалось добавить источник")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "limiter", "allow_forward",
+		fmt.Sprintf("Allowed forwards from %d (%s, thread=%d)", source.id, source.title, threadID))
+
+	return c.Send(fmt.Sprintf("✅ Пересылки из «%s» (%d) разрешены\nДействует при политике whitelist: /setforward whitelist", source.title, source.id))
+}
+
+// handleRemoveForward — /removeforward <id>: убрать источник из whitelist.
+func (m *LimiterModule) handleRemoveForward(c tele.Context) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+	args := c.Args()
+
+	if len(args) != 1 {
+		return c.Send("Использование: /removeforward <id>\nID источников: /forwardpolicy")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return c.Send("❌ Укажите числовой ID источника")
+	}
+
+	removed, err := m.forwardRepo.RemoveSource(chatID, threadID, id)
+	if err != nil {
+		m.logger.Error("failed to remove forward source", zap.Error(err))
+		return c.Send("❌ Не удалось удалить источник")
+	}
+	if !removed {
+		return c.Send("ℹ️ Источника нет в whitelist этого чата/топика")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "limiter", "remove_forward",
+		fmt.Sprintf("Removed forward source %d (thread=%d)", id, threadID))
+
+	return c.Send(fmt.Sprintf("✅ Источник %d убран из whitelist", id))
+}
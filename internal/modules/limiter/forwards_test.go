@@ -0,0 +1,105 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// TestForwardSource проверяет источник пересылки: forward_origin и устаревшие поля
+func TestForwardSource(t *testing.T) {
+	channel := &tele.Chat{ID: -1001, Title: "Новости", Type: tele.ChatChannel}
+	group := &tele.Chat{ID: -1002, Username: "some_group", Type: tele.ChatSuperGroup}
+	user := &tele.User{ID: 42, FirstName: "Иван"}
+
+	tests := []struct {
+		name string
+		msg  *tele.Message
+		want forwardOrigin
+	}{
+		{
+			name: "Пользователь",
+			msg:  &tele.Message{Origin: &tele.MessageOrigin{Type: "user", Sender: user}},
+			want: forwardOrigin{id: 42, title: "Иван"},
+		},
+		{
+			name: "Скрытый пользователь",
+			msg:  &tele.Message{Origin: &tele.MessageOrigin{Type: "hidden_user", SenderUsername: "Аноним"}},
+			want: forwardOrigin{title: "Аноним"},
+		},
+		{
+			name: "Группа от имени чата",
+			msg:  &tele.Message{Origin: &tele.MessageOrigin{Type: "chat", SenderChat: group}},
+			want: forwardOrigin{id: -1002, title: "@some_group"},
+		},
+		{
+			name: "Канал",
+			msg:  &tele.Message{Origin: &tele.MessageOrigin{Type: "channel", Chat: channel}},
+			want: forwardOrigin{id: -1001, title: "Новости", channel: true},
+		},
+		{
+			name: "Устаревшее forward_from_chat",
+			msg:  &tele.Message{OriginalChat: channel},
+			want: forwardOrigin{id: -1001, title: "Новости", channel: true},
+		},
+		{
+			name: "Устаревшее forward_from",
+			msg:  &tele.Message{OriginalSender: user},
+			want: forwardOrigin{id: 42, title: "Иван"},
+		},
+		{
+			name: "Устаревшее forward_sender_name",
+			msg:  &tele.Message{OriginalSenderName: "Аноним"},
+			want: forwardOrigin{title: "Аноним"},
+		},
+		{
+			name: "forward_origin важнее устаревших полей",
+			msg:  &tele.Message{Origin: &tele.MessageOrigin{Type: "user", Sender: user}, OriginalChat: channel},
+			want: forwardOrigin{id: 42, title: "Иван"},
+		},
+	}
+	for _, tc := range tests {
+		if got := forwardSource(tc.msg); got != tc.want {
+			t.Errorf("%s: forwardSource = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestMarkForwardNotice проверяет, что уведомление о запрещённой пересылке
+// отправляется пользователю не чаще раза за forwardNoticeInterval
+func TestMarkForwardNotice(t *testing.T) {
+	m := &LimiterModule{forwardNotices: make(map[forwardNoticeKey]time.Time)}
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	key := forwardNoticeKey{chatID: 1, userID: 42}
+
+	steps := []struct {
+		after time.Duration
+		want  bool
+	}{
+		{0, true},
+		{time.Second, false},
+		{forwardNoticeInterval - time.Second, false},
+		{forwardNoticeInterval, true},
+		{forwardNoticeInterval + time.Minute, false},
+	}
+	for _, s := range steps {
+		if got := m.markForwardNotice(key, start.Add(s.after)); got != s.want {
+			t.Errorf("markForwardNotice(+%s) = %t, want %t", s.after, got, s.want)
+		}
+	}
+
+	// Другой пользователь и другой чат — свои отметки
+	if !m.markForwardNotice(forwardNoticeKey{chatID: 1, userID: 43}, start.Add(forwardNoticeInterval)) {
+		t.Error("markForwardNotice(other user) = false, want true")
+	}
+	if !m.markForwardNotice(forwardNoticeKey{chatID: 2, userID: 42}, start.Add(forwardNoticeInterval)) {
+		t.Error("markForwardNotice(other chat) = false, want true")
+	}
+
+	// Устаревшие отметки удаляются
+	m.markForwardNotice(forwardNoticeKey{chatID: 3, userID: 1}, start.Add(3*forwardNoticeInterval))
+	if len(m.forwardNotices) != 1 {
+		t.Errorf("forwardNotices = %d entries, want 1", len(m.forwardNotices))
+	}
+}
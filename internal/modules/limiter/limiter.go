@@ -3,8 +3,11 @@ package limiter
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/flybasist/bmft/internal/core"
 	"github.com/flybasist/bmft/internal/postgresql/repositories"
@@ -13,31 +16,58 @@ import (
 )
 
 // LimiterModule управляет лимитами на контент в чатах.
-// Использует messageRepo.GetTodayCountByType() для подсчёта сообщений.
+// Лимиты считаются за день (content_limits) и за окна: час, 24 часа, неделя,
+// своё окно (content_limit_windows). Счётчики — messageRepo.CountByTypeInWindow().
+// Пересылки считаются по своему типу контента и отдельно по лимиту forward;
+// политика пересылок (forward_settings) — в forwards.go.
 type LimiterModule struct {
 	db                *sql.DB
 	vipRepo           *repositories.VIPRepository
 	contentLimitsRepo *repositories.ContentLimitsRepository
+	forwardRepo       *repositories.ForwardRepository
 	messageRepo       *repositories.MessageRepository
 	eventRepo         *repositories.EventRepository
+	shadowRepo        *repositories.ShadowRepository // превышения лимитов в теневом режиме
+	warner            core.Warner                    // автопредупреждения за превышение лимита
+	punisher          core.Punisher                  // политики mute_until_reset и restrict_media
 	logger            *zap.Logger
 	bot               *tele.Bot
+
+	noticeMu       sync.Mutex
+	forwardNotices map[forwardNoticeKey]time.Time // последнее уведомление о запрещённой пересылке
 }
 
 // New создаёт новый экземпляр LimiterModule.
 // messageRepo — общий экземпляр из initModules (не создаём дубликат).
-func New(db *sql.DB, vipRepo *repositories.VIPRepository, contentLimitsRepo *repositories.ContentLimitsRepository, messageRepo *repositories.MessageRepository, eventRepo *repositories.EventRepository, logger *zap.Logger, bot *tele.Bot) *LimiterModule {
+func New(db *sql.DB, vipRepo *repositories.VIPRepository, contentLimitsRepo *repositories.ContentLimitsRepository, forwardRepo *repositories.ForwardRepository, messageRepo *repositories.MessageRepository, eventRepo *repositories.EventRepository, shadowRepo *repositories.ShadowRepository, warner core.Warner, punisher core.Punisher, logger *zap.Logger, bot *tele.Bot) *LimiterModule {
 	return &LimiterModule{
 		db:                db,
 		vipRepo:           vipRepo,
 		contentLimitsRepo: contentLimitsRepo,
+		forwardRepo:       forwardRepo,
 		messageRepo:       messageRepo,
 		eventRepo:         eventRepo,
+		shadowRepo:        shadowRepo,
+		warner:            warner,
+		punisher:          punisher,
 		logger:            logger,
 		bot:               bot,
+		forwardNotices:    make(map[forwardNoticeKey]time.Time),
 	}
 }
 
+// Name возвращает имя модуля.
+func (m *LimiterModule) Name() string { return "limiter" }
+
+// Priority — limiter идёт после statistics (счётчик уже включает текущее сообщение).
+func (m *LimiterModule) Priority() int { return core.PriorityLimiter }
+
+// Start — фоновых задач нет.
+func (m *LimiterModule) Start() error { return nil }
+
+// Shutdown — фоновых задач нет, очищать нечего.
+func (m *LimiterModule) Shutdown() error { return nil }
+
 // RegisterCommands регистрирует пользовательские команды
 func (m *LimiterModule) RegisterCommands(bot *tele.Bot) {
 	// /limiter — справка по модулю
@@ -54,7 +84,8 @@ func (m *LimiterModule) RegisterCommands(bot *tele.Bot) {
 		msg += "<b>Доступные типы:</b>\n"
 		msg += "• <code>text</code>, <code>photo</code>, <code>video</code>, <code>sticker</code>\n"
 		msg += "• <code>animation</code>, <code>voice</code>, <code>video_note</code>, <code>audio</code>\n"
-		msg += "• <code>document</code>, <code>location</code>, <code>contact</code>\n\n"
+		msg += "• <code>document</code>, <code>location</code>, <code>contact</code>\n"
+		msg += "• <code>forward</code> — пересланные сообщения любого типа\n\n"
 
 		msg += "<b>⚠️ ОСОБЫЙ ТИП - banned_words:</b>\n"
 		msg += "• <code>/setlimit banned_words 3</code> - макс 3 мата/день, потом бан\n"
@@ -67,6 +98,18 @@ func (m *LimiterModule) RegisterCommands(bot *tele.Bot) {
 		msg += "• <code>/setlimit text 0</code> — 0 = отключить лимит\n"
 		msg += "• <code>/setlimit photo -1</code> — -1 = полный запрет\n\n"
 
+		msg += "<b>⏱ Окна лимитов</b> (третий аргумент, по умолчанию <code>day</code>):\n"
+		msg += "• <code>hour</code> — текущий час, <code>day</code> — сегодня, <code>24h</code> — последние 24 часа\n"
+		msg += "• <code>week</code> — текущая неделя, <code>30m</code>, <code>6h</code>, <code>3d</code> — своё окно\n"
+		msg += "• <code>/setlimit photo 5 hour</code> + <code>/setlimit photo 20 week</code> — оба лимита действуют вместе\n"
+		msg += "• <code>/setlimit photo 0 hour</code> — снять лимит за час\n\n"
+
+		msg += "<b>⚖️ Наказание за превышение</b> (<code>/setlimit penalty &lt;политика&gt;</code>):\n"
+		msg += "• <code>delete</code> — удалить сообщение (по умолчанию)\n"
+		msg += "• <code>mute_until_reset</code> — удалить и замутить до сброса окна лимита\n"
+		msg += "• <code>restrict_media</code> — удалить и запретить этот тип контента до сброса окна\n"
+		msg += "• <code>warn_only</code> — только предупредить, сообщение остаётся\n\n"
+
 		msg += "🔹 <code>/mystats</code> — Показать ваши текущие лимиты\n"
 		msg += "   Отображает все установленные лимиты и сколько осталось до превышения\n"
 		msg += "   📌 Пример: <code>/mystats</code>\n\n"
@@ -85,6 +128,9 @@ func (m *LimiterModule) RegisterCommands(bot *tele.Bot) {
 		msg += "🔹 <code>/listvips</code> — Список всех VIP-пользователей\n"
 		msg += "   📌 Пример: <code>/listvips</code>\n\n"
 
+		msg += "🔹 <code>/forwardpolicy</code> — Политика пересылок: allow, deny, deny_channels, whitelist\n"
+		msg += "   Настройка (только админы): <code>/setforward</code>, <code>/allowforward</code>, <code>/removeforward</code>\n\n"
+
 		msg += "⚙️ <b>Работа с топиками:</b>\n"
 		msg += "• Команда в <b>топике</b> настраивает лимиты только для этого топика\n"
 		msg += "• Команда в <b>основном чате</b> настраивает лимиты для всего чата\n"
@@ -101,6 +147,7 @@ func (m *LimiterModule) RegisterCommands(bot *tele.Bot) {
 
 	bot.Handle("/mystats", m.handleMyStats)
 	bot.Handle("/getlimit", m.handleGetLimit)
+	bot.Handle("/forwardpolicy", m.handleForwardPolicy)
 }
 
 // RegisterAdminCommands регистрирует административные команды
@@ -109,6 +156,9 @@ func (m *LimiterModule) RegisterAdminCommands(bot *tele.Bot) {
 	bot.Handle("/setvip", m.handleSetVIP)
 	bot.Handle("/removevip", m.handleRemoveVIP)
 	bot.Handle("/listvips", m.handleListVIPs)
+	bot.Handle("/setforward", m.handleSetForward)
+	bot.Handle("/allowforward", m.handleAllowForward)
+	bot.Handle("/removeforward", m.handleRemoveForward)
 }
 
 // OnMessage обрабатывает входящие сообщения
@@ -122,6 +172,12 @@ func (m *LimiterModule) OnMessage(ctx *core.MessageContext) error {
 		return nil
 	}
 
+	// Правка не новое сообщение: оно уже учтено в квоте при отправке.
+	// Сообщение, удалённое антифлудом, повторно не проверяем.
+	if ctx.IsEdit || ctx.MessageDeleted {
+		return nil
+	}
+
 	chatID := ctx.Chat.ID
 	// ThreadID уже вычислен в middleware и закеширован — без лишнего SQL-запроса.
 	threadID := ctx.ThreadID
@@ -137,9 +193,22 @@ func (m *LimiterModule) OnMessage(ctx *core.MessageContext) error {
 		return nil // VIP не имеет лимитов
 	}
 
-	// Определяем тип контента
-	contentType := core.DetectContentType(ctx.Message)
-	if contentType == "unknown" {
+	// Запрещённая пересылка удаляется до подсчёта лимитов
+	forward, denied := m.checkForwardPolicy(ctx)
+	if denied {
+		return nil
+	}
+
+	// Определяем тип контента. Пересылка считается и по своему типу контента,
+	// и по отдельному лимиту forward.
+	var contentTypes []string
+	if contentType := core.DetectContentType(ctx.Message); contentType != "unknown" {
+		contentTypes = append(contentTypes, contentType)
+	}
+	if forward {
+		contentTypes = append(contentTypes, forwardCategory)
+	}
+	if len(contentTypes) == 0 {
 		return nil
 	}
 
@@ -152,42 +221,18 @@ func (m *LimiterModule) OnMessage(ctx *core.MessageContext) error {
 		m.logger.Error("failed to get limits", zap.Error(err))
 		return nil
 	}
-
-	// Получаем текущий счётчик из messages (за сегодня)
-	// Statistics уже сохранил текущее сообщение (statistics → limiter в пайплайне),
-	// поэтому counter уже включает текущее сообщение
-	counter, err := m.messageRepo.GetTodayCountByType(chatID, threadID, userID, contentType)
+	windowLimits, err := m.contentLimitsRepo.GetWindowLimits(chatID, threadID, &userID)
 	if err != nil {
-		m.logger.Error("failed to get today counter", zap.Error(err))
+		m.logger.Error("failed to get window limits", zap.Error(err))
 		return nil
 	}
 
-	// Проверяем лимит
-	var limitValue int
-	switch contentType {
-	case "text":
-		limitValue = limits.LimitText
-	case "photo":
-		limitValue = limits.LimitPhoto
-	case "video":
-		limitValue = limits.LimitVideo
-	case "sticker":
-		limitValue = limits.LimitSticker
-	case "animation":
-		limitValue = limits.LimitAnimation
-	case "voice":
-		limitValue = limits.LimitVoice
-	case "document":
-		limitValue = limits.LimitDocument
-	case "audio":
-		limitValue = limits.LimitAudio
-	case "location":
-		limitValue = limits.LimitLocation
-	case "contact":
-		limitValue = limits.LimitContact
-	case "video_note":
-		limitValue = limits.LimitVideoNote
-	default:
+	// Дневной лимит из content_limits + лимиты за окна (час, 24 часа, неделя, своё окно)
+	var checks []limitCheck
+	for _, contentType := range contentTypes {
+		checks = append(checks, limitChecks(limits, windowLimits, contentType)...)
+	}
+	if len(checks) == 0 {
 		return nil
 	}
 
@@ -198,56 +243,172 @@ func (m *LimiterModule) OnMessage(ctx *core.MessageContext) error {
 		warnThreshold = 2 // fallback на случай некорректного значения
 	}
 
-	// Отправляем предупреждения в чате, если близко к лимиту
-	if limitValue > 0 && counter <= limitValue {
-		remaining := limitValue - counter
-		if remaining >= 0 && remaining < warnThreshold {
-			warning := fmt.Sprintf("⚠️ %s, %s: %d из %d (осталось %d)",
-				core.DisplayName(ctx.Sender), contentType, counter, limitValue, remaining)
-			if err := ctx.Send(warning); err != nil {
-				m.logger.Error("failed to send warning", zap.Error(err))
+	var exceeded []string // уведомления о первом превышении
+	limitExceeded := false
+	var resetIn time.Duration // до сброса самого длинного превышенного окна (для mute_until_reset, restrict_media)
+	var exceededType string   // тип контента с этим окном: за него наказание и автопредупреждение
+	now := time.Now()
+	for _, check := range checks {
+		// Statistics уже сохранил текущее сообщение (statistics → limiter в пайплайне),
+		// поэтому counter уже включает текущее сообщение
+		counter, err := m.countInWindow(chatID, threadID, userID, check.contentType, check.window, now)
+		if err != nil {
+			m.logger.Error("failed to get window counter", zap.String("window", check.window.Type), zap.Error(err))
+			continue
+		}
+		limitValue := check.limit
+		contentType := check.contentType
+		suffix := windowSuffix(check.window)
+
+		// Отправляем предупреждения в чате, если близко к лимиту.
+		// В теневом режиме пользователь ничего не видит.
+		if limitValue > 0 && counter <= limitValue {
+			if limits.Shadow {
+				continue
+			}
+			remaining := limitValue - counter
+			if remaining >= 0 && remaining < warnThreshold {
+				warning := fmt.Sprintf("⚠️ %s, %s: %d из %d%s (осталось %d)",
+					core.DisplayName(ctx.Sender), contentType, counter, limitValue, suffix, remaining)
+				if err := ctx.Send(warning); err != nil {
+					m.logger.Error("failed to send warning", zap.Error(err))
+				}
+			}
+			continue
+		}
+
+		// Лимит -1 (запрещено) или достигнут
+		if limitValue == -1 || (limitValue > 0 && counter > limitValue) {
+			limitExceeded = true
+			if exceededType == "" {
+				exceededType = contentType
+			}
+			if limitValue > 0 {
+				var oldest time.Time
+				if check.window.Rolling() {
+					if oldest, err = m.oldestInWindow(chatID, threadID, userID, contentType, check.window, now); err != nil {
+						m.logger.Error("failed to get oldest message in window", zap.String("window", check.window.Type), zap.Error(err))
+					}
+				}
+				if d := untilReset(check.window, now, oldest); d > resetIn {
+					resetIn, exceededType = d, contentType
+				}
+			}
+			m.logger.Info("limit exceeded",
+				zap.Int64("user_id", ctx.Sender.ID),
+				zap.String("username", ctx.Sender.Username),
+				zap.Int64("chat_id", ctx.Chat.ID),
+				zap.String("content_type", contentType),
+				zap.String("window", check.window.Type),
+				zap.Int("counter", counter),
+				zap.Int("limit", limitValue),
+				zap.String("penalty", limits.Penalty))
+
+			// Предупреждение отправляем только ОДИН раз — при первом превышении.
+			// Иначе пользователь может заспамить чат удалениями (видно: 6/5, 7/5, 8/5...).
+			// Для limitValue > 0: предупреждаем при counter == limitValue + 1.
+			// Для limitValue == -1 (запрещено): предупреждаем при counter == 1.
+			firstExceeded := (limitValue > 0 && counter == limitValue+1) || (limitValue == -1 && counter == 1)
+			if firstExceeded {
+				warning := fmt.Sprintf("❌ %s, лимит на %s%s достигнут (%d/%d)", core.DisplayName(ctx.Sender), contentType, suffix, counter, limitValue)
+				if limitValue == -1 {
+					warning = fmt.Sprintf("❌ %s, %s запрещено в этом чате", core.DisplayName(ctx.Sender), contentType)
+				}
+				exceeded = append(exceeded, warning)
 			}
 		}
 	}
 
-	// Если лимит -1 (запрещено) или достигнут
-	if limitValue == -1 || (limitValue > 0 && counter > limitValue) {
-		// Логируем превышение лимита
-		m.logger.Info("limit exceeded, deleting message",
-			zap.Int64("user_id", ctx.Sender.ID),
-			zap.String("username", ctx.Sender.Username),
-			zap.Int64("chat_id", ctx.Chat.ID),
-			zap.String("content_type", contentType),
-			zap.Int("counter", counter),
-			zap.Int("limit", limitValue))
+	if !limitExceeded {
+		return nil
+	}
 
-		// Удаляем сообщение (ctx.DeleteMessage автоматически ставит ctx.MessageDeleted = true)
-		if err := ctx.DeleteMessage(); err != nil {
-			m.logger.Error("failed to delete message", zap.Error(err))
+	// Теневой режим: превышение записывается для /shadowreport, сообщение остаётся
+	if limits.Shadow {
+		hit := repositories.ShadowHit{
+			ChatID:    chatID,
+			UserID:    userID,
+			Module:    "limiter",
+			ThreadID:  threadID,
+			MessageID: ctx.Message.ID,
+			Rule:      repositories.ShadowRuleLimit,
+			Pattern:   exceededType,
+			Action:    limits.Penalty,
+		}
+		if err := m.shadowRepo.Record(hit); err != nil {
+			m.logger.Error("failed to record shadow limit hit", zap.Error(err))
 		}
+		return nil
+	}
 
-		// Предупреждение отправляем только ОДИН раз — при первом превышении.
-		// Иначе пользователь может заспамить чат удалениями (видно: 6/5, 7/5, 8/5...).
-		// Для limitValue > 0: предупреждаем при counter == limitValue + 1.
-		// Для limitValue == -1 (запрещено): предупреждаем при counter == 1.
-		firstExceeded := (limitValue > 0 && counter == limitValue+1) || (limitValue == -1 && counter == 1)
-		if firstExceeded {
-			warning := fmt.Sprintf("❌ %s, лимит на %s достигнут (%d/%d)", core.DisplayName(ctx.Sender), contentType, counter, limitValue)
-			if limitValue == -1 {
-				warning = fmt.Sprintf("❌ %s, %s запрещено в этом чате", core.DisplayName(ctx.Sender), contentType)
-			}
-			if err := ctx.Send(warning); err != nil {
-				m.logger.Error("failed to send warning", zap.Error(err))
-			}
+	// warn_only оставляет сообщение, остальные политики удаляют его
+	// (ctx.DeleteMessage автоматически ставит ctx.MessageDeleted = true)
+	if limits.Penalty != repositories.PenaltyWarnOnly {
+		if err := ctx.DeleteMessage("limit_exceeded"); err != nil {
+			m.logger.Error("failed to delete message", zap.Error(err))
 		}
+	}
 
-		// MessageDeleted пропагируется через middleware → Reactions увидит и скорректирует.
-		return nil
+	// Уведомление и наказание — только при первом превышении, дальше сообщения просто удаляются
+	if len(exceeded) > 0 {
+		if text := m.applyPenalty(ctx, limits.Penalty, exceededType, resetIn); text != "" {
+			exceeded = append(exceeded, text)
+		}
+		if err := ctx.Send(strings.Join(exceeded, "\n")); err != nil {
+			m.logger.Error("failed to send warning", zap.Error(err))
+		}
+		m.warner.AutoWarn(ctx, "limiter", fmt.Sprintf("лимит на %s", exceededType))
 	}
 
+	// MessageDeleted пропагируется через middleware → Reactions увидит и скорректирует.
 	return nil
 }
 
+// countInWindow возвращает счётчик типа контента за окно. Пересылки считаются
+// по отметке в metadata (checkForwardPolicy), остальные типы — по content_type.
+func (m *LimiterModule) countInWindow(chatID int64, threadID int, userID int64, contentType string, w repositories.LimitWindow, now time.Time) (int, error) {
+	if contentType == forwardCategory {
+		return m.messageRepo.CountByMetadataInWindow(chatID, threadID, userID, forwardCategory, w, now)
+	}
+	return m.messageRepo.CountByTypeInWindow(chatID, threadID, userID, contentType, w, now)
+}
+
+// oldestInWindow — время самого старого учтённого сообщения за окно (для сброса скользящих окон).
+func (m *LimiterModule) oldestInWindow(chatID int64, threadID int, userID int64, contentType string, w repositories.LimitWindow, now time.Time) (time.Time, error) {
+	if contentType == forwardCategory {
+		return m.messageRepo.OldestByMetadataInWindow(chatID, threadID, userID, forwardCategory, w, now)
+	}
+	return m.messageRepo.OldestByTypeInWindow(chatID, threadID, userID, contentType, w, now)
+}
+
+// applyPenalty применяет наказание mute_until_reset или restrict_media до сброса окна
+// и возвращает текст для чата. resetIn = 0 — превышен только полный запрет (-1),
+// сбрасываться нечему: сообщение лишь удаляется.
+func (m *LimiterModule) applyPenalty(ctx *core.MessageContext, penalty, contentType string, resetIn time.Duration) string {
+	if resetIn == 0 {
+		return ""
+	}
+
+	name := core.DisplayName(ctx.Sender)
+	reason := fmt.Sprintf("лимит на %s", contentType)
+	switch penalty {
+	case repositories.PenaltyMuteUntilReset:
+		if err := m.punisher.Mute(ctx.Chat, ctx.Sender, resetIn, reason); err != nil {
+			m.logger.Error("failed to mute user for limit", zap.Error(err))
+			return ""
+		}
+		return fmt.Sprintf("🔇 %s в муте до сброса лимита: %s", name, core.FormatDuration(resetIn))
+	case repositories.PenaltyRestrictMedia:
+		if err := m.punisher.RestrictContent(ctx.Chat, ctx.Sender, contentType, resetIn, reason); err != nil {
+			m.logger.Error("failed to restrict content for limit", zap.Error(err))
+			return ""
+		}
+		return fmt.Sprintf("🚫 %s не может отправлять %s до сброса лимита: %s", name, contentType, core.FormatDuration(resetIn))
+	default:
+		return ""
+	}
+}
+
 // handleMyStats показывает статистику пользователя
 func (m *LimiterModule) handleMyStats(c tele.Context) error {
 	chatID := c.Chat().ID
@@ -294,6 +455,7 @@ func (m *LimiterModule) handleMyStats(c tele.Context) error {
 		{"🎵", "Аудио", "audio", limits.LimitAudio},
 		{"📍", "Геолокация", "location", limits.LimitLocation},
 		{"👤", "Контакты", "contact", limits.LimitContact},
+		{"↪️", "Пересылки", "forward", limits.LimitForward},
 		{"🔞", "Мат", "banned_words", limits.LimitBannedWords},
 		{"🎥", "Кружочки", "video_note", limits.LimitVideoNote},
 	}
@@ -308,28 +470,53 @@ func (m *LimiterModule) handleMyStats(c tele.Context) error {
 	text := fmt.Sprintf("📊 Ваша статистика за сегодня%s:\n\n", scope)
 
 	// Один SQL-запрос для всех типов контента (вместо 12 отдельных)
-	counters, err := m.messageRepo.GetTodayCountsAllTypes(chatID, threadID, userID)
+	now := time.Now()
+	counters, err := m.messageRepo.GetTodayCountsAllTypes(chatID, threadID, userID, now)
 	if err != nil {
 		m.logger.Error("failed to get today counts", zap.Error(err))
 		return c.Send("❌ Не удалось получить статистику")
 	}
 
+	windowLimits, err := m.contentLimitsRepo.GetWindowLimits(chatID, threadID, &userID)
+	if err != nil {
+		m.logger.Error("failed to get window limits", zap.Error(err))
+		return c.Send("❌ Не удалось получить лимиты")
+	}
+	byType := windowLimitsByType(windowLimits)
+
+	// Счётчики за окна: один запрос на каждое окно, а не на каждый лимит
+	windowCounters := make(map[repositories.LimitWindow]map[string]int)
+	for _, wl := range windowLimits {
+		if _, done := windowCounters[wl.Window]; done {
+			continue
+		}
+		counts, err := m.messageRepo.CountsAllTypesInWindow(chatID, threadID, userID, wl.Window, now)
+		if err != nil {
+			m.logger.Error("failed to get window counts", zap.Error(err))
+			return c.Send("❌ Не удалось получить статистику")
+		}
+		windowCounters[wl.Window] = counts
+	}
+
 	for _, t := range types {
 		counter := counters[t.field]
+		windows := byType[t.field]
+		var line string
 		switch {
 		case t.value == -1:
-			text += fmt.Sprintf("%s %s: %d из 0 (запрещено)\n", t.emoji, t.name, counter)
+			line = fmt.Sprintf("%s %s: %d из 0 (запрещено)", t.emoji, t.name, counter)
+		case t.value == 0 && len(windows) > 0:
+			line = fmt.Sprintf("%s %s: %d за сегодня", t.emoji, t.name, counter)
 		case t.value == 0:
-			text += fmt.Sprintf("%s %s: %d (без лимита)\n", t.emoji, t.name, counter)
+			line = fmt.Sprintf("%s %s: %d (без лимита)", t.emoji, t.name, counter)
 		default:
-			warn := ""
-			if counter >= t.value {
-				warn = "⛔️"
-			} else if counter >= t.value-2 {
-				warn = "⚠️"
-			}
-			text += fmt.Sprintf("%s %s: %d из %d%s\n", t.emoji, t.name, counter, t.value, warn)
+			line = fmt.Sprintf("%s %s: %d из %d%s", t.emoji, t.name, counter, t.value, usageMark(counter, t.value))
 		}
+		for _, wl := range windows {
+			windowCounter := windowCounters[wl.Window][t.field]
+			line += fmt.Sprintf("; %d из %d %s%s", windowCounter, wl.Limit, windowLabel(wl.Window), usageMark(windowCounter, wl.Limit))
+		}
+		text += line + "\n"
 	}
 	return c.Send(text, &tele.SendOptions{ParseMode: tele.ModeMarkdown})
 }
@@ -345,25 +532,33 @@ func (m *LimiterModule) handleGetLimit(c tele.Context) error {
 	if err != nil {
 		return c.Send("❌ Не удалось получить лимиты")
 	}
+	windowLimits, err := m.contentLimitsRepo.GetWindowLimits(chatID, threadID, nil)
+	if err != nil {
+		m.logger.Error("failed to get window limits", zap.Error(err))
+		return c.Send("❌ Не удалось получить лимиты")
+	}
+	byType := windowLimitsByType(windowLimits)
 
 	// Все типы контента для вывода
 	types := []struct {
 		emoji string
 		name  string
+		field string
 		value int
 	}{
-		{"📝", "Текст", limits.LimitText},
-		{"📷", "Фото", limits.LimitPhoto},
-		{"🎬", "Видео", limits.LimitVideo},
-		{"😀", "Стикеры", limits.LimitSticker},
-		{"🎞️", "Гифки", limits.LimitAnimation},
-		{"🎤", "Голосовые", limits.LimitVoice},
-		{"📎", "Документы", limits.LimitDocument},
-		{"🎵", "Аудио", limits.LimitAudio},
-		{"📍", "Геолокация", limits.LimitLocation},
-		{"👤", "Контакты", limits.LimitContact},
-		{"🔞", "Мат", limits.LimitBannedWords},
-		{"🎥", "Кружочки", limits.LimitVideoNote},
+		{"📝", "Текст", "text", limits.LimitText},
+		{"📷", "Фото", "photo", limits.LimitPhoto},
+		{"🎬", "Видео", "video", limits.LimitVideo},
+		{"😀", "Стикеры", "sticker", limits.LimitSticker},
+		{"🎞️", "Гифки", "animation", limits.LimitAnimation},
+		{"🎤", "Голосовые", "voice", limits.LimitVoice},
+		{"📎", "Документы", "document", limits.LimitDocument},
+		{"🎵", "Аудио", "audio", limits.LimitAudio},
+		{"📍", "Геолокация", "location", limits.LimitLocation},
+		{"👤", "Контакты", "contact", limits.LimitContact},
+		{"↪️", "Пересылки", "forward", limits.LimitForward},
+		{"🔞", "Мат", "banned_words", limits.LimitBannedWords},
+		{"🎥", "Кружочки", "video_note", limits.LimitVideoNote},
 	}
 
 	var scope string
@@ -376,18 +571,41 @@ func (m *LimiterModule) handleGetLimit(c tele.Context) error {
 	text := fmt.Sprintf("🚦 Установленные лимиты%s:\n\n", scope)
 	hasLimits := false
 	for _, t := range types {
-		switch {
-		case t.value == -1:
+		if t.value == -1 {
 			text += fmt.Sprintf("%s %s: запрещено ⛔️\n", t.emoji, t.name)
 			hasLimits = true
-		case t.value > 0:
-			text += fmt.Sprintf("%s %s: %d в день\n", t.emoji, t.name, t.value)
+			continue
+		}
+
+		// Все окна типа в одной строке, от короткого к длинному: «5 в час, 10 в день, 20 в неделю»
+		var checks []limitCheck
+		if t.value > 0 {
+			checks = append(checks, limitCheck{window: dayWindow, limit: t.value})
+		}
+		for _, wl := range byType[t.field] {
+			checks = append(checks, limitCheck{window: wl.Window, limit: wl.Limit})
+		}
+		sort.SliceStable(checks, func(i, j int) bool {
+			return windowLength(checks[i].window) < windowLength(checks[j].window)
+		})
+
+		var parts []string
+		for _, check := range checks {
+			parts = append(parts, fmt.Sprintf("%d %s", check.limit, windowLabel(check.window)))
+		}
+		if len(parts) > 0 {
+			text += fmt.Sprintf("%s %s: %s\n", t.emoji, t.name, strings.Join(parts, ", "))
 			hasLimits = true
 		}
 	}
 
 	if !hasLimits {
 		text += "✅ Лимиты не установлены. Все типы контента разрешены без ограничений.\n"
+	} else if description, ok := penaltyDescriptions[limits.Penalty]; ok && limits.Penalty != repositories.PenaltyDelete {
+		text += "\n⚖️ При превышении: " + description + "\n"
+	}
+	if hasLimits && limits.Shadow {
+		text += "\n👻 Теневой режим: превышения только записываются (/shadowreport)\n"
 	}
 
 	text += "\n💡 Используйте `/mystats` чтобы посмотреть вашу личную статистику"
@@ -410,8 +628,19 @@ func (m *LimiterModule) handleSetLimit(c tele.Context) error {
 	`, chatID)
 
 	args := c.Args()
-	if len(args) != 2 {
-		return c.Send("Использование: /setlimit <тип> <значение>\nДля персонального лимита: ответьте этой командой на сообщение пользователя")
+	if len(args) != 2 && len(args) != 3 {
+		return c.Send("Использование: /setlimit <тип> <значение> [окно]\n" +
+			"Окно: day (по умолчанию), hour, 24h, week или своё: 30m, 6h, 3d\n" +
+			"Наказание: /setlimit penalty delete|mute_until_reset|restrict_media|warn_only\n" +
+			"Теневой режим: /setlimit shadow on|off — превышения только записываются (/shadowreport)\n" +
+			"Для персонального лимита: ответьте этой командой на сообщение пользователя")
+	}
+
+	if args[0] == "penalty" {
+		return m.setPenalty(c, chatID, threadID, args[1])
+	}
+	if args[0] == "shadow" {
+		return m.setShadow(c, chatID, threadID, args[1])
 	}
 
 	contentType := args[0]
@@ -422,10 +651,10 @@ func (m *LimiterModule) handleSetLimit(c tele.Context) error {
 	validContentTypes := map[string]bool{
 		"text": true, "photo": true, "video": true, "sticker": true,
 		"animation": true, "voice": true, "video_note": true, "audio": true,
-		"document": true, "location": true, "contact": true, "banned_words": true,
+		"document": true, "location": true, "contact": true, "forward": true, "banned_words": true,
 	}
 	if !validContentTypes[contentType] {
-		return c.Send("❌ Неизвестный тип: " + contentType + "\n\nДопустимые: text, photo, video, sticker, animation, voice, video_note, audio, document, location, contact, banned_words")
+		return c.Send("❌ Неизвестный тип: " + contentType + "\n\nДопустимые: text, photo, video, sticker, animation, voice, video_note, audio, document, location, contact, forward, banned_words")
 	}
 
 	limitValue, err := strconv.Atoi(args[1])
@@ -433,6 +662,22 @@ func (m *LimiterModule) handleSetLimit(c tele.Context) error {
 		return c.Send("❌ Неверное значение лимита")
 	}
 
+	window := dayWindow
+	if len(args) == 3 {
+		if window, err = parseWindow(args[2]); err != nil {
+			return c.Send("❌ " + err.Error())
+		}
+	}
+	if window != dayWindow {
+		// Мат считается по metadata за день (Reactions), полный запрет — свойство дневного лимита
+		if contentType == "banned_words" {
+			return c.Send("❌ Лимит на мат бывает только дневным")
+		}
+		if limitValue == -1 {
+			return c.Send("❌ Полный запрет задаётся дневным лимитом: /setlimit " + contentType + " -1")
+		}
+	}
+
 	var userID *int64
 
 	// Для индивидуального лимита используем reply
@@ -441,31 +686,42 @@ func (m *LimiterModule) handleSetLimit(c tele.Context) error {
 		userID = &id
 	}
 
-	if err := m.contentLimitsRepo.SetLimit(chatID, threadID, userID, contentType, limitValue); err != nil {
+	if window == dayWindow {
+		err = m.contentLimitsRepo.SetLimit(chatID, threadID, userID, contentType, limitValue)
+	} else {
+		err = m.contentLimitsRepo.SetWindowLimit(chatID, threadID, userID, contentType, window, limitValue)
+	}
+	if err != nil {
+		m.logger.Error("failed to set limit", zap.Error(err))
 		return c.Send("❌ Не удалось установить лимит")
 	}
 
 	// Логируем событие
-	details := fmt.Sprintf("Set limit: %s=%d (chat=%d, thread=%d)", contentType, limitValue, chatID, threadID)
+	details := fmt.Sprintf("Set limit: %s=%d %s (chat=%d, thread=%d)", contentType, limitValue, windowLabel(window), chatID, threadID)
 	if userID != nil {
-		details = fmt.Sprintf("Set limit: %s=%d for user %d (chat=%d, thread=%d)", contentType, limitValue, *userID, chatID, threadID)
+		details = fmt.Sprintf("Set limit: %s=%d %s for user %d (chat=%d, thread=%d)", contentType, limitValue, windowLabel(window), *userID, chatID, threadID)
 	}
 	_ = m.eventRepo.Log(chatID, c.Sender().ID, "limiter", "set_limit", details)
 
+	label := windowLabel(window)
+	if limitValue == 0 && window != dayWindow {
+		label += " (лимит снят)"
+	}
+
 	var msg string
 	if threadID != 0 {
 		// Команда выполнена в топике
 		if userID == nil {
-			msg = fmt.Sprintf("✅ Лимит установлен для **этого топика**\n\n%s: %d в день\n\n💡 Для настройки всего чата используйте команду в основном чате (не в топике)", contentType, limitValue)
+			msg = fmt.Sprintf("✅ Лимит установлен для **этого топика**\n\n%s: %d %s\n\n💡 Для настройки всего чата используйте команду в основном чате (не в топике)", contentType, limitValue, label)
 		} else {
-			msg = fmt.Sprintf("✅ Персональный лимит установлен для пользователя **в этом топике**\n\n%s: %d в день\n\n💡 Для настройки на весь чат используйте команду в основном чате", contentType, limitValue)
+			msg = fmt.Sprintf("✅ Персональный лимит установлен для пользователя **в этом топике**\n\n%s: %d %s\n\n💡 Для настройки на весь чат используйте команду в основном чате", contentType, limitValue, label)
 		}
 	} else {
 		// Команда выполнена в основном чате
 		if userID == nil {
-			msg = fmt.Sprintf("✅ Лимит установлен для **всего чата**\n\n%s: %d в день\n\n💡 Для настройки конкретного топика используйте команду внутри топика", contentType, limitValue)
+			msg = fmt.Sprintf("✅ Лимит установлен для **всего чата**\n\n%s: %d %s\n\n💡 Для настройки конкретного топика используйте команду внутри топика", contentType, limitValue, label)
 		} else {
-			msg = fmt.Sprintf("✅ Персональный лимит установлен для пользователя **во всём чате**\n\n%s: %d в день", contentType, limitValue)
+			msg = fmt.Sprintf("✅ Персональный лимит установлен для пользователя **во всём чате**\n\n%s: %d %s", contentType, limitValue, label)
 		}
 	}
 
@@ -480,6 +736,87 @@ func (m *LimiterModule) handleSetLimit(c tele.Context) error {
 	return c.Send(msg, &tele.SendOptions{ParseMode: tele.ModeMarkdown})
 }
 
+// penaltyDescriptions — политики наказания за превышение лимита для /setlimit penalty.
+var penaltyDescriptions = map[string]string{
+	repositories.PenaltyDelete:         "сообщение удаляется",
+	repositories.PenaltyMuteUntilReset: "сообщение удаляется, пользователь в муте до сброса лимита",
+	repositories.PenaltyRestrictMedia:  "сообщение удаляется, этот тип контента запрещён пользователю до сброса лимита",
+	repositories.PenaltyWarnOnly:       "только предупреждение, сообщение остаётся",
+}
+
+// setPenalty — /setlimit penalty <политика>: что делать при превышении лимита.
+// Как и лимиты, политика задаётся для топика, чата или пользователя (reply).
+func (m *LimiterModule) setPenalty(c tele.Context, chatID int64, threadID int, penalty string) error {
+	description, ok := penaltyDescriptions[penalty]
+	if !ok {
+		return c.Send("❌ Неизвестное наказание: " + penalty + "\n\nДопустимые: delete, mute_until_reset, restrict_media, warn_only")
+	}
+
+	var userID *int64
+	if c.Message().ReplyTo != nil {
+		id := c.Message().ReplyTo.Sender.ID
+		userID = &id
+	}
+
+	if err := m.contentLimitsRepo.SetPenalty(chatID, threadID, userID, penalty); err != nil {
+		m.logger.Error("failed to set penalty", zap.Error(err))
+		return c.Send("❌ Не удалось установить наказание")
+	}
+
+	details := fmt.Sprintf("Set penalty: %s (chat=%d, thread=%d)", penalty, chatID, threadID)
+	if userID != nil {
+		details = fmt.Sprintf("Set penalty: %s for user %d (chat=%d, thread=%d)", penalty, *userID, chatID, threadID)
+	}
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "limiter", "set_penalty", details)
+
+	scope := "всего чата"
+	switch {
+	case userID != nil && threadID != 0:
+		scope = "пользователя в этом топике"
+	case userID != nil:
+		scope = "пользователя во всём чате"
+	case threadID != 0:
+		scope = "этого топика"
+	}
+	msg := fmt.Sprintf("✅ Наказание за превышение лимита для %s: %s\n%s", scope, penalty, description)
+	if penalty == repositories.PenaltyMuteUntilReset || penalty == repositories.PenaltyRestrictMedia {
+		msg += "\n\n⚠️ Боту нужно право ограничивать участников"
+	}
+	return c.Send(msg)
+}
+
+// setShadow — /setlimit shadow on|off: теневой режим лимитов.
+// Превышение записывается в event_log и metadata сообщения, но сообщение не удаляется,
+// предупреждений и наказаний нет. Так новые лимиты проверяются на большом чате до включения.
+func (m *LimiterModule) setShadow(c tele.Context, chatID int64, threadID int, value string) error {
+	if value != "on" && value != "off" {
+		return c.Send("Использование: /setlimit shadow on|off")
+	}
+	shadow := value == "on"
+
+	var userID *int64
+	if c.Message().ReplyTo != nil {
+		id := c.Message().ReplyTo.Sender.ID
+		userID = &id
+	}
+
+	if err := m.contentLimitsRepo.SetShadow(chatID, threadID, userID, shadow); err != nil {
+		m.logger.Error("failed to set limits shadow mode", zap.Error(err))
+		return c.Send("❌ Не удалось изменить теневой режим")
+	}
+
+	details := fmt.Sprintf("Set limits shadow=%t (chat=%d, thread=%d)", shadow, chatID, threadID)
+	if userID != nil {
+		details = fmt.Sprintf("Set limits shadow=%t for user %d (chat=%d, thread=%d)", shadow, *userID, chatID, threadID)
+	}
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "limiter", "set_shadow", details)
+
+	if shadow {
+		return c.Send("👻 Лимиты в теневом режиме: превышения только записываются, сообщения не удаляются\nОтчёт: /shadowreport")
+	}
+	return c.Send("✅ Теневой режим лимитов выключен — превышения снова наказываются")
+}
+
 // handleSetVIP устанавливает VIP-статус
 func (m *LimiterModule) handleSetVIP(c tele.Context) error {
 	chatID := c.Chat().ID
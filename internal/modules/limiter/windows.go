@@ -0,0 +1,184 @@
+package limiter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// dayWindow — окно дневных лимитов из колонок content_limits.
+var dayWindow = repositories.LimitWindow{Type: repositories.WindowDay}
+
+// forwardCategory — лимит на пересылки. Это не content_type сообщения:
+// пересланное фото считается и как photo, и как forward.
+const forwardCategory = "forward"
+
+// limitCheck — лимит типа контента за одно окно.
+type limitCheck struct {
+	contentType string
+	window      repositories.LimitWindow
+	limit       int // -1 = запрещено (только дневной лимит)
+}
+
+// limitChecks собирает лимиты типа контента: дневной из content_limits и лимиты за окна.
+// Пусто, если тип не лимитируется.
+func limitChecks(limits *repositories.ContentLimits, windowLimits []repositories.WindowLimit, contentType string) []limitCheck {
+	dayLimit, ok := dailyLimit(limits, contentType)
+	if !ok {
+		return nil
+	}
+	var checks []limitCheck
+	if dayLimit != 0 {
+		checks = append(checks, limitCheck{contentType: contentType, window: dayWindow, limit: dayLimit})
+	}
+	for _, wl := range windowLimits {
+		if wl.ContentType == contentType {
+			checks = append(checks, limitCheck{contentType: contentType, window: wl.Window, limit: wl.Limit})
+		}
+	}
+	return checks
+}
+
+// parseWindow разбирает окно лимита из /setlimit:
+// day, hour, 24h, week или длительность своего окна (30m, 6h, 3d, 2w).
+func parseWindow(s string) (repositories.LimitWindow, error) {
+	switch strings.ToLower(s) {
+	case "day":
+		return dayWindow, nil
+	case "hour":
+		return repositories.LimitWindow{Type: repositories.WindowHour}, nil
+	case "24h":
+		return repositories.LimitWindow{Type: repositories.WindowRolling24h}, nil
+	case "week":
+		return repositories.LimitWindow{Type: repositories.WindowWeek}, nil
+	}
+
+	d, err := core.ParseDuration(s)
+	if err != nil {
+		return repositories.LimitWindow{}, fmt.Errorf("неверное окно %q (day, hour, 24h, week или длительность: 30m, 6h, 3d)", s)
+	}
+	return repositories.LimitWindow{Type: repositories.WindowCustom, Seconds: int(d.Seconds())}, nil
+}
+
+// windowLabel — окно в тексте бота: «в день», «в час», «за 24 часа».
+func windowLabel(w repositories.LimitWindow) string {
+	switch w.Type {
+	case repositories.WindowHour:
+		return "в час"
+	case repositories.WindowRolling24h:
+		return "за 24 часа"
+	case repositories.WindowWeek:
+		return "в неделю"
+	case repositories.WindowCustom:
+		return "за " + core.FormatDuration(time.Duration(w.Seconds)*time.Second)
+	default:
+		return "в день"
+	}
+}
+
+// windowSuffix — пояснение окна в предупреждениях; для дневного лимита пусто,
+// чтобы сообщения о дневных лимитах остались прежними.
+func windowSuffix(w repositories.LimitWindow) string {
+	if w.Type == repositories.WindowDay {
+		return ""
+	}
+	return " " + windowLabel(w)
+}
+
+// windowLength — примерная длина окна для сортировки (час < день < неделя).
+func windowLength(w repositories.LimitWindow) int {
+	switch w.Type {
+	case repositories.WindowHour:
+		return 3600
+	case repositories.WindowDay:
+		return 86400
+	case repositories.WindowRolling24h:
+		return 86400 + 1
+	case repositories.WindowWeek:
+		return 7 * 86400
+	default:
+		return w.Seconds
+	}
+}
+
+// sortWindowLimits сортирует лимиты за окна по длине окна.
+func sortWindowLimits(limits []repositories.WindowLimit) {
+	sort.SliceStable(limits, func(i, j int) bool {
+		return windowLength(limits[i].Window) < windowLength(limits[j].Window)
+	})
+}
+
+// windowLimitsByType группирует лимиты за окна по типу контента.
+func windowLimitsByType(limits []repositories.WindowLimit) map[string][]repositories.WindowLimit {
+	sortWindowLimits(limits)
+	byType := make(map[string][]repositories.WindowLimit)
+	for _, l := range limits {
+		byType[l.ContentType] = append(byType[l.ContentType], l)
+	}
+	return byType
+}
+
+// dailyLimit возвращает дневной лимит типа контента из content_limits.
+// ok = false — тип контента не лимитируется.
+func dailyLimit(limits *repositories.ContentLimits, contentType string) (int, bool) {
+	switch contentType {
+	case "text":
+		return limits.LimitText, true
+	case "photo":
+		return limits.LimitPhoto, true
+	case "video":
+		return limits.LimitVideo, true
+	case "sticker":
+		return limits.LimitSticker, true
+	case "animation":
+		return limits.LimitAnimation, true
+	case "voice":
+		return limits.LimitVoice, true
+	case "document":
+		return limits.LimitDocument, true
+	case "audio":
+		return limits.LimitAudio, true
+	case "location":
+		return limits.LimitLocation, true
+	case "contact":
+		return limits.LimitContact, true
+	case forwardCategory:
+		return limits.LimitForward, true
+	case "video_note":
+		return limits.LimitVideoNote, true
+	default:
+		return 0, false
+	}
+}
+
+// usageMark — отметка близости к лимиту в /mystats.
+func usageMark(counter, limit int) string {
+	switch {
+	case counter >= limit:
+		return "⛔️"
+	case counter >= limit-2:
+		return "⚠️"
+	default:
+		return ""
+	}
+}
+
+// untilReset возвращает время до сброса окна (LimitWindow.Bounds): для календарных
+// окон — до начала следующего часа, дня или недели. Для скользящих — пока из окна
+// не выйдет самое старое учтённое сообщение oldest; без него — вся длина окна.
+// Границы те же, по которым считаются сообщения в CountByTypeInWindow.
+// Не меньше минуты: Telegram считает ограничения короче 30 секунд бессрочными.
+func untilReset(w repositories.LimitWindow, now, oldest time.Time) time.Duration {
+	start, reset, err := w.Bounds(now)
+	if err != nil {
+		return time.Minute
+	}
+	if w.Rolling() && !oldest.IsZero() {
+		reset = oldest.Add(now.Sub(start))
+	}
+	return max(reset.Sub(now), time.Minute)
+}
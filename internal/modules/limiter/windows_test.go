@@ -0,0 +1,128 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// TestUntilReset проверяет время до сброса календарных и скользящих окон,
+// в том числе на границах дня, недели, месяца и года. Скользящее окно
+// сбрасывается, когда из него выходит самое старое учтённое сообщение
+func TestUntilReset(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	at := func(y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, msk)
+	}
+	hour := repositories.LimitWindow{Type: repositories.WindowHour}
+	week := repositories.LimitWindow{Type: repositories.WindowWeek}
+	rolling := repositories.LimitWindow{Type: repositories.WindowRolling24h}
+
+	tests := []struct {
+		name   string
+		window repositories.LimitWindow
+		now    time.Time
+		oldest time.Time // самое старое учтённое сообщение; нулевое — не известно
+		want   time.Duration
+	}{
+		{"час: середина", hour, at(2026, 10, 14, 10, 15), time.Time{}, 45 * time.Minute},
+		{"час: последний час суток", hour, at(2026, 10, 14, 23, 30), time.Time{}, 30 * time.Minute},
+		{"день: полдень", dayWindow, at(2026, 10, 14, 12, 0), time.Time{}, 12 * time.Hour},
+		{"день: ровно полночь", dayWindow, at(2026, 10, 14, 0, 0), time.Time{}, 24 * time.Hour},
+		{"день: конец месяца", dayWindow, at(2026, 2, 28, 23, 0), time.Time{}, time.Hour},
+		{"день: конец года", dayWindow, at(2026, 12, 31, 22, 0), time.Time{}, 2 * time.Hour},
+		{"неделя: понедельник утром", week, at(2026, 10, 12, 6, 0), time.Time{}, 6*24*time.Hour + 18*time.Hour},
+		{"неделя: воскресенье вечером", week, at(2026, 10, 18, 23, 0), time.Time{}, time.Hour},
+		{"неделя: через границу месяца", week, at(2026, 10, 31, 0, 0), time.Time{}, 2 * 24 * time.Hour},
+		{"неделя: через границу года", week, at(2026, 12, 30, 12, 0), time.Time{}, 4*24*time.Hour + 12*time.Hour},
+		{"24 часа", rolling, at(2026, 10, 14, 10, 15), time.Time{}, 24 * time.Hour},
+		{"своё окно", repositories.LimitWindow{Type: repositories.WindowCustom, Seconds: 1800}, at(2026, 10, 14, 10, 15), time.Time{}, 30 * time.Minute},
+		{"не меньше минуты", dayWindow, time.Date(2026, 10, 14, 23, 59, 50, 0, msk), time.Time{}, time.Minute},
+		{"24 часа: старое сообщение выходит через час", rolling, at(2026, 10, 14, 10, 15), at(2026, 10, 13, 11, 15), time.Hour},
+		{"24 часа: старое сообщение только что", rolling, at(2026, 10, 14, 10, 15), at(2026, 10, 14, 10, 0), 23*time.Hour + 45*time.Minute},
+		{"своё окно: старое сообщение", repositories.LimitWindow{Type: repositories.WindowCustom, Seconds: 1800}, at(2026, 10, 14, 10, 15), at(2026, 10, 14, 10, 0), 15 * time.Minute},
+		{"час: старое сообщение не влияет", hour, at(2026, 10, 14, 10, 15), at(2026, 10, 14, 10, 1), 45 * time.Minute},
+		{"своё окно без длины", repositories.LimitWindow{Type: repositories.WindowCustom}, at(2026, 10, 14, 10, 15), time.Time{}, time.Minute},
+	}
+	for _, tc := range tests {
+		if got := untilReset(tc.window, tc.now, tc.oldest); got != tc.want {
+			t.Errorf("%s: untilReset(%s) = %v, want %v", tc.name, tc.now.Format(time.DateTime), got, tc.want)
+		}
+	}
+}
+
+// TestWindowBoundsConsistent проверяет, что подсчёт и сброс опираются на одни границы:
+// сброс окна совпадает с началом следующего окна
+func TestWindowBoundsConsistent(t *testing.T) {
+	msk := time.FixedZone("MSK", 3*60*60)
+	windows := []repositories.LimitWindow{
+		{Type: repositories.WindowHour},
+		dayWindow,
+		{Type: repositories.WindowWeek},
+	}
+	moments := []time.Time{
+		time.Date(2026, 10, 14, 10, 15, 0, 0, msk),
+		time.Date(2026, 10, 18, 23, 59, 59, 0, msk), // воскресенье
+		time.Date(2026, 10, 19, 0, 0, 0, 0, msk),    // понедельник, полночь
+		time.Date(2026, 12, 31, 23, 30, 0, 0, msk),
+	}
+	for _, w := range windows {
+		for _, now := range moments {
+			start, reset, err := w.Bounds(now)
+			if err != nil {
+				t.Fatalf("Bounds(%s, %s): %v", w.Type, now, err)
+			}
+			if now.Before(start) || !now.Before(reset) {
+				t.Errorf("Bounds(%s, %s) = [%s, %s): now вне окна", w.Type, now, start, reset)
+			}
+			next, _, err := w.Bounds(reset)
+			if err != nil {
+				t.Fatalf("Bounds(%s, %s): %v", w.Type, reset, err)
+			}
+			if !next.Equal(reset) {
+				t.Errorf("%s: следующее окно начинается в %s, а сброс в %s", w.Type, next, reset)
+			}
+		}
+	}
+}
+
+// TestLimitChecks проверяет сбор лимитов: пересылка не подменяет тип контента,
+// а проверяется отдельным лимитом forward
+func TestLimitChecks(t *testing.T) {
+	hour := repositories.LimitWindow{Type: repositories.WindowHour}
+	limits := &repositories.ContentLimits{LimitPhoto: 5, LimitForward: 3}
+	windowLimits := []repositories.WindowLimit{
+		{ContentType: "photo", Window: hour, Limit: 2},
+		{ContentType: forwardCategory, Window: hour, Limit: 1},
+		{ContentType: "video", Window: hour, Limit: 1},
+	}
+
+	tests := []struct {
+		contentType string
+		want        []limitCheck
+	}{
+		{"photo", []limitCheck{
+			{contentType: "photo", window: dayWindow, limit: 5},
+			{contentType: "photo", window: hour, limit: 2},
+		}},
+		{forwardCategory, []limitCheck{
+			{contentType: forwardCategory, window: dayWindow, limit: 3},
+			{contentType: forwardCategory, window: hour, limit: 1},
+		}},
+		{"text", nil}, // дневной лимит 0 — без ограничений
+		{"poll", nil}, // тип не лимитируется
+	}
+	for _, tc := range tests {
+		got := limitChecks(limits, windowLimits, tc.contentType)
+		if len(got) != len(tc.want) {
+			t.Errorf("limitChecks(%q) = %v, want %v", tc.contentType, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("limitChecks(%q)[%d] = %v, want %v", tc.contentType, i, got[i], tc.want[i])
+			}
+		}
+	}
+}
@@ -2,11 +2,16 @@ package maintenance
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
 )
 
 // MaintenanceModule обслуживает автоматическую ротацию данных в PostgreSQL.
@@ -16,7 +21,8 @@ type MaintenanceModule struct {
 	db              *sql.DB
 	logger          *zap.Logger
 	cron            *cron.Cron
-	retentionMonths int // Количество месяцев для хранения данных
+	retentionMonths int         // Количество месяцев для хранения данных
+	running         atomic.Bool // cron запущен (для /readyz)
 }
 
 // New создаёт новый инстанс модуля обслуживания.
@@ -32,6 +38,18 @@ func New(db *sql.DB, logger *zap.Logger, retentionMonths int) *MaintenanceModule
 	return m
 }
 
+// Name возвращает имя модуля.
+func (m *MaintenanceModule) Name() string { return "maintenance" }
+
+// Priority — maintenance работает по cron и не участвует в pipeline.
+func (m *MaintenanceModule) Priority() int { return core.PriorityNone }
+
+// OnMessage не вызывается (Priority = PriorityNone), нужен для core.Module.
+func (m *MaintenanceModule) OnMessage(ctx *core.MessageContext) error { return nil }
+
+// RegisterCommands — у maintenance нет команд, работает полностью автоматически.
+func (m *MaintenanceModule) RegisterCommands(bot *tele.Bot) {}
+
 // Start запускает фоновые задачи обслуживания.
 // Регистрирует cron-задачи для создания партиций и очистки старых данных.
 func (m *MaintenanceModule) Start() error {
@@ -66,6 +84,7 @@ func (m *MaintenanceModule) Start() error {
 	}
 
 	m.cron.Start()
+	m.running.Store(true)
 	m.logger.Info("maintenance scheduler started successfully")
 
 	return nil
@@ -74,12 +93,21 @@ func (m *MaintenanceModule) Start() error {
 // Shutdown выполняет graceful shutdown модуля.
 func (m *MaintenanceModule) Shutdown() error {
 	m.logger.Info("shutting down maintenance module")
+	m.running.Store(false)
 	ctx := m.cron.Stop()
 	<-ctx.Done()
 	m.logger.Info("maintenance scheduler stopped")
 	return nil
 }
 
+// HealthCheck сообщает /readyz, запущен ли cron обслуживания.
+func (m *MaintenanceModule) HealthCheck() error {
+	if !m.running.Load() {
+		return errors.New("maintenance cron is not running")
+	}
+	return nil
+}
+
 // ensurePartitions создаёт партиции на 3 месяца вперёд для messages и event_log.
 // Гарантирует, что всегда есть партиции на будущие месяцы.
 func (m *MaintenanceModule) ensurePartitions() error {
@@ -175,6 +203,15 @@ func (m *MaintenanceModule) cleanupOldData() error {
 		return fmt.Errorf("failed to drop event_log partitions: %w", err)
 	}
 
+	// message_edits не партиционирована — удаляем строки старше cutoff
+	result, err := m.db.Exec(`DELETE FROM message_edits WHERE edited_at < $1`, cutoffDate)
+	if err != nil {
+		return fmt.Errorf("failed to cleanup message_edits: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted > 0 {
+		m.logger.Info("old message edits deleted", zap.Int64("count", deleted))
+	}
+
 	m.logger.Info("data cleanup completed successfully")
 	return nil
 }
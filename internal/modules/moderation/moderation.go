@@ -0,0 +1,285 @@
+package moderation
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// ModerationModule — предупреждения с лестницей эскалации, наказания и жалобы участников.
+// Предупреждения выдаются вручную (/warn) или автоматически другими модулями
+// через core.Warner; antiflood и limiter наказывают через core.Punisher. При достижении ступени лестницы пользователь получает
+// мут, кик или бан. Наказания (/mute, /ban, /kick и лестница) хранятся в
+// punishments; истёкшие снимает cron воркер, в том числе после рестарта.
+// Жалобы (/report) хранятся в reports и разбираются админами кнопками оповещения.
+type ModerationModule struct {
+	db         *sql.DB
+	bot        *tele.Bot
+	logger     *zap.Logger
+	warnRepo   *repositories.WarningRepository
+	punishRepo *repositories.PunishmentRepository
+	eventRepo  *repositories.EventRepository
+	reportRepo *repositories.ReportRepository
+	cron       *cron.Cron
+	running    atomic.Bool // воркер истёкших наказаний запущен (для /readyz)
+}
+
+// New создаёт новый инстанс модуля модерации.
+func New(db *sql.DB, warnRepo *repositories.WarningRepository, punishRepo *repositories.PunishmentRepository, eventRepo *repositories.EventRepository, reportRepo *repositories.ReportRepository, logger *zap.Logger, bot *tele.Bot) *ModerationModule {
+	m := &ModerationModule{
+		db:         db,
+		bot:        bot,
+		logger:     logger,
+		warnRepo:   warnRepo,
+		punishRepo: punishRepo,
+		eventRepo:  eventRepo,
+		reportRepo: reportRepo,
+		cron:       cron.New(),
+	}
+
+	logger.Info("moderation module created")
+	return m
+}
+
+// Name возвращает имя модуля.
+func (m *ModerationModule) Name() string { return "moderation" }
+
+// Priority — модерация работает через команды и core.Warner, а не через pipeline.
+func (m *ModerationModule) Priority() int { return core.PriorityNone }
+
+// OnMessage не вызывается (Priority = PriorityNone), нужен для core.Module.
+func (m *ModerationModule) OnMessage(ctx *core.MessageContext) error { return nil }
+
+// Start запускает воркер, снимающий наказания с истёкшим сроком.
+// Наказания, истёкшие пока бот был выключен, снимаются первым же проходом.
+func (m *ModerationModule) Start() error {
+	m.logger.Info("starting moderation module")
+
+	if _, err := m.cron.AddFunc("@every 30s", m.liftExpired); err != nil {
+		return fmt.Errorf("failed to schedule punishment expiry worker: %w", err)
+	}
+
+	m.cron.Start()
+	m.running.Store(true)
+	m.logger.Info("punishment expiry worker started")
+	return nil
+}
+
+// Shutdown выполняет graceful shutdown модуля.
+func (m *ModerationModule) Shutdown() error {
+	m.logger.Info("shutting down moderation module")
+	m.running.Store(false)
+	ctx := m.cron.Stop()
+	<-ctx.Done()
+	m.logger.Info("punishment expiry worker stopped")
+	return nil
+}
+
+// HealthCheck сообщает /readyz, запущен ли воркер истёкших наказаний.
+func (m *ModerationModule) HealthCheck() error {
+	if !m.running.Load() {
+		return errors.New("punishment expiry worker is not running")
+	}
+	return nil
+}
+
+// RegisterCommands регистрирует пользовательские команды.
+func (m *ModerationModule) RegisterCommands(bot *tele.Bot) {
+	bot.Handle("/moderation", m.handleHelp)
+	bot.Handle("/warns", m.handleWarns)
+	bot.Handle("/warnpolicy", m.handleWarnPolicy)
+	bot.Handle("/report", m.handleReport)
+	bot.Handle(&tele.Btn{Unique: reportCallbackUnique}, m.handleReportCallback)
+}
+
+// RegisterAdminCommands регистрирует админские команды.
+func (m *ModerationModule) RegisterAdminCommands(bot *tele.Bot) {
+	bot.Handle("/warn", m.handleWarn)
+	bot.Handle("/unwarn", m.handleUnwarn)
+	bot.Handle("/resetwarns", m.handleResetWarns)
+	bot.Handle("/setwarnpolicy", m.handleSetWarnPolicy)
+	bot.Handle("/setautowarn", m.handleSetAutoWarn)
+
+	bot.Handle("/mute", m.handlePunish("mute", false))
+	bot.Handle("/tmute", m.handlePunish("mute", true))
+	bot.Handle("/unmute", m.handleUnmute)
+	bot.Handle("/ban", m.handlePunish("ban", false))
+	bot.Handle("/tban", m.handlePunish("ban", true))
+	bot.Handle("/unban", m.handleUnban)
+	bot.Handle("/kick", m.handlePunish("kick", false))
+
+	bot.Handle("/reports", m.handleReports)
+	bot.Handle("/setreport", m.handleSetReport)
+}
+
+// AutoWarn реализует core.Warner: выдаёт предупреждение от имени бота,
+// если автопредупреждения для source включены в чате.
+func (m *ModerationModule) AutoWarn(ctx *core.MessageContext, source, reason string) {
+	chatID := ctx.Chat.ID
+	settings, err := m.warnRepo.GetSettings(chatID)
+	if err != nil {
+		m.logger.Error("failed to get warn settings", zap.Error(err))
+		return
+	}
+
+	enabled := false
+	switch source {
+	case "antiflood":
+		// Действие warn выбрано в /setflood — отдельная настройка не нужна
+		enabled = true
+	case "limiter":
+		enabled = settings.AutoLimiter
+	case "profanity":
+		enabled = settings.AutoProfanity
+	case "banned_words":
+		enabled = settings.AutoBannedWords
+	case "links":
+		enabled = settings.AutoLinks
+	}
+	if !enabled {
+		return
+	}
+
+	// Админам предупреждения не выдаются: ограничить их бот всё равно не может
+	if m.isAdmin(ctx.Chat, ctx.Sender) {
+		return
+	}
+
+	// Правка сообщения снова проходит фильтры — одно нарушение = одно предупреждение
+	// (повтор отсекает уникальный индекс, warn возвращает пустой текст)
+	text, err := m.warn(ctx.Chat, ctx.Sender, 0, source, reason, ctx.Message.ID)
+	if err != nil {
+		m.logger.Error("failed to issue auto warning",
+			zap.Int64("chat_id", chatID),
+			zap.Int64("user_id", ctx.Sender.ID),
+			zap.String("source", source),
+			zap.Error(err))
+		return
+	}
+	if text == "" {
+		return
+	}
+	if err := ctx.Send(text); err != nil {
+		m.logger.Error("failed to send warning", zap.Error(err))
+	}
+}
+
+// Mute реализует core.Punisher: мут от имени бота (antiflood, limiter).
+func (m *ModerationModule) Mute(chat *tele.Chat, user *tele.User, d time.Duration, reason string) error {
+	return m.punish(chat, user, "mute", d, 0, reason)
+}
+
+// Kick реализует core.Punisher: кик от имени бота (antiraid).
+func (m *ModerationModule) Kick(chat *tele.Chat, user *tele.User, reason string) error {
+	return m.punish(chat, user, "kick", 0, 0, reason)
+}
+
+// Unrestrict реализует core.Punisher: возврат прав после капчи (captcha).
+func (m *ModerationModule) Unrestrict(chat *tele.Chat, user *tele.User, base tele.Rights) error {
+	return m.applyRestrictions(chat, user, base, m.activePunishments(chat.ID, user.ID, "", 0))
+}
+
+// RestrictContent реализует core.Punisher: запрет типа контента от имени бота (limiter).
+func (m *ModerationModule) RestrictContent(chat *tele.Chat, user *tele.User, contentType string, d time.Duration, reason string) error {
+	return m.restrictContent(chat, user, contentType, d, reason)
+}
+
+// warn записывает предупреждение, применяет ступень лестницы и возвращает текст для чата.
+// Пустой текст — за это сообщение из этого источника предупреждение уже выдано.
+func (m *ModerationModule) warn(chat *tele.Chat, user *tele.User, issuedBy int64, source, reason string, messageID int) (string, error) {
+	count, added, err := m.warnRepo.AddWarning(chat.ID, user.ID, issuedBy, source, reason, messageID)
+	if err != nil || !added {
+		return "", err
+	}
+
+	steps, err := m.warnRepo.GetEscalation(chat.ID)
+	if err != nil {
+		return "", err
+	}
+
+	_ = m.eventRepo.Log(chat.ID, user.ID, "moderation", "warn",
+		fmt.Sprintf("Warning %d issued by %d (source=%s): %s", count, issuedBy, source, reason))
+
+	text := fmt.Sprintf("⚠️ %s получает предупреждение (%d/%d)",
+		core.DisplayName(user), count, steps[len(steps)-1].WarnCount)
+	if reason != "" {
+		text += "\nПричина: " + reason
+	}
+
+	step := escalationStep(steps, count)
+	if step == nil {
+		return text, nil
+	}
+
+	if err := m.applyStep(chat, user, step, count); err != nil {
+		m.logger.Error("failed to apply escalation step",
+			zap.Int64("chat_id", chat.ID),
+			zap.Int64("user_id", user.ID),
+			zap.String("action", step.Action),
+			zap.Error(err))
+		return text + "\n❌ Не удалось применить наказание (нет прав администратора?)", nil
+	}
+
+	return text + "\n" + stepResultText(user, step, count), nil
+}
+
+// escalationStep возвращает ступень для count предупреждений.
+// Точное совпадение — своя ступень; больше последней ступени — последняя
+// (после изменения лестницы или ручных /warn сверх максимума).
+func escalationStep(steps []repositories.EscalationStep, count int) *repositories.EscalationStep {
+	for i := range steps {
+		if steps[i].WarnCount == count {
+			return &steps[i]
+		}
+	}
+	if last := &steps[len(steps)-1]; count > last.WarnCount {
+		return last
+	}
+	return nil
+}
+
+// applyStep применяет наказание ступени. После кика и бана предупреждения
+// сбрасываются — вернувшийся пользователь начинает с нуля.
+func (m *ModerationModule) applyStep(chat *tele.Chat, user *tele.User, step *repositories.EscalationStep, count int) error {
+	reason := fmt.Sprintf("%d предупреждений", count)
+	if err := m.punish(chat, user, step.Action, step.Duration, 0, reason); err != nil {
+		return err
+	}
+	if step.Action == "kick" || step.Action == "ban" {
+		if _, err := m.warnRepo.RevokeAll(chat.ID, user.ID, 0); err != nil {
+			m.logger.Error("failed to reset warnings after escalation", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// stepResultText — сообщение о применённой ступени.
+func stepResultText(user *tele.User, step *repositories.EscalationStep, count int) string {
+	name := core.DisplayName(user)
+	switch step.Action {
+	case "mute":
+		return fmt.Sprintf("🔇 %s в муте: %s (%d предупреждений)", name, core.FormatDuration(step.Duration), count)
+	case "kick":
+		return fmt.Sprintf("👢 %s удалён из чата (%d предупреждений)", name, count)
+	default:
+		return fmt.Sprintf("⛔ %s забанен: %s (%d предупреждений)", name, core.FormatDuration(step.Duration), count)
+	}
+}
+
+// isAdmin проверяет, является ли пользователь администратором или создателем чата.
+func (m *ModerationModule) isAdmin(chat *tele.Chat, user *tele.User) bool {
+	member, err := m.bot.ChatMemberOf(chat, user)
+	if err != nil {
+		return false
+	}
+	return member.Role == tele.Administrator || member.Role == tele.Creator
+}
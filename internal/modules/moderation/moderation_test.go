@@ -0,0 +1,89 @@
+package moderation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// ladder — лестница наказаний с пропуском: 2 → мут, 4 → кик, 6 → бан.
+var ladder = []repositories.EscalationStep{
+	{WarnCount: 2, Action: "mute", Duration: time.Hour},
+	{WarnCount: 4, Action: "kick"},
+	{WarnCount: 6, Action: "ban"},
+}
+
+// TestEscalationStep проверяет выбор ступени по количеству предупреждений
+func TestEscalationStep(t *testing.T) {
+	tests := []struct {
+		count int
+		want  string // "" — наказания нет
+	}{
+		{1, ""},
+		{2, "mute"},
+		{3, ""}, // между ступенями — только предупреждение
+		{4, "kick"},
+		{6, "ban"},
+		{7, "ban"}, // сверх последней ступени — последняя
+		{100, "ban"},
+	}
+	for _, tc := range tests {
+		step := escalationStep(ladder, tc.count)
+		got := ""
+		if step != nil {
+			got = step.Action
+		}
+		if got != tc.want {
+			t.Errorf("escalationStep(%d) = %q, want %q", tc.count, got, tc.want)
+		}
+	}
+
+	// Стандартная лестница: 3 → мут на час, 5 → бан
+	defaults := repositories.DefaultEscalation()
+	if step := escalationStep(defaults, 3); step == nil || step.Action != "mute" || step.Duration != time.Hour {
+		t.Errorf("escalationStep(default, 3) = %+v, want mute 1h", step)
+	}
+	if step := escalationStep(defaults, 4); step != nil {
+		t.Errorf("escalationStep(default, 4) = %+v, want nil", step)
+	}
+}
+
+// TestNextStep проверяет ближайшую ступень для /warns
+func TestNextStep(t *testing.T) {
+	tests := []struct {
+		count     int
+		wantCount int // 0 — ступеней больше нет
+	}{
+		{0, 2},
+		{2, 4},
+		{3, 4},
+		{5, 6},
+		{6, 0},
+		{9, 0},
+	}
+	for _, tc := range tests {
+		step := nextStep(ladder, tc.count)
+		got := 0
+		if step != nil {
+			got = step.WarnCount
+		}
+		if got != tc.wantCount {
+			t.Errorf("nextStep(%d) = %d, want %d", tc.count, got, tc.wantCount)
+		}
+	}
+}
+
+// TestSourceNames проверяет, что у каждого источника предупреждений есть название для /warns
+func TestSourceNames(t *testing.T) {
+	// manual и report — /warn и разбор жалоб, остальные — AutoWarn модулей
+	sources := []string{"manual", "report", "antiflood", "limiter", "profanity", "banned_words", "links"}
+	for _, source := range sources {
+		if sourceNames[source] == "" {
+			t.Errorf("sourceNames[%q] пусто", source)
+		}
+	}
+	if got := sourceNames["antiflood"]; got != "флуд" {
+		t.Errorf("sourceNames[antiflood] = %q, want %q", got, "флуд")
+	}
+}
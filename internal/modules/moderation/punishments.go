@@ -0,0 +1,404 @@
+package moderation
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/metrics"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// punish применяет наказание в Telegram и записывает его в punishments.
+// d = 0 — навсегда (для kick не используется). Telegram тоже получает until_date,
+// а воркер liftExpired снимает наказание явно — на случай, если бот был выключен
+// или ограничение изменили вручную.
+// Права и until_date в Telegram — одни на всех, поэтому мут и бан считаются
+// вместе с уже действующими наказаниями: короткий мут бота не сокращает длинный.
+func (m *ModerationModule) punish(chat *tele.Chat, user *tele.User, action string, d time.Duration, issuedBy int64, reason string) error {
+	switch action {
+	case "mute":
+		active := append(m.activePunishments(chat.ID, user.ID, action, issuedBy), pendingPunishment(action, "", d))
+		if err := m.applyRestrictions(chat, user, tele.NoRestrictions(), active); err != nil {
+			return err
+		}
+	case "ban":
+		active := append(m.activePunishments(chat.ID, user.ID, action, issuedBy), pendingPunishment(action, "", d))
+		expires, _ := latestExpiry(active, "ban")
+		if err := m.bot.Ban(chat, &tele.ChatMember{User: user, RestrictedUntil: untilDateOf(expires)}); err != nil {
+			return err
+		}
+		if issuedBy == 0 {
+			metrics.BansTotal.WithLabelValues("warnings").Inc()
+		} else {
+			metrics.BansTotal.WithLabelValues("manual").Inc()
+		}
+	case "kick":
+		// Кик = бан + немедленный разбан: пользователь удаляется, но может вернуться
+		if err := m.bot.Ban(chat, &tele.ChatMember{User: user, RestrictedUntil: tele.Forever()}); err != nil {
+			return err
+		}
+		if err := m.bot.Unban(chat, user); err != nil {
+			m.logger.Error("failed to unban kicked user", zap.Error(err))
+		}
+		d = 0
+	default:
+		return fmt.Errorf("unknown punishment action %q", action)
+	}
+
+	m.recordPunishment(chat, user, action, "", d, issuedBy, reason)
+	return nil
+}
+
+// recordPunishment записывает применённое наказание в punishments и event_log.
+func (m *ModerationModule) recordPunishment(chat *tele.Chat, user *tele.User, action, contentType string, d time.Duration, issuedBy int64, reason string) {
+	m.ensureChat(chat.ID)
+	if _, err := m.punishRepo.Create(chat.ID, user.ID, action, contentType, reason, issuedBy, expiryOf(d)); err != nil {
+		// Наказание уже применено в Telegram — не откатываем, только логируем
+		m.logger.Error("failed to save punishment", zap.Error(err))
+	}
+
+	_ = m.eventRepo.Log(chat.ID, user.ID, "moderation", action,
+		fmt.Sprintf("%s for %s by %d: %s", action, d, issuedBy, reason))
+}
+
+// activePunishments возвращает действующие наказания пользователя, с которыми
+// совмещается новое наказание action. Наказание от админа заменяет предыдущие
+// того же типа (см. PunishmentRepository.Create) — они не учитываются.
+// При ошибке БД новое наказание применяется само по себе.
+func (m *ModerationModule) activePunishments(chatID, userID int64, action string, issuedBy int64) []repositories.Punishment {
+	active, err := m.punishRepo.GetActive(chatID, userID, time.Now())
+	if err != nil {
+		m.logger.Error("failed to get active punishments", zap.Error(err))
+		return nil
+	}
+	if issuedBy == 0 {
+		return active
+	}
+	kept := active[:0]
+	for _, p := range active {
+		if p.Action != action {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// applyRestrictions выставляет пользователю права base, урезанные действующими мутами
+// и запретами контента. Без них права просто возвращаются к base.
+func (m *ModerationModule) applyRestrictions(chat *tele.Chat, user *tele.User, base tele.Rights, active []repositories.Punishment) error {
+	rights, restricted := restrictionRights(base, active)
+	if !restricted {
+		return m.bot.Restrict(chat, &tele.ChatMember{User: user, Rights: base})
+	}
+	expires, _ := latestExpiry(active, "mute", "restrict")
+	return m.bot.Restrict(chat, &tele.ChatMember{
+		User:            user,
+		Rights:          rights,
+		RestrictedUntil: untilDateOf(expires),
+	})
+}
+
+// restrictContent запрещает пользователю отправлять один тип контента до until_date.
+// Права считаются вместе с действующими мутами и запретами: restrict поверх мута
+// не должен его снимать. Снимается так же, как мут (unmute).
+func (m *ModerationModule) restrictContent(chat *tele.Chat, user *tele.User, contentType string, d time.Duration, reason string) error {
+	active := append(m.activePunishments(chat.ID, user.ID, "restrict", 0), pendingPunishment("restrict", contentType, d))
+	if err := m.applyRestrictions(chat, user, tele.NoRestrictions(), active); err != nil {
+		return err
+	}
+
+	m.recordPunishment(chat, user, "restrict", contentType, d, 0, reason)
+	return nil
+}
+
+// liftExpired снимает наказания с истёкшим сроком (cron воркер).
+// Если у пользователя остались другие действующие наказания, права пересчитываются
+// по ним, а бан не снимается, пока действует другой бан.
+func (m *ModerationModule) liftExpired() {
+	punishments, err := m.punishRepo.GetExpired(time.Now())
+	if err != nil {
+		m.logger.Error("failed to get expired punishments", zap.Error(err))
+		return
+	}
+
+	for _, p := range punishments {
+		chat := &tele.Chat{ID: p.ChatID}
+		user := &tele.User{ID: p.UserID}
+
+		// Истёкшие наказания (и это, и другие из той же выборки) в remaining не попадают
+		remaining, err := m.punishRepo.GetActive(p.ChatID, p.UserID, time.Now())
+		if err != nil {
+			// Без списка действующих наказаний можно освободить замьюченного — повторим на следующем проходе
+			m.logger.Error("failed to get active punishments", zap.Int64("punishment_id", p.ID), zap.Error(err))
+			continue
+		}
+
+		switch p.Action {
+		case "mute", "restrict":
+			err = m.applyRestrictions(chat, user, tele.NoRestrictions(), remaining)
+		case "ban":
+			if _, banned := latestExpiry(remaining, "ban"); !banned {
+				// only_if_banned: не выкидывать пользователя, если он уже в чате
+				err = m.bot.Unban(chat, user, true)
+			}
+		}
+		if err != nil {
+			// Пользователь мог покинуть чат или бот лишился прав — запись всё равно закрываем,
+			// иначе воркер будет повторять запрос каждые 30 секунд
+			m.logger.Warn("failed to lift expired punishment",
+				zap.Int64("punishment_id", p.ID),
+				zap.Int64("chat_id", p.ChatID),
+				zap.Int64("user_id", p.UserID),
+				zap.Error(err))
+		}
+
+		if err := m.punishRepo.MarkLifted(p.ID, 0); err != nil {
+			m.logger.Error("failed to mark punishment lifted", zap.Error(err))
+			continue
+		}
+
+		_ = m.eventRepo.Log(p.ChatID, p.UserID, "moderation", "un"+p.Action, "Punishment expired")
+		m.logger.Info("expired punishment lifted",
+			zap.Int64("punishment_id", p.ID),
+			zap.Int64("chat_id", p.ChatID),
+			zap.Int64("user_id", p.UserID),
+			zap.String("action", p.Action),
+			zap.Int("remaining", len(remaining)))
+	}
+}
+
+// unmute возвращает пользователю право писать.
+func (m *ModerationModule) unmute(chat *tele.Chat, user *tele.User) error {
+	return m.bot.Restrict(chat, &tele.ChatMember{
+		User:   user,
+		Rights: tele.NoRestrictions(),
+	})
+}
+
+// restrictionRights возвращает права base, урезанные действующими мутами и запретами
+// контента: мут запрещает всё, restrict — один тип контента.
+// restricted = false — таких наказаний нет.
+func restrictionRights(base tele.Rights, active []repositories.Punishment) (rights tele.Rights, restricted bool) {
+	rights = base
+	for _, p := range active {
+		switch p.Action {
+		case "mute":
+			rights = tele.NoRights()
+		case "restrict":
+			denyContent(&rights, p.ContentType)
+		default:
+			continue
+		}
+		restricted = true
+	}
+	// Каждое право — отдельно: без этого Telegram выводит одни права из других
+	rights.Independent = rights.Independent || restricted
+	return rights, restricted
+}
+
+// denyContent снимает право отправлять contentType.
+func denyContent(rights *tele.Rights, contentType string) {
+	switch contentType {
+	case "photo":
+		rights.CanSendPhotos = false
+	case "video":
+		rights.CanSendVideos = false
+	case "audio":
+		rights.CanSendAudios = false
+	case "document":
+		rights.CanSendDocuments = false
+	case "voice":
+		rights.CanSendVoiceNotes = false
+	case "video_note":
+		rights.CanSendVideoNotes = false
+	case "sticker", "animation":
+		// Стикеры и гифки в Telegram — одно право can_send_other_messages
+		rights.CanSendOther = false
+	default:
+		// Текст, геолокация, контакты — can_send_messages.
+		// Отдельного права на пересылки в Telegram нет, forward тоже сюда
+		rights.CanSendMessages = false
+	}
+}
+
+// latestExpiry возвращает самое позднее окончание действующих наказаний actions
+// (nil — среди них есть бессрочное); found = false — таких наказаний нет.
+func latestExpiry(active []repositories.Punishment, actions ...string) (expires *time.Time, found bool) {
+	for _, p := range active {
+		if !slices.Contains(actions, p.Action) {
+			continue
+		}
+		if p.ExpiresAt == nil {
+			return nil, true
+		}
+		if !found || p.ExpiresAt.After(*expires) {
+			expires = p.ExpiresAt
+		}
+		found = true
+	}
+	return expires, found
+}
+
+// pendingPunishment — новое наказание, ещё не записанное в punishments.
+func pendingPunishment(action, contentType string, d time.Duration) repositories.Punishment {
+	return repositories.Punishment{Action: action, ContentType: contentType, ExpiresAt: expiryOf(d)}
+}
+
+// expiryOf переводит длительность в момент окончания (nil — навсегда).
+func expiryOf(d time.Duration) *time.Time {
+	if d <= 0 {
+		return nil
+	}
+	t := time.Now().Add(d)
+	return &t
+}
+
+// untilDateOf переводит момент окончания в until_date Telegram (nil = навсегда).
+func untilDateOf(expires *time.Time) int64 {
+	if expires == nil {
+		return tele.Forever()
+	}
+	return expires.Unix()
+}
+
+// handlePunish — общий обработчик /mute, /tmute, /ban, /tban, /kick.
+// Цель: reply на сообщение или user ID первым аргументом.
+func (m *ModerationModule) handlePunish(action string, requireDuration bool) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		command := strings.SplitN(strings.Fields(c.Text())[0], "@", 2)[0]
+		target, args := m.resolveTarget(c)
+		if target == nil {
+			return c.Send(punishUsage(command, action, requireDuration))
+		}
+		if target.ID == c.Bot().Me.ID || m.isAdmin(c.Chat(), target) {
+			return c.Send("❌ Нельзя наказать администратора или бота")
+		}
+
+		// Первый аргумент — длительность, если он ею является
+		var d time.Duration
+		if action != "kick" && len(args) > 0 {
+			if parsed, err := core.ParseDuration(args[0]); err == nil {
+				d = parsed
+				args = args[1:]
+			} else if requireDuration {
+				return c.Send("❌ " + err.Error())
+			}
+		}
+		if requireDuration && d == 0 {
+			return c.Send(punishUsage(command, action, requireDuration))
+		}
+		reason := strings.Join(args, " ")
+
+		if err := m.punish(c.Chat(), target, action, d, c.Sender().ID, reason); err != nil {
+			m.logger.Error("failed to punish user",
+				zap.Int64("chat_id", c.Chat().ID),
+				zap.Int64("user_id", target.ID),
+				zap.String("action", action),
+				zap.Error(err))
+			return c.Send("❌ Не удалось применить наказание (нет прав администратора?)")
+		}
+
+		name := core.DisplayName(target)
+		var text string
+		switch action {
+		case "mute":
+			text = fmt.Sprintf("🔇 %s в муте: %s", name, core.FormatDuration(d))
+		case "ban":
+			text = fmt.Sprintf("⛔ %s забанен: %s", name, core.FormatDuration(d))
+		default:
+			text = fmt.Sprintf("👢 %s удалён из чата", name)
+		}
+		if reason != "" {
+			text += "\nПричина: " + reason
+		}
+		return c.Send(text)
+	}
+}
+
+// handleUnmute — /unmute: снимает мут (reply или user ID).
+func (m *ModerationModule) handleUnmute(c tele.Context) error {
+	target, _ := m.resolveTarget(c)
+	if target == nil {
+		return c.Send("Использование: ответьте на сообщение пользователя /unmute или /unmute <user_id>")
+	}
+
+	if err := m.unmute(c.Chat(), target); err != nil {
+		m.logger.Error("failed to unmute user", zap.Error(err))
+		return c.Send("❌ Не удалось снять мут")
+	}
+	// Мут и ограничение контента снимаются одним restrictChatMember
+	for _, action := range []string{"mute", "restrict"} {
+		if _, err := m.punishRepo.LiftActive(c.Chat().ID, target.ID, action, c.Sender().ID); err != nil {
+			m.logger.Error("failed to lift restriction", zap.String("action", action), zap.Error(err))
+		}
+	}
+
+	_ = m.eventRepo.Log(c.Chat().ID, target.ID, "moderation", "unmute",
+		fmt.Sprintf("Unmuted by %d", c.Sender().ID))
+	return c.Send(fmt.Sprintf("🔊 %s снова может писать", core.DisplayName(target)))
+}
+
+// handleUnban — /unban: снимает бан (reply или user ID).
+func (m *ModerationModule) handleUnban(c tele.Context) error {
+	target, _ := m.resolveTarget(c)
+	if target == nil {
+		return c.Send("Использование: /unban <user_id> или ответьте на сообщение пользователя /unban")
+	}
+
+	if err := m.bot.Unban(c.Chat(), target, true); err != nil {
+		m.logger.Error("failed to unban user", zap.Error(err))
+		return c.Send("❌ Не удалось снять бан")
+	}
+	if _, err := m.punishRepo.LiftActive(c.Chat().ID, target.ID, "ban", c.Sender().ID); err != nil {
+		m.logger.Error("failed to lift ban", zap.Error(err))
+	}
+
+	_ = m.eventRepo.Log(c.Chat().ID, target.ID, "moderation", "unban",
+		fmt.Sprintf("Unbanned by %d", c.Sender().ID))
+	return c.Send(fmt.Sprintf("✅ %s разбанен и может вернуться в чат", core.DisplayName(target)))
+}
+
+// resolveTarget возвращает цель команды и оставшиеся аргументы.
+// Reply на сообщение имеет приоритет; иначе первый аргумент — user ID.
+func (m *ModerationModule) resolveTarget(c tele.Context) (*tele.User, []string) {
+	args := c.Args()
+	if target := replyTarget(c); target != nil {
+		return target, args
+	}
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || userID <= 0 {
+		return nil, nil
+	}
+
+	// Имя пользователя для ответа — из getChatMember (если он ещё в чате)
+	user := &tele.User{ID: userID}
+	if member, err := m.bot.ChatMemberOf(c.Chat(), user); err == nil && member.User != nil {
+		user = member.User
+	}
+	return user, args[1:]
+}
+
+// punishUsage — подсказка по формату команды наказания.
+func punishUsage(command, action string, requireDuration bool) string {
+	switch {
+	case action == "kick":
+		return fmt.Sprintf("Использование: ответьте на сообщение %s [причина] или %s <user_id> [причина]", command, command)
+	case requireDuration:
+		return fmt.Sprintf("Использование: ответьте на сообщение %s <длительность> [причина] или %s <user_id> <длительность> [причина]\n"+
+			"Длительность: 30m, 1h, 2d, 1w", command, command)
+	default:
+		return fmt.Sprintf("Использование: ответьте на сообщение %s [длительность] [причина] или %s <user_id> [длительность] [причина]\n"+
+			"Длительность: 30m, 1h, 2d, 1w (без неё — навсегда)", command, command)
+	}
+}
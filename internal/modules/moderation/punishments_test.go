@@ -0,0 +1,180 @@
+package moderation
+
+import (
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// TestLatestExpiry проверяет until_date для набора действующих наказаний
+func TestLatestExpiry(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+
+	tests := []struct {
+		name      string
+		active    []repositories.Punishment
+		actions   []string
+		expected  *time.Time
+		wantFound bool
+	}{
+		{
+			name:    "No punishments",
+			actions: []string{"mute"},
+		},
+		{
+			name: "Other actions only",
+			active: []repositories.Punishment{
+				{Action: "ban", ExpiresAt: at(time.Hour)},
+			},
+			actions: []string{"mute", "restrict"},
+		},
+		{
+			name: "Latest of timed",
+			active: []repositories.Punishment{
+				{Action: "mute", ExpiresAt: at(5 * time.Minute)},
+				{Action: "restrict", ExpiresAt: at(24 * time.Hour)},
+				{Action: "mute", ExpiresAt: at(time.Hour)},
+			},
+			actions:   []string{"mute", "restrict"},
+			expected:  at(24 * time.Hour),
+			wantFound: true,
+		},
+		{
+			name: "Permanent wins over timed",
+			active: []repositories.Punishment{
+				{Action: "mute", ExpiresAt: at(5 * time.Minute)},
+				{Action: "mute"},
+			},
+			actions:   []string{"mute"},
+			wantFound: true,
+		},
+		{
+			name: "Ban ignores mutes",
+			active: []repositories.Punishment{
+				{Action: "mute"},
+				{Action: "ban", ExpiresAt: at(time.Hour)},
+			},
+			actions:   []string{"ban"},
+			expected:  at(time.Hour),
+			wantFound: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expires, found := latestExpiry(tt.active, tt.actions...)
+			if found != tt.wantFound {
+				t.Fatalf("Expected found=%v, got %v", tt.wantFound, found)
+			}
+			switch {
+			case tt.expected == nil && expires != nil:
+				t.Errorf("Expected permanent (nil), got %v", *expires)
+			case tt.expected != nil && (expires == nil || !expires.Equal(*tt.expected)):
+				t.Errorf("Expected %v, got %v", *tt.expected, expires)
+			}
+		})
+	}
+}
+
+// TestRestrictionRightsAfterExpiry проверяет права, которые остаются после истечения
+// одного из наказаний: короткий мут внутри длинного не освобождает пользователя
+func TestRestrictionRightsAfterExpiry(t *testing.T) {
+	// Мут антифлуда на 5 минут истёк, /mute на сутки ещё действует
+	rights, restricted := restrictionRights(tele.NoRestrictions(), []repositories.Punishment{{Action: "mute"}})
+	if !restricted || rights.CanSendMessages || rights.CanSendPhotos || rights.CanSendOther {
+		t.Errorf("Expected full mute to remain, got restricted=%v rights=%+v", restricted, rights)
+	}
+
+	// Ничего не осталось — ограничения снимаются полностью
+	rights, restricted = restrictionRights(tele.NoRestrictions(), nil)
+	if restricted || rights != tele.NoRestrictions() {
+		t.Errorf("Expected no restrictions, got restricted=%v rights=%+v", restricted, rights)
+	}
+
+	// Бан не влияет на права в чате
+	if _, restricted = restrictionRights(tele.NoRestrictions(), []repositories.Punishment{{Action: "ban"}}); restricted {
+		t.Error("Expected ban not to restrict rights")
+	}
+}
+
+// TestRestrictionRights проверяет совмещение мутов и запретов контента
+func TestRestrictionRights(t *testing.T) {
+	allowed := tele.NoRestrictions()
+
+	tests := []struct {
+		name   string
+		active []repositories.Punishment
+		check  func(r tele.Rights) bool
+	}{
+		{
+			name:   "Single content type",
+			active: []repositories.Punishment{{Action: "restrict", ContentType: "photo"}},
+			check: func(r tele.Rights) bool {
+				return !r.CanSendPhotos && r.CanSendMessages && r.CanSendVideos && r.CanSendOther
+			},
+		},
+		{
+			name: "Two content types",
+			active: []repositories.Punishment{
+				{Action: "restrict", ContentType: "photo"},
+				{Action: "restrict", ContentType: "sticker"},
+			},
+			check: func(r tele.Rights) bool {
+				return !r.CanSendPhotos && !r.CanSendOther && r.CanSendMessages && r.CanSendVideos
+			},
+		},
+		{
+			name: "Restrict does not lift mute",
+			active: []repositories.Punishment{
+				{Action: "mute"},
+				{Action: "restrict", ContentType: "photo"},
+			},
+			check: func(r tele.Rights) bool {
+				return !r.CanSendMessages && !r.CanSendPhotos && !r.CanSendVideos && !r.CanSendOther
+			},
+		},
+		{
+			name: "Mute after restrict",
+			active: []repositories.Punishment{
+				{Action: "restrict", ContentType: "video"},
+				{Action: "mute"},
+			},
+			check: func(r tele.Rights) bool {
+				return !r.CanSendMessages && !r.CanSendVideos && !r.CanSendDocuments
+			},
+		},
+		{
+			name:   "Forward and text share can_send_messages",
+			active: []repositories.Punishment{{Action: "restrict", ContentType: "forward"}},
+			check: func(r tele.Rights) bool {
+				return !r.CanSendMessages && r.CanSendPhotos
+			},
+		},
+		{
+			name:   "Legacy row without content type",
+			active: []repositories.Punishment{{Action: "restrict"}},
+			check: func(r tele.Rights) bool {
+				return !r.CanSendMessages && r.CanSendPhotos
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rights, restricted := restrictionRights(allowed, tt.active)
+			if !restricted {
+				t.Fatal("Expected restricted=true")
+			}
+			if !rights.Independent {
+				t.Error("Expected independent permissions")
+			}
+			if rights == allowed || !tt.check(rights) {
+				t.Errorf("Unexpected rights: %+v", rights)
+			}
+		})
+	}
+}
@@ -0,0 +1,548 @@
+package moderation
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+const (
+	// reportCallbackUnique — идентификатор кнопок разбора жалобы (telebot: "\freport|<id>|<действие>").
+	reportCallbackUnique = "report"
+	// reportCooldown — пауза между жалобами одного участника в чате.
+	reportCooldown = time.Minute
+	// reportHourlyLimit — сколько жалоб участник может отправить в чате за час.
+	reportHourlyLimit = 5
+	// reportPreviewLen — сколько символов сообщения сохранить в жалобе и показать админам.
+	reportPreviewLen = 300
+	// reportListLimit — сколько последних жалоб показывает /reports.
+	reportListLimit = 10
+)
+
+// reportActions — кнопки разбора жалобы: действие → статус жалобы.
+var reportActions = map[string]string{
+	"delete":  repositories.ReportDeleted,
+	"warn":    repositories.ReportWarned,
+	"ban":     repositories.ReportBanned,
+	"dismiss": repositories.ReportDismissed,
+}
+
+// reportStatusNames — статусы жалоб для /reports и итогов разбора.
+var reportStatusNames = map[string]string{
+	repositories.ReportOpen:      "🕓 ожидает",
+	repositories.ReportDeleted:   "🗑 сообщение удалено",
+	repositories.ReportWarned:    "⚠️ удалено, предупреждение",
+	repositories.ReportBanned:    "⛔ удалено, бан",
+	repositories.ReportDismissed: "👌 отклонена",
+}
+
+// handleReport — /report [причина] в ответ на сообщение: жалоба админам.
+func (m *ModerationModule) handleReport(c tele.Context) error {
+	chat := c.Chat()
+	if chat.Type != tele.ChatGroup && chat.Type != tele.ChatSuperGroup {
+		return c.Send("ℹ️ /report работает в группах: ответьте командой на сообщение-нарушение")
+	}
+
+	settings, err := m.reportRepo.GetSettings(chat.ID)
+	if err != nil {
+		m.logger.Error("failed to get report settings", zap.Error(err))
+		return c.Send("❌ Ошибка при отправке жалобы")
+	}
+	if !settings.Enabled {
+		return c.Send("ℹ️ Жалобы в этом чате выключены")
+	}
+
+	// В форуме ReplyTo без явного ответа указывает на создание топика
+	reported := c.Message().ReplyTo
+	if reported == nil || reported.Sender == nil || reported.TopicCreated != nil {
+		return c.Send("Использование: ответьте на сообщение-нарушение командой /report [причина]")
+	}
+	reporter, target := c.Sender(), reported.Sender
+	if target.ID == reporter.ID {
+		return c.Send("❌ Нельзя пожаловаться на своё сообщение")
+	}
+	if target.ID == c.Bot().Me.ID || m.isAdmin(chat, target) {
+		return c.Send("❌ Нельзя пожаловаться на администратора или бота")
+	}
+
+	m.ensureChat(chat.ID)
+
+	text := reported.Text
+	if text == "" {
+		text = reported.Caption
+	}
+	report := &repositories.Report{
+		ChatID:         chat.ID,
+		ThreadID:       core.GetThreadID(m.db, c),
+		MessageID:      reported.ID,
+		ReporterID:     reporter.ID,
+		ReportedUserID: target.ID,
+		Reason:         strings.TrimSpace(c.Message().Payload),
+		MessageText:    truncateRunes(text, reportPreviewLen),
+	}
+
+	// Ограничение частоты — по истории жалоб в PostgreSQL, переживает рестарт.
+	// Проверка и вставка атомарны: параллельные /report не обходят лимит и не дублируют жалобу.
+	now := time.Now()
+	var denied string
+	report.ID, denied, err = m.reportRepo.Create(report, now.Add(-time.Hour), func(count int, last time.Time) string {
+		return reportRateLimit(count, last, now)
+	})
+	if err != nil {
+		m.logger.Error("failed to create report", zap.Error(err))
+		return c.Send("❌ Ошибка при отправке жалобы")
+	}
+	if denied != "" {
+		return c.Send(denied)
+	}
+	if report.ID == 0 {
+		return c.Send("ℹ️ На это сообщение уже пожаловались — администраторы оповещены")
+	}
+
+	_ = m.eventRepo.Log(chat.ID, target.ID, "moderation", "report",
+		fmt.Sprintf("Report #%d by %d on message %d: %s", report.ID, reporter.ID, reported.ID, report.Reason))
+
+	m.logger.Info("report created",
+		zap.Int64("chat_id", chat.ID),
+		zap.Int64("report_id", report.ID),
+		zap.Int64("reporter_id", reporter.ID),
+		zap.Int64("reported_user_id", target.ID),
+		zap.String("notify", settings.Notify))
+
+	if m.notifyAdmins(chat, report, settings, reporter, target) == repositories.ReportNotifyMention {
+		// Оповещение с упоминанием админов уже в чате — отдельное подтверждение не нужно
+		return nil
+	}
+	return c.Send(fmt.Sprintf("✅ Жалоба #%d отправлена администраторам", report.ID))
+}
+
+// reportRateLimit решает, можно ли участнику отправить жалобу: count жалоб за последний час,
+// last — время последней. Возвращает причину отказа или "" — жалобу можно отправить.
+func reportRateLimit(count int, last, now time.Time) string {
+	if left := reportCooldown - now.Sub(last); count > 0 && left > 0 {
+		return fmt.Sprintf("⏳ Следующую жалобу можно отправить через %d сек.", int(left.Seconds())+1)
+	}
+	if count >= reportHourlyLimit {
+		return fmt.Sprintf("⏳ Не больше %d жалоб в час. Администраторы уже оповещены о ваших жалобах", reportHourlyLimit)
+	}
+	return ""
+}
+
+// notifyAdmins отправляет оповещение о жалобе с кнопками разбора.
+// Если личные сообщения или чат модерации недоступны — упоминает админов в чате.
+// Возвращает способ, которым оповещение фактически отправлено.
+func (m *ModerationModule) notifyAdmins(chat *tele.Chat, report *repositories.Report, settings *repositories.ReportSettings, reporter, target *tele.User) string {
+	text := reportText(chat, report, reporter, target)
+	markup := reportMarkup(report.ID)
+
+	switch settings.Notify {
+	case repositories.ReportNotifyLog:
+		if settings.LogChatID != 0 && m.sendNotification(report.ID, &tele.Chat{ID: settings.LogChatID}, text, &tele.SendOptions{ReplyMarkup: markup, ParseMode: tele.ModeHTML}) {
+			return repositories.ReportNotifyLog
+		}
+		m.logger.Warn("report log chat unavailable, mentioning admins", zap.Int64("chat_id", chat.ID), zap.Int64("log_chat_id", settings.LogChatID))
+
+	case repositories.ReportNotifyDM:
+		delivered := 0
+		for _, adminID := range m.adminsWhoStartedBot(chat) {
+			if m.sendNotification(report.ID, &tele.Chat{ID: adminID}, text, &tele.SendOptions{ReplyMarkup: markup, ParseMode: tele.ModeHTML}) {
+				delivered++
+			}
+		}
+		if delivered > 0 {
+			return repositories.ReportNotifyDM
+		}
+		m.logger.Warn("no admins reachable in private, mentioning admins", zap.Int64("chat_id", chat.ID))
+	}
+
+	// Упоминание в чате — ответом на сообщение-нарушение
+	if mentions := m.adminMentions(chat); mentions != "" {
+		text += "\n\n👮 " + mentions
+	}
+	opts := &tele.SendOptions{
+		ReplyTo:     &tele.Message{ID: report.MessageID, Chat: chat},
+		ReplyMarkup: markup,
+		ParseMode:   tele.ModeHTML,
+	}
+	if report.ThreadID != 0 {
+		opts.ThreadID = report.ThreadID
+	}
+	m.sendNotification(report.ID, chat, text, opts)
+	return repositories.ReportNotifyMention
+}
+
+// sendNotification отправляет оповещение и запоминает его, чтобы убрать кнопки после разбора.
+func (m *ModerationModule) sendNotification(reportID int64, to *tele.Chat, text string, opts *tele.SendOptions) bool {
+	sent, err := m.bot.Send(to, text, opts)
+	if err != nil {
+		m.logger.Debug("failed to send report notification", zap.Int64("to", to.ID), zap.Error(err))
+		return false
+	}
+	if err := m.reportRepo.AddNotification(reportID, repositories.ReportNotification{ChatID: to.ID, MessageID: sent.ID}); err != nil {
+		m.logger.Error("failed to save report notification", zap.Error(err))
+	}
+	return true
+}
+
+// chatAdmins возвращает админов чата — людей, не ботов.
+func (m *ModerationModule) chatAdmins(chat *tele.Chat) []tele.ChatMember {
+	members, err := m.bot.AdminsOf(chat)
+	if err != nil {
+		m.logger.Error("failed to get chat admins", zap.Int64("chat_id", chat.ID), zap.Error(err))
+		return nil
+	}
+	admins := members[:0]
+	for _, member := range members {
+		if member.User != nil && !member.User.IsBot {
+			admins = append(admins, member)
+		}
+	}
+	return admins
+}
+
+// adminsWhoStartedBot возвращает ID админов чата, которым бот может написать в личку.
+func (m *ModerationModule) adminsWhoStartedBot(chat *tele.Chat) []int64 {
+	var ids []int64
+	for _, admin := range m.chatAdmins(chat) {
+		ids = append(ids, admin.User.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	started, err := m.reportRepo.StartedBot(ids)
+	if err != nil {
+		m.logger.Error("failed to get admins who started bot", zap.Error(err))
+		return nil
+	}
+	return started
+}
+
+// adminMentions — упоминания админов чата (HTML). Анонимные админы не упоминаются.
+func (m *ModerationModule) adminMentions(chat *tele.Chat) string {
+	var mentions []string
+	for _, admin := range m.chatAdmins(chat) {
+		if admin.Anonymous {
+			continue
+		}
+		mentions = append(mentions, userLink(admin.User.ID, core.DisplayName(admin.User)))
+	}
+	return strings.Join(mentions, ", ")
+}
+
+// reportText — текст оповещения о жалобе (HTML).
+func reportText(chat *tele.Chat, report *repositories.Report, reporter, target *tele.User) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🚨 <b>Жалоба #%d</b>", report.ID))
+	if chat.Title != "" {
+		sb.WriteString(" — " + html.EscapeString(chat.Title))
+	}
+	sb.WriteString("\n\n👤 Нарушитель: " + userLink(target.ID, core.DisplayName(target)))
+	sb.WriteString(fmt.Sprintf(" (<code>%d</code>)", target.ID))
+	sb.WriteString("\n🙋 Пожаловался: " + userLink(reporter.ID, core.DisplayName(reporter)))
+	if report.Reason != "" {
+		sb.WriteString("\n📝 Причина: " + html.EscapeString(report.Reason))
+	}
+	if report.MessageText != "" {
+		sb.WriteString("\n💬 <i>" + html.EscapeString(report.MessageText) + "</i>")
+	}
+	if link := messageLink(chat, report.MessageID); link != "" {
+		sb.WriteString(fmt.Sprintf("\n🔗 <a href=\"%s\">Перейти к сообщению</a>", link))
+	}
+	return sb.String()
+}
+
+// reportMarkup — кнопки разбора жалобы.
+func reportMarkup(reportID int64) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	id := strconv.FormatInt(reportID, 10)
+	markup.Inline(
+		markup.Row(
+			markup.Data("🗑 Удалить", reportCallbackUnique, id, "delete"),
+			markup.Data("⚠️ Предупредить", reportCallbackUnique, id, "warn"),
+		),
+		markup.Row(
+			markup.Data("⛔ Забанить", reportCallbackUnique, id, "ban"),
+			markup.Data("👌 Отклонить", reportCallbackUnique, id, "dismiss"),
+		),
+	)
+	return markup
+}
+
+// handleReportCallback обрабатывает кнопки разбора жалобы.
+// Нажать может только админ чата, в котором оставлена жалоба: кнопки бывают
+// в самом чате, в личке админа и в чате модерации.
+func (m *ModerationModule) handleReportCallback(c tele.Context) error {
+	reportID, status, ok := parseReportCallback(c.Callback().Data)
+	if !ok {
+		return c.Respond()
+	}
+
+	report, err := m.reportRepo.Get(reportID)
+	if err != nil {
+		m.logger.Error("failed to get report", zap.Error(err))
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Ошибка, попробуйте ещё раз"})
+	}
+	if report == nil {
+		return c.Respond(&tele.CallbackResponse{Text: "Жалоба не найдена"})
+	}
+
+	chat := &tele.Chat{ID: report.ChatID}
+	admin := c.Sender()
+	if !m.isAdmin(chat, admin) {
+		return c.Respond(&tele.CallbackResponse{Text: "Разбирать жалобы могут только администраторы чата", ShowAlert: true})
+	}
+
+	// Resolve = false: жалобу уже разобрал другой админ
+	if resolved, err := m.reportRepo.Resolve(reportID, status, admin.ID); err != nil || !resolved {
+		if err != nil {
+			m.logger.Error("failed to resolve report", zap.Error(err))
+		}
+		return c.Respond(&tele.CallbackResponse{Text: "Жалоба уже разобрана"})
+	}
+
+	result, err := m.applyReportAction(chat, report, status, admin)
+	if err != nil {
+		m.logger.Error("failed to apply report action",
+			zap.Int64("chat_id", report.ChatID),
+			zap.Int64("report_id", reportID),
+			zap.String("status", status),
+			zap.Error(err))
+		if err := m.reportRepo.Reopen(reportID); err != nil {
+			m.logger.Error("failed to reopen report", zap.Error(err))
+		}
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Не удалось применить действие (нет прав администратора?)", ShowAlert: true})
+	}
+
+	_ = m.eventRepo.Log(report.ChatID, report.ReportedUserID, "moderation", "report_resolved",
+		fmt.Sprintf("Report #%d resolved by %d: %s", reportID, admin.ID, status))
+
+	m.closeNotifications(c, report, result+" — "+core.DisplayName(admin))
+	return c.Respond(&tele.CallbackResponse{Text: "✅ " + result})
+}
+
+// parseReportCallback разбирает данные кнопки разбора ("<id>|<действие>")
+// и возвращает ID жалобы и статус, в который её переводит действие.
+func parseReportCallback(data string) (reportID int64, status string, ok bool) {
+	parts := strings.SplitN(data, "|", 2)
+	if len(parts) != 2 {
+		return 0, "", false
+	}
+	reportID, err := strconv.ParseInt(parts[0], 10, 64)
+	status, ok = reportActions[parts[1]]
+	if err != nil || !ok {
+		return 0, "", false
+	}
+	return reportID, status, true
+}
+
+// applyReportAction выполняет решение по жалобе и возвращает итог для оповещений.
+// Предупреждение и бан тоже удаляют сообщение-нарушение.
+func (m *ModerationModule) applyReportAction(chat *tele.Chat, report *repositories.Report, status string, admin *tele.User) (string, error) {
+	if status == repositories.ReportDismissed {
+		return reportStatusNames[status], nil
+	}
+
+	// Сообщение могли удалить раньше (автор, другой админ, фильтр) — это не ошибка разбора
+	msg := tele.StoredMessage{MessageID: strconv.Itoa(report.MessageID), ChatID: report.ChatID}
+	if err := m.bot.Delete(msg); err != nil {
+		m.logger.Debug("failed to delete reported message", zap.Int64("report_id", report.ID), zap.Error(err))
+	}
+
+	// Имя нарушителя для сообщения в чат — из getChatMember (если он ещё в чате)
+	user := &tele.User{ID: report.ReportedUserID}
+	if member, err := m.bot.ChatMemberOf(chat, user); err == nil && member.User != nil {
+		user = member.User
+	}
+	reason := "жалоба участника"
+	if report.Reason != "" {
+		reason = "жалоба: " + report.Reason
+	}
+
+	var text string
+	switch status {
+	case repositories.ReportWarned:
+		m.ensureChat(chat.ID)
+		warnText, err := m.warn(chat, user, admin.ID, "report", reason, report.MessageID)
+		if err != nil {
+			return "", err
+		}
+		text = warnText
+	case repositories.ReportBanned:
+		if err := m.punish(chat, user, "ban", 0, admin.ID, reason); err != nil {
+			return "", err
+		}
+		text = fmt.Sprintf("⛔ %s забанен\nПричина: %s", core.DisplayName(user), reason)
+	}
+
+	if text != "" {
+		opts := &tele.SendOptions{}
+		if report.ThreadID != 0 {
+			opts.ThreadID = report.ThreadID
+		}
+		if _, err := m.bot.Send(chat, text, opts); err != nil {
+			m.logger.Error("failed to send report result", zap.Error(err))
+		}
+	}
+	return reportStatusNames[status], nil
+}
+
+// closeNotifications дописывает итог во все оповещения о жалобе и убирает кнопки.
+// Текст берётся из нажатого оповещения: у одной жалобы все оповещения одинаковые.
+func (m *ModerationModule) closeNotifications(c tele.Context, report *repositories.Report, result string) {
+	pressed := c.Message()
+	if pressed == nil {
+		return
+	}
+	text := pressed.Text + "\n\n✅ " + result
+	opts := &tele.SendOptions{Entities: pressed.Entities, DisableWebPagePreview: true}
+
+	notifications, err := m.reportRepo.GetNotifications(report.ID)
+	if err != nil {
+		m.logger.Error("failed to get report notifications", zap.Error(err))
+		notifications = []repositories.ReportNotification{{ChatID: pressed.Chat.ID, MessageID: pressed.ID}}
+	}
+	for _, n := range notifications {
+		msg := tele.StoredMessage{MessageID: strconv.Itoa(n.MessageID), ChatID: n.ChatID}
+		if _, err := m.bot.Edit(msg, text, opts); err != nil {
+			m.logger.Debug("failed to close report notification", zap.Int64("chat_id", n.ChatID), zap.Error(err))
+		}
+	}
+}
+
+// handleReports — /reports: последние жалобы чата.
+func (m *ModerationModule) handleReports(c tele.Context) error {
+	reports, err := m.reportRepo.GetRecent(c.Chat().ID, reportListLimit)
+	if err != nil {
+		m.logger.Error("failed to get reports", zap.Error(err))
+		return c.Send("❌ Ошибка при получении жалоб")
+	}
+	if len(reports) == 0 {
+		return c.Send("✅ Жалоб в этом чате не было")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🚨 <b>Последние жалобы</b> (%d)\n", len(reports)))
+	for _, r := range reports {
+		sb.WriteString(fmt.Sprintf("\n<b>#%d</b> %s — %s\n", r.ID, r.CreatedAt.Format("02.01.2006 15:04"), reportStatusNames[r.Status]))
+		sb.WriteString(fmt.Sprintf("   На %s от %s", userLink(r.ReportedUserID, strconv.FormatInt(r.ReportedUserID, 10)),
+			userLink(r.ReporterID, strconv.FormatInt(r.ReporterID, 10))))
+		if r.Reason != "" {
+			sb.WriteString(": " + html.EscapeString(r.Reason))
+		}
+		sb.WriteString("\n")
+		if r.MessageText != "" {
+			sb.WriteString("   💬 <i>" + html.EscapeString(truncateRunes(r.MessageText, 100)) + "</i>\n")
+		}
+		if r.ResolvedBy != 0 {
+			sb.WriteString(fmt.Sprintf("   Разобрал: %s\n", userLink(r.ResolvedBy, strconv.FormatInt(r.ResolvedBy, 10))))
+		}
+	}
+	return c.Send(sb.String(), &tele.SendOptions{ParseMode: tele.ModeHTML, DisableWebPagePreview: true})
+}
+
+// handleSetReport — /setreport on|off|mention|dm|log <chat_id>: куда оповещать админов о жалобах.
+func (m *ModerationModule) handleSetReport(c tele.Context) error {
+	chatID := c.Chat().ID
+	settings, err := m.reportRepo.GetSettings(chatID)
+	if err != nil {
+		m.logger.Error("failed to get report settings", zap.Error(err))
+		return c.Send("❌ Ошибка при получении настроек жалоб")
+	}
+
+	args := c.Args()
+	if len(args) == 0 {
+		return c.Send(reportSettingsText(settings) + "\n\n" +
+			"Использование:\n" +
+			"/setreport on|off — включить или выключить /report\n" +
+			"/setreport mention — упоминать админов в чате\n" +
+			"/setreport dm — писать в личку админам, запустившим бота (/start)\n" +
+			"/setreport log <chat_id> — отправлять в чат модерации\n" +
+			"Если личка или чат модерации недоступны, админы упоминаются в чате")
+	}
+
+	switch mode := strings.ToLower(args[0]); mode {
+	case "on", "off":
+		settings.Enabled = mode == "on"
+	case repositories.ReportNotifyMention, repositories.ReportNotifyDM:
+		settings.Enabled, settings.Notify, settings.LogChatID = true, mode, 0
+	case repositories.ReportNotifyLog:
+		if len(args) < 2 {
+			return c.Send("Использование: /setreport log <chat_id>\nДобавьте бота в чат модерации; ID чата — из /start в нём")
+		}
+		logChatID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || logChatID == chatID {
+			return c.Send("❌ Укажите ID другого чата, например -1001234567890")
+		}
+		// Настроить чат модерации может только его админ — иначе жалобы можно увести в чужой чат
+		if !m.isAdmin(&tele.Chat{ID: logChatID}, c.Sender()) {
+			return c.Send("❌ Вы должны быть администратором чата модерации, и бот должен быть в нём")
+		}
+		if _, err := m.bot.Send(&tele.Chat{ID: logChatID}, fmt.Sprintf("✅ Сюда будут приходить жалобы из «%s»", c.Chat().Title)); err != nil {
+			m.logger.Warn("failed to send to report log chat", zap.Int64("log_chat_id", logChatID), zap.Error(err))
+			return c.Send("❌ Бот не может писать в этот чат")
+		}
+		settings.Enabled, settings.Notify, settings.LogChatID = true, mode, logChatID
+	default:
+		return c.Send("❌ Неизвестный режим. Доступные: on, off, mention, dm, log <chat_id>")
+	}
+
+	m.ensureChat(chatID)
+	if err := m.reportRepo.SetSettings(chatID, settings, c.Sender().ID); err != nil {
+		m.logger.Error("failed to save report settings", zap.Error(err))
+		return c.Send("❌ Ошибка при сохранении настроек жалоб")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "moderation", "set_report",
+		fmt.Sprintf("Report settings: enabled=%t, notify=%s, log_chat=%d", settings.Enabled, settings.Notify, settings.LogChatID))
+
+	return c.Send("✅ " + reportSettingsText(settings))
+}
+
+// reportSettingsText — текущие настройки жалоб.
+func reportSettingsText(s *repositories.ReportSettings) string {
+	if !s.Enabled {
+		return "🚨 Жалобы (/report) выключены"
+	}
+	switch s.Notify {
+	case repositories.ReportNotifyDM:
+		return "🚨 Жалобы включены: в личку админам, запустившим бота"
+	case repositories.ReportNotifyLog:
+		return fmt.Sprintf("🚨 Жалобы включены: в чат модерации %d", s.LogChatID)
+	default:
+		return "🚨 Жалобы включены: упоминание админов в чате"
+	}
+}
+
+// userLink — ссылка на пользователя (HTML), упоминание уведомляет его.
+func userLink(userID int64, name string) string {
+	return fmt.Sprintf("<a href=\"tg://user?id=%d\">%s</a>", userID, html.EscapeString(name))
+}
+
+// messageLink возвращает ссылку на сообщение супергруппы ("" — у обычной группы ссылок нет).
+func messageLink(chat *tele.Chat, messageID int) string {
+	if chat.Username != "" {
+		return fmt.Sprintf("https://t.me/%s/%d", chat.Username, messageID)
+	}
+	// ID супергруппы: -100<внутренний ID>
+	if internal := -chat.ID - 1_000_000_000_000; internal > 0 {
+		return fmt.Sprintf("https://t.me/c/%d/%d", internal, messageID)
+	}
+	return ""
+}
+
+// truncateRunes обрезает строку до n символов.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,111 @@
+package moderation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// TestReportRateLimit проверяет паузу между жалобами и лимит жалоб в час
+func TestReportRateLimit(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		count   int
+		last    time.Time
+		allowed bool
+		want    string // подстрока отказа
+	}{
+		{"Первая жалоба", 0, time.Time{}, true, ""},
+		{"Сразу после жалобы", 1, now.Add(-10 * time.Second), false, "через 51 сек."},
+		{"Пауза почти прошла", 1, now.Add(-reportCooldown + 100*time.Millisecond), false, "через 1 сек."},
+		{"Пауза прошла", 1, now.Add(-reportCooldown), true, ""},
+		{"Последняя жалоба в часе", reportHourlyLimit - 1, now.Add(-10 * time.Minute), true, ""},
+		{"Лимит в час", reportHourlyLimit, now.Add(-10 * time.Minute), false, "Не больше 5 жалоб в час"},
+		{"Пауза важнее лимита", reportHourlyLimit, now.Add(-time.Second), false, "через 60 сек."},
+	}
+	for _, tc := range tests {
+		got := reportRateLimit(tc.count, tc.last, now)
+		if tc.allowed != (got == "") {
+			t.Errorf("%s: reportRateLimit(%d) = %q, allowed = %t", tc.name, tc.count, got, tc.allowed)
+			continue
+		}
+		if !strings.Contains(got, tc.want) {
+			t.Errorf("%s: reportRateLimit(%d) = %q, want substring %q", tc.name, tc.count, got, tc.want)
+		}
+	}
+}
+
+// TestParseReportCallback проверяет разбор кнопок жалобы: действие → статус
+func TestParseReportCallback(t *testing.T) {
+	tests := []struct {
+		data   string
+		id     int64
+		status string
+		ok     bool
+	}{
+		{"42|delete", 42, repositories.ReportDeleted, true},
+		{"42|warn", 42, repositories.ReportWarned, true},
+		{"42|ban", 42, repositories.ReportBanned, true},
+		{"42|dismiss", 42, repositories.ReportDismissed, true},
+		{"42|open", 0, "", false}, // вернуть жалобу в открытые кнопкой нельзя
+		{"42|kick", 0, "", false},
+		{"abc|ban", 0, "", false},
+		{"42", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, tc := range tests {
+		id, status, ok := parseReportCallback(tc.data)
+		if id != tc.id || status != tc.status || ok != tc.ok {
+			t.Errorf("parseReportCallback(%q) = (%d, %q, %t), want (%d, %q, %t)",
+				tc.data, id, status, ok, tc.id, tc.status, tc.ok)
+		}
+	}
+}
+
+// TestReportMarkupActions проверяет, что каждая кнопка разбора переводит жалобу
+// в разобранный статус с названием для /reports
+func TestReportMarkupActions(t *testing.T) {
+	markup := reportMarkup(7)
+	buttons := 0
+	for _, row := range markup.InlineKeyboard {
+		for _, btn := range row {
+			buttons++
+			id, status, ok := parseReportCallback(btn.Data)
+			if !ok || id != 7 {
+				t.Errorf("button %q: parseReportCallback(%q) = (%d, %t)", btn.Text, btn.Data, id, ok)
+				continue
+			}
+			if status == repositories.ReportOpen {
+				t.Errorf("button %q оставляет жалобу открытой", btn.Text)
+			}
+			if reportStatusNames[status] == "" {
+				t.Errorf("status %q без названия в reportStatusNames", status)
+			}
+		}
+	}
+	if buttons != len(reportActions) {
+		t.Errorf("reportMarkup: %d кнопок, want %d", buttons, len(reportActions))
+	}
+}
+
+// TestMessageLink проверяет ссылки на сообщение для публичных и приватных чатов
+func TestMessageLink(t *testing.T) {
+	tests := []struct {
+		chat *tele.Chat
+		want string
+	}{
+		{&tele.Chat{ID: -1001234567890, Username: "bmft_chat"}, "https://t.me/bmft_chat/15"},
+		{&tele.Chat{ID: -1001234567890}, "https://t.me/c/1234567890/15"},
+		{&tele.Chat{ID: -123456789}, ""}, // обычная группа
+	}
+	for _, tc := range tests {
+		if got := messageLink(tc.chat, 15); got != tc.want {
+			t.Errorf("messageLink(%d, %q) = %q, want %q", tc.chat.ID, tc.chat.Username, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package moderation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// sourceNames — источники предупреждений для /warns: ручной /warn, AutoWarn модулей и жалобы.
+var sourceNames = map[string]string{
+	"manual":       "админ",
+	"antiflood":    "флуд",
+	"limiter":      "лимит",
+	"profanity":    "мат",
+	"banned_words": "запрещённое слово",
+	"links":        "запрещённая ссылка",
+	"report":       "жалоба",
+}
+
+// handleHelp — /moderation: справка по модулю.
+func (m *ModerationModule) handleHelp(c tele.Context) error {
+	msg := "🛡 <b>Модуль Moderation</b> — Предупреждения, наказания и жалобы\n\n"
+	msg += "Предупреждения копятся, при достижении ступени лестницы пользователь получает мут, кик или бан.\n"
+	msg += "По умолчанию: 3 предупреждения → мут на 1 час, 5 → бан.\n\n"
+
+	msg += "<b>Доступные команды:</b>\n\n"
+	msg += "🔹 <code>/warns</code> — Ваши предупреждения (reply — предупреждения пользователя)\n"
+	msg += "🔹 <code>/warnpolicy</code> — Лестница наказаний и автопредупреждения чата\n\n"
+
+	msg += "🔹 <code>/warn [причина]</code> — Выдать предупреждение (reply, только админы)\n"
+	msg += "🔹 <code>/unwarn</code> — Снять последнее предупреждение (reply, только админы)\n"
+	msg += "🔹 <code>/resetwarns</code> — Снять все предупреждения (reply, только админы)\n\n"
+
+	msg += "🔹 <code>/setwarnpolicy &lt;N&gt; mute|ban|kick [длительность]</code> — Ступень лестницы (только админы)\n"
+	msg += "   📌 <code>/setwarnpolicy 3 mute 1h</code>, <code>/setwarnpolicy 5 ban</code>, <code>/setwarnpolicy 4 ban 7d</code>\n"
+	msg += "   📌 <code>/setwarnpolicy 4 off</code> — удалить ступень, <code>/setwarnpolicy reset</code> — по умолчанию\n"
+	msg += "   Длительность: <code>30m</code>, <code>1h</code>, <code>2d</code>, <code>1w</code> (без неё — навсегда)\n\n"
+
+	msg += "🔹 <code>/setautowarn &lt;источник&gt; on|off</code> — Автопредупреждения (только админы)\n"
+	msg += "   Источники: <code>limiter</code>, <code>profanity</code>, <code>banned_words</code>, <code>links</code>, <code>all</code>\n"
+	msg += "   📌 <code>/setautowarn profanity on</code>\n\n"
+
+	msg += "<b>Наказания (только админы, reply или user ID):</b>\n"
+	msg += "🔹 <code>/mute [длительность] [причина]</code>, <code>/tmute &lt;длительность&gt;</code> — Мут\n"
+	msg += "🔹 <code>/ban [длительность] [причина]</code>, <code>/tban &lt;длительность&gt;</code> — Бан\n"
+	msg += "🔹 <code>/kick [причина]</code> — Удалить из чата (может вернуться)\n"
+	msg += "🔹 <code>/unmute</code>, <code>/unban</code> — Снять мут или бан\n"
+	msg += "   📌 <code>/tmute 2h флуд</code> (reply), <code>/ban 123456789 спам</code>\n"
+	msg += "   Истёкшие наказания снимаются автоматически, даже после перезапуска бота.\n\n"
+
+	msg += "<b>Жалобы:</b>\n"
+	msg += "🔹 <code>/report [причина]</code> — Пожаловаться админам (reply на сообщение-нарушение)\n"
+	msg += "   Не чаще раза в минуту и 5 жалоб в час. Админы разбирают жалобу кнопками: удалить, предупредить, забанить, отклонить\n"
+	msg += "🔹 <code>/reports</code> — Последние жалобы и как они разобраны (только админы)\n"
+	msg += "🔹 <code>/setreport on|off|mention|dm|log &lt;chat_id&gt;</code> — Куда оповещать админов (только админы)\n\n"
+
+	msg += "⚠️ Боту нужны права администратора на ограничение и бан участников."
+
+	return c.Send(msg, &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// handleWarn — /warn [причина] (reply на сообщение нарушителя).
+func (m *ModerationModule) handleWarn(c tele.Context) error {
+	target := replyTarget(c)
+	if target == nil {
+		return c.Send("Использование: ответьте на сообщение пользователя и напишите /warn [причина]")
+	}
+	if target.ID == c.Bot().Me.ID || m.isAdmin(c.Chat(), target) {
+		return c.Send("❌ Нельзя выдать предупреждение администратору или боту")
+	}
+
+	m.ensureChat(c.Chat().ID)
+
+	text, err := m.warn(c.Chat(), target, c.Sender().ID, "manual", strings.TrimSpace(c.Message().Payload), c.Message().ReplyTo.ID)
+	if err != nil {
+		m.logger.Error("failed to issue warning", zap.Error(err))
+		return c.Send("❌ Ошибка при выдаче предупреждения")
+	}
+	if text == "" {
+		return c.Send("ℹ️ За это сообщение предупреждение уже выдано")
+	}
+	return c.Send(text)
+}
+
+// handleUnwarn — /unwarn (reply): снимает последнее активное предупреждение.
+func (m *ModerationModule) handleUnwarn(c tele.Context) error {
+	target := replyTarget(c)
+	if target == nil {
+		return c.Send("Использование: ответьте на сообщение пользователя и напишите /unwarn")
+	}
+
+	revoked, err := m.warnRepo.RevokeLast(c.Chat().ID, target.ID, c.Sender().ID)
+	if err != nil {
+		m.logger.Error("failed to revoke warning", zap.Error(err))
+		return c.Send("❌ Ошибка при снятии предупреждения")
+	}
+	if !revoked {
+		return c.Send(fmt.Sprintf("ℹ️ У %s нет активных предупреждений", core.DisplayName(target)))
+	}
+
+	count, err := m.warnRepo.CountActive(c.Chat().ID, target.ID)
+	if err != nil {
+		m.logger.Error("failed to count warnings", zap.Error(err))
+	}
+
+	_ = m.eventRepo.Log(c.Chat().ID, target.ID, "moderation", "unwarn",
+		fmt.Sprintf("Last warning revoked by %d", c.Sender().ID))
+
+	return c.Send(fmt.Sprintf("✅ С %s снято предупреждение. Осталось: %d", core.DisplayName(target), count))
+}
+
+// handleResetWarns — /resetwarns (reply): снимает все активные предупреждения.
+func (m *ModerationModule) handleResetWarns(c tele.Context) error {
+	target := replyTarget(c)
+	if target == nil {
+		return c.Send("Использование: ответьте на сообщение пользователя и напишите /resetwarns")
+	}
+
+	revoked, err := m.warnRepo.RevokeAll(c.Chat().ID, target.ID, c.Sender().ID)
+	if err != nil {
+		m.logger.Error("failed to reset warnings", zap.Error(err))
+		return c.Send("❌ Ошибка при сбросе предупреждений")
+	}
+
+	_ = m.eventRepo.Log(c.Chat().ID, target.ID, "moderation", "reset_warns",
+		fmt.Sprintf("%d warnings revoked by %d", revoked, c.Sender().ID))
+
+	return c.Send(fmt.Sprintf("✅ С %s снято предупреждений: %d", core.DisplayName(target), revoked))
+}
+
+// handleWarns — /warns: свои предупреждения или (reply) предупреждения пользователя.
+func (m *ModerationModule) handleWarns(c tele.Context) error {
+	target := replyTarget(c)
+	if target == nil {
+		target = c.Sender()
+	}
+
+	warnings, err := m.warnRepo.GetActive(c.Chat().ID, target.ID)
+	if err != nil {
+		m.logger.Error("failed to get warnings", zap.Error(err))
+		return c.Send("❌ Ошибка при получении предупреждений")
+	}
+	if len(warnings) == 0 {
+		return c.Send(fmt.Sprintf("✅ У %s нет активных предупреждений", core.DisplayName(target)))
+	}
+
+	steps, err := m.warnRepo.GetEscalation(c.Chat().ID)
+	if err != nil {
+		m.logger.Error("failed to get warn escalation", zap.Error(err))
+		return c.Send("❌ Ошибка при получении предупреждений")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("⚠️ Предупреждения %s: %d/%d\n\n",
+		core.DisplayName(target), len(warnings), steps[len(steps)-1].WarnCount))
+	for i, w := range warnings {
+		sb.WriteString(fmt.Sprintf("%d. %s — %s", i+1, w.CreatedAt.Format("02.01.2006 15:04"), sourceNames[w.Source]))
+		if w.Reason != "" {
+			sb.WriteString(": " + w.Reason)
+		}
+		sb.WriteString("\n")
+	}
+
+	if next := nextStep(steps, len(warnings)); next != nil {
+		sb.WriteString(fmt.Sprintf("\nСледующая ступень: %d → %s", next.WarnCount, stepDescription(next)))
+	}
+
+	return c.Send(sb.String())
+}
+
+// handleWarnPolicy — /warnpolicy: лестница и автопредупреждения чата.
+func (m *ModerationModule) handleWarnPolicy(c tele.Context) error {
+	chatID := c.Chat().ID
+	steps, err := m.warnRepo.GetEscalation(chatID)
+	if err != nil {
+		m.logger.Error("failed to get warn escalation", zap.Error(err))
+		return c.Send("❌ Ошибка при получении политики предупреждений")
+	}
+	settings, err := m.warnRepo.GetSettings(chatID)
+	if err != nil {
+		m.logger.Error("failed to get warn settings", zap.Error(err))
+		return c.Send("❌ Ошибка при получении политики предупреждений")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🪜 Лестница наказаний:\n")
+	for _, step := range steps {
+		sb.WriteString(fmt.Sprintf("• %d предупр. → %s\n", step.WarnCount, stepDescription(&step)))
+	}
+
+	sb.WriteString("\n🤖 Автопредупреждения:\n")
+	sb.WriteString(fmt.Sprintf("• limiter (превышение лимита): %s\n", onOff(settings.AutoLimiter)))
+	sb.WriteString(fmt.Sprintf("• profanity (мат): %s\n", onOff(settings.AutoProfanity)))
+	sb.WriteString(fmt.Sprintf("• banned_words (запрещённые слова): %s\n", onOff(settings.AutoBannedWords)))
+	sb.WriteString(fmt.Sprintf("• links (запрещённые ссылки): %s\n", onOff(settings.AutoLinks)))
+
+	return c.Send(sb.String())
+}
+
+// handleSetWarnPolicy — /setwarnpolicy <N> mute|ban|kick [длительность] | <N> off | reset.
+func (m *ModerationModule) handleSetWarnPolicy(c tele.Context) error {
+	chatID := c.Chat().ID
+	args := c.Args()
+	usage := "Использование:\n" +
+		"/setwarnpolicy <N> mute <длительность>\n" +
+		"/setwarnpolicy <N> ban [длительность]\n" +
+		"/setwarnpolicy <N> kick\n" +
+		"/setwarnpolicy <N> off\n" +
+		"/setwarnpolicy reset\n" +
+		"Пример: /setwarnpolicy 3 mute 1h"
+
+	if len(args) == 1 && strings.ToLower(args[0]) == "reset" {
+		if err := m.warnRepo.SetEscalation(chatID, nil); err != nil {
+			m.logger.Error("failed to reset warn escalation", zap.Error(err))
+			return c.Send("❌ Ошибка при сохранении политики")
+		}
+		_ = m.eventRepo.Log(chatID, c.Sender().ID, "moderation", "set_warn_policy", "reset to default")
+		return c.Send("✅ Лестница наказаний сброшена: 3 → мут 1 ч., 5 → бан")
+	}
+	if len(args) < 2 {
+		return c.Send(usage)
+	}
+
+	count, err := strconv.Atoi(args[0])
+	if err != nil || count < 1 || count > 100 {
+		return c.Send("❌ Число предупреждений должно быть от 1 до 100")
+	}
+
+	steps, err := m.warnRepo.GetEscalation(chatID)
+	if err != nil {
+		m.logger.Error("failed to get warn escalation", zap.Error(err))
+		return c.Send("❌ Ошибка при получении политики")
+	}
+
+	// Убираем ступень с тем же N — ниже она либо заменяется, либо удаляется (off)
+	var updated []repositories.EscalationStep
+	for _, step := range steps {
+		if step.WarnCount != count {
+			updated = append(updated, step)
+		}
+	}
+
+	action := strings.ToLower(args[1])
+	switch action {
+	case "off":
+		if len(updated) == len(steps) {
+			return c.Send(fmt.Sprintf("ℹ️ Ступени для %d предупреждений нет", count))
+		}
+		if len(updated) == 0 {
+			return c.Send("❌ Нельзя удалить последнюю ступень — лестница не может быть пустой")
+		}
+	case "mute", "ban", "kick":
+		step := repositories.EscalationStep{WarnCount: count, Action: action}
+		if len(args) >= 3 && action != "kick" {
+			d, err := core.ParseDuration(args[2])
+			if err != nil {
+				return c.Send("❌ " + err.Error())
+			}
+			step.Duration = d
+		} else if action == "mute" {
+			return c.Send("❌ Для mute укажите длительность, например: /setwarnpolicy 3 mute 1h")
+		}
+		updated = append(updated, step)
+	default:
+		return c.Send(usage)
+	}
+
+	m.ensureChat(chatID)
+
+	if err := m.warnRepo.SetEscalation(chatID, updated); err != nil {
+		m.logger.Error("failed to save warn escalation", zap.Error(err))
+		return c.Send("❌ Ошибка при сохранении политики")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "moderation", "set_warn_policy",
+		strings.Join(args, " "))
+
+	return m.handleWarnPolicy(c)
+}
+
+// handleSetAutoWarn — /setautowarn <limiter|profanity|banned_words|links|all> on|off.
+func (m *ModerationModule) handleSetAutoWarn(c tele.Context) error {
+	chatID := c.Chat().ID
+	args := c.Args()
+	if len(args) != 2 {
+		return c.Send("Использование: /setautowarn <limiter|profanity|banned_words|links|all> on|off\nПример: /setautowarn profanity on")
+	}
+
+	var enabled bool
+	switch strings.ToLower(args[1]) {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return c.Send("❌ Второй параметр: on или off")
+	}
+
+	settings, err := m.warnRepo.GetSettings(chatID)
+	if err != nil {
+		m.logger.Error("failed to get warn settings", zap.Error(err))
+		return c.Send("❌ Ошибка при получении настроек")
+	}
+
+	source := strings.ToLower(args[0])
+	switch source {
+	case "limiter":
+		settings.AutoLimiter = enabled
+	case "profanity":
+		settings.AutoProfanity = enabled
+	case "banned_words":
+		settings.AutoBannedWords = enabled
+	case "links":
+		settings.AutoLinks = enabled
+	case "all":
+		settings.AutoLimiter = enabled
+		settings.AutoProfanity = enabled
+		settings.AutoBannedWords = enabled
+		settings.AutoLinks = enabled
+	default:
+		return c.Send("❌ Источник: limiter, profanity, banned_words, links или all")
+	}
+
+	m.ensureChat(chatID)
+
+	if err := m.warnRepo.SetSettings(chatID, settings); err != nil {
+		m.logger.Error("failed to save warn settings", zap.Error(err))
+		return c.Send("❌ Ошибка при сохранении настроек")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "moderation", "set_auto_warn",
+		fmt.Sprintf("%s=%t", source, enabled))
+
+	return c.Send(fmt.Sprintf("✅ Автопредупреждения (%s): %s", source, onOff(enabled)))
+}
+
+// ensureChat создаёт запись чата: таблицы модерации имеют REFERENCES chats(chat_id).
+func (m *ModerationModule) ensureChat(chatID int64) {
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, chatID)
+}
+
+// replyTarget возвращает автора сообщения, на которое ответила команда.
+func replyTarget(c tele.Context) *tele.User {
+	reply := c.Message().ReplyTo
+	if reply == nil || reply.Sender == nil {
+		return nil
+	}
+	return reply.Sender
+}
+
+// nextStep возвращает ближайшую ступень выше count (nil — ступеней больше нет).
+func nextStep(steps []repositories.EscalationStep, count int) *repositories.EscalationStep {
+	for i := range steps {
+		if steps[i].WarnCount > count {
+			return &steps[i]
+		}
+	}
+	return nil
+}
+
+// stepDescription — «мут 1 ч.», «бан навсегда», «кик».
+func stepDescription(step *repositories.EscalationStep) string {
+	switch step.Action {
+	case "mute":
+		return "мут " + core.FormatDuration(step.Duration)
+	case "kick":
+		return "кик"
+	default:
+		return "бан " + core.FormatDuration(step.Duration)
+	}
+}
+
+func onOff(v bool) string {
+	if v {
+		return "✅ вкл"
+	}
+	return "❌ выкл"
+}
@@ -0,0 +1,174 @@
+package probation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+const (
+	// maxProbation — максимальная длительность испытательного срока.
+	maxProbation = 30 * 24 * time.Hour
+	// maxMinMessages — максимальное число сообщений испытательного срока.
+	maxMinMessages = 1000
+)
+
+// RegisterCommands регистрирует пользовательские команды.
+func (m *ProbationModule) RegisterCommands(bot *tele.Bot) {
+	bot.Handle("/probation", m.handleHelp)
+}
+
+// RegisterAdminCommands регистрирует админские команды.
+func (m *ProbationModule) RegisterAdminCommands(bot *tele.Bot) {
+	bot.Handle("/setprobation", m.handleSetProbation)
+	bot.Handle("/trust", m.handleTrust)
+}
+
+// handleHelp — /probation: справка и текущие условия испытательного срока.
+func (m *ProbationModule) handleHelp(c tele.Context) error {
+	msg := "🐣 <b>Модуль Probation</b> — Испытательный срок новичков\n\n"
+	msg += "Новые участники первое время могут писать только текст без ссылок: "
+	msg += "медиа, пересылки, опросы и сообщения со ссылками удаляются. "
+	msg += "Срок снимается автоматически, админы и VIP не ограничиваются.\n\n"
+	msg += "<b>Команды (только админы):</b>\n\n"
+	msg += "🔹 <code>/setprobation &lt;длительность&gt; [N]</code> — Включить испытательный срок\n"
+	msg += "   📌 <code>/setprobation 24h</code> — первые 24 часа\n"
+	msg += "   📌 <code>/setprobation 0 10</code> — первые 10 сообщений\n"
+	msg += "   📌 <code>/setprobation 1d 5</code> — сутки и не меньше 5 сообщений\n"
+	msg += "🔹 <code>/setprobation off</code> — Выключить\n"
+	msg += "🔹 <code>/trust</code> — Досрочно снять испытательный срок (reply или user ID)\n\n"
+
+	settings, err := m.probationRepo.GetSettings(c.Chat().ID)
+	if err != nil {
+		m.logger.Error("failed to get probation settings", zap.Error(err))
+		return c.Send("❌ Не удалось получить настройки испытательного срока")
+	}
+	msg += "📊 <b>Сейчас:</b> " + describe(settings)
+
+	return c.Send(msg, &tele.SendOptions{ParseMode: tele.ModeHTML})
+}
+
+// handleSetProbation — /setprobation <длительность|0> [N] | off
+func (m *ProbationModule) handleSetProbation(c tele.Context) error {
+	chatID := c.Chat().ID
+	args := c.Args()
+
+	if len(args) == 1 && strings.ToLower(args[0]) == "off" {
+		removed, err := m.probationRepo.DeleteSettings(chatID)
+		if err != nil {
+			m.logger.Error("failed to delete probation settings", zap.Error(err))
+			return c.Send("❌ Не удалось выключить испытательный срок")
+		}
+		if !removed {
+			return c.Send("ℹ️ Испытательный срок и так выключен")
+		}
+		_ = m.eventRepo.Log(chatID, c.Sender().ID, "probation", "set_probation", "Probation disabled")
+		return c.Send("✅ Испытательный срок выключен")
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return c.Send(setProbationUsage)
+	}
+
+	settings := &repositories.ProbationSettings{}
+	if args[0] != "0" {
+		d, err := core.ParseDuration(args[0])
+		if err != nil {
+			return c.Send("❌ " + err.Error())
+		}
+		if d > maxProbation {
+			return c.Send("❌ Испытательный срок — не дольше " + core.FormatDuration(maxProbation))
+		}
+		settings.Duration = d
+	}
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 || n > maxMinMessages {
+			return c.Send(fmt.Sprintf("❌ N — число от 0 до %d", maxMinMessages))
+		}
+		settings.MinMessages = n
+	}
+	if settings.Duration == 0 && settings.MinMessages == 0 {
+		return c.Send("❌ Укажите длительность, число сообщений или оба условия\n\n" + setProbationUsage)
+	}
+
+	// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, chatID)
+
+	if err := m.probationRepo.SetSettings(chatID, settings, c.Sender().ID); err != nil {
+		m.logger.Error("failed to set probation settings", zap.Error(err))
+		return c.Send("❌ Не удалось сохранить настройки испытательного срока")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "probation", "set_probation",
+		fmt.Sprintf("Set probation: duration=%s min_messages=%d", settings.Duration, settings.MinMessages))
+
+	return c.Send("✅ Испытательный срок: " + describe(settings) +
+		"\n\nДействует для участников, чьё вступление видел бот.")
+}
+
+// handleTrust — /trust: досрочно снять испытательный срок (reply или user ID).
+func (m *ProbationModule) handleTrust(c tele.Context) error {
+	chatID := c.Chat().ID
+
+	var target *tele.User
+	if reply := c.Message().ReplyTo; reply != nil && reply.Sender != nil {
+		target = reply.Sender
+	} else if args := c.Args(); len(args) == 1 {
+		userID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || userID <= 0 {
+			return c.Send("Использование: ответьте на сообщение /trust или /trust <user_id>")
+		}
+		target = &tele.User{ID: userID}
+		if member, err := m.bot.ChatMemberOf(c.Chat(), target); err == nil && member.User != nil {
+			target = member.User
+		}
+	} else {
+		return c.Send("Использование: ответьте на сообщение /trust или /trust <user_id>")
+	}
+
+	trusted, err := m.probationRepo.Trust(chatID, target.ID, c.Sender().ID)
+	if err != nil {
+		m.logger.Error("failed to trust member", zap.Error(err))
+		return c.Send("❌ Не удалось снять испытательный срок")
+	}
+	if !trusted {
+		return c.Send(fmt.Sprintf("ℹ️ %s не на испытательном сроке", core.DisplayName(target)))
+	}
+
+	_ = m.eventRepo.Log(chatID, target.ID, "probation", "trust",
+		fmt.Sprintf("Probation lifted early by %d", c.Sender().ID))
+	return c.Send(fmt.Sprintf("✅ %s больше не на испытательном сроке", core.DisplayName(target)))
+}
+
+// setProbationUsage — подсказка по формату /setprobation.
+const setProbationUsage = "Использование:\n" +
+	"/setprobation <длительность> [N] — срок и минимум сообщений\n" +
+	"/setprobation 0 <N> — только первые N сообщений\n" +
+	"/setprobation off"
+
+// describe — условия испытательного срока в тексте бота.
+func describe(s *repositories.ProbationSettings) string {
+	if s == nil {
+		return "выключен"
+	}
+	var parts []string
+	if s.Duration > 0 {
+		parts = append(parts, "первые "+core.FormatDuration(s.Duration))
+	}
+	if s.MinMessages > 0 {
+		parts = append(parts, fmt.Sprintf("первые %d сообщ.", s.MinMessages))
+	}
+	return strings.Join(parts, " и ") + " — только текст без ссылок"
+}
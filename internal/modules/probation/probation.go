@@ -0,0 +1,164 @@
+package probation
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// ProbationModule ограничивает новых участников на испытательный срок:
+// первые часы или первые N сообщений можно писать только текст без ссылок.
+// Медиа, пересылки, опросы и сообщения со ссылками удаляются.
+// Время вступления пишется в chat_members при каждом вступлении (JoinHandler),
+// срок снимается автоматически при первом сообщении после его окончания
+// или досрочно командой /trust. Админы и VIP не ограничиваются.
+type ProbationModule struct {
+	db            *sql.DB
+	bot           *tele.Bot
+	logger        *zap.Logger
+	probationRepo *repositories.ProbationRepository
+	vipRepo       *repositories.VIPRepository
+	eventRepo     *repositories.EventRepository
+}
+
+// New создаёт новый инстанс модуля испытательного срока.
+func New(db *sql.DB, probationRepo *repositories.ProbationRepository, vipRepo *repositories.VIPRepository, eventRepo *repositories.EventRepository, logger *zap.Logger, bot *tele.Bot) *ProbationModule {
+	logger.Info("probation module created")
+	return &ProbationModule{
+		db:            db,
+		bot:           bot,
+		logger:        logger,
+		probationRepo: probationRepo,
+		vipRepo:       vipRepo,
+		eventRepo:     eventRepo,
+	}
+}
+
+// Name возвращает имя модуля.
+func (m *ProbationModule) Name() string { return "probation" }
+
+// Priority — после antiflood, до limiter: сообщение новичка, удалённое здесь,
+// не расходует его лимиты.
+func (m *ProbationModule) Priority() int { return core.PriorityProbation }
+
+// Start — фоновых задач нет: срок снимается при первом сообщении после его окончания.
+func (m *ProbationModule) Start() error { return nil }
+
+// Shutdown — фоновых задач нет, очищать нечего.
+func (m *ProbationModule) Shutdown() error { return nil }
+
+// OnUserJoined записывает время вступления. Модуль регистрируется первым
+// среди JoinHandler, чтобы вступление записывалось и во время рейда.
+func (m *ProbationModule) OnUserJoined(ctx *core.JoinContext) error {
+	if ctx.User.IsBot {
+		return nil
+	}
+
+	// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, ctx.Chat.ID)
+
+	if err := m.probationRepo.RecordJoin(ctx.Chat.ID, ctx.User.ID, time.Now()); err != nil {
+		return fmt.Errorf("record join: %w", err)
+	}
+	return nil
+}
+
+// OnMessage удаляет сообщения новичка, кроме текста без ссылок.
+// Для всех остальных — один SQL-запрос по первичному ключу chat_members.
+func (m *ProbationModule) OnMessage(ctx *core.MessageContext) error {
+	if ctx.Message.Private() || ctx.MessageDeleted || ctx.Sender == nil || ctx.Sender.IsBot {
+		return nil
+	}
+	chatID := ctx.Chat.ID
+
+	p, err := m.probationRepo.GetProbation(chatID, ctx.Sender.ID)
+	if err != nil {
+		m.logger.Error("failed to get probation", zap.Error(err))
+		return nil // Не блокируем сообщение из-за ошибки
+	}
+	if p == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if !onProbation(p, now) {
+		if _, err := m.probationRepo.Trust(chatID, ctx.Sender.ID, 0); err != nil {
+			m.logger.Error("failed to lift probation", zap.Error(err))
+			return nil
+		}
+		_ = m.eventRepo.Log(chatID, ctx.Sender.ID, "probation", "probation_end", "Probation completed")
+		return nil
+	}
+
+	if core.DetectContentType(ctx.Message) == "text" && !core.HasLink(ctx.Message) {
+		// Правки и команды не приближают конец испытательного срока
+		if !ctx.IsEdit && !strings.HasPrefix(ctx.Message.Text, "/") {
+			if err := m.probationRepo.CountMessage(chatID, ctx.Sender.ID); err != nil {
+				m.logger.Error("failed to count probation message", zap.Error(err))
+			}
+		}
+		return nil
+	}
+
+	if m.isExempt(ctx) {
+		return nil
+	}
+
+	if err := ctx.DeleteMessage("probation"); err != nil {
+		m.logger.Error("failed to delete probation message", zap.Error(err))
+	}
+
+	_ = m.eventRepo.Log(chatID, ctx.Sender.ID, "probation", "probation_delete",
+		fmt.Sprintf("Deleted %s from member on probation", core.DetectContentType(ctx.Message)))
+
+	text := fmt.Sprintf("❌ %s, новым участникам пока можно писать только текст без ссылок.\nОграничение снимется %s",
+		core.DisplayName(ctx.Sender), describeRemaining(p, now))
+	if err := ctx.Send(text); err != nil {
+		m.logger.Error("failed to send probation notice", zap.Error(err))
+	}
+	return nil
+}
+
+// onProbation — на испытательном сроке ли пользователь в момент now.
+// p = nil — срок в чате выключен или пользователь доверенный (/trust, срок пройден).
+// Срок идёт, пока не выполнены все заданные условия: время с вступления и число сообщений.
+func onProbation(p *repositories.MemberProbation, now time.Time) bool {
+	return p != nil && !p.Completed(now)
+}
+
+// isExempt — админы и VIP испытательным сроком не ограничиваются.
+// Проверяется только при нарушении, чтобы не дёргать Bot API на каждое сообщение.
+func (m *ProbationModule) isExempt(ctx *core.MessageContext) bool {
+	if member, err := m.bot.ChatMemberOf(ctx.Chat, ctx.Sender); err == nil &&
+		(member.Role == tele.Administrator || member.Role == tele.Creator) {
+		return true
+	}
+	isVIP, err := m.vipRepo.IsVIP(ctx.Chat.ID, ctx.ThreadID, ctx.Sender.ID)
+	if err != nil {
+		m.logger.Error("failed to check VIP status", zap.Error(err))
+	}
+	return isVIP
+}
+
+// describeRemaining — сколько осталось до конца испытательного срока.
+func describeRemaining(p *repositories.MemberProbation, now time.Time) string {
+	var parts []string
+	if left := p.Settings.Duration - now.Sub(p.JoinedAt); left > 0 {
+		parts = append(parts, "через "+core.FormatDuration(left))
+	}
+	if left := p.Settings.MinMessages - p.MessageCount; left > 0 {
+		parts = append(parts, fmt.Sprintf("после ещё %d сообщ.", left))
+	}
+	return strings.Join(parts, " и ")
+}
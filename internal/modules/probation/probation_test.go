@@ -0,0 +1,61 @@
+package probation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// TestOnProbation проверяет окончание испытательного срока по времени, по числу сообщений
+// и по обоим условиям сразу
+func TestOnProbation(t *testing.T) {
+	joined := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	member := func(d time.Duration, minMessages, count int) *repositories.MemberProbation {
+		return &repositories.MemberProbation{
+			Settings:     repositories.ProbationSettings{Duration: d, MinMessages: minMessages},
+			JoinedAt:     joined,
+			MessageCount: count,
+		}
+	}
+
+	tests := []struct {
+		name  string
+		p     *repositories.MemberProbation
+		after time.Duration // с момента вступления
+		want  bool
+	}{
+		{"Только время: срок идёт", member(24*time.Hour, 0, 0), 23 * time.Hour, true},
+		{"Только время: срок прошёл", member(24*time.Hour, 0, 0), 24 * time.Hour, false},
+		{"Только время: сообщения не ускоряют", member(24*time.Hour, 0, 100), time.Hour, true},
+		{"Только сообщения: мало сообщений", member(0, 10, 9), 30 * 24 * time.Hour, true},
+		{"Только сообщения: достаточно", member(0, 10, 10), time.Minute, false},
+		{"Оба условия: время прошло, сообщений мало", member(time.Hour, 5, 4), 2 * time.Hour, true},
+		{"Оба условия: сообщений достаточно, время не прошло", member(time.Hour, 5, 5), 30 * time.Minute, true},
+		{"Оба условия выполнены", member(time.Hour, 5, 5), time.Hour, false},
+		{"Доверенный пользователь", nil, 0, false},
+	}
+	for _, tc := range tests {
+		if got := onProbation(tc.p, joined.Add(tc.after)); got != tc.want {
+			t.Errorf("%s: onProbation(+%s) = %t, want %t", tc.name, tc.after, got, tc.want)
+		}
+	}
+}
+
+// TestDescribeRemaining проверяет текст «ограничение снимется ...»
+func TestDescribeRemaining(t *testing.T) {
+	joined := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	p := &repositories.MemberProbation{
+		Settings:     repositories.ProbationSettings{Duration: 24 * time.Hour, MinMessages: 10},
+		JoinedAt:     joined,
+		MessageCount: 7,
+	}
+	if got := describeRemaining(p, joined.Add(25*time.Hour)); got != "после ещё 3 сообщ." {
+		t.Errorf("describeRemaining(время прошло) = %q", got)
+	}
+	p.MessageCount = 10
+	if got := describeRemaining(p, joined.Add(23*time.Hour)); !strings.HasPrefix(got, "через ") {
+		t.Errorf("describeRemaining(сообщений достаточно) = %q, want «через ...»", got)
+	}
+}
@@ -8,29 +8,40 @@ import (
 	"database/sql"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/metrics"
 	"github.com/flybasist/bmft/internal/postgresql/repositories"
+	"github.com/flybasist/bmft/internal/profanity"
 	"go.uber.org/zap"
 	"gopkg.in/telebot.v3"
 )
 
 // ProfanitySettings — настройки фильтра мата для конкретного чата/топика.
 type ProfanitySettings struct {
-	ChatID   int64
-	ThreadID int64
-	Action   string
-	WarnText string
+	ChatID      int64
+	ThreadID    int64
+	Action      string
+	WarnText    string
+	MinSeverity string            // слова ниже этого уровня не проверяются
+	Actions     map[string]string // действие для уровня; нет записи — Action
+	Shadow      bool              // теневой режим: срабатывание только записывается
 }
 
-// ProfanityWord — слово из глобального словаря мата.
-type ProfanityWord struct {
-	Pattern  string
-	IsRegex  bool
-	Severity string
+// ActionFor возвращает действие для слова уровня severity.
+func (s *ProfanitySettings) ActionFor(severity string) string {
+	if action, ok := s.Actions[severity]; ok {
+		return action
+	}
+	return s.Action
 }
 
+// profanityDictTTL — как часто проверяется, не изменился ли словарь в БД.
+const profanityDictTTL = 5 * time.Minute
+
 // checkProfanity проверяет сообщение на мат.
 // textToCheck — уже подготовленный текст (caption или text).
 // При ctx.MessageDeleted=true (сообщение удалено Limiter-ом): мат считается, metadata
@@ -50,66 +61,105 @@ func (m *ReactionsModule) checkProfanity(ctx *core.MessageContext, chatID int64,
 		return false
 	}
 
-	// Загружаем глобальный словарь мата
-	words, err := m.loadProfanityDictionary()
+	match, found, err := m.findProfanity(chatID, textToCheck, settings.MinSeverity)
 	if err != nil {
 		m.logger.Error("failed to load profanity dictionary", zap.Error(err))
 		return false
 	}
+	if !found {
+		return false
+	}
 
-	// Проверяем текст на совпадение со словарём
-	textLower := strings.ToLower(textToCheck)
-	for _, word := range words {
-		matched := false
-		if word.IsRegex {
-			re, err := regexp.Compile(word.Pattern)
-			if err != nil {
-				continue
-			}
-			matched = re.MatchString(textLower)
-		} else {
-			matched = strings.Contains(textLower, strings.ToLower(word.Pattern))
+	action := settings.ActionFor(match.Severity)
+
+	// Теневой режим: без metadata profanity, лимита banned_words и действия
+	if settings.Shadow {
+		m.recordShadow(ctx, repositories.ShadowRuleProfanity, 0, match.Pattern, action)
+		return false
+	}
+
+	m.logger.Info("profanity detected",
+		zap.Int64("chat_id", chatID),
+		zap.Int64("user_id", userID),
+		zap.String("pattern", match.Pattern),
+		zap.String("severity", match.Severity),
+	)
+	metrics.ReactionTriggersTotal.WithLabelValues("profanity").Inc()
+
+	// Правка сообщения, в котором мат уже найден, не увеличивает счётчик нарушений
+	alreadyCounted := false
+	if ctx.IsEdit {
+		alreadyCounted, err = m.messageRepo.IsMetadataDetected(chatID, ctx.Message.ID, "profanity")
+		if err != nil {
+			m.logger.Error("failed to check profanity metadata", zap.Error(err))
 		}
+	}
 
-		if matched {
-			m.logger.Info("profanity detected",
-				zap.Int64("chat_id", chatID),
-				zap.Int64("user_id", userID),
-				zap.String("pattern", word.Pattern),
-			)
+	// Проверяем лимит banned_words (автобан при превышении)
+	if m.checkProfanityLimit(ctx, chatID, threadID, userID, alreadyCounted) {
+		return true // Пользователь забанен, pipeline останавливается
+	}
 
-			// Проверяем лимит banned_words (автобан при превышении)
-			if m.checkProfanityLimit(ctx, chatID, threadID, userID) {
-				return true // Пользователь забанен, pipeline останавливается
-			}
+	// Обновляем metadata сообщения (для подсчёта нарушений)
+	profanityMeta := repositories.ProfanityMetadata{
+		Detected: true,
+		Action:   action,
+		Severity: match.Severity,
+	}
+	if err := m.messageRepo.UpdateMessageMetadata(
+		ctx.Chat.ID, ctx.Message.ID, "profanity", profanityMeta,
+	); err != nil {
+		m.logger.Error("failed to update profanity metadata", zap.Error(err))
+	}
 
-			// Обновляем metadata сообщения (для подсчёта нарушений)
-			profanityMeta := repositories.ProfanityMetadata{
-				Detected: true,
-				Action:   settings.Action,
-			}
-			if err := m.messageRepo.UpdateMessageMetadata(
-				ctx.Chat.ID, ctx.Message.ID, "profanity", profanityMeta,
-			); err != nil {
-				m.logger.Error("failed to update profanity metadata", zap.Error(err))
-			}
+	// Выполняем действие только если сообщение ещё не удалено.
+	// При ctx.MessageDeleted=true (Limiter удалил) мат посчитан,
+	// metadata обновлена, banned_words проверен — но delete/warn не нужны.
+	if !ctx.MessageDeleted {
+		m.performProfanityAction(ctx, settings, action)
+	}
+	m.warner.AutoWarn(ctx, "profanity", "ненормативная лексика")
+	return true
+}
 
-			// Выполняем действие только если сообщение ещё не удалено.
-			// При ctx.MessageDeleted=true (Limiter удалил) мат посчитан,
-			// metadata обновлена, banned_words проверен — но delete/warn не нужны.
-			if !ctx.MessageDeleted {
-				m.performProfanityAction(ctx, settings)
-			}
-			return true
-		}
+// findProfanity ищет в тексте самое серьёзное слово не ниже minSeverity:
+// глобальный словарь плюс слова чата (дополнения и исключения).
+// Ничего не меняет — используется и pipeline, и /testfilter.
+func (m *ReactionsModule) findProfanity(chatID int64, textToCheck, minSeverity string) (profanity.Entry, bool, error) {
+	// Глобальный словарь — в памяти, пересобирается при изменении в БД
+	matcher, err := m.profanityMatcher()
+	if err != nil {
+		return profanity.Entry{}, false, err
 	}
 
-	return false
+	// Слова чата: дополнения словаря и исключения, убирающие ложные срабатывания
+	chatWords, err := m.profanityRepo.GetChatWords(chatID)
+	if err != nil {
+		m.logger.Error("failed to load profanity chat words", zap.Error(err))
+	}
+	textToCheck, extra := applyChatWords(textToCheck, chatWords)
+
+	// Проверяем текст на совпадение со словарём.
+	// Нормализованный текст ловит обходы: «х у й», «xуй», «хуууй», «х*й».
+	// Срабатывает самое серьёзное слово не ниже порога чата.
+	text := profanity.Prepare(textToCheck)
+	match, found := matcher.Match(text, minSeverity)
+	minRank := profanity.SeverityRank(minSeverity)
+	for _, word := range extra {
+		rank := profanity.SeverityRank(word.Severity)
+		if rank < minRank || (found && rank <= profanity.SeverityRank(match.Severity)) {
+			continue
+		}
+		if text.Contains(word) {
+			match, found = word, true
+		}
+	}
+	return match, found, nil
 }
 
 // checkProfanityLimit проверяет лимит banned_words и банит пользователя при превышении.
 // Возвращает true если пользователь забанен.
-func (m *ReactionsModule) checkProfanityLimit(ctx *core.MessageContext, chatID int64, threadID int, userID int64) bool {
+func (m *ReactionsModule) checkProfanityLimit(ctx *core.MessageContext, chatID int64, threadID int, userID int64, alreadyCounted bool) bool {
 	limits, err := m.contentLimitsRepo.GetLimits(chatID, threadID, nil)
 	if err != nil || limits == nil || limits.LimitBannedWords <= 0 {
 		return false
@@ -124,13 +174,17 @@ func (m *ReactionsModule) checkProfanityLimit(ctx *core.MessageContext, chatID i
 		return false
 	}
 
-	// +1 потому что текущее сообщение ещё не обновлено
-	actualCount := count + 1
+	// +1 потому что текущее сообщение ещё не обновлено.
+	// Исключение — правка уже помеченного сообщения: оно есть в count.
+	actualCount := count
+	if !alreadyCounted {
+		actualCount++
+	}
 
 	// Предупреждение перед баном — как в rts_bot.
 	// Если до бана осталось warning_threshold нарушений — предупреждаем.
 	if actualCount < limits.LimitBannedWords {
-		if limits.WarningThreshold > 0 && actualCount+limits.WarningThreshold >= limits.LimitBannedWords {
+		if !limits.Shadow && limits.WarningThreshold > 0 && actualCount+limits.WarningThreshold >= limits.LimitBannedWords {
 			warnMsg := fmt.Sprintf("⚠️ %s, у вас %d из %d нарушений за мат. При достижении лимита — бан.",
 				core.DisplayName(ctx.Message.Sender), actualCount, limits.LimitBannedWords)
 			if err := ctx.Send(warnMsg); err != nil {
@@ -140,6 +194,12 @@ func (m *ReactionsModule) checkProfanityLimit(ctx *core.MessageContext, chatID i
 		return false
 	}
 
+	// Лимиты в теневом режиме — бан только записывается
+	if limits.Shadow {
+		m.recordShadow(ctx, repositories.ShadowRuleLimit, 0, "banned_words", "ban")
+		return false
+	}
+
 	m.logger.Warn("banned_words limit exceeded, banning user",
 		zap.Int64("chat_id", chatID),
 		zap.Int64("user_id", userID),
@@ -149,7 +209,7 @@ func (m *ReactionsModule) checkProfanityLimit(ctx *core.MessageContext, chatID i
 
 	// Удаляем сообщение (если ещё не удалено Limiter-ом)
 	if !ctx.MessageDeleted {
-		if err := ctx.DeleteMessage(); err != nil {
+		if err := ctx.DeleteMessage("profanity_limit"); err != nil {
 			m.logger.Error("failed to delete message before ban", zap.Error(err))
 		}
 	}
@@ -160,6 +220,7 @@ func (m *ReactionsModule) checkProfanityLimit(ctx *core.MessageContext, chatID i
 	}); err != nil {
 		m.logger.Error("failed to ban user", zap.Error(err))
 	} else {
+		metrics.BansTotal.WithLabelValues("profanity_limit").Inc()
 		banMsg := fmt.Sprintf("⛔ Пользователь %s забанен за превышение лимита ненормативной лексики (%d/%d)",
 			core.DisplayName(ctx.Message.Sender), actualCount, limits.LimitBannedWords)
 		ctx.Send(banMsg)
@@ -169,10 +230,11 @@ func (m *ReactionsModule) checkProfanityLimit(ctx *core.MessageContext, chatID i
 }
 
 // performProfanityAction выполняет действие при обнаружении мата.
-func (m *ReactionsModule) performProfanityAction(ctx *core.MessageContext, settings *ProfanitySettings) {
-	switch settings.Action {
+// action — действие для уровня найденного слова (ProfanitySettings.ActionFor).
+func (m *ReactionsModule) performProfanityAction(ctx *core.MessageContext, settings *ProfanitySettings, action string) {
+	switch action {
 	case "delete":
-		if err := ctx.DeleteMessage(); err != nil {
+		if err := ctx.DeleteMessage("profanity"); err != nil {
 			m.logger.Error("failed to delete message", zap.Error(err))
 		}
 	case "warn":
@@ -186,7 +248,7 @@ func (m *ReactionsModule) performProfanityAction(ctx *core.MessageContext, setti
 		if warnText == "" {
 			warnText = "⚠️ Сообщение удалено: использование ненормативной лексики запрещено."
 		}
-		if err := ctx.DeleteMessage(); err != nil {
+		if err := ctx.DeleteMessage("profanity"); err != nil {
 			m.logger.Error("failed to delete message", zap.Error(err))
 		}
 		ctx.Send(warnText)
@@ -197,13 +259,13 @@ func (m *ReactionsModule) performProfanityAction(ctx *core.MessageContext, setti
 func (m *ReactionsModule) performFilterAction(ctx *core.MessageContext, reaction KeywordReaction) {
 	switch reaction.Action {
 	case "delete":
-		if err := ctx.DeleteMessage(); err != nil {
+		if err := ctx.DeleteMessage("banned_words"); err != nil {
 			m.logger.Error("failed to delete message", zap.Error(err))
 		}
 	case "warn":
 		_ = ctx.SendReply(fmt.Sprintf("⚠️ %s, пожалуйста, следите за своими словами", core.DisplayName(ctx.Message.Sender)))
 	case "delete_warn":
-		if err := ctx.DeleteMessage(); err != nil {
+		if err := ctx.DeleteMessage("banned_words"); err != nil {
 			m.logger.Error("failed to delete message", zap.Error(err))
 		}
 		// Отправляем в чат без ReplyTo — сообщение уже удалено,
@@ -242,8 +304,10 @@ func (m *ReactionsModule) loadProfanitySettings(chatID int64, threadID int) (*Pr
 // queryProfanitySettings загружает настройки для конкретного chat_id + thread_id.
 func (m *ReactionsModule) queryProfanitySettings(chatID int64, threadID int) (*ProfanitySettings, error) {
 	var settings ProfanitySettings
+	var actionMild, actionModerate, actionSevere sql.NullString
 	err := m.db.QueryRow(`
-		SELECT chat_id, thread_id, action, COALESCE(warn_text, '')
+		SELECT chat_id, thread_id, action, COALESCE(warn_text, ''),
+		       min_severity, action_mild, action_moderate, action_severe, shadow
 		FROM profanity_settings
 		WHERE chat_id = $1 AND thread_id = $2
 	`, chatID, threadID).Scan(
@@ -251,6 +315,11 @@ func (m *ReactionsModule) queryProfanitySettings(chatID int64, threadID int) (*P
 		&settings.ThreadID,
 		&settings.Action,
 		&settings.WarnText,
+		&settings.MinSeverity,
+		&actionMild,
+		&actionModerate,
+		&actionSevere,
+		&settings.Shadow,
 	)
 
 	if err == sql.ErrNoRows {
@@ -261,31 +330,91 @@ func (m *ReactionsModule) queryProfanitySettings(chatID int64, threadID int) (*P
 		return nil, err
 	}
 
+	settings.Actions = make(map[string]string)
+	for severity, action := range map[string]sql.NullString{
+		profanity.SeverityMild:     actionMild,
+		profanity.SeverityModerate: actionModerate,
+		profanity.SeveritySevere:   actionSevere,
+	} {
+		if action.Valid {
+			settings.Actions[severity] = action.String
+		}
+	}
+
 	return &settings, nil
 }
 
+// profanityMatcher возвращает автомат глобального словаря мата.
+// Словарь загружается из БД один раз; раз в profanityDictTTL сверяется
+// контрольная сумма словаря, и автомат пересобирается, только если словарь изменился.
+// При ошибке БД продолжает работать прежний автомат.
+func (m *ReactionsModule) profanityMatcher() (*profanity.Matcher, error) {
+	m.dictMu.RLock()
+	matcher, checkedAt := m.dict, m.dictCheckedAt
+	m.dictMu.RUnlock()
+	if matcher != nil && time.Since(checkedAt) < profanityDictTTL {
+		return matcher, nil
+	}
+
+	m.dictMu.Lock()
+	defer m.dictMu.Unlock()
+	if m.dict != nil && time.Since(m.dictCheckedAt) < profanityDictTTL {
+		return m.dict, nil // Уже проверил другой обработчик
+	}
+
+	var version string
+	err := m.db.QueryRow(`
+		SELECT COALESCE(md5(string_agg(pattern || '|' || is_regex || '|' || COALESCE(severity, ''), ',' ORDER BY id)), '')
+		FROM profanity_dictionary
+	`).Scan(&version)
+	if err != nil {
+		if m.dict != nil {
+			m.logger.Warn("failed to check profanity dictionary version, using cached", zap.Error(err))
+			m.dictCheckedAt = time.Now()
+			return m.dict, nil
+		}
+		return nil, err
+	}
+	m.dictCheckedAt = time.Now()
+	if m.dict != nil && version == m.dictVersion {
+		return m.dict, nil
+	}
+
+	words, err := m.loadProfanityDictionary()
+	if err != nil {
+		if m.dict != nil {
+			m.logger.Warn("failed to reload profanity dictionary, using cached", zap.Error(err))
+			return m.dict, nil
+		}
+		return nil, err
+	}
+	m.dict = profanity.NewMatcher(words)
+	m.dictVersion = version
+	m.logger.Info("profanity matcher built", zap.Int("words", m.dict.Len()))
+	return m.dict, nil
+}
+
 // loadProfanityDictionary загружает глобальный словарь мата из БД.
-func (m *ReactionsModule) loadProfanityDictionary() ([]ProfanityWord, error) {
+func (m *ReactionsModule) loadProfanityDictionary() ([]profanity.Entry, error) {
 	rows, err := m.db.Query(`
 		SELECT pattern, is_regex, severity
 		FROM profanity_dictionary
-		ORDER BY severity DESC
 	`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var words []ProfanityWord
+	var words []profanity.Entry
 	for rows.Next() {
-		var word ProfanityWord
+		var word profanity.Entry
 		if err := rows.Scan(&word.Pattern, &word.IsRegex, &word.Severity); err != nil {
 			continue
 		}
 		words = append(words, word)
 	}
 
-	return words, nil
+	return words, rows.Err()
 }
 
 // ============================================================================
@@ -300,8 +429,13 @@ func (m *ReactionsModule) handleAddBan(c telebot.Context) error {
 	m.logger.Info("handleAddBan called", zap.Int64("chat_id", chatID), zap.Int("thread_id", threadID), zap.Int64("user_id", c.Sender().ID))
 
 	args := c.Args()
+	// Необязательный последний аргумент shadow — фильтр в теневом режиме
+	shadow := len(args) > 2 && strings.EqualFold(args[len(args)-1], "shadow")
+	if shadow {
+		args = args[:len(args)-1]
+	}
 	if len(args) < 2 {
-		return c.Send("Использование: /addban <pattern> <action>\nAction: delete, warn, delete_warn\nПример: /addban мат delete_warn")
+		return c.Send("Использование: /addban <pattern> <action> [shadow]\nAction: delete, warn, delete_warn\nshadow — только записывать срабатывания (/shadowreport)\nПример: /addban мат delete_warn")
 	}
 
 	action := args[len(args)-1]                      // Последний аргумент — действие
@@ -349,18 +483,19 @@ func (m *ReactionsModule) handleAddBan(c telebot.Context) error {
 
 	// Вставляем в keyword_reactions с полем action (фильтр, не реакция)
 	_, err = m.db.Exec(`
-		INSERT INTO keyword_reactions (chat_id, thread_id, pattern, is_regex, response_type, response_content, description, action, is_active)
-		VALUES ($1, $2, $3, $4, 'none', '', '', $5, true)
-	`, chatID, threadID, pattern, isRegex, action)
+		INSERT INTO keyword_reactions (chat_id, thread_id, pattern, is_regex, response_type, response_content, description, action, is_active, shadow)
+		VALUES ($1, $2, $3, $4, 'none', '', '', $5, true, $6)
+	`, chatID, threadID, pattern, isRegex, action, shadow)
 
 	if err != nil {
 		m.logger.Error("failed to add banned word", zap.Error(err))
 		return c.Send("❌ Не удалось добавить запрещённое слово")
 	}
+	m.invalidateRules(chatID)
 
 	// Логируем событие
 	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", "add_filter",
-		fmt.Sprintf("Added filter: pattern='%s', action=%s (chat=%d, thread=%d)", pattern, action, chatID, threadID))
+		fmt.Sprintf("Added filter: pattern='%s', action=%s, shadow=%t (chat=%d, thread=%d)", pattern, action, shadow, chatID, threadID))
 
 	var scopeMsg string
 	if threadID != 0 {
@@ -368,6 +503,9 @@ func (m *ReactionsModule) handleAddBan(c telebot.Context) error {
 	} else {
 		scopeMsg = fmt.Sprintf("✅ Запрещённое слово добавлено <b>для всего чата</b>\n\n💡 Для настройки топика используйте команду внутри топика\n\nПаттерн: <code>%s</code>\nДействие: %s", pattern, action)
 	}
+	if shadow {
+		scopeMsg += "\n\n👻 Теневой режим: действие не выполняется, срабатывания — в /shadowreport\nВключить: /banshadow <id> off"
+	}
 
 	return c.Send(scopeMsg, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
 }
@@ -381,7 +519,7 @@ func (m *ReactionsModule) handleListBans(c telebot.Context) error {
 
 	// Читаем только фильтры (action IS NOT NULL) из keyword_reactions
 	rows, err := m.db.Query(`
-		SELECT id, chat_id, thread_id, pattern, action, is_regex, is_active
+		SELECT id, chat_id, thread_id, pattern, action, is_regex, is_active, shadow
 		FROM keyword_reactions
 		WHERE chat_id = $1 AND (thread_id = $2 OR thread_id = 0)
 		  AND action IS NOT NULL
@@ -405,12 +543,13 @@ func (m *ReactionsModule) handleListBans(c telebot.Context) error {
 		Action   string
 		IsRegex  bool
 		IsActive bool
+		Shadow   bool
 	}
 
 	var bans []BanEntry
 	for rows.Next() {
 		var b BanEntry
-		if err := rows.Scan(&b.ID, &b.ChatID, &b.ThreadID, &b.Pattern, &b.Action, &b.IsRegex, &b.IsActive); err != nil {
+		if err := rows.Scan(&b.ID, &b.ChatID, &b.ThreadID, &b.Pattern, &b.Action, &b.IsRegex, &b.IsActive, &b.Shadow); err != nil {
 			m.logger.Error("failed to scan ban entry", zap.Error(err))
 			continue
 		}
@@ -441,7 +580,11 @@ func (m *ReactionsModule) handleListBans(c telebot.Context) error {
 		if b.ThreadID != 0 {
 			scope = "топик"
 		}
-		text += fmt.Sprintf("%d. %s ID: %d [%s]\n   Паттерн: <code>%s</code>\n   Действие: %s\n\n", i+1, status, b.ID, scope, b.Pattern, b.Action)
+		action := b.Action
+		if b.Shadow {
+			action += " 👻 теневой режим"
+		}
+		text += fmt.Sprintf("%d. %s ID: %d [%s]\n   Паттерн: <code>%s</code>\n   Действие: %s\n\n", i+1, status, b.ID, scope, b.Pattern, action)
 	}
 
 	return c.Send(text, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
@@ -475,6 +618,7 @@ func (m *ReactionsModule) handleRemoveBan(c telebot.Context) error {
 	if rowsAffected == 0 {
 		return c.Send("ℹ️ Запись не найдена")
 	}
+	m.invalidateRules(chatID)
 
 	// Логируем событие
 	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", "remove_filter",
@@ -483,22 +627,68 @@ func (m *ReactionsModule) handleRemoveBan(c telebot.Context) error {
 	return c.Send(fmt.Sprintf("✅ Запрет #%s удалён", banID), &telebot.SendOptions{ParseMode: telebot.ModeHTML})
 }
 
+// handleBanShadow обрабатывает команду /banshadow — теневой режим запрещённого слова.
+func (m *ReactionsModule) handleBanShadow(c telebot.Context) error {
+	chatID := c.Chat().ID
+
+	m.logger.Info("handleBanShadow called", zap.Int64("chat_id", chatID), zap.Int64("user_id", c.Sender().ID))
+
+	args := c.Args()
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		return c.Send("Использование: /banshadow <id> on|off\non — только записывать срабатывания (/shadowreport)\noff — выполнять действие\nПример: /banshadow 3 off")
+	}
+	banID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return c.Send("❌ ID должен быть числом")
+	}
+	shadow := args[1] == "on"
+
+	result, err := m.db.Exec(`
+		UPDATE keyword_reactions SET shadow = $3
+		WHERE chat_id = $1 AND id = $2 AND action IS NOT NULL
+	`, chatID, banID, shadow)
+	if err != nil {
+		m.logger.Error("failed to set filter shadow", zap.Error(err))
+		return c.Send("❌ Не удалось изменить запрет")
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return c.Send("ℹ️ Запись не найдена")
+	}
+	m.invalidateRules(chatID)
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", "set_filter_shadow",
+		fmt.Sprintf("Set filter ID=%d shadow=%t (chat=%d)", banID, shadow, chatID))
+
+	if shadow {
+		return c.Send(fmt.Sprintf("👻 Запрет #%d в теневом режиме: срабатывания только записываются\nОтчёт: /shadowreport", banID))
+	}
+	return c.Send(fmt.Sprintf("✅ Запрет #%d включён: действие выполняется", banID))
+}
+
 // ============================================================================
 // Обработчики команд фильтра мата (бывший ProfanityFilter)
 // ============================================================================
 
 // handleSetProfanity обрабатывает команду /setprofanity — включение фильтра мата.
+// /setprofanity <действие> — включить фильтр с общим действием,
+// /setprofanity level <уровень> — минимальный уровень серьёзности,
+// /setprofanity <уровень> <действие|default> — своё действие для уровня,
+// /setprofanity shadow <on|off> — теневой режим.
 func (m *ReactionsModule) handleSetProfanity(c telebot.Context) error {
 	m.logger.Info("handleSetProfanity called", zap.Int64("chat_id", c.Chat().ID), zap.Int64("user_id", c.Sender().ID))
 
+	args := c.Args()
+	if len(args) == 2 {
+		return m.setProfanitySeverity(c, strings.ToLower(args[0]), strings.ToLower(args[1]))
+	}
+
 	action := c.Message().Payload
 	if action == "" {
 		action = "delete"
 	}
 
-	validActions := map[string]bool{"delete": true, "warn": true, "delete_warn": true}
-	if !validActions[action] {
-		return c.Reply("❌ Неверное действие. Доступные: delete, warn, delete_warn")
+	if !validProfanityActions[action] {
+		return c.Reply("❌ Неверное действие. Доступные: delete, warn, delete_warn\n\n" + setProfanityUsage)
 	}
 
 	chatID := c.Chat().ID
@@ -536,6 +726,68 @@ func (m *ReactionsModule) handleSetProfanity(c telebot.Context) error {
 	return c.Reply(fmt.Sprintf("✅ Фильтр мата включен для %s\nДействие: %s", scope, action))
 }
 
+// validProfanityActions — действия фильтра мата.
+var validProfanityActions = map[string]bool{"delete": true, "warn": true, "delete_warn": true}
+
+// setProfanityUsage — подсказка по формату /setprofanity.
+const setProfanityUsage = "Использование:\n" +
+	"/setprofanity <delete|warn|delete_warn> — включить фильтр\n" +
+	"/setprofanity level <mild|moderate|severe> — реагировать начиная с уровня\n" +
+	"/setprofanity <mild|moderate|severe> <действие|default> — действие для уровня\n" +
+	"/setprofanity shadow <on|off> — теневой режим: только записывать срабатывания"
+
+// setProfanitySeverity меняет порог, действие уровня или теневой режим у включённого фильтра.
+func (m *ReactionsModule) setProfanitySeverity(c telebot.Context, first, second string) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+
+	var (
+		query  string
+		value  any
+		result string
+	)
+	switch {
+	case first == "level" && profanity.ValidSeverity(second):
+		query = `UPDATE profanity_settings SET min_severity = $3, updated_at = NOW() WHERE chat_id = $1 AND thread_id = $2`
+		value = second
+		result = "Фильтр реагирует на слова уровня " + second + " и выше"
+	case profanity.ValidSeverity(first) && (validProfanityActions[second] || second == "default"):
+		// Имя колонки собирается из проверенного уровня: action_mild | action_moderate | action_severe
+		query = `UPDATE profanity_settings SET action_` + first + ` = $3, updated_at = NOW() WHERE chat_id = $1 AND thread_id = $2`
+		if second == "default" {
+			value = nil
+			result = "Для уровня " + first + " — общее действие фильтра"
+		} else {
+			value = second
+			result = "Для уровня " + first + " действие: " + second
+		}
+	case first == "shadow" && (second == "on" || second == "off"):
+		query = `UPDATE profanity_settings SET shadow = $3, updated_at = NOW() WHERE chat_id = $1 AND thread_id = $2`
+		value = second == "on"
+		if second == "on" {
+			result = "Фильтр мата в теневом режиме: срабатывания только записываются\nОтчёт: /shadowreport"
+		} else {
+			result = "Теневой режим фильтра мата выключен: действия выполняются"
+		}
+	default:
+		return c.Reply("❌ Неверные параметры\n\n" + setProfanityUsage)
+	}
+
+	res, err := m.db.Exec(query, chatID, threadID, value)
+	if err != nil {
+		m.logger.Error("failed to set profanity severity", zap.Error(err))
+		return c.Reply("❌ Ошибка при настройке фильтра")
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return c.Reply("ℹ️ Сначала включите фильтр мата здесь: /setprofanity <действие>")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", "set_profanity",
+		fmt.Sprintf("Set profanity severity: %s %s (chat=%d, thread=%d)", first, second, chatID, threadID))
+
+	return c.Reply("✅ " + result)
+}
+
 // handleRemoveProfanity обрабатывает команду /removeprofanity — выключение фильтра мата.
 func (m *ReactionsModule) handleRemoveProfanity(c telebot.Context) error {
 	m.logger.Info("handleRemoveProfanity called", zap.Int64("chat_id", c.Chat().ID), zap.Int64("user_id", c.Sender().ID))
@@ -594,10 +846,29 @@ func (m *ReactionsModule) handleProfanityStatus(c telebot.Context) error {
 	msg := "📊 <b>Статус фильтра мата</b>\n\n"
 	msg += fmt.Sprintf("Область: %s\n", scope)
 	msg += fmt.Sprintf("Действие: %s\n", settings.Action)
+	msg += fmt.Sprintf("Минимальный уровень: %s\n", settings.MinSeverity)
+	if settings.Shadow {
+		msg += "👻 Теневой режим: срабатывания только записываются (/shadowreport)\n"
+	}
+	for _, severity := range []string{profanity.SeverityMild, profanity.SeverityModerate, profanity.SeveritySevere} {
+		if action, ok := settings.Actions[severity]; ok {
+			msg += fmt.Sprintf("Действие для %s: %s\n", severity, action)
+		}
+	}
 
 	var wordCount int
 	m.db.QueryRow("SELECT COUNT(*) FROM profanity_dictionary").Scan(&wordCount)
 	msg += fmt.Sprintf("\nСлов в словаре: %d", wordCount)
 
+	if chatWords, err := m.profanityRepo.GetChatWords(chatID); err == nil {
+		allow := 0
+		for _, cw := range chatWords {
+			if cw.Kind == repositories.ProfanityAllow {
+				allow++
+			}
+		}
+		msg += fmt.Sprintf("\nСлова чата: исключений %d, дополнений %d (/profanitylist)", allow, len(chatWords)-allow)
+	}
+
 	return c.Send(msg, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
 }
@@ -0,0 +1,558 @@
+package reactions
+
+// Этот файл содержит фильтр ссылок — часть модуля Reactions.
+// Ссылки берутся из entities сообщения (url, text_link) и из текста,
+// отдельно распознаются приглашения Telegram и упоминания каналов.
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/metrics"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+)
+
+const (
+	// channelCacheTTL — сколько помнится, является ли @username каналом или группой.
+	channelCacheTTL = time.Hour
+	// maxChannelCache — при переполнении кэш каналов очищается целиком.
+	maxChannelCache = 10000
+)
+
+// telegramHosts — домены ссылок Telegram (приглашения и ссылки на каналы).
+var telegramHosts = map[string]bool{
+	"t.me":         true,
+	"telegram.me":  true,
+	"telegram.dog": true,
+}
+
+// usernameRe — username канала или группы (без @).
+var usernameRe = regexp.MustCompile(`^[a-z][a-z0-9_]{3,31}$`)
+
+// domainRe — домен в allowlist/denylist.
+var domainRe = regexp.MustCompile(`^[\p{L}0-9]([\p{L}0-9-]*[\p{L}0-9])?(\.[\p{L}0-9]([\p{L}0-9-]*[\p{L}0-9])?)+$`)
+
+// foundLink — ссылка из сообщения.
+type foundLink struct {
+	host    string // домен без www., в нижнем регистре
+	invite  bool   // приглашение в чат Telegram (t.me/+..., t.me/joinchat/...)
+	channel string // username из t.me/<username> (без @), иначе пусто
+}
+
+// channelEntry — кэш ответа getChat для @username.
+type channelEntry struct {
+	isChannel bool
+	checkedAt time.Time
+}
+
+// extractLinks собирает ссылки и упоминания (@username, без @) из сообщения.
+func extractLinks(msg *telebot.Message) ([]foundLink, []string) {
+	text := getTextForMatching(msg)
+	entities := msg.Entities
+	if msg.Caption != "" {
+		entities = msg.CaptionEntities
+	}
+
+	var raw, mentions []string
+	for _, e := range entities {
+		switch e.Type {
+		case telebot.EntityURL:
+			raw = append(raw, msg.EntityText(e))
+		case telebot.EntityTextLink:
+			raw = append(raw, e.URL)
+		case telebot.EntityMention:
+			mentions = append(mentions, strings.ToLower(strings.TrimPrefix(msg.EntityText(e), "@")))
+		}
+	}
+	raw = append(raw, core.PlainLinkRe.FindAllString(text, -1)...)
+
+	seen := make(map[foundLink]bool)
+	var links []foundLink
+	for _, r := range raw {
+		l, ok := parseLink(r)
+		if !ok || seen[l] {
+			continue
+		}
+		seen[l] = true
+		links = append(links, l)
+	}
+	return links, mentions
+}
+
+// parseLink разбирает ссылку: домен, приглашение, канал Telegram.
+func parseLink(raw string) (foundLink, bool) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), ".,;:!?)]}»")
+	if raw == "" {
+		return foundLink{}, false
+	}
+
+	lower := strings.ToLower(raw)
+	if strings.HasPrefix(lower, "tg://") {
+		// tg://join?invite=... — приглашение, tg://resolve?domain=... — канал
+		u, err := url.Parse(raw)
+		if err != nil {
+			return foundLink{}, false
+		}
+		l := foundLink{host: "t.me"}
+		switch strings.ToLower(u.Host) {
+		case "join":
+			l.invite = true
+		case "resolve":
+			l.channel = strings.ToLower(u.Query().Get("domain"))
+		}
+		return l, true
+	}
+	if !strings.Contains(lower, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return foundLink{}, false
+	}
+	l := foundLink{host: strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(u.Hostname()), "."), "www.")}
+
+	if telegramHosts[l.host] {
+		path := strings.TrimPrefix(u.EscapedPath(), "/")
+		first, _, _ := strings.Cut(path, "/")
+		switch {
+		case strings.HasPrefix(first, "+") || first == "joinchat" || first == "addlist":
+			l.invite = true
+		case usernameRe.MatchString(strings.ToLower(first)):
+			l.channel = strings.ToLower(first)
+		}
+	}
+	return l, true
+}
+
+// domainList возвращает список (allow | deny), в который попадает домен
+// или ближайший родительский домен: a.b.example.com → b.example.com → example.com.
+func domainList(lists map[string]string, host string) string {
+	for h := host; h != ""; {
+		if list, ok := lists[h]; ok {
+			return list
+		}
+		_, parent, found := strings.Cut(h, ".")
+		if !found {
+			break
+		}
+		h = parent
+	}
+	return ""
+}
+
+// normalizeDomain приводит аргумент /allowdomain, /denydomain к записи списка:
+// example.com (без схемы, пути и www.) или @channel.
+func normalizeDomain(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if name, ok := strings.CutPrefix(s, "@"); ok {
+		if !usernameRe.MatchString(name) {
+			return "", fmt.Errorf("неверный username %q", s)
+		}
+		return s, nil
+	}
+
+	l, ok := parseLink(s)
+	if !ok || !domainRe.MatchString(l.host) {
+		return "", fmt.Errorf("неверный домен %q (пример: example.com или @channel)", s)
+	}
+	return l.host, nil
+}
+
+// checkLinks проверяет сообщение фильтром ссылок и выполняет действие.
+// Без ссылок и упоминаний в сообщении SQL-запросов не делает.
+// Возвращает true, если ссылка запрещена.
+func (m *ReactionsModule) checkLinks(ctx *core.MessageContext, chatID int64, threadID int) bool {
+	links, mentions := extractLinks(ctx.Message)
+	if len(links) == 0 && len(mentions) == 0 {
+		return false
+	}
+
+	settings, err := m.linkFilterRepo.GetSettings(chatID, threadID)
+	if err != nil {
+		m.logger.Error("failed to load link filter settings", zap.Error(err))
+		return false
+	}
+	if settings == nil {
+		return false
+	}
+
+	domains, err := m.linkFilterRepo.GetDomains(chatID, threadID)
+	if err != nil {
+		m.logger.Error("failed to load link filter domains", zap.Error(err))
+		return false
+	}
+	lists := make(map[string]string, len(domains))
+	for _, d := range domains {
+		lists[d.Domain] = d.ListType
+	}
+
+	reason := m.linkViolation(ctx.Chat, settings, lists, links, mentions)
+	if reason == "" {
+		return false
+	}
+
+	m.logger.Info("forbidden link detected",
+		zap.Int64("chat_id", chatID),
+		zap.Int64("user_id", ctx.Sender.ID),
+		zap.String("reason", reason),
+		zap.String("action", settings.Action),
+	)
+	metrics.ReactionTriggersTotal.WithLabelValues("links").Inc()
+	m.performLinkAction(ctx, settings.Action, reason)
+	m.warner.AutoWarn(ctx, "links", "запрещённая ссылка")
+	return true
+}
+
+// linkViolation возвращает описание первой запрещённой ссылки («ссылки на example.com»)
+// или пустую строку. Порядок: приглашения, denylist, каналы, allowlist, режим.
+// Ссылка t.me/<username> проверяется и как ссылка, и как упоминание канала.
+func (m *ReactionsModule) linkViolation(chat *telebot.Chat, settings *repositories.LinkFilterSettings, lists map[string]string, links []foundLink, mentions []string) string {
+	for _, l := range links {
+		if l.invite && settings.BlockInvites {
+			return "ссылки-приглашения"
+		}
+		list := domainList(lists, l.host)
+		if list == "deny" {
+			return "ссылки на " + l.host
+		}
+		if l.channel != "" {
+			// Свой чат и каналы из allowlist разрешены независимо от домена t.me
+			switch m.channelVerdict(chat, l.channel, settings, lists) {
+			case "deny":
+				return "ссылки на каналы"
+			case "allow":
+				continue
+			}
+		}
+		if list == "allow" || settings.Mode != "all" {
+			continue
+		}
+		return "ссылки"
+	}
+
+	for _, name := range mentions {
+		if m.channelVerdict(chat, name, settings, lists) == "deny" {
+			return "упоминания каналов"
+		}
+	}
+	return ""
+}
+
+// channelVerdict решает, можно ли упоминать канал или группу username:
+// allow — свой чат или allowlist, deny — denylist или чужой канал при запрете упоминаний,
+// пустая строка — решения нет (пользователь или упоминания разрешены).
+func (m *ReactionsModule) channelVerdict(chat *telebot.Chat, name string, settings *repositories.LinkFilterSettings, lists map[string]string) string {
+	switch {
+	case strings.EqualFold(name, chat.Username):
+		return "allow"
+	case lists["@"+name] != "":
+		return lists["@"+name]
+	case settings.BlockMentions && m.isChannel(name):
+		return "deny"
+	}
+	return ""
+}
+
+// isChannel проверяет через getChat, что username — канал или группа, а не пользователь
+// (Bot API не отдаёт пользователей по username). Ответ кэшируется на час.
+func (m *ReactionsModule) isChannel(name string) bool {
+	m.channelMu.Lock()
+	entry, ok := m.channels[name]
+	m.channelMu.Unlock()
+	if ok && time.Since(entry.checkedAt) < channelCacheTTL {
+		return entry.isChannel
+	}
+
+	isChannel := false
+	chat, err := m.bot.ChatByUsername("@" + name)
+	if err == nil && chat.Type != telebot.ChatPrivate {
+		isChannel = true
+	}
+
+	m.channelMu.Lock()
+	if len(m.channels) >= maxChannelCache {
+		m.channels = make(map[string]channelEntry)
+	}
+	m.channels[name] = channelEntry{isChannel: isChannel, checkedAt: time.Now()}
+	m.channelMu.Unlock()
+	return isChannel
+}
+
+// performLinkAction выполняет действие фильтра ссылок (словарь как у /addban).
+func (m *ReactionsModule) performLinkAction(ctx *core.MessageContext, action, reason string) {
+	switch action {
+	case "delete":
+		if err := ctx.DeleteMessage("links"); err != nil {
+			m.logger.Error("failed to delete message", zap.Error(err))
+		}
+	case "warn":
+		_ = ctx.SendReply(fmt.Sprintf("⚠️ %s, %s здесь запрещены", core.DisplayName(ctx.Message.Sender), reason))
+	case "delete_warn":
+		if err := ctx.DeleteMessage("links"); err != nil {
+			m.logger.Error("failed to delete message", zap.Error(err))
+		}
+		// Без ReplyTo — сообщение уже удалено
+		ctx.Send(fmt.Sprintf("🚫 %s, сообщение удалено: %s здесь запрещены", core.DisplayName(ctx.Message.Sender), reason))
+	}
+}
+
+// ============================================================================
+// Обработчики команд фильтра ссылок
+// ============================================================================
+
+// handleSetLinks обрабатывает /setlinks <действие> [all|deny] | invites on|off | mentions on|off.
+func (m *ReactionsModule) handleSetLinks(c telebot.Context) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+	args := c.Args()
+	if len(args) == 0 {
+		return c.Send(setLinksUsage)
+	}
+
+	// Изменяем собственную запись области (топика или чата), а не унаследованную
+	settings, err := m.linkFilterRepo.GetOwnSettings(chatID, threadID)
+	if err != nil {
+		m.logger.Error("failed to get link filter settings", zap.Error(err))
+		return c.Send("❌ Не удалось получить настройки фильтра ссылок")
+	}
+	if settings == nil {
+		settings = repositories.DefaultLinkFilterSettings()
+	}
+	settings.ThreadID = threadID
+
+	switch arg := strings.ToLower(args[0]); arg {
+	case "invites", "mentions":
+		if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+			return c.Send(setLinksUsage)
+		}
+		if arg == "invites" {
+			settings.BlockInvites = args[1] == "on"
+		} else {
+			settings.BlockMentions = args[1] == "on"
+		}
+	case "delete", "warn", "delete_warn":
+		settings.Action = arg
+		if len(args) > 1 {
+			mode := strings.ToLower(args[1])
+			if mode != "all" && mode != "deny" {
+				return c.Send(setLinksUsage)
+			}
+			settings.Mode = mode
+		}
+	default:
+		return c.Send(setLinksUsage)
+	}
+
+	// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, chatID)
+
+	if err := m.linkFilterRepo.SetSettings(chatID, settings, c.Sender().ID); err != nil {
+		m.logger.Error("failed to set link filter settings", zap.Error(err))
+		return c.Send("❌ Не удалось сохранить настройки фильтра ссылок")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", "set_links",
+		fmt.Sprintf("Set link filter: action=%s mode=%s invites=%t mentions=%t (chat=%d, thread=%d)",
+			settings.Action, settings.Mode, settings.BlockInvites, settings.BlockMentions, chatID, threadID))
+
+	scope := "всего чата"
+	if threadID != 0 {
+		scope = "этого топика"
+	}
+	return c.Send(fmt.Sprintf("✅ Фильтр ссылок включён для %s\n\n%s", scope, describeLinkFilter(settings)))
+}
+
+// handleRemoveLinks обрабатывает /removelinks — выключение фильтра ссылок.
+func (m *ReactionsModule) handleRemoveLinks(c telebot.Context) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+
+	removed, err := m.linkFilterRepo.DeleteSettings(chatID, threadID)
+	if err != nil {
+		m.logger.Error("failed to remove link filter", zap.Error(err))
+		return c.Send("❌ Ошибка при отключении фильтра ссылок")
+	}
+	if !removed {
+		return c.Send("ℹ️ Фильтр ссылок не был настроен")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", "remove_links",
+		fmt.Sprintf("Removed link filter (chat=%d, thread=%d)", chatID, threadID))
+
+	scope := "всего чата"
+	if threadID != 0 {
+		scope = "этого топика"
+	}
+	return c.Send(fmt.Sprintf("✅ Фильтр ссылок отключен для %s\n\nAllowlist и denylist сохранены", scope))
+}
+
+// handleAllowDomain обрабатывает /allowdomain <домен|@канал> ...
+func (m *ReactionsModule) handleAllowDomain(c telebot.Context) error {
+	return m.setDomains(c, "allow")
+}
+
+// handleDenyDomain обрабатывает /denydomain <домен|@канал> ...
+func (m *ReactionsModule) handleDenyDomain(c telebot.Context) error {
+	return m.setDomains(c, "deny")
+}
+
+// setDomains добавляет домены из аргументов команды в allowlist или denylist.
+func (m *ReactionsModule) setDomains(c telebot.Context, listType string) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+	args := c.Args()
+	if len(args) == 0 {
+		return c.Send(fmt.Sprintf("Использование: /%sdomain <домен|@канал> ...\nПример: /%sdomain example.com @channel", listType, listType))
+	}
+
+	var domains []string
+	for _, arg := range args {
+		domain, err := normalizeDomain(arg)
+		if err != nil {
+			return c.Send("❌ " + err.Error())
+		}
+		domains = append(domains, domain)
+	}
+
+	// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, chatID)
+
+	for _, domain := range domains {
+		if err := m.linkFilterRepo.SetDomain(chatID, threadID, domain, listType, c.Sender().ID); err != nil {
+			m.logger.Error("failed to set link filter domain", zap.Error(err))
+			return c.Send("❌ Не удалось сохранить домен")
+		}
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", listType+"_domain",
+		fmt.Sprintf("Added to %slist: %s (chat=%d, thread=%d)", listType, strings.Join(domains, ", "), chatID, threadID))
+
+	list := "Allowlist"
+	if listType == "deny" {
+		list = "Denylist"
+	}
+	text := fmt.Sprintf("✅ %s: %s", list, strings.Join(domains, ", "))
+	if threadID != 0 {
+		text += "\n\n💡 Действует только в этом топике"
+	}
+	return c.Send(text)
+}
+
+// handleRemoveDomain обрабатывает /removedomain <домен|@канал>.
+func (m *ReactionsModule) handleRemoveDomain(c telebot.Context) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send("Использование: /removedomain <домен|@канал>\nПример: /removedomain example.com")
+	}
+
+	domain, err := normalizeDomain(args[0])
+	if err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+
+	removed, err := m.linkFilterRepo.RemoveDomain(chatID, threadID, domain)
+	if err != nil {
+		m.logger.Error("failed to remove link filter domain", zap.Error(err))
+		return c.Send("❌ Не удалось удалить домен")
+	}
+	if !removed {
+		if threadID != 0 {
+			return c.Send("ℹ️ Домена нет в списках этого топика")
+		}
+		return c.Send("ℹ️ Домена нет в списках чата")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", "remove_domain",
+		fmt.Sprintf("Removed domain %s (chat=%d, thread=%d)", domain, chatID, threadID))
+	return c.Send(fmt.Sprintf("✅ %s удалён из списков", domain))
+}
+
+// handleLinkStatus обрабатывает /linkstatus — настройки фильтра ссылок и списки доменов.
+func (m *ReactionsModule) handleLinkStatus(c telebot.Context) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+
+	settings, err := m.linkFilterRepo.GetSettings(chatID, threadID)
+	if err != nil {
+		m.logger.Error("failed to get link filter settings", zap.Error(err))
+		return c.Send("❌ Ошибка при загрузке настроек")
+	}
+	domains, err := m.linkFilterRepo.GetDomains(chatID, threadID)
+	if err != nil {
+		m.logger.Error("failed to get link filter domains", zap.Error(err))
+		return c.Send("❌ Ошибка при загрузке настроек")
+	}
+
+	msg := "🔗 <b>Фильтр ссылок</b>\n\n"
+	if settings == nil {
+		msg += "Выключен\n"
+	} else {
+		scope := "чата"
+		if settings.ThreadID != 0 {
+			scope = "топика"
+		}
+		msg += fmt.Sprintf("Область: %s\n%s\n", scope, describeLinkFilter(settings))
+	}
+
+	var allow, deny []string
+	for _, d := range domains {
+		entry := d.Domain
+		if d.ThreadID != 0 {
+			entry += " (топик)"
+		}
+		if d.ListType == "allow" {
+			allow = append(allow, entry)
+		} else {
+			deny = append(deny, entry)
+		}
+	}
+	if len(allow) > 0 {
+		msg += "\n✅ <b>Allowlist:</b> " + strings.Join(allow, ", ") + "\n"
+	}
+	if len(deny) > 0 {
+		msg += "\n🚫 <b>Denylist:</b> " + strings.Join(deny, ", ") + "\n"
+	}
+
+	return c.Send(msg, &telebot.SendOptions{ParseMode: telebot.ModeHTML, DisableWebPagePreview: true})
+}
+
+// setLinksUsage — подсказка по формату /setlinks.
+const setLinksUsage = "Использование:\n" +
+	"/setlinks <delete|warn|delete_warn> [all|deny]\n" +
+	"/setlinks invites on|off\n" +
+	"/setlinks mentions on|off"
+
+// describeLinkFilter — настройки фильтра ссылок в тексте бота.
+func describeLinkFilter(s *repositories.LinkFilterSettings) string {
+	mode := "все ссылки, кроме allowlist"
+	if s.Mode == "deny" {
+		mode = "только домены из denylist"
+	}
+	return fmt.Sprintf("Действие: %s\nЗапрещены: %s\nПриглашения в чаты: %s\nУпоминания каналов: %s",
+		s.Action, mode, blockedOrAllowed(s.BlockInvites), blockedOrAllowed(s.BlockMentions))
+}
+
+// blockedOrAllowed — «запрещены» / «разрешены».
+func blockedOrAllowed(blocked bool) string {
+	if blocked {
+		return "запрещены"
+	}
+	return "разрешены"
+}
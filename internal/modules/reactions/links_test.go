@@ -0,0 +1,180 @@
+package reactions
+
+import (
+	"testing"
+
+	"gopkg.in/telebot.v3"
+)
+
+// TestParseLink проверяет разбор ссылок: домен, приглашения и каналы Telegram
+func TestParseLink(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want foundLink
+		ok   bool
+	}{
+		// Обычные ссылки: www. и регистр убираются, схема не обязательна
+		{"https://Example.com/path?q=1", foundLink{host: "example.com"}, true},
+		{"www.example.com", foundLink{host: "example.com"}, true},
+		{"http://WWW.Example.COM./", foundLink{host: "example.com"}, true},
+		{"sub.example.com:8080/x", foundLink{host: "sub.example.com"}, true},
+		// Пунктуация после ссылки в тексте не часть ссылки
+		{"example.com).", foundLink{host: "example.com"}, true},
+		{"https://example.com/page!?", foundLink{host: "example.com"}, true},
+		{"example.com»", foundLink{host: "example.com"}, true},
+		// Приглашения Telegram
+		{"https://t.me/+AbCdEf123", foundLink{host: "t.me", invite: true}, true},
+		{"t.me/joinchat/AbCdEf", foundLink{host: "t.me", invite: true}, true},
+		{"https://telegram.me/addlist/xyz", foundLink{host: "telegram.me", invite: true}, true},
+		{"tg://join?invite=AbCdEf", foundLink{host: "t.me", invite: true}, true},
+		// Каналы Telegram
+		{"https://t.me/Durov", foundLink{host: "t.me", channel: "durov"}, true},
+		{"t.me/some_channel/123", foundLink{host: "t.me", channel: "some_channel"}, true},
+		{"tg://resolve?domain=Some_Channel", foundLink{host: "t.me", channel: "some_channel"}, true},
+		{"https://t.me/ab", foundLink{host: "t.me"}, true}, // слишком короткий username
+		// Не ссылки
+		{"", foundLink{}, false},
+		{"  .,;  ", foundLink{}, false},
+		{"http://", foundLink{}, false},
+	}
+	for _, tc := range tests {
+		got, ok := parseLink(tc.raw)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("parseLink(%q) = (%+v, %t), want (%+v, %t)", tc.raw, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// TestExtractLinks проверяет сбор ссылок из entities, текста и подписи
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      *telebot.Message
+		links    []foundLink
+		mentions []string
+	}{
+		{
+			name:  "Ссылка в тексте без entity",
+			msg:   &telebot.Message{Text: "заходи на www.example.com, там всё"},
+			links: []foundLink{{host: "example.com"}},
+		},
+		{
+			name: "text_link: адрес только в entity",
+			msg: &telebot.Message{
+				Text:     "жми сюда",
+				Entities: telebot.Entities{{Type: telebot.EntityTextLink, Offset: 4, Length: 4, URL: "https://evil.example.org/x"}},
+			},
+			links: []foundLink{{host: "evil.example.org"}},
+		},
+		{
+			name: "url entity и тот же адрес в тексте — одна ссылка",
+			msg: &telebot.Message{
+				Text:     "https://t.me/+invite",
+				Entities: telebot.Entities{{Type: telebot.EntityURL, Offset: 0, Length: 20}},
+			},
+			links: []foundLink{{host: "t.me", invite: true}},
+		},
+		{
+			name: "Подпись: entities подписи, упоминание",
+			msg: &telebot.Message{
+				Caption: "от @Some_Channel: example.net",
+				CaptionEntities: telebot.Entities{
+					{Type: telebot.EntityMention, Offset: 3, Length: 13},
+					{Type: telebot.EntityURL, Offset: 18, Length: 11},
+				},
+			},
+			links:    []foundLink{{host: "example.net"}},
+			mentions: []string{"some_channel"},
+		},
+		{
+			name: "Текст без ссылок",
+			msg:  &telebot.Message{Text: "просто текст, без ссылок."},
+		},
+	}
+	for _, tc := range tests {
+		links, mentions := extractLinks(tc.msg)
+		if len(links) != len(tc.links) {
+			t.Errorf("%s: links = %+v, want %+v", tc.name, links, tc.links)
+		} else {
+			for i := range links {
+				if links[i] != tc.links[i] {
+					t.Errorf("%s: links[%d] = %+v, want %+v", tc.name, i, links[i], tc.links[i])
+				}
+			}
+		}
+		if len(mentions) != len(tc.mentions) {
+			t.Errorf("%s: mentions = %v, want %v", tc.name, mentions, tc.mentions)
+		} else {
+			for i := range mentions {
+				if mentions[i] != tc.mentions[i] {
+					t.Errorf("%s: mentions[%d] = %q, want %q", tc.name, i, mentions[i], tc.mentions[i])
+				}
+			}
+		}
+	}
+}
+
+// TestDomainList проверяет поиск домена и ближайшего родительского домена в списках
+func TestDomainList(t *testing.T) {
+	lists := map[string]string{
+		"example.com":      "allow",
+		"evil.example.com": "deny",
+		"t.me":             "deny",
+	}
+	tests := map[string]string{
+		"example.com":          "allow",
+		"a.b.example.com":      "allow", // родитель example.com
+		"evil.example.com":     "deny",  // точное совпадение важнее родителя
+		"x.evil.example.com":   "deny",  // ближайший родитель
+		"deep.a.b.c.t.me":      "deny",
+		"notexample.com":       "", // не поддомен
+		"sub.notexample.com":   "",
+		"example.com.evil.org": "",
+		"com":                  "",
+		"":                     "",
+	}
+	for host, want := range tests {
+		if got := domainList(lists, host); got != want {
+			t.Errorf("domainList(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+// TestNormalizeDomain проверяет аргумент /allowdomain, /denydomain
+func TestNormalizeDomain(t *testing.T) {
+	valid := map[string]string{
+		"example.com":                "example.com",
+		"  Example.COM ":             "example.com",
+		"https://www.example.com/x":  "example.com",
+		"sub.example.com":            "sub.example.com",
+		"пример.рф":                  "пример.рф",
+		"@Some_Channel":              "@some_channel",
+		"t.me/+invite":               "t.me",
+		"http://xn--e1afmkfd.xn--p1": "xn--e1afmkfd.xn--p1",
+	}
+	for in, want := range valid {
+		got, err := normalizeDomain(in)
+		if err != nil || got != want {
+			t.Errorf("normalizeDomain(%q) = (%q, %v), want %q", in, got, err, want)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"localhost",     // без точки
+		"@ab",           // короткий username
+		"@1channel",     // username с цифры
+		"@bad-name",     // недопустимый символ
+		"-example.com",  // дефис в начале метки
+		"example-.com",  // дефис в конце метки
+		"exa_mple.com",  // подчёркивание в домене
+		"example..com",  // пустая метка
+		"http://",       // нет домена
+		"192.168.0.1:x", // неверный порт
+	}
+	for _, in := range invalid {
+		if got, err := normalizeDomain(in); err == nil {
+			t.Errorf("normalizeDomain(%q) = %q, want error", in, got)
+		}
+	}
+}
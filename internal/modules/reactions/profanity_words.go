@@ -0,0 +1,196 @@
+package reactions
+
+// Этот файл содержит слова фильтра мата per-chat — часть модуля Reactions.
+// Исключения (allow) убирают ложные срабатывания поиска подстроки,
+// дополнения (add) расширяют глобальный словарь только для своего чата.
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+	"github.com/flybasist/bmft/internal/profanity"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+)
+
+const (
+	// minChatWordLen — минимальная длина слова чата после нормализации:
+	// исключение из одной-двух букв отключило бы фильтр почти целиком.
+	minChatWordLen = 3
+	// maxChatWordLen — максимальная длина слова чата (profanity_chat_words.word).
+	maxChatWordLen = 100
+)
+
+// applyChatWords накладывает слова чата на словарь: возвращает текст без слов,
+// попадающих под исключения, и дополнения чата.
+// Слово текста пропускается, если его нормализованная форма содержит
+// исключение: /profanityallow оскорбл покрывает «оскорблять» и «оскорбление».
+func applyChatWords(text string, chatWords []repositories.ProfanityChatWord) (string, []profanity.Entry) {
+	var allowed []string
+	var extra []profanity.Entry
+	for _, cw := range chatWords {
+		switch cw.Kind {
+		case repositories.ProfanityAllow:
+			allowed = append(allowed, profanity.Normalize(cw.Word))
+		case repositories.ProfanityAdd:
+			extra = append(extra, profanity.Entry{Pattern: cw.Word, Severity: cw.Severity})
+		}
+	}
+	if len(allowed) == 0 {
+		return text, extra
+	}
+
+	fields := strings.Fields(text)
+	kept := fields[:0]
+	for _, field := range fields {
+		normalized := profanity.Normalize(field)
+		isAllowed := false
+		for _, a := range allowed {
+			if a != "" && strings.Contains(normalized, a) {
+				isAllowed = true
+				break
+			}
+		}
+		if !isAllowed {
+			kept = append(kept, field)
+		}
+	}
+	return strings.Join(kept, " "), extra
+}
+
+// parseChatWord проверяет слово из аргументов /profanityallow и /profanityadd.
+func parseChatWord(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("укажите одно слово без пробелов")
+	}
+	word := strings.ToLower(strings.TrimSpace(args[0]))
+	if utf8.RuneCountInString(word) > maxChatWordLen {
+		return "", fmt.Errorf("слово длиннее %d символов", maxChatWordLen)
+	}
+	if utf8.RuneCountInString(profanity.Normalize(word)) < minChatWordLen {
+		return "", fmt.Errorf("слово короче %d букв", minChatWordLen)
+	}
+	return word, nil
+}
+
+// handleProfanityAllow обрабатывает /profanityallow <слово> — исключение из словаря.
+func (m *ReactionsModule) handleProfanityAllow(c telebot.Context) error {
+	return m.setChatWord(c, repositories.ProfanityAllow)
+}
+
+// handleProfanityAdd обрабатывает /profanityadd <слово> — дополнительное слово словаря.
+func (m *ReactionsModule) handleProfanityAdd(c telebot.Context) error {
+	return m.setChatWord(c, repositories.ProfanityAdd)
+}
+
+// setChatWord сохраняет исключение или дополнение словаря для чата.
+func (m *ReactionsModule) setChatWord(c telebot.Context, kind string) error {
+	chatID := c.Chat().ID
+	args := c.Args()
+	usage := "/profanity" + kind + " <слово>"
+
+	// У дополнения может быть уровень: /profanityadd <слово> [mild|moderate|severe]
+	severity := profanity.SeverityModerate
+	if kind == repositories.ProfanityAdd {
+		usage += " [mild|moderate|severe]"
+		if len(args) == 2 {
+			severity = strings.ToLower(args[1])
+			if !profanity.ValidSeverity(severity) {
+				return c.Send("❌ Неизвестный уровень: " + args[1] + "\n\nИспользование: " + usage)
+			}
+			args = args[:1]
+		}
+	}
+
+	word, err := parseChatWord(args)
+	if err != nil {
+		return c.Send(fmt.Sprintf("❌ %s\n\nИспользование: %s", err.Error(), usage))
+	}
+
+	// Убеждаемся что chat_id существует в таблице chats (для foreign key)
+	_, _ = m.db.Exec(`
+		INSERT INTO chats (chat_id, chat_type, title)
+		VALUES ($1, 'unknown', 'unknown')
+		ON CONFLICT (chat_id) DO NOTHING
+	`, chatID)
+
+	if err := m.profanityRepo.SetChatWord(chatID, word, kind, severity, c.Sender().ID); err != nil {
+		m.logger.Error("failed to set profanity chat word", zap.Error(err))
+		return c.Send("❌ Не удалось сохранить слово")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", "profanity_"+kind,
+		fmt.Sprintf("Profanity chat word %q set as %s (severity=%s)", word, kind, severity))
+
+	if kind == repositories.ProfanityAllow {
+		return c.Send(fmt.Sprintf("✅ «%s» больше не считается матом в этом чате\nИсключение действует на все слова, которые его содержат", word))
+	}
+	return c.Send(fmt.Sprintf("✅ «%s» добавлено в словарь мата этого чата (уровень %s)", word, severity))
+}
+
+// handleProfanityRemove обрабатывает /profanityremove <слово> — удалить слово чата.
+func (m *ReactionsModule) handleProfanityRemove(c telebot.Context) error {
+	chatID := c.Chat().ID
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send("Использование: /profanityremove <слово>\nСлова чата: /profanitylist")
+	}
+	word := strings.ToLower(args[0])
+
+	removed, err := m.profanityRepo.RemoveChatWord(chatID, word)
+	if err != nil {
+		m.logger.Error("failed to remove profanity chat word", zap.Error(err))
+		return c.Send("❌ Не удалось удалить слово")
+	}
+	if !removed {
+		return c.Send("ℹ️ Такого слова нет в списках чата: /profanitylist")
+	}
+
+	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", "profanity_remove",
+		fmt.Sprintf("Profanity chat word %q removed", word))
+	return c.Send(fmt.Sprintf("✅ «%s» удалено из списков чата", word))
+}
+
+// handleProfanityList обрабатывает /profanitylist — исключения и дополнения чата.
+func (m *ReactionsModule) handleProfanityList(c telebot.Context) error {
+	chatWords, err := m.profanityRepo.GetChatWords(c.Chat().ID)
+	if err != nil {
+		m.logger.Error("failed to get profanity chat words", zap.Error(err))
+		return c.Send("❌ Ошибка при загрузке списка")
+	}
+
+	var allow, add []string
+	for _, cw := range chatWords {
+		if cw.Kind == repositories.ProfanityAllow {
+			allow = append(allow, "<code>"+html.EscapeString(cw.Word)+"</code>")
+		} else {
+			entry := "<code>" + html.EscapeString(cw.Word) + "</code>"
+			if cw.Severity != profanity.SeverityModerate {
+				entry += " (" + cw.Severity + ")"
+			}
+			add = append(add, entry)
+		}
+	}
+
+	msg := "📝 <b>Слова фильтра мата этого чата</b>\n\n"
+	msg += "✅ <b>Исключения:</b> "
+	if len(allow) == 0 {
+		msg += "нет"
+	} else {
+		msg += strings.Join(allow, ", ")
+	}
+	msg += "\n🚫 <b>Дополнения:</b> "
+	if len(add) == 0 {
+		msg += "нет"
+	} else {
+		msg += strings.Join(add, ", ")
+	}
+	msg += "\n\n🔹 <code>/profanityallow &lt;слово&gt;</code> — Исключение\n"
+	msg += "🔹 <code>/profanityadd &lt;слово&gt; [mild|moderate|severe]</code> — Дополнение\n"
+	msg += "🔹 <code>/profanityremove &lt;слово&gt;</code> — Удалить из списков"
+
+	return c.Send(msg, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+}
@@ -3,13 +3,16 @@ package reactions
 import (
 	"database/sql"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/metrics"
+	"github.com/flybasist/bmft/internal/postgresql"
 	"github.com/flybasist/bmft/internal/postgresql/repositories"
+	"github.com/flybasist/bmft/internal/profanity"
 	"go.uber.org/zap"
 	"gopkg.in/telebot.v3"
 )
@@ -20,8 +23,25 @@ type ReactionsModule struct {
 	contentLimitsRepo *repositories.ContentLimitsRepository
 	messageRepo       *repositories.MessageRepository
 	eventRepo         *repositories.EventRepository
+	linkFilterRepo    *repositories.LinkFilterRepository
+	profanityRepo     *repositories.ProfanityRepository
+	shadowRepo        *repositories.ShadowRepository // срабатывания в теневом режиме
+	warner            core.Warner                    // автопредупреждения за мат, запрещённые слова и ссылки
 	logger            *zap.Logger
 	bot               *telebot.Bot
+
+	channelMu sync.Mutex
+	channels  map[string]channelEntry // username → канал или группа (кэш getChat)
+
+	dictMu        sync.RWMutex
+	dict          *profanity.Matcher // автомат глобального словаря мата
+	dictVersion   string             // md5 словаря, по которому собран dict
+	dictCheckedAt time.Time
+
+	listener *postgresql.Listener // уведомления об изменении keyword_reactions; nil — только TTL
+	rulesMu  sync.RWMutex
+	rules    map[int64]*chatRules // key = chatID
+	rulesGen uint64               // счётчик сбросов кэша правил
 }
 
 type KeywordReaction struct {
@@ -40,6 +60,7 @@ type KeywordReaction struct {
 	DeleteOnLimit      bool
 	Action             string // пустая строка = реакция (ответ), 'delete'/'warn'/'delete_warn' = фильтр
 	IsActive           bool
+	Shadow             bool // фильтр в теневом режиме: срабатывание только записывается
 }
 
 // getTextForMatching возвращает текст сообщения для проверки на совпадение.
@@ -59,6 +80,11 @@ func New(
 	contentLimitsRepo *repositories.ContentLimitsRepository,
 	messageRepo *repositories.MessageRepository,
 	eventRepo *repositories.EventRepository,
+	linkFilterRepo *repositories.LinkFilterRepository,
+	profanityRepo *repositories.ProfanityRepository,
+	shadowRepo *repositories.ShadowRepository,
+	listener *postgresql.Listener,
+	warner core.Warner,
 	logger *zap.Logger,
 	bot *telebot.Bot,
 ) *ReactionsModule {
@@ -68,11 +94,33 @@ func New(
 		contentLimitsRepo: contentLimitsRepo,
 		messageRepo:       messageRepo,
 		eventRepo:         eventRepo,
+		linkFilterRepo:    linkFilterRepo,
+		profanityRepo:     profanityRepo,
+		shadowRepo:        shadowRepo,
+		listener:          listener,
+		warner:            warner,
 		logger:            logger,
 		bot:               bot,
+		channels:          make(map[string]channelEntry),
+		rules:             make(map[int64]*chatRules),
 	}
 }
 
+// Name возвращает имя модуля.
+func (m *ReactionsModule) Name() string { return "reactions" }
+
+// Priority — reactions последний: видит MessageDeleted от limiter.
+func (m *ReactionsModule) Priority() int { return core.PriorityReactions }
+
+// Start подписывает кэш правил на уведомления об их изменении.
+func (m *ReactionsModule) Start() error {
+	m.subscribeRules()
+	return nil
+}
+
+// Shutdown — фоновых задач нет. Соединение LISTEN общее, его закрывает владелец (run).
+func (m *ReactionsModule) Shutdown() error { return nil }
+
 // RegisterCommands регистрирует команды модуля в боте.
 func (m *ReactionsModule) RegisterCommands(bot *telebot.Bot) {
 	// /reactions — справка по модулю реакций
@@ -83,7 +131,8 @@ func (m *ReactionsModule) RegisterCommands(bot *telebot.Bot) {
 		msg += "<b>📋 Разделы:</b>\n"
 		msg += "• /reactions — автоответы на ключевые слова (эта справка)\n"
 		msg += "• /textfilter — фильтр запрещённых слов\n"
-		msg += "• /profanity — фильтр ненормативной лексики\n\n"
+		msg += "• /profanity — фильтр ненормативной лексики\n"
+		msg += "• /linkfilter — фильтр ссылок и приглашений\n\n"
 
 		msg += "<b>🔹 Команды автоответов:</b>\n\n"
 		msg += "🔸 <code>/addreaction</code> — Добавить реакцию (только админы)\n"
@@ -107,9 +156,11 @@ func (m *ReactionsModule) RegisterCommands(bot *telebot.Bot) {
 		msg += "⚠️ <b>Топики:</b> Команда в топике = реакция только в нём\n\n"
 		msg += "📌 <b>Приоритет обработки сообщений:</b>\n"
 		msg += "1. Фильтр мата (/profanity) — высший приоритет\n"
-		msg += "2. Фильтр запрещённых слов (/textfilter)\n"
-		msg += "3. Автоответы на ключевые слова\n"
-		msg += "ℹ️ VIP-пользователи игнорируют все фильтры и автоответы"
+		msg += "2. Фильтр ссылок (/linkfilter)\n"
+		msg += "3. Фильтр запрещённых слов (/textfilter)\n"
+		msg += "4. Автоответы на ключевые слова\n"
+		msg += "ℹ️ VIP-пользователи игнорируют все фильтры и автоответы\n\n"
+		msg += "🧪 <code>/testfilter &lt;текст&gt;</code> (или ответом на сообщение) — какое правило сработает, без удаления и ответов (только админы)"
 
 		return c.Send(msg, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
 	})
@@ -134,6 +185,11 @@ func (m *ReactionsModule) RegisterCommands(bot *telebot.Bot) {
 
 		msg += "🔹 <code>/removeban &lt;ID&gt;</code> — Удалить бан-слово (только админы)\n\n"
 
+		msg += "👻 <b>Теневой режим</b> — проверить правило перед включением (только админы):\n"
+		msg += "• <code>/addban спам delete shadow</code> — добавить, только записывая срабатывания\n"
+		msg += "• <code>/banshadow &lt;ID&gt; on|off</code> — включить/выключить теневой режим\n"
+		msg += "• <code>/shadowreport [дней]</code> — что сработало бы\n\n"
+
 		msg += "⚠️ <b>Действия:</b>\n"
 		msg += "• <code>delete</code> — удалить сообщение молча\n"
 		msg += "• <code>warn</code> — предупредить (сообщение остаётся)\n"
@@ -157,6 +213,53 @@ func (m *ReactionsModule) RegisterCommands(bot *telebot.Bot) {
 
 		msg += "🔹 <code>/removeprofanity</code> — Отключить фильтр (только админы)\n\n"
 
+		msg += "<b>Слова чата</b> (поверх общего словаря, только админы):\n"
+		msg += "🔹 <code>/profanityallow &lt;слово&gt;</code> — Исключение: слова, содержащие его, не считаются матом\n"
+		msg += "   📌 Пример: <code>/profanityallow оскорбл</code>\n"
+		msg += "🔹 <code>/profanityadd &lt;слово&gt; [уровень]</code> — Считать матом в этом чате\n"
+		msg += "🔹 <code>/profanityremove &lt;слово&gt;</code> — Удалить из списков\n"
+		msg += "🔹 <code>/profanitylist</code> — Исключения и дополнения чата\n\n"
+
+		msg += "⚠️ <b>Действия:</b>\n"
+		msg += "• <code>delete</code> — удалить сообщение молча\n"
+		msg += "• <code>warn</code> — предупредить (сообщение остаётся)\n"
+		msg += "• <code>delete_warn</code> — удалить И предупредить\n\n"
+
+		msg += "📶 <b>Уровни серьёзности</b> (<code>mild</code> &lt; <code>moderate</code> &lt; <code>severe</code>):\n"
+		msg += "🔹 <code>/setprofanity level &lt;уровень&gt;</code> — Реагировать начиная с уровня\n"
+		msg += "🔹 <code>/setprofanity &lt;уровень&gt; &lt;действие|default&gt;</code> — Своё действие для уровня\n"
+		msg += "   📌 <code>/setprofanity mild warn</code> и <code>/setprofanity severe delete_warn</code>\n\n"
+
+		msg += "👻 <code>/setprofanity shadow on|off</code> — Теневой режим: срабатывания только записываются, отчёт — <code>/shadowreport</code>\n\n"
+
+		msg += "🛡️ <i>VIP-защита:</i> VIP игнорируют фильтр."
+		return c.Send(msg, &telebot.SendOptions{ParseMode: telebot.ModeHTML})
+	})
+
+	// /linkfilter — справка по фильтру ссылок
+	bot.Handle("/linkfilter", func(c telebot.Context) error {
+		msg := "🔗 <b>Фильтр ссылок</b> (часть модуля Reactions)\n\n"
+		msg += "Ссылки ищутся в тексте и подписях, в том числе скрытые под текстом. "
+		msg += "Отдельно распознаются приглашения в чаты (t.me/+..., t.me/joinchat/...) и упоминания каналов (@channel, t.me/channel).\n\n"
+		msg += "<b>Доступные команды:</b>\n\n"
+
+		msg += "🔹 <code>/setlinks &lt;действие&gt; [all|deny]</code> — Включить фильтр (только админы)\n"
+		msg += "   <code>all</code> (по умолчанию) — запрещены все ссылки, кроме allowlist\n"
+		msg += "   <code>deny</code> — запрещены только домены из denylist\n"
+		msg += "   📌 Пример: <code>/setlinks delete_warn</code>\n\n"
+
+		msg += "🔹 <code>/setlinks invites on|off</code> — Приглашения в чаты (по умолчанию запрещены)\n"
+		msg += "🔹 <code>/setlinks mentions on|off</code> — Упоминания чужих каналов и групп (по умолчанию запрещены)\n\n"
+
+		msg += "🔹 <code>/allowdomain &lt;домен|@канал&gt; ...</code> — Добавить в allowlist (только админы)\n"
+		msg += "🔹 <code>/denydomain &lt;домен|@канал&gt; ...</code> — Добавить в denylist (только админы)\n"
+		msg += "   📌 Пример: <code>/allowdomain github.com @mychannel</code>\n"
+		msg += "   Домен включает поддомены: <code>example.com</code> = <code>docs.example.com</code>\n"
+		msg += "🔹 <code>/removedomain &lt;домен|@канал&gt;</code> — Убрать из списков (только админы)\n\n"
+
+		msg += "🔹 <code>/linkstatus</code> — Настройки и списки (только админы)\n"
+		msg += "🔹 <code>/removelinks</code> — Отключить фильтр (только админы)\n\n"
+
 		msg += "⚠️ <b>Действия:</b>\n"
 		msg += "• <code>delete</code> — удалить сообщение молча\n"
 		msg += "• <code>warn</code> — предупредить (сообщение остаётся)\n"
@@ -178,11 +281,30 @@ func (m *ReactionsModule) RegisterAdminCommands(bot *telebot.Bot) {
 	bot.Handle("/addban", m.handleAddBan)
 	bot.Handle("/listbans", m.handleListBans)
 	bot.Handle("/removeban", m.handleRemoveBan)
+	bot.Handle("/banshadow", m.handleBanShadow)
 
 	// Фильтр мата (бывший ProfanityFilter)
 	bot.Handle("/setprofanity", m.handleSetProfanity)
 	bot.Handle("/removeprofanity", m.handleRemoveProfanity)
 	bot.Handle("/profanitystatus", m.handleProfanityStatus)
+	bot.Handle("/profanityallow", m.handleProfanityAllow)
+	bot.Handle("/profanityadd", m.handleProfanityAdd)
+	bot.Handle("/profanityremove", m.handleProfanityRemove)
+	bot.Handle("/profanitylist", m.handleProfanityList)
+
+	// Фильтр ссылок
+	bot.Handle("/setlinks", m.handleSetLinks)
+	bot.Handle("/removelinks", m.handleRemoveLinks)
+	bot.Handle("/allowdomain", m.handleAllowDomain)
+	bot.Handle("/denydomain", m.handleDenyDomain)
+	bot.Handle("/removedomain", m.handleRemoveDomain)
+	bot.Handle("/linkstatus", m.handleLinkStatus)
+
+	// Пробный прогон фильтров и автоответов
+	bot.Handle("/testfilter", m.handleTestFilter)
+
+	// Отчёт теневого режима (фильтры, мат, лимиты)
+	bot.Handle("/shadowreport", m.handleShadowReport)
 }
 
 func (m *ReactionsModule) OnMessage(ctx *core.MessageContext) error {
@@ -223,7 +345,12 @@ func (m *ReactionsModule) OnMessage(ctx *core.MessageContext) error {
 		return nil
 	}
 
-	// ─── Этап 2: Загружаем keyword_reactions (и фильтры, и автоответы) ───
+	// ─── Этап 2: Фильтр ссылок (link_filter_settings) ───
+	if m.checkLinks(ctx, chatID, threadID) {
+		return nil // Ссылка запрещена, действие выполнено
+	}
+
+	// ─── Этап 3: Правила keyword_reactions из кэша (и фильтры, и автоответы) ───
 	reactions, err := m.loadReactions(chatID, threadID, userID)
 	if err != nil {
 		m.logger.Error("failed to load reactions", zap.Error(err))
@@ -231,40 +358,40 @@ func (m *ReactionsModule) OnMessage(ctx *core.MessageContext) error {
 	}
 
 	m.logger.Debug("loaded reactions", zap.Int("count", len(reactions)))
+	lowerText := strings.ToLower(textToCheck)
 
-	// ─── Этап 3: Проверяем фильтры (action IS NOT NULL) ───
+	// ─── Этап 4: Проверяем фильтры (action IS NOT NULL) ───
 	for _, reaction := range reactions {
 		if !reaction.IsActive || reaction.Action == "" {
 			continue // Пропускаем неактивные и обычные реакции
 		}
 
-		matched := false
-		if textToCheck != "" {
-			if reaction.IsRegex {
-				re, err := regexp.Compile(reaction.Pattern)
-				if err != nil {
-					m.logger.Warn("invalid regex pattern", zap.String("pattern", reaction.Pattern))
-					continue
-				}
-				matched = re.MatchString(textToCheck)
-			} else {
-				matched = strings.Contains(strings.ToLower(textToCheck), strings.ToLower(reaction.Pattern))
+		if textToCheck != "" && reaction.matches(textToCheck, lowerText) {
+			// Теневой фильтр не останавливает проверку: следующие фильтры и автоответы работают как без него
+			if reaction.Shadow {
+				m.recordShadow(ctx, repositories.ShadowRuleBannedWord, reaction.ID, reaction.Pattern, reaction.Action)
+				continue
 			}
-		}
-
-		if matched {
 			m.logger.Info("filter word detected",
 				zap.Int64("chat_id", chatID),
 				zap.Int64("user_id", userID),
 				zap.String("pattern", reaction.Pattern),
 				zap.String("action", reaction.Action),
 			)
-			m.performFilterAction(ctx, reaction)
+			metrics.ReactionTriggersTotal.WithLabelValues("filter").Inc()
+			m.performFilterAction(ctx, reaction.KeywordReaction)
+			m.warner.AutoWarn(ctx, "banned_words", "запрещённое слово")
 			return nil // Фильтр сработал, автоответы не нужны
 		}
 	}
 
-	// ─── Этап 4: Проверяем автоответы (action IS NULL) ───
+	// Правка проверяется фильтрами (мат, бан-слова), но автоответ на неё не отправляется:
+	// иначе каждая правка вызывала бы повторный ответ бота
+	if ctx.IsEdit {
+		return nil
+	}
+
+	// ─── Этап 5: Проверяем автоответы (action IS NULL) ───
 	for _, reaction := range reactions {
 		if !reaction.IsActive || reaction.Action != "" {
 			continue // Пропускаем неактивные и фильтры
@@ -272,56 +399,11 @@ func (m *ReactionsModule) OnMessage(ctx *core.MessageContext) error {
 
 		// Проверяем фильтр по типу контента.
 		// Если trigger_content_type задан, проверяем соответствие типа сообщения.
-		if reaction.TriggerContentType != "" {
-			contentMatched := false
-			switch reaction.TriggerContentType {
-			case "photo":
-				contentMatched = msg.Photo != nil
-			case "video":
-				contentMatched = msg.Video != nil
-			case "sticker":
-				contentMatched = msg.Sticker != nil
-			case "animation":
-				contentMatched = msg.Animation != nil
-			case "voice":
-				contentMatched = msg.Voice != nil
-			case "video_note":
-				contentMatched = msg.VideoNote != nil
-			case "audio":
-				contentMatched = msg.Audio != nil
-			case "document":
-				contentMatched = msg.Document != nil
-			case "text":
-				contentMatched = msg.Text != ""
-			}
-
-			if !contentMatched {
-				continue // Тип контента не совпадает, пропускаем эту реакцию
-			}
-		}
-
-		// Проверяем соответствие паттерна.
-		// Если pattern пустой и user_id совпадает - срабатывает (без проверки текста).
-		matched := false
-
-		// Персональная реакция на любой контент (pattern пустой)
-		if reaction.Pattern == "" && reaction.UserID > 0 && reaction.UserID == userID {
-			matched = true
-		} else if textToCheck != "" {
-			// Обычная текстовая/caption реакция
-			if reaction.IsRegex {
-				re, err := regexp.Compile(reaction.Pattern)
-				if err != nil {
-					m.logger.Warn("invalid regex pattern", zap.String("pattern", reaction.Pattern))
-					continue
-				}
-				matched = re.MatchString(textToCheck)
-			} else {
-				matched = strings.Contains(strings.ToLower(textToCheck), strings.ToLower(reaction.Pattern))
-			}
+		if !contentTypeMatches(msg, reaction.TriggerContentType) {
+			continue // Тип контента не совпадает, пропускаем эту реакцию
 		}
 
-		if matched {
+		if reaction.replyMatches(userID, textToCheck, lowerText) {
 			if reaction.Cooldown > 0 {
 				lastTriggered, err := m.getLastTriggered(chatID, reaction.ID)
 				if err == nil && time.Since(lastTriggered) < time.Duration(reaction.Cooldown)*time.Second {
@@ -341,7 +423,7 @@ func (m *ReactionsModule) OnMessage(ctx *core.MessageContext) error {
 				if count >= reaction.DailyLimit {
 					if reaction.DeleteOnLimit {
 						// Удаляем сообщение и отправляем предупреждение
-						if err := ctx.DeleteMessage(); err != nil {
+						if err := ctx.DeleteMessage("reaction_daily_limit"); err != nil {
 							m.logger.Error("failed to delete message", zap.Error(err))
 						}
 						// Отправляем warning только при ПЕРВОМ превышении
@@ -385,6 +467,7 @@ func (m *ReactionsModule) OnMessage(ctx *core.MessageContext) error {
 				m.logger.Error("failed to send reaction", zap.Error(err))
 			}
 
+			metrics.ReactionTriggersTotal.WithLabelValues("auto_reply").Inc()
 			m.recordTrigger(chatID, reaction.ID, userID)
 			if reaction.DailyLimit > 0 {
 				// Инкрементируем счётчик для того же user_id, что проверяли выше
@@ -397,48 +480,31 @@ func (m *ReactionsModule) OnMessage(ctx *core.MessageContext) error {
 	return nil
 }
 
-func (m *ReactionsModule) loadReactions(chatID int64, threadID int, userID int64) ([]KeywordReaction, error) {
-	m.logger.Debug("loadReactions called", zap.Int64("chat_id", chatID), zap.Int("thread_id", threadID), zap.Int64("user_id", userID))
-
-	// Читаем реакции напрямую из БД (без кеша).
-	// Чтение ~1-2ms, не критично для производительности.
-	// Fallback логика (приоритет сверху вниз):
-	// 1. Персональная реакция для user_id в конкретном топике (thread_id + user_id)
-	// 2. Персональная реакция для user_id во всём чате (thread_id=0 + user_id)
-	// 3. Общая реакция для топика (thread_id, user_id IS NULL)
-	// 4. Общая реакция для чата (thread_id=0, user_id IS NULL)
-	rows, err := m.db.Query(`
-		SELECT id, chat_id, thread_id, COALESCE(user_id, 0), pattern, response_type, response_content, description, COALESCE(trigger_content_type, ''), is_regex, cooldown, daily_limit, delete_on_limit, COALESCE(action, ''), is_active
-		FROM keyword_reactions
-		WHERE chat_id = $1 
-		  AND (thread_id = $2 OR thread_id = 0) 
-		  AND (user_id = $3 OR user_id IS NULL)
-		  AND is_active = true
-		ORDER BY 
-		  CASE WHEN action IS NOT NULL THEN 0 ELSE 1 END,  -- Фильтры в приоритете
-		  CASE WHEN user_id IS NOT NULL THEN 0 ELSE 1 END,  -- Персональные реакции в приоритете
-		  thread_id DESC,  -- Топик приоритетнее чата
-		  id
-	`, chatID, threadID, userID)
-	if err != nil {
-		m.logger.Error("loadReactions query failed", zap.Error(err), zap.Int64("chat_id", chatID), zap.Int("thread_id", threadID))
-		return nil, err
-	}
-	defer rows.Close()
-
-	var reactions []KeywordReaction
-	for rows.Next() {
-		var r KeywordReaction
-		if err := rows.Scan(&r.ID, &r.ChatID, &r.ThreadID, &r.UserID, &r.Pattern, &r.ResponseType, &r.ResponseContent, &r.Description, &r.TriggerContentType, &r.IsRegex, &r.Cooldown, &r.DailyLimit, &r.DeleteOnLimit, &r.Action, &r.IsActive); err != nil {
-			m.logger.Error("failed to scan reaction", zap.Error(err))
-			continue
-		}
-		reactions = append(reactions, r)
+// contentTypeMatches проверяет trigger_content_type реакции: "" — любой контент.
+func contentTypeMatches(msg *telebot.Message, contentType string) bool {
+	switch contentType {
+	case "":
+		return true
+	case "photo":
+		return msg.Photo != nil
+	case "video":
+		return msg.Video != nil
+	case "sticker":
+		return msg.Sticker != nil
+	case "animation":
+		return msg.Animation != nil
+	case "voice":
+		return msg.Voice != nil
+	case "video_note":
+		return msg.VideoNote != nil
+	case "audio":
+		return msg.Audio != nil
+	case "document":
+		return msg.Document != nil
+	case "text":
+		return msg.Text != ""
 	}
-
-	m.logger.Debug("loadReactions completed", zap.Int("count", len(reactions)))
-
-	return reactions, nil
+	return false
 }
 
 func (m *ReactionsModule) getLastTriggered(chatID, reactionID int64) (time.Time, error) {
@@ -769,6 +835,8 @@ func (m *ReactionsModule) handleAddReaction(c telebot.Context) error {
 		return c.Send("❌ Не удалось добавить реакцию")
 	}
 
+	m.invalidateRules(chatID)
+
 	m.logger.Info("reaction added successfully",
 		zap.Int64("chat_id", chatID),
 		zap.Int("thread_id", threadID),
@@ -1038,6 +1106,7 @@ func (m *ReactionsModule) handleRemoveReaction(c telebot.Context) error {
 	if rows == 0 {
 		return c.Send("ℹ️ Реакция не найдена")
 	}
+	m.invalidateRules(chatID)
 
 	// Логируем событие
 	_ = m.eventRepo.Log(chatID, c.Sender().ID, "reactions", "remove_reaction",
@@ -0,0 +1,178 @@
+package reactions
+
+// Этот файл содержит кэш правил keyword_reactions — часть модуля Reactions.
+// Правила чата (и фильтры /addban, и автоответы /addreaction) читаются из БД
+// один раз, regex компилируются при загрузке. Кэш чата сбрасывается командами,
+// изменяющими правила, на всех экземплярах бота через PostgreSQL NOTIFY.
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flybasist/bmft/internal/postgresql"
+	"go.uber.org/zap"
+)
+
+const (
+	// rulesChannel — канал NOTIFY об изменении keyword_reactions, payload — chat_id.
+	rulesChannel = "bmft_keyword_reactions"
+	// rulesCacheTTL — страховка на случай потерянного уведомления:
+	// правила чата перечитываются не реже этого интервала.
+	rulesCacheTTL = 10 * time.Minute
+)
+
+// rule — правило keyword_reactions, подготовленное для проверки сообщений.
+type rule struct {
+	KeywordReaction
+	re    *regexp.Regexp // скомпилированный Pattern при IsRegex
+	lower string         // Pattern в нижнем регистре при !IsRegex
+}
+
+// matches проверяет текст сообщения; lowerText — тот же текст в нижнем регистре.
+func (r *rule) matches(text, lowerText string) bool {
+	if r.re != nil {
+		return r.re.MatchString(text)
+	}
+	return strings.Contains(lowerText, r.lower)
+}
+
+// chatRules — правила чата во всех топиках, в порядке проверки.
+type chatRules struct {
+	rules     []rule
+	fetchedAt time.Time
+}
+
+// subscribeRules подписывает кэш на уведомления об изменении правил.
+func (m *ReactionsModule) subscribeRules() {
+	if m.listener == nil {
+		return
+	}
+	m.listener.Subscribe(rulesChannel, func(payload string) {
+		if payload == "" {
+			// Соединение восстановлено — уведомления могли потеряться
+			m.rulesMu.Lock()
+			m.rules = make(map[int64]*chatRules)
+			m.rulesGen++
+			m.rulesMu.Unlock()
+			return
+		}
+		chatID, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			m.logger.Warn("invalid keyword_reactions notification", zap.String("payload", payload))
+			return
+		}
+		m.dropRules(chatID)
+	})
+}
+
+// invalidateRules сбрасывает кэш правил чата на этом и остальных экземплярах бота.
+// Вызывается после изменения keyword_reactions.
+func (m *ReactionsModule) invalidateRules(chatID int64) {
+	m.dropRules(chatID)
+	if err := postgresql.Notify(m.db, rulesChannel, strconv.FormatInt(chatID, 10)); err != nil {
+		m.logger.Error("failed to notify keyword_reactions change", zap.Int64("chat_id", chatID), zap.Error(err))
+	}
+}
+
+// dropRules удаляет правила чата из кэша этого экземпляра.
+func (m *ReactionsModule) dropRules(chatID int64) {
+	m.rulesMu.Lock()
+	delete(m.rules, chatID)
+	m.rulesGen++
+	m.rulesMu.Unlock()
+}
+
+// loadReactions возвращает правила, действующие для пользователя в топике.
+// Порядок (приоритет сверху вниз):
+// 1. Фильтры (action IS NOT NULL) раньше автоответов
+// 2. Персональные реакции (user_id) раньше общих
+// 3. Правила топика раньше правил всего чата
+func (m *ReactionsModule) loadReactions(chatID int64, threadID int, userID int64) ([]rule, error) {
+	all, err := m.chatRules(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []rule
+	for _, r := range all {
+		if (r.ThreadID == int64(threadID) || r.ThreadID == 0) && (r.UserID == 0 || r.UserID == userID) {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// chatRules возвращает правила чата из кэша или из БД.
+func (m *ReactionsModule) chatRules(chatID int64) ([]rule, error) {
+	m.rulesMu.RLock()
+	entry, exists := m.rules[chatID]
+	gen := m.rulesGen
+	m.rulesMu.RUnlock()
+	if exists && time.Since(entry.fetchedAt) < rulesCacheTTL {
+		return entry.rules, nil
+	}
+
+	rows, err := m.db.Query(`
+		SELECT id, chat_id, thread_id, COALESCE(user_id, 0), pattern, response_type, response_content, description, COALESCE(trigger_content_type, ''), is_regex, cooldown, daily_limit, delete_on_limit, COALESCE(action, ''), is_active, shadow
+		FROM keyword_reactions
+		WHERE chat_id = $1
+		  AND is_active = true
+		ORDER BY
+		  CASE WHEN action IS NOT NULL THEN 0 ELSE 1 END,  -- Фильтры в приоритете
+		  CASE WHEN user_id IS NOT NULL THEN 0 ELSE 1 END,  -- Персональные реакции в приоритете
+		  thread_id DESC,  -- Топик приоритетнее чата
+		  id
+	`, chatID)
+	if err != nil {
+		m.logger.Error("loadReactions query failed", zap.Error(err), zap.Int64("chat_id", chatID))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []rule
+	for rows.Next() {
+		var r rule
+		if err := rows.Scan(&r.ID, &r.ChatID, &r.ThreadID, &r.UserID, &r.Pattern, &r.ResponseType, &r.ResponseContent, &r.Description, &r.TriggerContentType, &r.IsRegex, &r.Cooldown, &r.DailyLimit, &r.DeleteOnLimit, &r.Action, &r.IsActive, &r.Shadow); err != nil {
+			m.logger.Error("failed to scan reaction", zap.Error(err))
+			continue
+		}
+		if r.IsRegex {
+			// Команды не сохраняют некорректные regex; такие правила могли
+			// остаться от старых версий — пропускаем, лог один раз на загрузку
+			r.re, err = regexp.Compile(r.Pattern)
+			if err != nil {
+				m.logger.Warn("skipping reaction with invalid regex",
+					zap.Int64("chat_id", chatID), zap.Int64("reaction_id", r.ID),
+					zap.String("pattern", r.Pattern), zap.Error(err))
+				continue
+			}
+		} else {
+			r.lower = strings.ToLower(r.Pattern)
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Сброс во время чтения — прочитанные правила могли устареть, не кэшируем
+	m.rulesMu.Lock()
+	if gen == m.rulesGen {
+		m.rules[chatID] = &chatRules{rules: rules, fetchedAt: time.Now()}
+	}
+	m.rulesMu.Unlock()
+
+	m.logger.Debug("keyword_reactions loaded", zap.Int64("chat_id", chatID), zap.Int("count", len(rules)))
+	return rules, nil
+}
+
+// replyMatches проверяет паттерн автоответа.
+// Персональная реакция с пустым паттерном срабатывает на любой контент своего пользователя.
+func (r *rule) replyMatches(userID int64, text, lowerText string) bool {
+	if r.Pattern == "" && r.UserID > 0 && r.UserID == userID {
+		return true
+	}
+	return text != "" && r.matches(text, lowerText)
+}
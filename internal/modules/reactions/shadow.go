@@ -0,0 +1,100 @@
+package reactions
+
+// Этот файл содержит теневой режим — часть модуля Reactions.
+// Правило с shadow = true проверяется как обычно, но вместо действия
+// срабатывание записывается; /shadowreport показывает, что сработало бы.
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"time"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+)
+
+const (
+	shadowReportDefaultDays = 7
+	shadowReportMaxDays     = 90
+)
+
+// recordShadow записывает срабатывание правила в теневом режиме.
+func (m *ReactionsModule) recordShadow(ctx *core.MessageContext, rule string, ruleID int64, pattern, action string) {
+	m.logger.Info("shadow rule hit",
+		zap.Int64("chat_id", ctx.Chat.ID),
+		zap.Int64("user_id", ctx.Sender.ID),
+		zap.String("rule", rule),
+		zap.Int64("rule_id", ruleID),
+		zap.String("pattern", pattern),
+		zap.String("action", action),
+	)
+	hit := repositories.ShadowHit{
+		ChatID:    ctx.Chat.ID,
+		UserID:    ctx.Sender.ID,
+		Module:    "reactions",
+		ThreadID:  ctx.ThreadID,
+		MessageID: ctx.Message.ID,
+		Rule:      rule,
+		RuleID:    ruleID,
+		Pattern:   pattern,
+		Action:    action,
+	}
+	if err := m.shadowRepo.Record(hit); err != nil {
+		m.logger.Error("failed to record shadow hit", zap.Error(err))
+	}
+}
+
+// handleShadowReport обрабатывает /shadowreport [дней] — срабатывания теневого режима в чате.
+func (m *ReactionsModule) handleShadowReport(c telebot.Context) error {
+	chatID := c.Chat().ID
+
+	days := shadowReportDefaultDays
+	if args := c.Args(); len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > shadowReportMaxDays {
+			return c.Send(fmt.Sprintf("Использование: /shadowreport [дней]\nПериод от 1 до %d дней, по умолчанию %d", shadowReportMaxDays, shadowReportDefaultDays))
+		}
+		days = n
+	}
+
+	report, err := m.shadowRepo.Report(chatID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		m.logger.Error("failed to get shadow report", zap.Error(err))
+		return c.Send("❌ Ошибка при получении отчёта")
+	}
+	if len(report) == 0 {
+		return c.Send(fmt.Sprintf("👻 За %d дн. правила в теневом режиме не срабатывали\n\n"+
+			"Теневой режим: /addban ... shadow, /banshadow, /setprofanity shadow on, /setlimit shadow on", days))
+	}
+
+	lines := []string{fmt.Sprintf("👻 <b>Теневой режим за %d дн.</b> — что сработало бы", days), ""}
+	for _, row := range report {
+		lines = append(lines, fmt.Sprintf("%s <code>%s</code> → %s\n   %d раз, %d польз., последний %s",
+			shadowRuleName(row), html.EscapeString(truncateRunes(row.Pattern, 100)), row.Action,
+			row.Hits, row.Users, row.LastHit.Format("02.01.2006 15:04")))
+	}
+
+	for _, part := range splitIntoMessages(lines, 3500) {
+		if err := c.Send(part, &telebot.SendOptions{ParseMode: telebot.ModeHTML}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shadowRuleName возвращает название правила для отчёта.
+func shadowRuleName(row repositories.ShadowReportRow) string {
+	switch row.Rule {
+	case repositories.ShadowRuleBannedWord:
+		return fmt.Sprintf("🚫 Запрет #%d", row.RuleID)
+	case repositories.ShadowRuleProfanity:
+		return "🤬 Мат"
+	case repositories.ShadowRuleLimit:
+		return "📊 Лимит"
+	default:
+		return row.Module + "/" + row.Rule
+	}
+}
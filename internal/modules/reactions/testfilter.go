@@ -0,0 +1,220 @@
+package reactions
+
+// Этот файл содержит /testfilter — пробный прогон фильтров и автоответов.
+// Проверка идёт теми же функциями, что и pipeline, но без удаления сообщений,
+// счётчиков, предупреждений и ответов в чат.
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/profanity"
+	"go.uber.org/zap"
+	"gopkg.in/telebot.v3"
+)
+
+// testFilterPreviewLen — сколько символов проверяемого текста показать в отчёте.
+const testFilterPreviewLen = 200
+
+// shadowTestNote — пометка правила в теневом режиме: оно не останавливает проверку.
+const shadowTestNote = "👻 теневой режим — только запись в /shadowreport"
+
+// handleTestFilter обрабатывает /testfilter <текст> или /testfilter в ответ на сообщение.
+// Отчёт: какие правила совпали, какое сработает и почему пропущены остальные.
+func (m *ReactionsModule) handleTestFilter(c telebot.Context) error {
+	chatID := c.Chat().ID
+	threadID := core.GetThreadID(m.db, c)
+
+	// Проверяемое сообщение: текст команды от имени админа или сообщение, на которое ответили.
+	// В форуме ReplyTo без явного ответа указывает на создание топика — его не проверяем.
+	target := &telebot.Message{Text: c.Message().Payload}
+	user := c.Sender()
+	reply := c.Message().ReplyTo
+	if target.Text == "" && reply != nil && reply.Sender != nil && reply.TopicCreated == nil {
+		target, user = reply, reply.Sender
+	}
+	textToCheck := getTextForMatching(target)
+	if textToCheck == "" && target != reply {
+		return c.Send("Использование: /testfilter <текст>\nИли ответьте командой /testfilter на сообщение\n\nНичего не удаляется и не отправляется — только отчёт о правилах.")
+	}
+
+	lines := []string{"🧪 <b>Проверка фильтров</b> — ничего не удалено, счётчики не изменены", ""}
+	lines = append(lines, "👤 Пользователь: "+html.EscapeString(core.DisplayName(user)))
+	if textToCheck != "" {
+		lines = append(lines, "💬 Текст: <code>"+html.EscapeString(truncateRunes(textToCheck, testFilterPreviewLen))+"</code>")
+	}
+
+	isVIP, err := m.vipRepo.IsVIP(chatID, threadID, user.ID)
+	if err != nil {
+		m.logger.Error("failed to check vip", zap.Error(err))
+	}
+	if isVIP {
+		lines = append(lines, "⭐ <b>VIP</b> — в чате фильтры и автоответы к нему не применяются. Ниже — что было бы без VIP")
+	}
+
+	// winner — первое сработавшее правило: после него pipeline останавливается
+	winner := ""
+
+	// ─── Фильтр мата ───
+	lines = append(lines, "", "<b>1. Фильтр мата</b>")
+	profanityLine, fired := m.testProfanity(chatID, threadID, textToCheck)
+	lines = append(lines, profanityLine)
+	if fired != "" {
+		winner = fired
+	}
+
+	reactions, err := m.loadReactions(chatID, threadID, user.ID)
+	if err != nil {
+		m.logger.Error("failed to load reactions", zap.Error(err))
+		return c.Send("❌ Ошибка при загрузке правил")
+	}
+	lowerText := strings.ToLower(textToCheck)
+
+	// ─── Запрещённые слова ───
+	lines = append(lines, "", "<b>2. Запрещённые слова</b>")
+	matched := false
+	for _, r := range reactions {
+		if r.Action == "" || textToCheck == "" || !r.matches(textToCheck, lowerText) {
+			continue
+		}
+		matched = true
+		line := fmt.Sprintf("#%d <code>%s</code> → %s: ", r.ID, html.EscapeString(r.Pattern), r.Action)
+		if r.Shadow {
+			line += shadowTestNote
+		} else if winner != "" {
+			line += "⏭ не проверяется — раньше сработал " + winner
+		} else {
+			winner = fmt.Sprintf("запрет #%d", r.ID)
+			line += "🚫 <b>сработает</b>"
+		}
+		lines = append(lines, line)
+	}
+	if !matched {
+		lines = append(lines, "✅ Совпадений нет")
+	}
+
+	// ─── Автоответы ───
+	lines = append(lines, "", "<b>3. Автоответы</b>")
+	matched = false
+	for _, r := range reactions {
+		if r.Action != "" || !r.replyMatches(user.ID, textToCheck, lowerText) {
+			continue
+		}
+		matched = true
+		pattern := r.Pattern
+		if pattern == "" {
+			pattern = "(любое сообщение пользователя)"
+		}
+		line := fmt.Sprintf("#%d <code>%s</code> → %s: ", r.ID, html.EscapeString(pattern), r.ResponseType)
+		if skip := m.testReplySkipReason(chatID, target, r, winner); skip != "" {
+			line += "⏭ " + skip
+		} else {
+			winner = fmt.Sprintf("автоответ #%d", r.ID)
+			line += "💬 <b>сработает</b>"
+		}
+		lines = append(lines, line)
+	}
+	if !matched {
+		lines = append(lines, "✅ Совпадений нет")
+	}
+
+	lines = append(lines, "")
+	switch {
+	case isVIP:
+		lines = append(lines, "🏁 <b>Итог:</b> ничего — пользователь VIP")
+	case winner == "":
+		lines = append(lines, "🏁 <b>Итог:</b> ни одно правило не сработает")
+	default:
+		lines = append(lines, "🏁 <b>Итог:</b> сработает "+winner)
+	}
+
+	for _, part := range splitIntoMessages(lines, 3500) {
+		if err := c.Send(part, &telebot.SendOptions{ParseMode: telebot.ModeHTML}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// testProfanity проверяет текст фильтром мата. Возвращает строку отчёта
+// и название сработавшего правила ("" — не сработал).
+func (m *ReactionsModule) testProfanity(chatID int64, threadID int, text string) (string, string) {
+	settings, err := m.loadProfanitySettings(chatID, threadID)
+	if err != nil {
+		m.logger.Error("failed to load profanity settings", zap.Error(err))
+		return "❌ Ошибка при загрузке настроек", ""
+	}
+	if settings == nil {
+		return "⚪ Выключен (/setprofanity)", ""
+	}
+	if text == "" {
+		return "✅ Текста нет — не проверяется", ""
+	}
+
+	match, found, err := m.findProfanity(chatID, text, settings.MinSeverity)
+	if err != nil {
+		m.logger.Error("failed to load profanity dictionary", zap.Error(err))
+		return "❌ Ошибка при загрузке словаря", ""
+	}
+	if found {
+		line := fmt.Sprintf("🚫 <code>%s</code> (%s) → %s: ",
+			html.EscapeString(match.Pattern), match.Severity, settings.ActionFor(match.Severity))
+		if settings.Shadow {
+			return line + shadowTestNote, ""
+		}
+		return line + "<b>сработает</b>", "фильтр мата"
+	}
+
+	// Слова ниже порога чата не срабатывают — но админу полезно знать, что они есть
+	if settings.MinSeverity != profanity.SeverityMild {
+		if below, ok, _ := m.findProfanity(chatID, text, profanity.SeverityMild); ok {
+			return fmt.Sprintf("⏭ <code>%s</code> (%s): ниже порога чата %s",
+				html.EscapeString(below.Pattern), below.Severity, settings.MinSeverity), ""
+		}
+	}
+	return "✅ Мата не найдено", ""
+}
+
+// testReplySkipReason возвращает причину, по которой автоответ не сработает ("" — сработает).
+// Проверки те же, что в OnMessage, но только на чтение.
+func (m *ReactionsModule) testReplySkipReason(chatID int64, msg *telebot.Message, r rule, winner string) string {
+	if winner != "" {
+		return "не проверяется — раньше сработал " + winner
+	}
+	if !contentTypeMatches(msg, r.TriggerContentType) {
+		return "только для контента " + r.TriggerContentType
+	}
+	if r.Cooldown > 0 {
+		lastTriggered, err := m.getLastTriggered(chatID, r.ID)
+		if left := time.Duration(r.Cooldown)*time.Second - time.Since(lastTriggered); err == nil && left > 0 {
+			return fmt.Sprintf("кулдаун, осталось %d сек", int(left.Seconds())+1)
+		}
+	}
+	if r.DailyLimit > 0 {
+		count, err := m.getDailyCount(chatID, r.ID, r.UserID)
+		if err != nil {
+			m.logger.Error("failed to get daily count", zap.Error(err))
+			return "ошибка чтения дневного лимита"
+		}
+		if count >= r.DailyLimit {
+			reason := fmt.Sprintf("дневной лимит исчерпан (%d/%d)", count, r.DailyLimit)
+			if r.DeleteOnLimit {
+				reason += ", сообщение было бы удалено"
+			}
+			return reason
+		}
+	}
+	return ""
+}
+
+// truncateRunes обрезает строку до n символов.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
@@ -0,0 +1,183 @@
+package scheduler
+
+// Этот файл содержит действия планировщика над состоянием чата (ночной режим):
+// lock / unlock — права участников только на чтение и обратно,
+// close_topic / reopen_topic — закрыть и открыть топик форума,
+// slowmode <сек> — slow mode через антифлуд (0 — выключить).
+// Перед изменением исходное состояние сохраняется в chat_state_snapshots
+// и восстанавливается именно оно, а не права «по умолчанию».
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	tele "gopkg.in/telebot.v3"
+
+	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/postgresql/repositories"
+)
+
+// maxSlowMode — максимальная задержка slow mode (как окно /setflood).
+const maxSlowMode = 300
+
+// stateActions — действия над состоянием чата: тип задачи → нужны ли данные.
+var stateActions = map[string]bool{
+	"lock":         false,
+	"unlock":       false,
+	"close_topic":  false,
+	"reopen_topic": false,
+	"slowmode":     true,
+}
+
+// validateStateAction проверяет задачу-действие до сохранения.
+// Возвращает текст ошибки для пользователя или пустую строку.
+func (m *SchedulerModule) validateStateAction(chatID int64, threadID int, taskType, taskData string) string {
+	switch taskType {
+	case "lock", "unlock":
+		if threadID != 0 {
+			return "❌ Права участников общие для всего чата — создайте задачу " + taskType + " в основном чате.\nДля топика используйте close_topic / reopen_topic"
+		}
+	case "close_topic", "reopen_topic":
+		if !core.CheckIsForum(m.bot, chatID) {
+			return "❌ " + taskType + " работает только в форуме (чате с топиками)"
+		}
+	case "slowmode":
+		delay, err := strconv.Atoi(taskData)
+		if err != nil || delay < 0 || delay > maxSlowMode {
+			return fmt.Sprintf("❌ slowmode — задержка в секундах от 1 до %d (0 — выключить)", maxSlowMode)
+		}
+	}
+	return ""
+}
+
+// executeStateAction выполняет действие над состоянием чата.
+func (m *SchedulerModule) executeStateAction(task *repositories.ScheduledTask) error {
+	chat := &tele.Chat{ID: task.ChatID}
+	threadID := int(task.ThreadID)
+
+	switch task.TaskType {
+	case "lock":
+		return m.lockChat(task.ChatID)
+	case "unlock":
+		return m.unlockChat(task.ChatID)
+	case "close_topic":
+		if threadID == 0 {
+			return m.bot.CloseGeneralTopic(chat)
+		}
+		return m.bot.CloseTopic(chat, &tele.Topic{ThreadID: threadID})
+	case "reopen_topic":
+		if threadID == 0 {
+			return m.bot.ReopenGeneralTopic(chat)
+		}
+		return m.bot.ReopenTopic(chat, &tele.Topic{ThreadID: threadID})
+	case "slowmode":
+		delay, err := strconv.Atoi(task.TaskData)
+		if err != nil {
+			return fmt.Errorf("invalid slow mode delay %q: %w", task.TaskData, err)
+		}
+		return m.slowModer.SetSlowMode(task.ChatID, threadID, time.Duration(delay)*time.Second)
+	default:
+		return fmt.Errorf("unknown state action %q", task.TaskType)
+	}
+}
+
+// chatStateStore — снимки состояния чата (repositories.ChatStateRepository).
+// SaveSnapshot не перезаписывает существующий снимок и возвращает false.
+type chatStateStore interface {
+	SaveSnapshot(chatID int64, threadID int, kind string, data []byte) (bool, error)
+	GetSnapshot(chatID int64, threadID int, kind string) (data []byte, found bool, err error)
+	DeleteSnapshot(chatID int64, threadID int, kind string) error
+}
+
+// permissionsSetter меняет права участников чата (*tele.Bot).
+type permissionsSetter interface {
+	SetGroupPermissions(chat *tele.Chat, perms tele.Rights) error
+}
+
+// lockChat оставляет участникам чата только чтение. Текущие права сохраняются
+// снимком; если чат уже закрыт планировщиком, снимок не перезаписывается —
+// иначе unlock «восстановил» бы режим только для чтения.
+func (m *SchedulerModule) lockChat(chatID int64) error {
+	chat, err := m.bot.ChatByID(chatID)
+	if err != nil {
+		return fmt.Errorf("get chat: %w", err)
+	}
+	if chat.Permissions == nil {
+		return errors.New("chat permissions are not available")
+	}
+
+	saved, err := lockPermissions(m.chatStateRepo, m.bot, chat)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		m.logger.Info("chat is already locked, keeping saved permissions", zap.Int64("chat_id", chatID))
+	}
+	return nil
+}
+
+// unlockChat возвращает права, сохранённые при lock. Без снимка права не меняются:
+// чат закрыли не по расписанию, и открывать его планировщик не должен.
+func (m *SchedulerModule) unlockChat(chatID int64) error {
+	restored, err := unlockPermissions(m.chatStateRepo, m.bot, chatID)
+	if err != nil {
+		return err
+	}
+	if !restored {
+		m.logger.Info("chat is not locked by scheduler, nothing to restore", zap.Int64("chat_id", chatID))
+	}
+	return nil
+}
+
+// lockPermissions сохраняет права чата снимком (если снимка ещё нет) и оставляет
+// участникам только чтение. saved = false — снимок уже был, он не изменился.
+func lockPermissions(store chatStateStore, setter permissionsSetter, chat *tele.Chat) (saved bool, err error) {
+	data, err := json.Marshal(chat.Permissions)
+	if err != nil {
+		return false, fmt.Errorf("marshal chat permissions: %w", err)
+	}
+	saved, err = store.SaveSnapshot(chat.ID, 0, repositories.SnapshotPermissions, data)
+	if err != nil {
+		return false, err
+	}
+	return saved, setter.SetGroupPermissions(chat, readOnlyRights())
+}
+
+// unlockPermissions восстанавливает права из снимка и удаляет снимок.
+// restored = false — снимка нет, права не менялись.
+func unlockPermissions(store chatStateStore, setter permissionsSetter, chatID int64) (restored bool, err error) {
+	data, found, err := store.GetSnapshot(chatID, 0, repositories.SnapshotPermissions)
+	if err != nil || !found {
+		return false, err
+	}
+	rights, err := snapshotRights(data)
+	if err != nil {
+		return false, err
+	}
+	if err := setter.SetGroupPermissions(&tele.Chat{ID: chatID}, rights); err != nil {
+		return false, err
+	}
+	return true, store.DeleteSnapshot(chatID, 0, repositories.SnapshotPermissions)
+}
+
+// readOnlyRights — права участников при lock: только чтение.
+// Independent: права задаются по отдельности, без вывода одних из других.
+func readOnlyRights() tele.Rights {
+	rights := tele.NoRights()
+	rights.Independent = true
+	return rights
+}
+
+// snapshotRights — права участников из снимка, сохранённого при lock.
+func snapshotRights(data []byte) (tele.Rights, error) {
+	var rights tele.Rights
+	if err := json.Unmarshal(data, &rights); err != nil {
+		return tele.Rights{}, fmt.Errorf("unmarshal chat permissions: %w", err)
+	}
+	rights.Independent = true
+	return rights, nil
+}
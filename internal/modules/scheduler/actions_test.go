@@ -0,0 +1,141 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+// fakeStateStore — снимки в памяти с той же семантикой, что chat_state_snapshots:
+// SaveSnapshot не перезаписывает существующий снимок.
+type fakeStateStore struct {
+	snapshots map[string][]byte
+}
+
+func (s *fakeStateStore) SaveSnapshot(chatID int64, threadID int, kind string, data []byte) (bool, error) {
+	if _, ok := s.snapshots[kind]; ok {
+		return false, nil
+	}
+	s.snapshots[kind] = data
+	return true, nil
+}
+
+func (s *fakeStateStore) GetSnapshot(chatID int64, threadID int, kind string) ([]byte, bool, error) {
+	data, ok := s.snapshots[kind]
+	return data, ok, nil
+}
+
+func (s *fakeStateStore) DeleteSnapshot(chatID int64, threadID int, kind string) error {
+	delete(s.snapshots, kind)
+	return nil
+}
+
+// fakeSetter запоминает последние выставленные права.
+type fakeSetter struct {
+	calls int
+	last  tele.Rights
+	err   error
+}
+
+func (s *fakeSetter) SetGroupPermissions(chat *tele.Chat, perms tele.Rights) error {
+	s.calls++
+	s.last = perms
+	return s.err
+}
+
+// TestReadOnlyRights проверяет права при lock: ничего отправлять нельзя
+func TestReadOnlyRights(t *testing.T) {
+	rights := readOnlyRights()
+	if rights.CanSendMessages || rights.CanSendPhotos || rights.CanSendOther || rights.CanSendPolls || rights.CanAddPreviews {
+		t.Errorf("readOnlyRights() = %+v, want no send rights", rights)
+	}
+	if !rights.Independent {
+		t.Error("readOnlyRights().Independent = false, want true")
+	}
+}
+
+// TestLockUnlock проверяет цикл lock → lock → unlock: повторный lock не перезаписывает
+// снимок, unlock возвращает исходные права и удаляет снимок
+func TestLockUnlock(t *testing.T) {
+	store := &fakeStateStore{snapshots: make(map[string][]byte)}
+	setter := &fakeSetter{}
+	original := tele.Rights{CanSendMessages: true, CanSendPhotos: true, CanInviteUsers: true}
+	chat := &tele.Chat{ID: -100, Permissions: &original}
+
+	saved, err := lockPermissions(store, setter, chat)
+	if err != nil || !saved {
+		t.Fatalf("lockPermissions() = (%t, %v), want (true, nil)", saved, err)
+	}
+	if setter.last != readOnlyRights() {
+		t.Errorf("lock: права = %+v, want read-only", setter.last)
+	}
+
+	// Чат уже закрыт: текущие права — только чтение, снимок должен остаться исходным
+	readOnly := readOnlyRights()
+	chat.Permissions = &readOnly
+	saved, err = lockPermissions(store, setter, chat)
+	if err != nil || saved {
+		t.Fatalf("повторный lockPermissions() = (%t, %v), want (false, nil)", saved, err)
+	}
+
+	restored, err := unlockPermissions(store, setter, chat.ID)
+	if err != nil || !restored {
+		t.Fatalf("unlockPermissions() = (%t, %v), want (true, nil)", restored, err)
+	}
+	want := original
+	want.Independent = true
+	if setter.last != want {
+		t.Errorf("unlock: права = %+v, want %+v", setter.last, want)
+	}
+	if len(store.snapshots) != 0 {
+		t.Errorf("после unlock осталось снимков: %d", len(store.snapshots))
+	}
+}
+
+// TestUnlockWithoutSnapshot проверяет, что без снимка unlock права не трогает:
+// чат закрыли не по расписанию
+func TestUnlockWithoutSnapshot(t *testing.T) {
+	store := &fakeStateStore{snapshots: make(map[string][]byte)}
+	setter := &fakeSetter{}
+
+	restored, err := unlockPermissions(store, setter, -100)
+	if err != nil || restored {
+		t.Errorf("unlockPermissions() = (%t, %v), want (false, nil)", restored, err)
+	}
+	if setter.calls != 0 {
+		t.Errorf("SetGroupPermissions вызван %d раз, want 0", setter.calls)
+	}
+}
+
+// TestUnlockKeepsSnapshotOnError проверяет, что снимок остаётся, если права
+// не удалось вернуть: следующий unlock повторит попытку
+func TestUnlockKeepsSnapshotOnError(t *testing.T) {
+	store := &fakeStateStore{snapshots: make(map[string][]byte)}
+	setter := &fakeSetter{}
+	if _, err := lockPermissions(store, setter, &tele.Chat{ID: -100, Permissions: &tele.Rights{CanSendMessages: true}}); err != nil {
+		t.Fatalf("lockPermissions(): %v", err)
+	}
+
+	setter.err = errors.New("not enough rights")
+	if restored, err := unlockPermissions(store, setter, -100); err == nil || restored {
+		t.Errorf("unlockPermissions() = (%t, %v), want error", restored, err)
+	}
+	if len(store.snapshots) != 1 {
+		t.Errorf("снимков после неудачного unlock: %d, want 1", len(store.snapshots))
+	}
+}
+
+// TestSnapshotRights проверяет разбор снимка прав
+func TestSnapshotRights(t *testing.T) {
+	rights, err := snapshotRights([]byte(`{"can_send_messages":true,"can_send_polls":false}`))
+	if err != nil {
+		t.Fatalf("snapshotRights(): %v", err)
+	}
+	if !rights.CanSendMessages || rights.CanSendPolls || !rights.Independent {
+		t.Errorf("snapshotRights() = %+v", rights)
+	}
+	if _, err := snapshotRights([]byte("not json")); err == nil {
+		t.Error("snapshotRights(not json): want error")
+	}
+}
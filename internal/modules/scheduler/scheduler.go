@@ -2,10 +2,12 @@ package scheduler
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -13,27 +15,35 @@ import (
 	tele "gopkg.in/telebot.v3"
 
 	"github.com/flybasist/bmft/internal/core"
+	"github.com/flybasist/bmft/internal/metrics"
 	"github.com/flybasist/bmft/internal/postgresql/repositories"
 )
 
 // SchedulerModule реализует модуль планировщика задач.
+// Кроме отправки сообщений задачи умеют менять состояние чата
+// (lock/unlock, close_topic/reopen_topic, slowmode) — см. actions.go.
 type SchedulerModule struct {
 	db            *sql.DB
 	bot           *tele.Bot
 	logger        *zap.Logger
 	schedulerRepo *repositories.SchedulerRepository
+	chatStateRepo *repositories.ChatStateRepository
 	eventRepo     *repositories.EventRepository
+	slowModer     core.SlowModer // slow mode реализован в antiflood
 	cron          *cron.Cron
 	taskEntries   map[int64]cron.EntryID // task DB ID → cron entry ID
 	mu            sync.Mutex             // защита taskEntries
+	running       atomic.Bool            // cron запущен (для /readyz)
 }
 
 // New создаёт новый инстанс модуля планировщика.
-func New(db *sql.DB, schedulerRepo *repositories.SchedulerRepository, eventRepo *repositories.EventRepository, logger *zap.Logger, bot *tele.Bot) *SchedulerModule {
+func New(db *sql.DB, schedulerRepo *repositories.SchedulerRepository, chatStateRepo *repositories.ChatStateRepository, eventRepo *repositories.EventRepository, slowModer core.SlowModer, logger *zap.Logger, bot *tele.Bot) *SchedulerModule {
 	m := &SchedulerModule{
 		db:            db,
 		schedulerRepo: schedulerRepo,
+		chatStateRepo: chatStateRepo,
 		eventRepo:     eventRepo,
+		slowModer:     slowModer,
 		logger:        logger,
 		bot:           bot,
 		cron:          cron.New(),
@@ -44,6 +54,15 @@ func New(db *sql.DB, schedulerRepo *repositories.SchedulerRepository, eventRepo
 	return m
 }
 
+// Name возвращает имя модуля.
+func (m *SchedulerModule) Name() string { return "scheduler" }
+
+// Priority — scheduler работает по cron и не участвует в pipeline.
+func (m *SchedulerModule) Priority() int { return core.PriorityNone }
+
+// OnMessage не вызывается (Priority = PriorityNone), нужен для core.Module.
+func (m *SchedulerModule) OnMessage(ctx *core.MessageContext) error { return nil }
+
 // Start запускает планировщик задач.
 // Явный метод для управления жизненным циклом.
 // Загружает активные задачи из БД и запускает cron scheduler.
@@ -58,6 +77,7 @@ func (m *SchedulerModule) Start() error {
 
 	// Запускаем cron scheduler
 	m.cron.Start()
+	m.running.Store(true)
 	m.logger.Info("cron scheduler started successfully")
 
 	return nil
@@ -66,17 +86,26 @@ func (m *SchedulerModule) Start() error {
 // Shutdown выполняет graceful shutdown модуля.
 func (m *SchedulerModule) Shutdown() error {
 	m.logger.Info("shutting down scheduler module")
+	m.running.Store(false)
 	ctx := m.cron.Stop()
 	<-ctx.Done()
 	m.logger.Info("cron scheduler stopped")
 	return nil
 }
 
+// HealthCheck сообщает /readyz, запущен ли cron планировщика.
+func (m *SchedulerModule) HealthCheck() error {
+	if !m.running.Load() {
+		return errors.New("scheduler cron is not running")
+	}
+	return nil
+}
+
 func (m *SchedulerModule) RegisterCommands(bot *tele.Bot) {
 	// /scheduler — справка по модулю
 	bot.Handle("/scheduler", func(c tele.Context) error {
 		msg := "⏰ <b>Модуль Scheduler</b> — Запланированные задачи\n\n"
-		msg += "Автоматическая отправка сообщений по расписанию (cron).\n\n"
+		msg += "Автоматическая отправка сообщений и ночной режим по расписанию (cron).\n\n"
 		msg += "<b>Доступные команды:</b>\n\n"
 
 		msg += "🔹 <code>/addtask</code> — Добавить задачу (только админы)\n\n"
@@ -92,6 +121,16 @@ func (m *SchedulerModule) RegisterCommands(bot *tele.Bot) {
 		msg += "📌 Пример:\n"
 		msg += "<code>/addtask стикер \"0 9 * * 1\"</code> (reply на стикер)\n\n"
 
+		msg += "<b>Способ 3 - Ночной режим:</b>\n"
+		msg += "<code>/addtask &lt;имя&gt; \"&lt;cron&gt;\" &lt;действие&gt;</code>\n"
+		msg += "• <code>lock</code> / <code>unlock</code> — чат только для чтения и обратно (в основном чате)\n"
+		msg += "• <code>close_topic</code> / <code>reopen_topic</code> — закрыть и открыть топик\n"
+		msg += "• <code>slowmode &lt;сек&gt;</code> — slow mode, <code>slowmode 0</code> — выключить\n"
+		msg += "📌 Пример (тишина с 23:00 до 07:00):\n"
+		msg += "<code>/addtask ночь \"0 23 * * *\" lock</code>\n"
+		msg += "<code>/addtask утро \"0 7 * * *\" unlock</code>\n"
+		msg += "При открытии возвращаются права, которые были до закрытия.\n\n"
+
 		msg += "🔹 <code>/listtasks</code> — Список всех активных задач (только админы)\n\n"
 
 		msg += "🔹 <code>/deltask &lt;ID&gt;</code> — Удалить задачу (только админы)\n"
@@ -189,64 +228,37 @@ func (m *SchedulerModule) executeTask(task *repositories.ScheduledTask) {
 		sendOpts.ThreadID = int(task.ThreadID)
 	}
 
+	metrics.SchedulerExecutionsTotal.WithLabelValues(task.TaskType).Inc()
+
+	var err error
 	switch task.TaskType {
 	case "sticker":
-		sticker := &tele.Sticker{File: tele.File{FileID: task.TaskData}}
-		if _, err := m.bot.Send(chat, sticker, sendOpts); err != nil {
-			m.logger.Error("failed to send sticker", zap.Error(err))
-			return
-		}
-
+		_, err = m.bot.Send(chat, &tele.Sticker{File: tele.File{FileID: task.TaskData}}, sendOpts)
 	case "text":
-		if _, err := m.bot.Send(chat, task.TaskData, sendOpts); err != nil {
-			m.logger.Error("failed to send text", zap.Error(err))
-			return
-		}
-
+		_, err = m.bot.Send(chat, task.TaskData, sendOpts)
 	case "photo":
-		photo := &tele.Photo{File: tele.File{FileID: task.TaskData}}
-		if _, err := m.bot.Send(chat, photo, sendOpts); err != nil {
-			m.logger.Error("failed to send photo", zap.Error(err))
-			return
-		}
-
+		_, err = m.bot.Send(chat, &tele.Photo{File: tele.File{FileID: task.TaskData}}, sendOpts)
 	case "animation":
-		animation := &tele.Animation{File: tele.File{FileID: task.TaskData}}
-		if _, err := m.bot.Send(chat, animation, sendOpts); err != nil {
-			m.logger.Error("failed to send animation", zap.Error(err))
-			return
-		}
-
+		_, err = m.bot.Send(chat, &tele.Animation{File: tele.File{FileID: task.TaskData}}, sendOpts)
 	case "video":
-		video := &tele.Video{File: tele.File{FileID: task.TaskData}}
-		if _, err := m.bot.Send(chat, video, sendOpts); err != nil {
-			m.logger.Error("failed to send video", zap.Error(err))
-			return
-		}
-
+		_, err = m.bot.Send(chat, &tele.Video{File: tele.File{FileID: task.TaskData}}, sendOpts)
 	case "voice":
-		voice := &tele.Voice{File: tele.File{FileID: task.TaskData}}
-		if _, err := m.bot.Send(chat, voice, sendOpts); err != nil {
-			m.logger.Error("failed to send voice", zap.Error(err))
-			return
-		}
-
+		_, err = m.bot.Send(chat, &tele.Voice{File: tele.File{FileID: task.TaskData}}, sendOpts)
 	case "document":
-		document := &tele.Document{File: tele.File{FileID: task.TaskData}}
-		if _, err := m.bot.Send(chat, document, sendOpts); err != nil {
-			m.logger.Error("failed to send document", zap.Error(err))
-			return
-		}
-
+		_, err = m.bot.Send(chat, &tele.Document{File: tele.File{FileID: task.TaskData}}, sendOpts)
 	case "audio":
-		audio := &tele.Audio{File: tele.File{FileID: task.TaskData}}
-		if _, err := m.bot.Send(chat, audio, sendOpts); err != nil {
-			m.logger.Error("failed to send audio", zap.Error(err))
-			return
-		}
-
+		_, err = m.bot.Send(chat, &tele.Audio{File: tele.File{FileID: task.TaskData}}, sendOpts)
+	case "lock", "unlock", "close_topic", "reopen_topic", "slowmode":
+		err = m.executeStateAction(task)
 	default:
-		m.logger.Error("unknown task type", zap.String("task_type", task.TaskType))
+		err = fmt.Errorf("unknown task type %q", task.TaskType)
+	}
+	if err != nil {
+		metrics.SchedulerFailuresTotal.WithLabelValues(task.TaskType).Inc()
+		m.logger.Error("failed to execute scheduled task",
+			zap.Int64("task_id", task.ID),
+			zap.String("task_type", task.TaskType),
+			zap.Error(err))
 		return
 	}
 
@@ -297,7 +309,8 @@ func (m *SchedulerModule) handleListTasks(c tele.Context) error {
 		msg.WriteString(fmt.Sprintf("%d. %s %s\n", i+1, status, task.TaskName))
 		msg.WriteString(fmt.Sprintf("   ID: %d\n", task.ID))
 		msg.WriteString(fmt.Sprintf("   Расписание: %s\n", task.CronExpr))
-		msg.WriteString(fmt.Sprintf("   Тип: %s\n", task.TaskType))
+		// В legacy Markdown «_» открывает курсив: close_topic ломал бы разметку
+		msg.WriteString(fmt.Sprintf("   Тип: %s\n", strings.ReplaceAll(task.TaskType, "_", "\\_")))
 
 		if task.LastRun != nil {
 			msg.WriteString(fmt.Sprintf("   Последний запуск: %s\n", task.LastRun.Format("02.01.2006 15:04")))
@@ -311,6 +324,7 @@ func (m *SchedulerModule) handleListTasks(c tele.Context) error {
 	msg.WriteString("/deltask <id> - удалить задачу\n")
 	msg.WriteString("/runtask <id> - запустить сейчас\n\n")
 	msg.WriteString("Поддерживаемые типы: text, sticker, photo, animation, video, voice, document, audio\n")
+	msg.WriteString("Действия: lock, unlock, close\\_topic, reopen\\_topic, slowmode\n")
 	msg.WriteString("Reply на сообщение для автоматического определения типа")
 
 	return c.Send(msg.String(), &tele.SendOptions{ParseMode: tele.ModeMarkdown})
@@ -480,23 +494,29 @@ func (m *SchedulerModule) handleAddTask(c tele.Context) error {
 		remaining = strings.TrimSpace(remaining)
 
 		parts = strings.SplitN(remaining, " ", 2)
-		if len(parts) < 2 {
+		taskType = parts[0]
+		needsData, isAction := stateActions[taskType]
+		if len(parts) < 2 && (!isAction || needsData) {
 			return c.Send("❌ Неверный формат команды")
 		}
-
-		taskType = parts[0]
-		taskData = strings.Trim(parts[1], "\"")
+		if len(parts) == 2 {
+			taskData = strings.Trim(parts[1], "\"")
+		}
 
 		if _, err := cron.ParseStandard(cronExpr); err != nil {
 			return c.Send(fmt.Sprintf("❌ Неверное cron выражение: %v", err))
 		}
 
-		if taskType != "sticker" && taskType != "text" && taskType != "photo" && taskType != "animation" && taskType != "video" && taskType != "voice" && taskType != "document" && taskType != "audio" {
-			return c.Send("❌ Неверный тип задачи. Доступны: sticker, text, photo, animation, video, voice, document, audio")
+		if !isAction && taskType != "sticker" && taskType != "text" && taskType != "photo" && taskType != "animation" && taskType != "video" && taskType != "voice" && taskType != "document" && taskType != "audio" {
+			return c.Send("❌ Неверный тип задачи. Доступны: sticker, text, photo, animation, video, voice, document, audio\n" +
+				"Действия: lock, unlock, close_topic, reopen_topic, slowmode")
 		}
 
-		chatID := c.Chat().ID
-		threadID := core.GetThreadID(m.db, c)
+		if isAction {
+			if errMsg := m.validateStateAction(chatID, threadID, taskType, taskData); errMsg != "" {
+				return c.Send(errMsg)
+			}
+		}
 
 		taskID, err := m.schedulerRepo.CreateTask(chatID, threadID, name, cronExpr, taskType, taskData)
 		if err != nil {
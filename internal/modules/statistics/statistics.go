@@ -42,6 +42,18 @@ func New(
 	}
 }
 
+// Name возвращает имя модуля.
+func (m *StatisticsModule) Name() string { return "statistics" }
+
+// Priority — statistics всегда первый в pipeline: limiter и reactions считают по messages.
+func (m *StatisticsModule) Priority() int { return core.PriorityStatistics }
+
+// Start — модуль stateless, фоновых задач нет.
+func (m *StatisticsModule) Start() error { return nil }
+
+// Shutdown — модуль stateless, очищать нечего.
+func (m *StatisticsModule) Shutdown() error { return nil }
+
 // OnMessage обрабатывает входящее сообщение.
 // При каждом сообщении инкрементим счётчик в БД.
 func (m *StatisticsModule) OnMessage(ctx *core.MessageContext) error {
@@ -53,6 +65,12 @@ func (m *StatisticsModule) OnMessage(ctx *core.MessageContext) error {
 	// ThreadID уже вычислен в middleware и закеширован — без лишнего SQL-запроса.
 	threadID := ctx.ThreadID
 
+	// Правка: сохраняем новую версию в историю, новую строку в messages не создаём
+	// (иначе limiter посчитал бы правку как ещё одно сообщение)
+	if ctx.IsEdit {
+		return m.recordEdit(ctx, threadID)
+	}
+
 	m.logger.Debug("statistics: received message",
 		zap.Int64("chat_id", ctx.Chat.ID),
 		zap.Int("thread_id", threadID),
@@ -125,6 +143,39 @@ func (m *StatisticsModule) OnMessage(ctx *core.MessageContext) error {
 	return nil
 }
 
+// recordEdit сохраняет отредактированную версию сообщения в message_edits.
+func (m *StatisticsModule) recordEdit(ctx *core.MessageContext, threadID int) error {
+	editedAt := time.Now()
+	if ctx.Message.LastEdit > 0 {
+		editedAt = ctx.Message.LastEdited()
+	}
+
+	err := m.messageRepo.RecordEdit(
+		ctx.Chat.ID,
+		threadID,
+		ctx.Sender.ID,
+		ctx.Message.ID,
+		ctx.Message.Text,
+		ctx.Message.Caption,
+		editedAt,
+	)
+	if err != nil {
+		m.logger.Error("statistics: failed to record message edit",
+			zap.Int64("chat_id", ctx.Chat.ID),
+			zap.Int("thread_id", threadID),
+			zap.Int64("user_id", ctx.Sender.ID),
+			zap.Int("message_id", ctx.Message.ID),
+			zap.Error(err))
+		return err
+	}
+
+	m.logger.Debug("statistics: message edit recorded",
+		zap.Int64("chat_id", ctx.Chat.ID),
+		zap.Int("message_id", ctx.Message.ID),
+	)
+	return nil
+}
+
 // getFileID извлекает file_id из сообщения если есть медиа.
 func (m *StatisticsModule) getFileID(msg *tele.Message) string {
 	if msg.Photo != nil {
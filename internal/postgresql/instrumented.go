@@ -0,0 +1,132 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/flybasist/bmft/internal/metrics"
+)
+
+// Open открывает пул соединений PostgreSQL с метриками длительности запросов.
+// Замена sql.Open("postgres", dsn): репозитории работают с тем же *sql.DB,
+// а каждый Query/Exec попадает в гистограмму bmft_db_query_duration_seconds.
+func Open(dsn string) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&instrumentedConnector{base: connector}), nil
+}
+
+// instrumentedConnector создаёт соединения, обёрнутые instrumentedConn.
+type instrumentedConnector struct {
+	base driver.Connector
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn}, nil
+}
+
+func (c *instrumentedConnector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// instrumentedConn замеряет QueryContext/ExecContext и пробрасывает
+// остальные опциональные интерфейсы драйвера pq без изменений.
+type instrumentedConn struct {
+	driver.Conn
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	observeQuery(query, start, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	observeQuery(query, start, err)
+	return result, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() //nolint:staticcheck // fallback для драйверов без BeginTx
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// observeQuery записывает длительность запроса. driver.ErrSkip не учитывается —
+// database/sql повторит запрос через Prepare.
+func observeQuery(query string, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	metrics.DBQueryDuration.WithLabelValues(queryOperation(query), status).Observe(time.Since(start).Seconds())
+}
+
+// queryOperation возвращает тип SQL-оператора для метки (без текста запроса — иначе кардинальность).
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+	op := strings.ToLower(fields[0])
+	switch op {
+	case "select", "insert", "update", "delete", "with", "create", "drop", "alter", "set", "listen", "notify":
+		return op
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,145 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// listenerPingInterval — как часто проверять соединение LISTEN без уведомлений:
+// pq.Listener не замечает обрыв, пока не попытается что-то отправить.
+const listenerPingInterval = 90 * time.Second
+
+// Listener получает уведомления PostgreSQL (LISTEN/NOTIFY) по отдельному соединению.
+// Через него экземпляры бота сбрасывают кэши друг друга: экземпляр, изменивший
+// данные, вызывает Notify, обработчики канала получают payload на всех экземплярах
+// (включая отправителя).
+// После переподключения обработчики вызываются с payload "" — уведомления за время
+// обрыва потеряны, кэш нужно сбросить целиком.
+// Один Listener делят несколько модулей: у каждого свой канал.
+type Listener struct {
+	pq     *pq.Listener
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]func(payload string) // канал → обработчик
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewListener открывает соединение для LISTEN и запускает приём уведомлений.
+// Соединение восстанавливается автоматически (от 1 секунды до минуты между попытками).
+func NewListener(dsn string, logger *zap.Logger) *Listener {
+	l := &Listener{
+		logger:   logger,
+		handlers: make(map[string]func(payload string)),
+		done:     make(chan struct{}),
+	}
+	l.pq = pq.NewListener(dsn, time.Second, time.Minute, l.onEvent)
+
+	l.wg.Add(1)
+	go l.run()
+	return l
+}
+
+// Subscribe подписывает обработчик на канал. Не блокирует: LISTEN выполняется
+// в фоне, пока соединение не установлено. Обработчик вызывается из горутины
+// Listener и не должен долго блокировать.
+func (l *Listener) Subscribe(channel string, handler func(payload string)) {
+	l.mu.Lock()
+	l.handlers[channel] = handler
+	l.mu.Unlock()
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		err := l.pq.Listen(channel)
+		if err == nil || errors.Is(err, pq.ErrChannelAlreadyOpen) {
+			return
+		}
+		select {
+		case <-l.done: // Close до установки соединения — не ошибка
+		default:
+			l.logger.Error("failed to listen postgres channel", zap.String("channel", channel), zap.Error(err))
+		}
+	}()
+}
+
+// Close закрывает соединение и дожидается остановки приёма уведомлений.
+// Вызывает владелец Listener после остановки всех модулей-подписчиков.
+func (l *Listener) Close() error {
+	close(l.done)
+	err := l.pq.Close()
+	l.wg.Wait()
+	return err
+}
+
+// Notify отправляет уведомление в канал через пул соединений.
+func Notify(db *sql.DB, channel, payload string) error {
+	if _, err := db.Exec(`SELECT pg_notify($1, $2)`, channel, payload); err != nil {
+		return fmt.Errorf("notify %s: %w", channel, err)
+	}
+	return nil
+}
+
+// run раздаёт уведомления обработчикам каналов.
+func (l *Listener) run() {
+	defer l.wg.Done()
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case n, ok := <-l.pq.Notify:
+			if !ok {
+				return
+			}
+			l.dispatch(n)
+		case <-ticker.C:
+			go func() {
+				if err := l.pq.Ping(); err != nil {
+					l.logger.Debug("postgres listener ping failed", zap.Error(err))
+				}
+			}()
+		}
+	}
+}
+
+// dispatch вызывает обработчик канала. n == nil — соединение восстановлено,
+// вызываются все обработчики с пустым payload.
+func (l *Listener) dispatch(n *pq.Notification) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if n == nil {
+		for _, handler := range l.handlers {
+			handler("")
+		}
+		return
+	}
+	if handler, ok := l.handlers[n.Channel]; ok {
+		handler(n.Extra)
+	}
+}
+
+// onEvent логирует состояние соединения LISTEN.
+func (l *Listener) onEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventConnected:
+		l.logger.Info("postgres listener connected")
+	case pq.ListenerEventReconnected:
+		l.logger.Info("postgres listener reconnected")
+	case pq.ListenerEventDisconnected:
+		l.logger.Warn("postgres listener disconnected", zap.Error(err))
+	case pq.ListenerEventConnectionAttemptFailed:
+		l.logger.Warn("postgres listener connection attempt failed", zap.Error(err))
+	}
+}
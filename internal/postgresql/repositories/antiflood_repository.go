@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// AntifloodRepository - настройки антифлуда per-chat/per-topic
+// ============================================================================
+
+// AntifloodRepository управляет таблицей antiflood_settings.
+// Отсутствие записи означает, что антифлуд выключен.
+type AntifloodRepository struct {
+	db *sql.DB
+}
+
+// NewAntifloodRepository создаёт новый репозиторий настроек антифлуда.
+func NewAntifloodRepository(db *sql.DB) *AntifloodRepository {
+	return &AntifloodRepository{db: db}
+}
+
+// AntifloodSettings — порог флуда в чате или топике.
+type AntifloodSettings struct {
+	ThreadID     int // 0 = настройка для всего чата, >0 = только для топика
+	Enabled      bool
+	MaxMessages  int
+	Window       time.Duration
+	Action       string         // delete | mute | warn
+	MuteDuration time.Duration  // для Action = mute
+	Weights      map[string]int // тип контента → вес (нет ключа = 1)
+}
+
+// DefaultAntifloodSettings — порог, с которого начинается настройка топика
+// (например, /setflood off в топике без собственной записи).
+func DefaultAntifloodSettings() *AntifloodSettings {
+	return &AntifloodSettings{
+		MaxMessages:  5,
+		Window:       5 * time.Second,
+		Action:       "delete",
+		MuteDuration: 10 * time.Minute,
+		Weights:      map[string]int{},
+	}
+}
+
+// GetChatSettings возвращает настройки антифлуда чата для всех топиков.
+// Вызывается кэшем модуля antiflood один раз на чат за TTL.
+func (r *AntifloodRepository) GetChatSettings(chatID int64) ([]AntifloodSettings, error) {
+	rows, err := r.db.Query(`
+		SELECT thread_id, enabled, max_messages, window_seconds, action, mute_seconds, weights
+		FROM antiflood_settings
+		WHERE chat_id = $1
+	`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get antiflood settings: %w", err)
+	}
+	defer rows.Close()
+
+	var settings []AntifloodSettings
+	for rows.Next() {
+		var s AntifloodSettings
+		var windowSeconds, muteSeconds int
+		var weights []byte
+		if err := rows.Scan(&s.ThreadID, &s.Enabled, &s.MaxMessages, &windowSeconds, &s.Action, &muteSeconds, &weights); err != nil {
+			return nil, fmt.Errorf("scan antiflood settings: %w", err)
+		}
+		s.Window = time.Duration(windowSeconds) * time.Second
+		s.MuteDuration = time.Duration(muteSeconds) * time.Second
+		s.Weights = map[string]int{}
+		if err := json.Unmarshal(weights, &s.Weights); err != nil {
+			return nil, fmt.Errorf("unmarshal antiflood weights: %w", err)
+		}
+		settings = append(settings, s)
+	}
+
+	return settings, rows.Err()
+}
+
+// Set сохраняет настройки антифлуда для чата/топика (s.ThreadID).
+func (r *AntifloodRepository) Set(chatID int64, s *AntifloodSettings, updatedBy int64) error {
+	weights, err := json.Marshal(s.Weights)
+	if err != nil {
+		return fmt.Errorf("marshal antiflood weights: %w", err)
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO antiflood_settings (chat_id, thread_id, enabled, max_messages, window_seconds, action, mute_seconds, weights, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		ON CONFLICT (chat_id, thread_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+		    max_messages = EXCLUDED.max_messages,
+		    window_seconds = EXCLUDED.window_seconds,
+		    action = EXCLUDED.action,
+		    mute_seconds = EXCLUDED.mute_seconds,
+		    weights = EXCLUDED.weights,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+	`, chatID, s.ThreadID, s.Enabled, s.MaxMessages, int(s.Window/time.Second),
+		s.Action, int(s.MuteDuration/time.Second), weights, updatedBy)
+	if err != nil {
+		return fmt.Errorf("set antiflood settings: %w", err)
+	}
+	return nil
+}
+
+// Delete удаляет собственную настройку чата/топика: топик снова наследует настройку чата.
+func (r *AntifloodRepository) Delete(chatID int64, threadID int) error {
+	_, err := r.db.Exec(`
+		DELETE FROM antiflood_settings
+		WHERE chat_id = $1 AND thread_id = $2
+	`, chatID, threadID)
+	if err != nil {
+		return fmt.Errorf("delete antiflood settings: %w", err)
+	}
+	return nil
+}
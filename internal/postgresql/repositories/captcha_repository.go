@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// CaptchaRepository - капча для новых участников
+// ============================================================================
+
+// CaptchaRepository управляет таблицами captcha_settings и captcha_challenges.
+type CaptchaRepository struct {
+	db *sql.DB
+}
+
+// NewCaptchaRepository создаёт новый репозиторий капчи.
+func NewCaptchaRepository(db *sql.DB) *CaptchaRepository {
+	return &CaptchaRepository{db: db}
+}
+
+// CaptchaSettings — настройки капчи чата.
+type CaptchaSettings struct {
+	Enabled        bool
+	TimeoutSeconds int
+	ChallengeType  string // button | math
+	FailAction     string // kick | ban
+}
+
+// DefaultCaptchaSettings — настройки по умолчанию (капча выключена).
+func DefaultCaptchaSettings() *CaptchaSettings {
+	return &CaptchaSettings{
+		Enabled:        false,
+		TimeoutSeconds: 120,
+		ChallengeType:  "button",
+		FailAction:     "kick",
+	}
+}
+
+// CaptchaChallenge — ожидающая проверки капча новичка.
+type CaptchaChallenge struct {
+	ChatID        int64
+	UserID        int64
+	ThreadID      int
+	MessageID     int // сообщение бота с капчей
+	ChallengeType string
+	Answer        string
+	Attempts      int
+	FailAction    string
+	ExpiresAt     time.Time
+}
+
+// GetSettings возвращает настройки капчи чата (или настройки по умолчанию).
+func (r *CaptchaRepository) GetSettings(chatID int64) (*CaptchaSettings, error) {
+	s := &CaptchaSettings{}
+	err := r.db.QueryRow(`
+		SELECT enabled, timeout_seconds, challenge_type, fail_action
+		FROM captcha_settings
+		WHERE chat_id = $1
+	`, chatID).Scan(&s.Enabled, &s.TimeoutSeconds, &s.ChallengeType, &s.FailAction)
+	if err == sql.ErrNoRows {
+		return DefaultCaptchaSettings(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get captcha settings: %w", err)
+	}
+	return s, nil
+}
+
+// SetSettings сохраняет настройки капчи чата.
+func (r *CaptchaRepository) SetSettings(chatID int64, s *CaptchaSettings, updatedBy int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO captcha_settings (chat_id, enabled, timeout_seconds, challenge_type, fail_action, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (chat_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+		    timeout_seconds = EXCLUDED.timeout_seconds,
+		    challenge_type = EXCLUDED.challenge_type,
+		    fail_action = EXCLUDED.fail_action,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+	`, chatID, s.Enabled, s.TimeoutSeconds, s.ChallengeType, s.FailAction, updatedBy)
+	if err != nil {
+		return fmt.Errorf("set captcha settings: %w", err)
+	}
+	return nil
+}
+
+// CreateChallenge сохраняет капчу новичка. Повторное вступление заменяет старую капчу.
+func (r *CaptchaRepository) CreateChallenge(ch *CaptchaChallenge) error {
+	_, err := r.db.Exec(`
+		INSERT INTO captcha_challenges
+			(chat_id, user_id, thread_id, message_id, challenge_type, answer, attempts, fail_action, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 0, $7, $8)
+		ON CONFLICT (chat_id, user_id) DO UPDATE
+		SET thread_id = EXCLUDED.thread_id,
+		    message_id = EXCLUDED.message_id,
+		    challenge_type = EXCLUDED.challenge_type,
+		    answer = EXCLUDED.answer,
+		    attempts = 0,
+		    fail_action = EXCLUDED.fail_action,
+		    expires_at = EXCLUDED.expires_at,
+		    created_at = NOW()
+	`, ch.ChatID, ch.UserID, ch.ThreadID, ch.MessageID, ch.ChallengeType, ch.Answer, ch.FailAction, ch.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("create captcha challenge: %w", err)
+	}
+	return nil
+}
+
+// SetChallengeMessage запоминает ID сообщения бота с капчей (известен после отправки).
+func (r *CaptchaRepository) SetChallengeMessage(chatID, userID int64, messageID int) error {
+	_, err := r.db.Exec(`
+		UPDATE captcha_challenges SET message_id = $3
+		WHERE chat_id = $1 AND user_id = $2
+	`, chatID, userID, messageID)
+	if err != nil {
+		return fmt.Errorf("set captcha message: %w", err)
+	}
+	return nil
+}
+
+// GetChallenge возвращает капчу пользователя или nil, если её нет.
+func (r *CaptchaRepository) GetChallenge(chatID, userID int64) (*CaptchaChallenge, error) {
+	ch := &CaptchaChallenge{}
+	err := r.db.QueryRow(`
+		SELECT chat_id, user_id, thread_id, message_id, challenge_type, answer, attempts, fail_action, expires_at
+		FROM captcha_challenges
+		WHERE chat_id = $1 AND user_id = $2
+	`, chatID, userID).Scan(&ch.ChatID, &ch.UserID, &ch.ThreadID, &ch.MessageID,
+		&ch.ChallengeType, &ch.Answer, &ch.Attempts, &ch.FailAction, &ch.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get captcha challenge: %w", err)
+	}
+	return ch, nil
+}
+
+// NextAttempt засчитывает неверный ответ и заменяет правильный ответ на newAnswer
+// (пользователь получает новый пример). Срабатывает, только если текущий ответ
+// всё ещё oldAnswer: повторное нажатие по старым кнопкам возвращает ok = false.
+func (r *CaptchaRepository) NextAttempt(chatID, userID int64, oldAnswer, newAnswer string) (attempts int, ok bool, err error) {
+	err = r.db.QueryRow(`
+		UPDATE captcha_challenges SET attempts = attempts + 1, answer = $4
+		WHERE chat_id = $1 AND user_id = $2 AND answer = $3
+		RETURNING attempts
+	`, chatID, userID, oldAnswer, newAnswer).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("record captcha attempt: %w", err)
+	}
+	return attempts, true, nil
+}
+
+// DeleteChallenge удаляет капчу. Возвращает false, если её уже нет
+// (решена или обработана воркером) — так два обработчика не выполнят действие дважды.
+func (r *CaptchaRepository) DeleteChallenge(chatID, userID int64) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM captcha_challenges
+		WHERE chat_id = $1 AND user_id = $2
+	`, chatID, userID)
+	if err != nil {
+		return false, fmt.Errorf("delete captcha challenge: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// GetExpiredChallenges возвращает капчи, срок которых истёк к моменту now.
+func (r *CaptchaRepository) GetExpiredChallenges(now time.Time) ([]CaptchaChallenge, error) {
+	rows, err := r.db.Query(`
+		SELECT chat_id, user_id, thread_id, message_id, challenge_type, answer, attempts, fail_action, expires_at
+		FROM captcha_challenges
+		WHERE expires_at <= $1
+		ORDER BY expires_at
+		LIMIT 100
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("get expired captcha challenges: %w", err)
+	}
+	defer rows.Close()
+
+	var challenges []CaptchaChallenge
+	for rows.Next() {
+		var ch CaptchaChallenge
+		if err := rows.Scan(&ch.ChatID, &ch.UserID, &ch.ThreadID, &ch.MessageID,
+			&ch.ChallengeType, &ch.Answer, &ch.Attempts, &ch.FailAction, &ch.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan captcha challenge: %w", err)
+		}
+		challenges = append(challenges, ch)
+	}
+	return challenges, rows.Err()
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ============================================================================
+// ChatStateRepository - снимки состояния чата для восстановления
+// ============================================================================
+
+// Виды снимков состояния чата (chat_state_snapshots.kind).
+const (
+	SnapshotPermissions = "permissions" // права участников чата до lock
+	SnapshotSlowMode    = "slowmode"    // настройка антифлуда до slow mode
+)
+
+// ChatStateRepository управляет таблицей chat_state_snapshots.
+// Снимок сохраняется перед изменением состояния чата по расписанию
+// и удаляется после восстановления.
+type ChatStateRepository struct {
+	db *sql.DB
+}
+
+// NewChatStateRepository создаёт новый репозиторий снимков состояния чата.
+func NewChatStateRepository(db *sql.DB) *ChatStateRepository {
+	return &ChatStateRepository{db: db}
+}
+
+// SaveSnapshot сохраняет снимок, если его ещё нет. data = nil — состояния не было (NULL).
+// Возвращает false, если снимок уже есть: состояние уже изменено и исходное не перезаписывается.
+func (r *ChatStateRepository) SaveSnapshot(chatID int64, threadID int, kind string, data []byte) (bool, error) {
+	var value any
+	if data != nil {
+		value = string(data)
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO chat_state_snapshots (chat_id, thread_id, kind, data)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chat_id, thread_id, kind) DO NOTHING
+	`, chatID, threadID, kind, value)
+	if err != nil {
+		return false, fmt.Errorf("save chat state snapshot: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// GetSnapshot возвращает снимок. found = false — снимка нет, восстанавливать нечего.
+func (r *ChatStateRepository) GetSnapshot(chatID int64, threadID int, kind string) (data []byte, found bool, err error) {
+	err = r.db.QueryRow(`
+		SELECT data
+		FROM chat_state_snapshots
+		WHERE chat_id = $1 AND thread_id = $2 AND kind = $3
+	`, chatID, threadID, kind).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("get chat state snapshot: %w", err)
+	}
+	return data, true, nil
+}
+
+// DeleteSnapshot удаляет снимок после восстановления состояния.
+func (r *ChatStateRepository) DeleteSnapshot(chatID int64, threadID int, kind string) error {
+	_, err := r.db.Exec(`
+		DELETE FROM chat_state_snapshots
+		WHERE chat_id = $1 AND thread_id = $2 AND kind = $3
+	`, chatID, threadID, kind)
+	if err != nil {
+		return fmt.Errorf("delete chat state snapshot: %w", err)
+	}
+	return nil
+}
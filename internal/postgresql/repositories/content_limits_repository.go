@@ -3,6 +3,7 @@ package repositories
 import (
 	"database/sql"
 	"fmt"
+	"time"
 )
 
 // ============================================================================
@@ -37,10 +38,21 @@ type ContentLimits struct {
 	LimitDocument    int
 	LimitLocation    int
 	LimitContact     int
+	LimitForward     int
 	LimitBannedWords int
 	WarningThreshold int
+	Penalty          string // delete | mute_until_reset | restrict_media | warn_only
+	Shadow           bool   // теневой режим: превышение только записывается
 }
 
+// Политики наказания за превышение лимита (content_limits.penalty).
+const (
+	PenaltyDelete         = "delete"           // удалить сообщение
+	PenaltyMuteUntilReset = "mute_until_reset" // удалить и замутить до сброса окна
+	PenaltyRestrictMedia  = "restrict_media"   // удалить и запретить тип контента до сброса окна
+	PenaltyWarnOnly       = "warn_only"        // только предупредить
+)
+
 // GetLimits получает лимиты для пользователя в чате/топике (или allmembers если не указан).
 // Логика fallback: сначала ищем лимит для (chat_id, thread_id, user_id),
 // затем для (chat_id, thread_id, NULL), затем для (chat_id, 0, user_id), затем для (chat_id, 0, NULL).
@@ -53,8 +65,8 @@ func (r *ContentLimitsRepository) GetLimits(chatID int64, threadID int, userID *
 			chat_id, thread_id, user_id,
 			limit_text, limit_photo, limit_video, limit_sticker,
 			limit_animation, limit_voice, limit_video_note, limit_audio,
-			limit_document, limit_location, limit_contact, limit_banned_words,
-			warning_threshold
+			limit_document, limit_location, limit_contact, limit_forward, limit_banned_words,
+			warning_threshold, COALESCE(penalty, 'delete'), shadow
 		FROM content_limits
 		WHERE chat_id = $1 AND thread_id = $2 AND user_id = $3
 		LIMIT 1
@@ -64,8 +76,8 @@ func (r *ContentLimitsRepository) GetLimits(chatID int64, threadID int, userID *
 		&limits.ChatID, &limits.ThreadID, &limits.UserID,
 		&limits.LimitText, &limits.LimitPhoto, &limits.LimitVideo, &limits.LimitSticker,
 		&limits.LimitAnimation, &limits.LimitVoice, &limits.LimitVideoNote, &limits.LimitAudio,
-		&limits.LimitDocument, &limits.LimitLocation, &limits.LimitContact, &limits.LimitBannedWords,
-		&limits.WarningThreshold,
+		&limits.LimitDocument, &limits.LimitLocation, &limits.LimitContact, &limits.LimitForward, &limits.LimitBannedWords,
+		&limits.WarningThreshold, &limits.Penalty, &limits.Shadow,
 	)
 
 	if err != sql.ErrNoRows {
@@ -81,8 +93,8 @@ func (r *ContentLimitsRepository) GetLimits(chatID int64, threadID int, userID *
 			chat_id, thread_id, user_id,
 			limit_text, limit_photo, limit_video, limit_sticker,
 			limit_animation, limit_voice, limit_video_note, limit_audio,
-			limit_document, limit_location, limit_contact, limit_banned_words,
-			warning_threshold
+			limit_document, limit_location, limit_contact, limit_forward, limit_banned_words,
+			warning_threshold, COALESCE(penalty, 'delete'), shadow
 		FROM content_limits
 		WHERE chat_id = $1 AND thread_id = $2 AND user_id IS NULL
 		LIMIT 1
@@ -91,8 +103,8 @@ func (r *ContentLimitsRepository) GetLimits(chatID int64, threadID int, userID *
 		&limits.ChatID, &limits.ThreadID, &limits.UserID,
 		&limits.LimitText, &limits.LimitPhoto, &limits.LimitVideo, &limits.LimitSticker,
 		&limits.LimitAnimation, &limits.LimitVoice, &limits.LimitVideoNote, &limits.LimitAudio,
-		&limits.LimitDocument, &limits.LimitLocation, &limits.LimitContact, &limits.LimitBannedWords,
-		&limits.WarningThreshold,
+		&limits.LimitDocument, &limits.LimitLocation, &limits.LimitContact, &limits.LimitForward, &limits.LimitBannedWords,
+		&limits.WarningThreshold, &limits.Penalty, &limits.Shadow,
 	)
 
 	if err != sql.ErrNoRows {
@@ -108,8 +120,8 @@ func (r *ContentLimitsRepository) GetLimits(chatID int64, threadID int, userID *
 			&limits.ChatID, &limits.ThreadID, &limits.UserID,
 			&limits.LimitText, &limits.LimitPhoto, &limits.LimitVideo, &limits.LimitSticker,
 			&limits.LimitAnimation, &limits.LimitVoice, &limits.LimitVideoNote, &limits.LimitAudio,
-			&limits.LimitDocument, &limits.LimitLocation, &limits.LimitContact, &limits.LimitBannedWords,
-			&limits.WarningThreshold,
+			&limits.LimitDocument, &limits.LimitLocation, &limits.LimitContact, &limits.LimitForward, &limits.LimitBannedWords,
+			&limits.WarningThreshold, &limits.Penalty, &limits.Shadow,
 		)
 
 		if err != sql.ErrNoRows {
@@ -126,8 +138,8 @@ func (r *ContentLimitsRepository) GetLimits(chatID int64, threadID int, userID *
 			&limits.ChatID, &limits.ThreadID, &limits.UserID,
 			&limits.LimitText, &limits.LimitPhoto, &limits.LimitVideo, &limits.LimitSticker,
 			&limits.LimitAnimation, &limits.LimitVoice, &limits.LimitVideoNote, &limits.LimitAudio,
-			&limits.LimitDocument, &limits.LimitLocation, &limits.LimitContact, &limits.LimitBannedWords,
-			&limits.WarningThreshold,
+			&limits.LimitDocument, &limits.LimitLocation, &limits.LimitContact, &limits.LimitForward, &limits.LimitBannedWords,
+			&limits.WarningThreshold, &limits.Penalty, &limits.Shadow,
 		)
 
 		if err != sql.ErrNoRows {
@@ -144,6 +156,7 @@ func (r *ContentLimitsRepository) GetLimits(chatID int64, threadID int, userID *
 		ThreadID:         threadID,
 		UserID:           userID,
 		WarningThreshold: 2,
+		Penalty:          PenaltyDelete,
 	}, nil
 }
 
@@ -174,6 +187,8 @@ func (r *ContentLimitsRepository) SetLimit(chatID int64, threadID int, userID *i
 		columnName = "limit_location"
 	case "contact":
 		columnName = "limit_contact"
+	case "forward":
+		columnName = "limit_forward"
 	case "banned_words":
 		columnName = "limit_banned_words"
 	default:
@@ -195,3 +210,173 @@ func (r *ContentLimitsRepository) SetLimit(chatID int64, threadID int, userID *i
 
 	return nil
 }
+
+// SetPenalty устанавливает политику наказания для записи лимитов чата/топика/пользователя.
+func (r *ContentLimitsRepository) SetPenalty(chatID int64, threadID int, userID *int64, penalty string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO content_limits (chat_id, thread_id, user_id, penalty)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chat_id, thread_id, COALESCE(user_id, -1))
+		DO UPDATE SET penalty = EXCLUDED.penalty, updated_at = NOW()
+	`, chatID, threadID, userID, penalty)
+	if err != nil {
+		return fmt.Errorf("set penalty: %w", err)
+	}
+	return nil
+}
+
+// SetShadow включает или выключает теневой режим записи лимитов чата/топика/пользователя.
+func (r *ContentLimitsRepository) SetShadow(chatID int64, threadID int, userID *int64, shadow bool) error {
+	_, err := r.db.Exec(`
+		INSERT INTO content_limits (chat_id, thread_id, user_id, shadow)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chat_id, thread_id, COALESCE(user_id, -1))
+		DO UPDATE SET shadow = EXCLUDED.shadow, updated_at = NOW()
+	`, chatID, threadID, userID, shadow)
+	if err != nil {
+		return fmt.Errorf("set shadow: %w", err)
+	}
+	return nil
+}
+
+// Типы окон лимитов. WindowDay — дневные лимиты из колонок content_limits,
+// остальные хранятся в content_limit_windows.
+const (
+	WindowHour       = "hour"       // текущий час
+	WindowDay        = "day"        // текущий день
+	WindowRolling24h = "rolling24h" // последние 24 часа
+	WindowWeek       = "week"       // текущая неделя с понедельника
+	WindowCustom     = "custom"     // последние Seconds секунд
+)
+
+// LimitWindow — окно, за которое считается лимит.
+type LimitWindow struct {
+	Type    string
+	Seconds int // только для WindowCustom
+}
+
+// Rolling — окно скользящее (24 часа или своё): начало сдвигается вместе с now.
+func (w LimitWindow) Rolling() bool {
+	return w.Type == WindowRolling24h || w.Type == WindowCustom
+}
+
+// Bounds возвращает начало окна, в которое попадает now, и момент его сброса.
+// Календарные окна (час, день, неделя с понедельника) считаются в часовом поясе now,
+// поэтому подсчёт сообщений и время до сброса опираются на одни и те же границы
+// независимо от часового пояса сессии БД. Для скользящих окон сброс — через длину
+// окна: к этому моменту все учтённые сообщения из него выйдут. Это верхняя граница:
+// место под лимитом освобождается раньше, когда из окна выходит самое старое
+// сообщение (см. OldestByTypeInWindow).
+func (w LimitWindow) Bounds(now time.Time) (start, reset time.Time, err error) {
+	y, m, d := now.Date()
+	loc := now.Location()
+	switch w.Type {
+	case WindowHour:
+		start = time.Date(y, m, d, now.Hour(), 0, 0, 0, loc)
+		return start, time.Date(y, m, d, now.Hour()+1, 0, 0, 0, loc), nil
+	case WindowDay:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc), time.Date(y, m, d+1, 0, 0, 0, 0, loc), nil
+	case WindowWeek:
+		sinceMonday := (int(now.Weekday()) + 6) % 7
+		start = time.Date(y, m, d-sinceMonday, 0, 0, 0, 0, loc)
+		return start, time.Date(y, m, d-sinceMonday+7, 0, 0, 0, 0, loc), nil
+	case WindowRolling24h:
+		return now.Add(-24 * time.Hour), now.Add(24 * time.Hour), nil
+	case WindowCustom:
+		if w.Seconds <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("custom window without length")
+		}
+		length := time.Duration(w.Seconds) * time.Second
+		return now.Add(-length), now.Add(length), nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown window type: %s", w.Type)
+	}
+}
+
+// WindowLimit — лимит типа контента за окно (content_limit_windows).
+type WindowLimit struct {
+	ContentType string
+	Window      LimitWindow
+	Limit       int
+}
+
+// GetWindowLimits возвращает лимиты за окна для пользователя в чате/топике.
+// Fallback тот же, что у GetLimits, но для каждой пары (тип контента, окно) отдельно:
+// пользователь+топик → топик → пользователь+чат → чат.
+func (r *ContentLimitsRepository) GetWindowLimits(chatID int64, threadID int, userID *int64) ([]WindowLimit, error) {
+	rows, err := r.db.Query(`
+		SELECT thread_id, user_id, content_type, window_type, window_seconds, limit_value
+		FROM content_limit_windows
+		WHERE chat_id = $1
+		  AND thread_id IN ($2, 0)
+		  AND (user_id IS NULL OR user_id = $3)
+	`, chatID, threadID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get window limits: %w", err)
+	}
+	defer rows.Close()
+
+	type key struct {
+		contentType string
+		window      LimitWindow
+	}
+	best := make(map[key]int) // key → ранг найденной записи (меньше = точнее)
+	var limits []WindowLimit
+	index := make(map[key]int)
+	for rows.Next() {
+		var rowThread int
+		var rowUser sql.NullInt64
+		var l WindowLimit
+		if err := rows.Scan(&rowThread, &rowUser, &l.ContentType, &l.Window.Type, &l.Window.Seconds, &l.Limit); err != nil {
+			return nil, fmt.Errorf("scan window limit: %w", err)
+		}
+
+		rank := 0
+		if rowThread != threadID {
+			rank += 2
+		}
+		if !rowUser.Valid {
+			rank++
+		}
+
+		k := key{l.ContentType, l.Window}
+		if prev, ok := best[k]; ok {
+			if rank < prev {
+				best[k] = rank
+				limits[index[k]] = l
+			}
+			continue
+		}
+		best[k] = rank
+		index[k] = len(limits)
+		limits = append(limits, l)
+	}
+
+	return limits, rows.Err()
+}
+
+// SetWindowLimit устанавливает лимит за окно. limit = 0 удаляет запись.
+func (r *ContentLimitsRepository) SetWindowLimit(chatID int64, threadID int, userID *int64, contentType string, window LimitWindow, limit int) error {
+	if limit == 0 {
+		_, err := r.db.Exec(`
+			DELETE FROM content_limit_windows
+			WHERE chat_id = $1 AND thread_id = $2 AND COALESCE(user_id, -1) = COALESCE($3::BIGINT, -1)
+			  AND content_type = $4 AND window_type = $5 AND window_seconds = $6
+		`, chatID, threadID, userID, contentType, window.Type, window.Seconds)
+		if err != nil {
+			return fmt.Errorf("delete window limit: %w", err)
+		}
+		return nil
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO content_limit_windows (chat_id, thread_id, user_id, content_type, window_type, window_seconds, limit_value)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (chat_id, thread_id, COALESCE(user_id, -1), content_type, window_type, window_seconds)
+		DO UPDATE SET limit_value = EXCLUDED.limit_value, updated_at = NOW()
+	`, chatID, threadID, userID, contentType, window.Type, window.Seconds, limit)
+	if err != nil {
+		return fmt.Errorf("set window limit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ============================================================================
+// ForwardRepository - политика пересылок per-chat/per-topic
+// ============================================================================
+
+// Политики пересылок (forward_settings.policy).
+const (
+	ForwardAllow        = "allow"         // пересылки разрешены
+	ForwardDeny         = "deny"          // запрещены все пересылки
+	ForwardDenyChannels = "deny_channels" // запрещены пересылки из каналов
+	ForwardWhitelist    = "whitelist"     // разрешены только источники из forward_whitelist
+)
+
+// ForwardRepository управляет таблицами forward_settings и forward_whitelist.
+// Отсутствие записи политики означает allow.
+type ForwardRepository struct {
+	db *sql.DB
+}
+
+// NewForwardRepository создаёт новый репозиторий политики пересылок.
+func NewForwardRepository(db *sql.DB) *ForwardRepository {
+	return &ForwardRepository{db: db}
+}
+
+// ForwardSource — разрешённый источник пересылок.
+type ForwardSource struct {
+	ThreadID int
+	SourceID int64
+	Title    string
+}
+
+// GetPolicy возвращает политику пересылок топика с fallback на чат (allow, если не настроена).
+func (r *ForwardRepository) GetPolicy(chatID int64, threadID int) (string, error) {
+	var policy string
+	err := r.db.QueryRow(`
+		SELECT policy
+		FROM forward_settings
+		WHERE chat_id = $1 AND (thread_id = $2 OR thread_id = 0)
+		ORDER BY thread_id DESC
+		LIMIT 1
+	`, chatID, threadID).Scan(&policy)
+	if err == sql.ErrNoRows {
+		return ForwardAllow, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get forward policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SetPolicy сохраняет политику пересылок чата/топика.
+func (r *ForwardRepository) SetPolicy(chatID int64, threadID int, policy string, updatedBy int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO forward_settings (chat_id, thread_id, policy, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (chat_id, thread_id) DO UPDATE
+		SET policy = EXCLUDED.policy,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+	`, chatID, threadID, policy, updatedBy)
+	if err != nil {
+		return fmt.Errorf("set forward policy: %w", err)
+	}
+	return nil
+}
+
+// GetWhitelist возвращает разрешённые источники пересылок чата и топика.
+func (r *ForwardRepository) GetWhitelist(chatID int64, threadID int) ([]ForwardSource, error) {
+	rows, err := r.db.Query(`
+		SELECT thread_id, source_id, COALESCE(title, '')
+		FROM forward_whitelist
+		WHERE chat_id = $1 AND (thread_id = $2 OR thread_id = 0)
+		ORDER BY thread_id, created_at
+	`, chatID, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("get forward whitelist: %w", err)
+	}
+	defer rows.Close()
+
+	var sources []ForwardSource
+	for rows.Next() {
+		var s ForwardSource
+		if err := rows.Scan(&s.ThreadID, &s.SourceID, &s.Title); err != nil {
+			return nil, fmt.Errorf("scan forward source: %w", err)
+		}
+		sources = append(sources, s)
+	}
+	return sources, rows.Err()
+}
+
+// IsWhitelisted проверяет, разрешён ли источник пересылок в топике или во всём чате.
+func (r *ForwardRepository) IsWhitelisted(chatID int64, threadID int, sourceID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM forward_whitelist
+			WHERE chat_id = $1 AND (thread_id = $2 OR thread_id = 0) AND source_id = $3
+		)
+	`, chatID, threadID, sourceID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check forward whitelist: %w", err)
+	}
+	return exists, nil
+}
+
+// AddSource добавляет источник в whitelist пересылок чата/топика.
+func (r *ForwardRepository) AddSource(chatID int64, threadID int, sourceID int64, title string, createdBy int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO forward_whitelist (chat_id, thread_id, source_id, title, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chat_id, thread_id, source_id) DO UPDATE
+		SET title = EXCLUDED.title,
+		    created_by = EXCLUDED.created_by,
+		    created_at = NOW()
+	`, chatID, threadID, sourceID, title, createdBy)
+	if err != nil {
+		return fmt.Errorf("add forward source: %w", err)
+	}
+	return nil
+}
+
+// RemoveSource удаляет источник из whitelist чата/топика. Возвращает false, если его не было.
+func (r *ForwardRepository) RemoveSource(chatID int64, threadID int, sourceID int64) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM forward_whitelist
+		WHERE chat_id = $1 AND thread_id = $2 AND source_id = $3
+	`, chatID, threadID, sourceID)
+	if err != nil {
+		return false, fmt.Errorf("remove forward source: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ============================================================================
+// LinkFilterRepository - фильтр ссылок per-chat/per-topic
+// ============================================================================
+
+// LinkFilterRepository управляет таблицами link_filter_settings и link_filter_domains.
+// Отсутствие записи настроек означает, что фильтр ссылок выключен.
+type LinkFilterRepository struct {
+	db *sql.DB
+}
+
+// NewLinkFilterRepository создаёт новый репозиторий фильтра ссылок.
+func NewLinkFilterRepository(db *sql.DB) *LinkFilterRepository {
+	return &LinkFilterRepository{db: db}
+}
+
+// LinkFilterSettings — настройки фильтра ссылок чата или топика.
+type LinkFilterSettings struct {
+	ThreadID      int    // 0 = настройка для всего чата, >0 = только для топика
+	Action        string // delete | warn | delete_warn
+	Mode          string // all — все ссылки, кроме allowlist; deny — только denylist
+	BlockInvites  bool   // ссылки-приглашения t.me/+..., t.me/joinchat/...
+	BlockMentions bool   // упоминания каналов и групп (@channel, t.me/channel)
+}
+
+// DefaultLinkFilterSettings — настройки, с которых начинается включение фильтра.
+func DefaultLinkFilterSettings() *LinkFilterSettings {
+	return &LinkFilterSettings{
+		Action:        "delete",
+		Mode:          "all",
+		BlockInvites:  true,
+		BlockMentions: true,
+	}
+}
+
+// LinkDomain — запись allowlist/denylist: домен (example.com) или канал (@channel).
+type LinkDomain struct {
+	ThreadID int
+	Domain   string
+	ListType string // allow | deny
+}
+
+// GetSettings возвращает настройки фильтра ссылок для топика с fallback на чат.
+// nil — фильтр выключен.
+func (r *LinkFilterRepository) GetSettings(chatID int64, threadID int) (*LinkFilterSettings, error) {
+	s, err := r.GetOwnSettings(chatID, threadID)
+	if err != nil || s != nil || threadID == 0 {
+		return s, err
+	}
+	return r.GetOwnSettings(chatID, 0)
+}
+
+// GetOwnSettings возвращает собственную запись чата/топика без fallback.
+func (r *LinkFilterRepository) GetOwnSettings(chatID int64, threadID int) (*LinkFilterSettings, error) {
+	s := &LinkFilterSettings{}
+	err := r.db.QueryRow(`
+		SELECT thread_id, action, mode, block_invites, block_mentions
+		FROM link_filter_settings
+		WHERE chat_id = $1 AND thread_id = $2
+	`, chatID, threadID).Scan(&s.ThreadID, &s.Action, &s.Mode, &s.BlockInvites, &s.BlockMentions)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get link filter settings: %w", err)
+	}
+	return s, nil
+}
+
+// SetSettings сохраняет настройки фильтра ссылок для чата/топика (s.ThreadID).
+func (r *LinkFilterRepository) SetSettings(chatID int64, s *LinkFilterSettings, updatedBy int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO link_filter_settings (chat_id, thread_id, action, mode, block_invites, block_mentions, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (chat_id, thread_id) DO UPDATE
+		SET action = EXCLUDED.action,
+		    mode = EXCLUDED.mode,
+		    block_invites = EXCLUDED.block_invites,
+		    block_mentions = EXCLUDED.block_mentions,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+	`, chatID, s.ThreadID, s.Action, s.Mode, s.BlockInvites, s.BlockMentions, updatedBy)
+	if err != nil {
+		return fmt.Errorf("set link filter settings: %w", err)
+	}
+	return nil
+}
+
+// DeleteSettings выключает фильтр ссылок чата/топика. Возвращает false, если он не был настроен.
+func (r *LinkFilterRepository) DeleteSettings(chatID int64, threadID int) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM link_filter_settings
+		WHERE chat_id = $1 AND thread_id = $2
+	`, chatID, threadID)
+	if err != nil {
+		return false, fmt.Errorf("delete link filter settings: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// GetDomains возвращает allowlist и denylist чата и топика.
+// Записи топика идут после записей чата — при совпадении домена побеждает топик.
+func (r *LinkFilterRepository) GetDomains(chatID int64, threadID int) ([]LinkDomain, error) {
+	rows, err := r.db.Query(`
+		SELECT thread_id, domain, list_type
+		FROM link_filter_domains
+		WHERE chat_id = $1 AND (thread_id = $2 OR thread_id = 0)
+		ORDER BY thread_id, domain
+	`, chatID, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("get link filter domains: %w", err)
+	}
+	defer rows.Close()
+
+	var domains []LinkDomain
+	for rows.Next() {
+		var d LinkDomain
+		if err := rows.Scan(&d.ThreadID, &d.Domain, &d.ListType); err != nil {
+			return nil, fmt.Errorf("scan link filter domain: %w", err)
+		}
+		domains = append(domains, d)
+	}
+	return domains, rows.Err()
+}
+
+// SetDomain добавляет домен в allowlist или denylist чата/топика (переносит из другого списка).
+func (r *LinkFilterRepository) SetDomain(chatID int64, threadID int, domain, listType string, createdBy int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO link_filter_domains (chat_id, thread_id, domain, list_type, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chat_id, thread_id, domain) DO UPDATE
+		SET list_type = EXCLUDED.list_type,
+		    created_by = EXCLUDED.created_by,
+		    created_at = NOW()
+	`, chatID, threadID, domain, listType, createdBy)
+	if err != nil {
+		return fmt.Errorf("set link filter domain: %w", err)
+	}
+	return nil
+}
+
+// RemoveDomain удаляет домен из списков чата/топика. Возвращает false, если его не было.
+func (r *LinkFilterRepository) RemoveDomain(chatID int64, threadID int, domain string) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM link_filter_domains
+		WHERE chat_id = $1 AND thread_id = $2 AND domain = $3
+	`, chatID, threadID, domain)
+	if err != nil {
+		return false, fmt.Errorf("remove link filter domain: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -52,12 +53,21 @@ type ReactionsMetadata struct {
 type StatisticsMetadata struct {
 	Processed        bool `json:"processed"`
 	ProcessingTimeMs int  `json:"processing_time_ms,omitempty"`
+	EditCount        int  `json:"edit_count,omitempty"` // Сколько раз сообщение редактировали
 }
 
 // ProfanityMetadata хранит информацию об обнаружении мата в сообщении.
 type ProfanityMetadata struct {
 	Detected bool   `json:"detected"`
 	Action   string `json:"action,omitempty"`
+	Severity string `json:"severity,omitempty"` // уровень найденного слова
+}
+
+// ForwardMetadata отмечает пересланное сообщение (metadata->'forward').
+// content_type остаётся типом пересланного контента, лимит forward считается по отметке.
+type ForwardMetadata struct {
+	Detected bool  `json:"detected"`
+	SourceID int64 `json:"source_id,omitempty"` // 0 — отправитель скрыт
 }
 
 // InsertMessage сохраняет сообщение в БД с метаданными.
@@ -105,70 +115,209 @@ func (r *MessageRepository) InsertMessage(
 	return id, nil
 }
 
-// GetTodayCountByType возвращает количество сообщений определённого типа за сегодня.
-// Используется Limiter для проверки лимитов.
+// CountByTypeInWindow возвращает количество сообщений типа contentType за окно w, в которое попадает now.
+// Используется Limiter для проверки лимитов: за день, час, 24 часа, неделю и своё окно.
+// Начало окна считается в Go (LimitWindow.Bounds), а не через NOW() БД.
 // threadID = 0 означает подсчёт для всего чата, >0 - только для конкретного топика.
-func (r *MessageRepository) GetTodayCountByType(chatID int64, threadID int, userID int64, contentType string) (int, error) {
+func (r *MessageRepository) CountByTypeInWindow(chatID int64, threadID int, userID int64, contentType string, w LimitWindow, now time.Time) (int, error) {
+	start, _, err := w.Bounds(now)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
-		SELECT COUNT(*) 
-		FROM messages 
-		WHERE chat_id = $1 
+		SELECT COUNT(*)
+		FROM messages
+		WHERE chat_id = $1
 		  AND thread_id = $2
-		  AND user_id = $3 
-		  AND content_type = $4 
-		  AND DATE(created_at) = CURRENT_DATE
+		  AND user_id = $3
+		  AND content_type = $4
+		  AND created_at >= $5
 		  AND was_deleted = FALSE
 	`
 
 	var count int
-	err := r.db.QueryRow(query, chatID, threadID, userID, contentType).Scan(&count)
+	if err := r.db.QueryRow(query, chatID, threadID, userID, contentType, start).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to get window count: %w", err)
+	}
+	return count, nil
+}
+
+// CountByMetadataInWindow возвращает количество сообщений с metadata->key->detected = true
+// за окно w, в которое попадает now. Используется Limiter для лимита на пересылки.
+func (r *MessageRepository) CountByMetadataInWindow(chatID int64, threadID int, userID int64, metadataKey string, w LimitWindow, now time.Time) (int, error) {
+	start, _, err := w.Bounds(now)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get today count: %w", err)
+		return 0, err
 	}
 
+	query := `
+		SELECT COUNT(*)
+		FROM messages
+		WHERE chat_id = $1
+		  AND thread_id = $2
+		  AND user_id = $3
+		  AND metadata->$4->'detected' = 'true'::jsonb
+		  AND created_at >= $5
+		  AND was_deleted = FALSE
+	`
+
+	var count int
+	if err := r.db.QueryRow(query, chatID, threadID, userID, metadataKey, start).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to get window count by metadata: %w", err)
+	}
 	return count, nil
 }
 
+// OldestByTypeInWindow возвращает время самого старого учтённого сообщения типа contentType
+// за окно w, в которое попадает now. Для скользящих окон: когда оно выйдет из окна,
+// у пользователя освободится место под лимитом. Нулевое время — сообщений нет.
+func (r *MessageRepository) OldestByTypeInWindow(chatID int64, threadID int, userID int64, contentType string, w LimitWindow, now time.Time) (time.Time, error) {
+	start, _, err := w.Bounds(now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	query := `
+		SELECT MIN(created_at)
+		FROM messages
+		WHERE chat_id = $1
+		  AND thread_id = $2
+		  AND user_id = $3
+		  AND content_type = $4
+		  AND created_at >= $5
+		  AND was_deleted = FALSE
+	`
+
+	var oldest sql.NullTime
+	if err := r.db.QueryRow(query, chatID, threadID, userID, contentType, start).Scan(&oldest); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get oldest message in window: %w", err)
+	}
+	return oldest.Time, nil
+}
+
+// OldestByMetadataInWindow — OldestByTypeInWindow для сообщений с metadata->key->detected = true
+// (лимит на пересылки).
+func (r *MessageRepository) OldestByMetadataInWindow(chatID int64, threadID int, userID int64, metadataKey string, w LimitWindow, now time.Time) (time.Time, error) {
+	start, _, err := w.Bounds(now)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	query := `
+		SELECT MIN(created_at)
+		FROM messages
+		WHERE chat_id = $1
+		  AND thread_id = $2
+		  AND user_id = $3
+		  AND metadata->$4->'detected' = 'true'::jsonb
+		  AND created_at >= $5
+		  AND was_deleted = FALSE
+	`
+
+	var oldest sql.NullTime
+	if err := r.db.QueryRow(query, chatID, threadID, userID, metadataKey, start).Scan(&oldest); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get oldest message in window by metadata: %w", err)
+	}
+	return oldest.Time, nil
+}
+
+// CountsAllTypesInWindow возвращает счётчики по всем типам контента за окно w, в которое попадает now.
+// Для /mystats: один запрос на окно. Пересылки (metadata->'forward') — под ключом "forward".
+func (r *MessageRepository) CountsAllTypesInWindow(chatID int64, threadID int, userID int64, w LimitWindow, now time.Time) (map[string]int, error) {
+	start, _, err := w.Bounds(now)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT
+			content_type,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE metadata->'forward'->>'detected' = 'true')
+		FROM messages
+		WHERE chat_id = $1
+		  AND thread_id = $2
+		  AND user_id = $3
+		  AND created_at >= $4
+		  AND was_deleted = FALSE
+		GROUP BY content_type
+	`
+
+	rows, err := r.db.Query(query, chatID, threadID, userID, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get window counts: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]int)
+	var forwards int
+	for rows.Next() {
+		var contentType string
+		var cnt, forwardCnt int
+		if err := rows.Scan(&contentType, &cnt, &forwardCnt); err != nil {
+			return nil, fmt.Errorf("failed to scan window counts: %w", err)
+		}
+		result[contentType] = cnt
+		forwards += forwardCnt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	result["forward"] = forwards
+	return result, nil
+}
+
 // GetTodayCountsAllTypes возвращает счётчики по ВСЕМ типам контента + мат за сегодня.
-// Один SQL-запрос вместо 12 отдельных вызовов GetTodayCountByType.
-// Возвращает map[content_type]count + ключ "banned_words" для мата (из metadata).
-func (r *MessageRepository) GetTodayCountsAllTypes(chatID int64, threadID int, userID int64) (map[string]int, error) {
+// Один SQL-запрос вместо 12 отдельных вызовов CountByTypeInWindow.
+// Возвращает map[content_type]count + ключи "banned_words" для мата и "forward" для пересылок (из metadata).
+// "Сегодня" — дневное окно лимитов (LimitWindow.Bounds) для now.
+func (r *MessageRepository) GetTodayCountsAllTypes(chatID int64, threadID int, userID int64, now time.Time) (map[string]int, error) {
+	start, _, err := LimitWindow{Type: WindowDay}.Bounds(now)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT 
 			content_type,
 			COUNT(*) AS cnt,
-			COUNT(*) FILTER (WHERE metadata->'profanity'->>'detected' = 'true') AS profanity_cnt
+			COUNT(*) FILTER (WHERE metadata->'profanity'->>'detected' = 'true') AS profanity_cnt,
+			COUNT(*) FILTER (WHERE metadata->'forward'->>'detected' = 'true') AS forward_cnt
 		FROM messages
 		WHERE chat_id = $1
 		  AND thread_id = $2
 		  AND user_id = $3
-		  AND DATE(created_at) = CURRENT_DATE
+		  AND created_at >= $4
 		  AND was_deleted = FALSE
 		GROUP BY content_type
 	`
 
-	rows, err := r.db.Query(query, chatID, threadID, userID)
+	rows, err := r.db.Query(query, chatID, threadID, userID, start)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get today counts: %w", err)
 	}
 	defer rows.Close()
 
 	result := make(map[string]int)
-	var totalProfanity int
+	var totalProfanity, totalForwards int
 	for rows.Next() {
 		var contentType string
-		var cnt, profanityCnt int
-		if err := rows.Scan(&contentType, &cnt, &profanityCnt); err != nil {
+		var cnt, profanityCnt, forwardCnt int
+		if err := rows.Scan(&contentType, &cnt, &profanityCnt, &forwardCnt); err != nil {
 			return nil, fmt.Errorf("failed to scan today counts: %w", err)
 		}
 		result[contentType] = cnt
 		totalProfanity += profanityCnt
+		totalForwards += forwardCnt
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows iteration error: %w", err)
 	}
 
 	result["banned_words"] = totalProfanity
+	result["forward"] = totalForwards
 	return result, nil
 }
 
@@ -205,6 +354,72 @@ func (r *MessageRepository) UpdateMessageMetadata(chatID int64, messageID int, k
 	return nil
 }
 
+// RecordEdit сохраняет новую версию отредактированного сообщения в message_edits
+// и увеличивает metadata.statistics.edit_count исходного сообщения.
+// Новая строка в messages НЕ создаётся — правка не должна считаться в лимитах.
+func (r *MessageRepository) RecordEdit(
+	chatID int64,
+	threadID int,
+	userID int64,
+	messageID int,
+	text string,
+	caption string,
+	editedAt time.Time,
+) error {
+	_, err := r.db.Exec(`
+		INSERT INTO message_edits (chat_id, thread_id, user_id, message_id, text, caption, edited_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, chatID, threadID, userID, messageID, text, caption, editedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert message edit: %w", err)
+	}
+
+	// Исходного сообщения может не быть (отправлено до добавления бота или удалено ротацией) —
+	// тогда UPDATE просто ничего не затронет
+	_, err = r.db.Exec(`
+		UPDATE messages
+		SET metadata = jsonb_set(
+			COALESCE(metadata, '{}'::jsonb),
+			'{statistics}',
+			COALESCE(metadata->'statistics', '{}'::jsonb) || jsonb_build_object(
+				'edit_count', COALESCE((metadata->'statistics'->>'edit_count')::int, 0) + 1
+			),
+			true
+		)
+		WHERE chat_id = $1 AND message_id = $2
+	`, chatID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to update edit count: %w", err)
+	}
+
+	r.logger.Debug("message edit recorded",
+		zap.Int64("chat_id", chatID),
+		zap.Int("thread_id", threadID),
+		zap.Int64("user_id", userID),
+		zap.Int("message_id", messageID),
+	)
+
+	return nil
+}
+
+// IsMetadataDetected проверяет, помечено ли сообщение как metadata->key->detected = true.
+// Используется при правках: нарушение в исходной версии уже учтено в дневном счётчике.
+func (r *MessageRepository) IsMetadataDetected(chatID int64, messageID int, metadataKey string) (bool, error) {
+	var detected bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM messages
+			WHERE chat_id = $1
+			  AND message_id = $2
+			  AND metadata->$3->'detected' = 'true'::jsonb
+		)
+	`, chatID, messageID, metadataKey).Scan(&detected)
+	if err != nil {
+		return false, fmt.Errorf("failed to check message metadata: %w", err)
+	}
+	return detected, nil
+}
+
 // GetTodayCountByMetadata подсчитывает сообщения с определенным metadata за сегодня.
 // Используется для подсчета матов (profanity.detected = true) при проверке лимита banned_words.
 func (r *MessageRepository) GetTodayCountByMetadata(chatID int64, threadID int, userID int64, metadataKey string, metadataValue bool) (int, error) {
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ============================================================================
+// ModuleStateRepository - включение/выключение модулей per-chat
+// ============================================================================
+
+// ModuleStateRepository управляет таблицей chat_modules.
+// Отсутствие записи означает, что модуль включён.
+type ModuleStateRepository struct {
+	db *sql.DB
+}
+
+// NewModuleStateRepository создаёт новый репозиторий состояний модулей.
+func NewModuleStateRepository(db *sql.DB) *ModuleStateRepository {
+	return &ModuleStateRepository{db: db}
+}
+
+// ModuleState — явно заданное состояние модуля в чате или топике.
+type ModuleState struct {
+	ThreadID   int // 0 = настройка для всего чата, >0 = только для топика
+	ModuleName string
+	IsEnabled  bool
+}
+
+// GetChatStates возвращает все явно заданные состояния модулей чата (все топики).
+// Вызывается кэшем core.ModuleStates один раз на чат за TTL.
+func (r *ModuleStateRepository) GetChatStates(chatID int64) ([]ModuleState, error) {
+	rows, err := r.db.Query(`
+		SELECT thread_id, module_name, is_enabled
+		FROM chat_modules
+		WHERE chat_id = $1
+	`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get chat module states: %w", err)
+	}
+	defer rows.Close()
+
+	var states []ModuleState
+	for rows.Next() {
+		var s ModuleState
+		if err := rows.Scan(&s.ThreadID, &s.ModuleName, &s.IsEnabled); err != nil {
+			return nil, fmt.Errorf("scan chat module state: %w", err)
+		}
+		states = append(states, s)
+	}
+
+	return states, rows.Err()
+}
+
+// SetEnabled включает или выключает модуль в чате/топике.
+// threadID = 0 означает настройку для всего чата, >0 — только для топика.
+func (r *ModuleStateRepository) SetEnabled(chatID int64, threadID int, moduleName string, enabled bool, updatedBy int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO chat_modules (chat_id, thread_id, module_name, is_enabled, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (chat_id, thread_id, module_name) DO UPDATE
+		SET is_enabled = EXCLUDED.is_enabled,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+	`, chatID, threadID, moduleName, enabled, updatedBy)
+	if err != nil {
+		return fmt.Errorf("set module state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// ProbationRepository - вступления в чат и испытательный срок новичков
+// ============================================================================
+
+// ProbationRepository управляет таблицами chat_members и probation_settings.
+// Отсутствие записи probation_settings означает, что испытательный срок выключен.
+type ProbationRepository struct {
+	db *sql.DB
+}
+
+// NewProbationRepository создаёт новый репозиторий испытательного срока.
+func NewProbationRepository(db *sql.DB) *ProbationRepository {
+	return &ProbationRepository{db: db}
+}
+
+// ProbationSettings — условия окончания испытательного срока в чате.
+// Срок заканчивается, когда выполнены все заданные условия.
+type ProbationSettings struct {
+	Duration    time.Duration // 0 = время не учитывается
+	MinMessages int           // 0 = число сообщений не учитывается
+}
+
+// MemberProbation — новичок на испытательном сроке.
+type MemberProbation struct {
+	Settings     ProbationSettings
+	JoinedAt     time.Time
+	MessageCount int
+}
+
+// Completed — выполнены ли условия окончания испытательного срока в момент now.
+func (p *MemberProbation) Completed(now time.Time) bool {
+	return now.Sub(p.JoinedAt) >= p.Settings.Duration && p.MessageCount >= p.Settings.MinMessages
+}
+
+// GetSettings возвращает настройки испытательного срока чата. nil — выключен.
+func (r *ProbationRepository) GetSettings(chatID int64) (*ProbationSettings, error) {
+	var durationSeconds int
+	s := &ProbationSettings{}
+	err := r.db.QueryRow(`
+		SELECT duration_seconds, min_messages
+		FROM probation_settings
+		WHERE chat_id = $1
+	`, chatID).Scan(&durationSeconds, &s.MinMessages)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get probation settings: %w", err)
+	}
+	s.Duration = time.Duration(durationSeconds) * time.Second
+	return s, nil
+}
+
+// SetSettings включает испытательный срок в чате или меняет его условия.
+func (r *ProbationRepository) SetSettings(chatID int64, s *ProbationSettings, updatedBy int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO probation_settings (chat_id, duration_seconds, min_messages, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (chat_id) DO UPDATE
+		SET duration_seconds = EXCLUDED.duration_seconds,
+		    min_messages = EXCLUDED.min_messages,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+	`, chatID, int(s.Duration/time.Second), s.MinMessages, updatedBy)
+	if err != nil {
+		return fmt.Errorf("set probation settings: %w", err)
+	}
+	return nil
+}
+
+// DeleteSettings выключает испытательный срок. Возвращает false, если он не был включён.
+func (r *ProbationRepository) DeleteSettings(chatID int64) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM probation_settings WHERE chat_id = $1`, chatID)
+	if err != nil {
+		return false, fmt.Errorf("delete probation settings: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// RecordJoin записывает вступление пользователя в чат.
+// Повторное вступление начинает испытательный срок заново.
+func (r *ProbationRepository) RecordJoin(chatID, userID int64, joinedAt time.Time) error {
+	_, err := r.db.Exec(`
+		INSERT INTO chat_members (chat_id, user_id, joined_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (chat_id, user_id) DO UPDATE
+		SET joined_at = EXCLUDED.joined_at,
+		    message_count = 0,
+		    trusted_at = NULL,
+		    trusted_by = NULL
+	`, chatID, userID, joinedAt)
+	if err != nil {
+		return fmt.Errorf("record join: %w", err)
+	}
+	return nil
+}
+
+// GetProbation возвращает испытательный срок пользователя.
+// nil — срок в чате выключен, пользователь вступил до появления chat_members или уже доверенный.
+func (r *ProbationRepository) GetProbation(chatID, userID int64) (*MemberProbation, error) {
+	var durationSeconds int
+	p := &MemberProbation{}
+	err := r.db.QueryRow(`
+		SELECT s.duration_seconds, s.min_messages, m.joined_at, m.message_count
+		FROM chat_members m
+		JOIN probation_settings s ON s.chat_id = m.chat_id
+		WHERE m.chat_id = $1 AND m.user_id = $2 AND m.trusted_at IS NULL
+	`, chatID, userID).Scan(&durationSeconds, &p.Settings.MinMessages, &p.JoinedAt, &p.MessageCount)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get probation: %w", err)
+	}
+	p.Settings.Duration = time.Duration(durationSeconds) * time.Second
+	return p, nil
+}
+
+// CountMessage учитывает сообщение новичка на испытательном сроке.
+func (r *ProbationRepository) CountMessage(chatID, userID int64) error {
+	_, err := r.db.Exec(`
+		UPDATE chat_members
+		SET message_count = message_count + 1
+		WHERE chat_id = $1 AND user_id = $2 AND trusted_at IS NULL
+	`, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("count probation message: %w", err)
+	}
+	return nil
+}
+
+// Trust завершает испытательный срок пользователя. trustedBy = 0 — автоматически.
+// Возвращает false, если пользователь не был на испытательном сроке.
+func (r *ProbationRepository) Trust(chatID, userID, trustedBy int64) (bool, error) {
+	var by sql.NullInt64
+	if trustedBy != 0 {
+		by = sql.NullInt64{Int64: trustedBy, Valid: true}
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE chat_members
+		SET trusted_at = NOW(), trusted_by = $3
+		WHERE chat_id = $1 AND user_id = $2 AND trusted_at IS NULL
+	`, chatID, userID, by)
+	if err != nil {
+		return false, fmt.Errorf("trust member: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ============================================================================
+// ProfanityRepository - слова фильтра мата per-chat
+// ============================================================================
+
+// Виды слов чата (profanity_chat_words.kind).
+const (
+	ProfanityAllow = "allow" // исключение из словаря
+	ProfanityAdd   = "add"   // дополнительное слово словаря
+)
+
+// ProfanityRepository управляет таблицей profanity_chat_words —
+// исключениями и дополнениями чата поверх глобального profanity_dictionary.
+type ProfanityRepository struct {
+	db *sql.DB
+}
+
+// NewProfanityRepository создаёт новый репозиторий слов фильтра мата.
+func NewProfanityRepository(db *sql.DB) *ProfanityRepository {
+	return &ProfanityRepository{db: db}
+}
+
+// ProfanityChatWord — слово чата: исключение или дополнение словаря.
+type ProfanityChatWord struct {
+	Word     string
+	Kind     string // allow | add
+	Severity string // уровень дополнения (mild | moderate | severe)
+}
+
+// GetChatWords возвращает слова чата, сначала исключения, затем дополнения.
+func (r *ProfanityRepository) GetChatWords(chatID int64) ([]ProfanityChatWord, error) {
+	rows, err := r.db.Query(`
+		SELECT word, kind, severity
+		FROM profanity_chat_words
+		WHERE chat_id = $1
+		ORDER BY kind DESC, word
+	`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get profanity chat words: %w", err)
+	}
+	defer rows.Close()
+
+	var words []ProfanityChatWord
+	for rows.Next() {
+		var w ProfanityChatWord
+		if err := rows.Scan(&w.Word, &w.Kind, &w.Severity); err != nil {
+			return nil, fmt.Errorf("scan profanity chat word: %w", err)
+		}
+		words = append(words, w)
+	}
+	return words, rows.Err()
+}
+
+// SetChatWord добавляет слово чата. Слово, уже добавленное другим видом, меняет вид.
+// severity важен только для дополнений.
+func (r *ProfanityRepository) SetChatWord(chatID int64, word, kind, severity string, createdBy int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO profanity_chat_words (chat_id, word, kind, severity, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (chat_id, word) DO UPDATE
+		SET kind = EXCLUDED.kind,
+		    severity = EXCLUDED.severity,
+		    created_by = EXCLUDED.created_by,
+		    created_at = NOW()
+	`, chatID, word, kind, severity, createdBy)
+	if err != nil {
+		return fmt.Errorf("set profanity chat word: %w", err)
+	}
+	return nil
+}
+
+// RemoveChatWord удаляет слово чата. Возвращает false, если слова не было.
+func (r *ProfanityRepository) RemoveChatWord(chatID int64, word string) (bool, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM profanity_chat_words
+		WHERE chat_id = $1 AND word = $2
+	`, chatID, word)
+	if err != nil {
+		return false, fmt.Errorf("remove profanity chat word: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
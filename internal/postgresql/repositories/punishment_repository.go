@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// PunishmentRepository - муты, баны и кики
+// ============================================================================
+
+// PunishmentRepository управляет таблицей punishments.
+type PunishmentRepository struct {
+	db *sql.DB
+}
+
+// NewPunishmentRepository создаёт новый репозиторий наказаний.
+func NewPunishmentRepository(db *sql.DB) *PunishmentRepository {
+	return &PunishmentRepository{db: db}
+}
+
+// Punishment — наказание пользователя.
+type Punishment struct {
+	ID          int64
+	ChatID      int64
+	UserID      int64
+	Action      string // mute | ban | kick | restrict (запрет типа контента)
+	ContentType string // для restrict: photo, sticker, ... ("" — текст)
+	Reason      string
+	IssuedBy    int64 // 0 = бот
+	CreatedAt   time.Time
+	ExpiresAt   *time.Time // nil = навсегда
+}
+
+// Create записывает наказание. expiresAt = nil — бессрочное (или kick).
+// Наказание от админа (issuedBy != 0) закрывает предыдущие активные того же типа:
+// новый /mute заменяет старый. Наказания бота добавляются к действующим —
+// пятиминутный мут антифлуда не должен отменять бессрочный /mute.
+func (r *PunishmentRepository) Create(chatID, userID int64, action, contentType, reason string, issuedBy int64, expiresAt *time.Time) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if issuedBy != 0 {
+		if _, err := tx.Exec(`
+			UPDATE punishments SET lifted_at = NOW(), lifted_by = $4
+			WHERE chat_id = $1 AND user_id = $2 AND action = $3 AND lifted_at IS NULL
+		`, chatID, userID, action, issuedBy); err != nil {
+			return 0, fmt.Errorf("close previous punishments: %w", err)
+		}
+	}
+
+	// kick мгновенный — сразу помечается снятым, в выборку воркера не попадает
+	var liftedAt *time.Time
+	if action == "kick" {
+		now := time.Now()
+		liftedAt = &now
+	}
+
+	var id int64
+	err = tx.QueryRow(`
+		INSERT INTO punishments (chat_id, user_id, action, content_type, reason, issued_by, expires_at, lifted_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8)
+		RETURNING id
+	`, chatID, userID, action, contentType, reason, issuedBy, expiresAt, liftedAt).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("create punishment: %w", err)
+	}
+
+	return id, tx.Commit()
+}
+
+// GetExpired возвращает активные наказания с истёкшим сроком.
+func (r *PunishmentRepository) GetExpired(now time.Time) ([]Punishment, error) {
+	rows, err := r.db.Query(punishmentSelect+`
+		WHERE lifted_at IS NULL AND expires_at IS NOT NULL AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT 100
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("get expired punishments: %w", err)
+	}
+	return scanPunishments(rows)
+}
+
+// GetActive возвращает действующие на момент now наказания пользователя:
+// не снятые и не истёкшие (права в Telegram пересчитываются по ним всем).
+func (r *PunishmentRepository) GetActive(chatID, userID int64, now time.Time) ([]Punishment, error) {
+	rows, err := r.db.Query(punishmentSelect+`
+		WHERE chat_id = $1 AND user_id = $2 AND lifted_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > $3)
+		ORDER BY created_at
+	`, chatID, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("get active punishments: %w", err)
+	}
+	return scanPunishments(rows)
+}
+
+// MarkLifted помечает наказание снятым. liftedBy = 0 — воркер по истечении срока.
+func (r *PunishmentRepository) MarkLifted(id, liftedBy int64) error {
+	_, err := r.db.Exec(`
+		UPDATE punishments SET lifted_at = NOW(), lifted_by = $2
+		WHERE id = $1 AND lifted_at IS NULL
+	`, id, liftedBy)
+	if err != nil {
+		return fmt.Errorf("mark punishment lifted: %w", err)
+	}
+	return nil
+}
+
+// LiftActive снимает активные наказания типа action (/unmute, /unban).
+// Возвращает число снятых записей.
+func (r *PunishmentRepository) LiftActive(chatID, userID int64, action string, liftedBy int64) (int, error) {
+	result, err := r.db.Exec(`
+		UPDATE punishments SET lifted_at = NOW(), lifted_by = $4
+		WHERE chat_id = $1 AND user_id = $2 AND action = $3 AND lifted_at IS NULL
+	`, chatID, userID, action, liftedBy)
+	if err != nil {
+		return 0, fmt.Errorf("lift punishments: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return int(rows), nil
+}
+
+const punishmentSelect = `
+	SELECT id, chat_id, user_id, action, COALESCE(content_type, ''), COALESCE(reason, ''),
+	       issued_by, created_at, expires_at
+	FROM punishments`
+
+// scanPunishments читает строки punishmentSelect и закрывает rows.
+func scanPunishments(rows *sql.Rows) ([]Punishment, error) {
+	defer rows.Close()
+
+	var punishments []Punishment
+	for rows.Next() {
+		var p Punishment
+		if err := rows.Scan(&p.ID, &p.ChatID, &p.UserID, &p.Action, &p.ContentType, &p.Reason,
+			&p.IssuedBy, &p.CreatedAt, &p.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan punishment: %w", err)
+		}
+		punishments = append(punishments, p)
+	}
+	return punishments, rows.Err()
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// RaidRepository - настройки антирейда и состояние режима рейда
+// ============================================================================
+
+// RaidRepository управляет таблицей raid_settings.
+// Отсутствие записи означает автодетектор с порогами по умолчанию.
+type RaidRepository struct {
+	db *sql.DB
+}
+
+// NewRaidRepository создаёт новый репозиторий настроек антирейда.
+func NewRaidRepository(db *sql.DB) *RaidRepository {
+	return &RaidRepository{db: db}
+}
+
+// RaidSettings — порог рейда и текущий режим рейда в чате.
+type RaidSettings struct {
+	AutoEnabled   bool // автоматически включать режим рейда по всплеску вступлений
+	JoinThreshold int  // больше JoinThreshold вступлений за Window — рейд
+	Window        time.Duration
+	Action        string // restrict | kick — что делать с новыми участниками в режиме рейда
+	Cooldown      time.Duration
+	RaidUntil     *time.Time // конец режима рейда, nil = выключен
+}
+
+// DefaultRaidSettings — настройки чата без записи в raid_settings.
+func DefaultRaidSettings() *RaidSettings {
+	return &RaidSettings{
+		AutoEnabled:   true,
+		JoinThreshold: 10,
+		Window:        60 * time.Second,
+		Action:        "restrict",
+		Cooldown:      10 * time.Minute,
+	}
+}
+
+// Active — включён ли режим рейда в момент now.
+func (s *RaidSettings) Active(now time.Time) bool {
+	return s.RaidUntil != nil && now.Before(*s.RaidUntil)
+}
+
+// GetSettings возвращает настройки антирейда чата (по умолчанию, если записи нет).
+func (r *RaidRepository) GetSettings(chatID int64) (*RaidSettings, error) {
+	s := DefaultRaidSettings()
+	var windowSeconds, cooldownSeconds int
+	var raidUntil sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT auto_enabled, join_threshold, window_seconds, action, cooldown_seconds, raid_until
+		FROM raid_settings
+		WHERE chat_id = $1
+	`, chatID).Scan(&s.AutoEnabled, &s.JoinThreshold, &windowSeconds, &s.Action, &cooldownSeconds, &raidUntil)
+	if err == sql.ErrNoRows {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get raid settings: %w", err)
+	}
+
+	s.Window = time.Duration(windowSeconds) * time.Second
+	s.Cooldown = time.Duration(cooldownSeconds) * time.Second
+	if raidUntil.Valid {
+		s.RaidUntil = &raidUntil.Time
+	}
+	return s, nil
+}
+
+// SetSettings сохраняет порог рейда, действие и cooldown. raid_until не меняется.
+func (r *RaidRepository) SetSettings(chatID int64, s *RaidSettings, updatedBy int64) error {
+	_, err := r.db.Exec(`
+		INSERT INTO raid_settings (chat_id, auto_enabled, join_threshold, window_seconds, action, cooldown_seconds, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (chat_id) DO UPDATE
+		SET auto_enabled = EXCLUDED.auto_enabled,
+		    join_threshold = EXCLUDED.join_threshold,
+		    window_seconds = EXCLUDED.window_seconds,
+		    action = EXCLUDED.action,
+		    cooldown_seconds = EXCLUDED.cooldown_seconds,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+	`, chatID, s.AutoEnabled, s.JoinThreshold, int(s.Window/time.Second),
+		s.Action, int(s.Cooldown/time.Second), updatedBy)
+	if err != nil {
+		return fmt.Errorf("set raid settings: %w", err)
+	}
+	return nil
+}
+
+// SetRaidUntil включает режим рейда до until или выключает его (until = nil).
+// Для чата без записи создаёт запись с порогами по умолчанию.
+func (r *RaidRepository) SetRaidUntil(chatID int64, until *time.Time) error {
+	var value sql.NullTime
+	if until != nil {
+		value = sql.NullTime{Time: *until, Valid: true}
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO raid_settings (chat_id, raid_until)
+		VALUES ($1, $2)
+		ON CONFLICT (chat_id) DO UPDATE
+		SET raid_until = EXCLUDED.raid_until
+	`, chatID, value)
+	if err != nil {
+		return fmt.Errorf("set raid until: %w", err)
+	}
+	return nil
+}
+
+// GetExpiredRaids возвращает чаты, у которых режим рейда истёк к моменту now.
+func (r *RaidRepository) GetExpiredRaids(now time.Time) ([]int64, error) {
+	rows, err := r.db.Query(`
+		SELECT chat_id
+		FROM raid_settings
+		WHERE raid_until IS NOT NULL AND raid_until <= $1
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("get expired raids: %w", err)
+	}
+	defer rows.Close()
+
+	var chatIDs []int64
+	for rows.Next() {
+		var chatID int64
+		if err := rows.Scan(&chatID); err != nil {
+			return nil, fmt.Errorf("scan expired raid: %w", err)
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	return chatIDs, rows.Err()
+}
@@ -0,0 +1,291 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ============================================================================
+// ReportRepository - жалобы участников и настройки оповещений
+// ============================================================================
+
+// Статусы жалобы (reports.status).
+const (
+	ReportOpen      = "open"
+	ReportDeleted   = "deleted"
+	ReportWarned    = "warned"
+	ReportBanned    = "banned"
+	ReportDismissed = "dismissed"
+)
+
+// Куда оповещать админов о жалобах (report_settings.notify).
+const (
+	ReportNotifyMention = "mention" // упоминание админов в чате
+	ReportNotifyDM      = "dm"      // личные сообщения админам, запустившим бота
+	ReportNotifyLog     = "log"     // чат модерации log_chat_id
+)
+
+// ReportRepository управляет таблицами reports и report_settings.
+type ReportRepository struct {
+	db *sql.DB
+}
+
+// NewReportRepository создаёт новый репозиторий жалоб.
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// Report — жалоба на сообщение.
+type Report struct {
+	ID             int64
+	ChatID         int64
+	ThreadID       int
+	MessageID      int
+	ReporterID     int64
+	ReportedUserID int64
+	Reason         string
+	MessageText    string
+	Status         string
+	ResolvedBy     int64 // 0 = не разобрана
+	ResolvedAt     *time.Time
+	CreatedAt      time.Time
+}
+
+// ReportNotification — сообщение-оповещение админам с кнопками разбора.
+type ReportNotification struct {
+	ChatID    int64 `json:"chat_id"`
+	MessageID int   `json:"message_id"`
+}
+
+// ReportSettings — куда оповещать админов о жалобах.
+type ReportSettings struct {
+	Enabled   bool
+	Notify    string // ReportNotify*
+	LogChatID int64  // для Notify = log
+}
+
+// GetSettings возвращает настройки жалоб чата (включены, упоминание — если записи нет).
+func (r *ReportRepository) GetSettings(chatID int64) (*ReportSettings, error) {
+	s := &ReportSettings{Enabled: true, Notify: ReportNotifyMention}
+	var logChatID sql.NullInt64
+	err := r.db.QueryRow(`
+		SELECT enabled, notify, log_chat_id
+		FROM report_settings
+		WHERE chat_id = $1
+	`, chatID).Scan(&s.Enabled, &s.Notify, &logChatID)
+	if err == sql.ErrNoRows {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get report settings: %w", err)
+	}
+	s.LogChatID = logChatID.Int64
+	return s, nil
+}
+
+// SetSettings сохраняет настройки жалоб чата.
+func (r *ReportRepository) SetSettings(chatID int64, s *ReportSettings, updatedBy int64) error {
+	var logChatID sql.NullInt64
+	if s.LogChatID != 0 {
+		logChatID = sql.NullInt64{Int64: s.LogChatID, Valid: true}
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO report_settings (chat_id, enabled, notify, log_chat_id, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (chat_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled,
+		    notify = EXCLUDED.notify,
+		    log_chat_id = EXCLUDED.log_chat_id,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+	`, chatID, s.Enabled, s.Notify, logChatID, updatedBy)
+	if err != nil {
+		return fmt.Errorf("set report settings: %w", err)
+	}
+	return nil
+}
+
+// Create сохраняет новую жалобу и возвращает её ID. Проверка частоты и вставка идут
+// в одной транзакции под advisory-блокировкой автора жалобы: параллельные /report одного
+// участника выполняются по очереди и видят жалобы друг друга. rateLimit получает число
+// жалоб автора в чате с момента since и время последней и возвращает причину отказа
+// ("" — жалобу можно сохранить). На сообщение может быть только одна открытая жалоба
+// (уникальный idx_reports_open): id = 0 без ошибки — на сообщение уже пожаловались.
+func (r *ReportRepository) Create(report *Report, since time.Time, rateLimit func(count int, last time.Time) string) (id int64, denied string, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, "", fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockUser(tx, "reports", report.ChatID, report.ReporterID); err != nil {
+		return 0, "", err
+	}
+
+	var count int
+	var last sql.NullTime
+	if err := tx.QueryRow(`
+		SELECT COUNT(*), MAX(created_at)
+		FROM reports
+		WHERE chat_id = $1 AND reporter_id = $2 AND created_at >= $3
+	`, report.ChatID, report.ReporterID, since).Scan(&count, &last); err != nil {
+		return 0, "", fmt.Errorf("count reports by reporter: %w", err)
+	}
+	if denied := rateLimit(count, last.Time); denied != "" {
+		return 0, denied, nil
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO reports (chat_id, thread_id, message_id, reporter_id, reported_user_id, reason, message_text)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (chat_id, message_id) WHERE status = 'open' DO NOTHING
+		RETURNING id
+	`, report.ChatID, report.ThreadID, report.MessageID, report.ReporterID,
+		report.ReportedUserID, report.Reason, report.MessageText).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("create report: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, "", fmt.Errorf("commit report: %w", err)
+	}
+	return id, "", nil
+}
+
+// Get возвращает жалобу по ID (nil — не найдена).
+func (r *ReportRepository) Get(id int64) (*Report, error) {
+	rows, err := r.db.Query(reportSelect+` WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("get report: %w", err)
+	}
+	reports, err := scanReports(rows)
+	if err != nil || len(reports) == 0 {
+		return nil, err
+	}
+	return &reports[0], nil
+}
+
+// GetRecent возвращает последние limit жалоб чата, новые первыми.
+func (r *ReportRepository) GetRecent(chatID int64, limit int) ([]Report, error) {
+	rows, err := r.db.Query(reportSelect+` WHERE chat_id = $1 ORDER BY created_at DESC LIMIT $2`, chatID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get recent reports: %w", err)
+	}
+	return scanReports(rows)
+}
+
+// AddNotification запоминает отправленное админам оповещение о жалобе.
+func (r *ReportRepository) AddNotification(id int64, n ReportNotification) error {
+	data, err := json.Marshal([]ReportNotification{n})
+	if err != nil {
+		return fmt.Errorf("marshal report notification: %w", err)
+	}
+	if _, err := r.db.Exec(`
+		UPDATE reports SET notifications = notifications || $2::jsonb WHERE id = $1
+	`, id, data); err != nil {
+		return fmt.Errorf("add report notification: %w", err)
+	}
+	return nil
+}
+
+// GetNotifications возвращает оповещения админам о жалобе.
+func (r *ReportRepository) GetNotifications(id int64) ([]ReportNotification, error) {
+	var data []byte
+	if err := r.db.QueryRow(`SELECT notifications FROM reports WHERE id = $1`, id).Scan(&data); err != nil {
+		return nil, fmt.Errorf("get report notifications: %w", err)
+	}
+	var notifications []ReportNotification
+	if err := json.Unmarshal(data, &notifications); err != nil {
+		return nil, fmt.Errorf("unmarshal report notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// Resolve закрывает открытую жалобу. Возвращает false, если жалоба уже разобрана
+// (два админа нажали кнопки одновременно — действие выполняет только первый).
+func (r *ReportRepository) Resolve(id int64, status string, resolvedBy int64) (bool, error) {
+	res, err := r.db.Exec(`
+		UPDATE reports
+		SET status = $2, resolved_by = $3, resolved_at = NOW()
+		WHERE id = $1 AND status = 'open'
+	`, id, status, resolvedBy)
+	if err != nil {
+		return false, fmt.Errorf("resolve report: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	return rows > 0, nil
+}
+
+// Reopen возвращает жалобу в открытые — действие разбора не удалось выполнить.
+// Если на сообщение уже есть новая открытая жалоба, эта остаётся разобранной:
+// открытая жалоба на сообщение может быть только одна.
+func (r *ReportRepository) Reopen(id int64) error {
+	if _, err := r.db.Exec(`
+		UPDATE reports r SET status = 'open', resolved_by = NULL, resolved_at = NULL
+		WHERE r.id = $1
+		  AND NOT EXISTS (
+		      SELECT 1 FROM reports o
+		      WHERE o.chat_id = r.chat_id AND o.message_id = r.message_id AND o.status = 'open'
+		  )
+	`, id); err != nil {
+		return fmt.Errorf("reopen report: %w", err)
+	}
+	return nil
+}
+
+// StartedBot возвращает пользователей из userIDs, запустивших бота в личке
+// (/start создаёт запись chats с chat_type = 'private' и chat_id = user_id).
+func (r *ReportRepository) StartedBot(userIDs []int64) ([]int64, error) {
+	rows, err := r.db.Query(`
+		SELECT chat_id FROM chats
+		WHERE chat_type = 'private' AND is_active = true AND chat_id = ANY($1)
+	`, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("get admins who started bot: %w", err)
+	}
+	defer rows.Close()
+
+	var started []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan private chat: %w", err)
+		}
+		started = append(started, id)
+	}
+	return started, rows.Err()
+}
+
+const reportSelect = `
+	SELECT id, chat_id, thread_id, message_id, reporter_id, reported_user_id,
+	       COALESCE(reason, ''), COALESCE(message_text, ''), status,
+	       COALESCE(resolved_by, 0), resolved_at, created_at
+	FROM reports`
+
+// scanReports читает строки reportSelect и закрывает rows.
+func scanReports(rows *sql.Rows) ([]Report, error) {
+	defer rows.Close()
+
+	var reports []Report
+	for rows.Next() {
+		var rep Report
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&rep.ID, &rep.ChatID, &rep.ThreadID, &rep.MessageID, &rep.ReporterID,
+			&rep.ReportedUserID, &rep.Reason, &rep.MessageText, &rep.Status,
+			&rep.ResolvedBy, &resolvedAt, &rep.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan report: %w", err)
+		}
+		if resolvedAt.Valid {
+			rep.ResolvedAt = &resolvedAt.Time
+		}
+		reports = append(reports, rep)
+	}
+	return reports, rows.Err()
+}
@@ -3,6 +3,8 @@ package repositories
 import (
 	"database/sql"
 	"fmt"
+
+	"github.com/lib/pq"
 )
 
 // ============================================================================
@@ -35,3 +37,21 @@ func (r *SettingsRepository) GetVersion() (string, error) {
 
 	return version, nil
 }
+
+// GetAvailableModules возвращает список модулей, которыми чатам разрешено управлять
+// через /enable и /disable (bot_settings.available_modules).
+func (r *SettingsRepository) GetAvailableModules() ([]string, error) {
+	var modules []string
+	err := r.db.QueryRow(`
+		SELECT COALESCE(available_modules, '{}') FROM bot_settings WHERE id = 1
+	`).Scan(pq.Array(&modules))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get available modules: %w", err)
+	}
+
+	return modules, nil
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// ShadowRepository - срабатывания правил в теневом режиме
+// ============================================================================
+
+// Правила с теневым режимом (ShadowHit.Rule).
+const (
+	ShadowRuleBannedWord = "banned_word" // фильтр keyword_reactions (/addban)
+	ShadowRuleProfanity  = "profanity"   // фильтр мата
+	ShadowRuleLimit      = "limit"       // лимит контента (и лимит мата banned_words)
+)
+
+// ShadowRepository записывает срабатывания правил в теневом режиме (shadow = true)
+// и строит по ним отчёт. Срабатывание хранится в event_log (event_type = 'shadow_hit',
+// детали в metadata) и дописывается в массив shadow metadata сообщения.
+type ShadowRepository struct {
+	db *sql.DB
+}
+
+// NewShadowRepository создаёт новый репозиторий теневого режима.
+func NewShadowRepository(db *sql.DB) *ShadowRepository {
+	return &ShadowRepository{db: db}
+}
+
+// ShadowHit — срабатывание правила, действие которого не выполнено.
+type ShadowHit struct {
+	ChatID    int64  `json:"-"`
+	UserID    int64  `json:"-"`
+	Module    string `json:"-"`
+	ThreadID  int    `json:"thread_id"`
+	MessageID int    `json:"message_id"`
+	Rule      string `json:"rule"`              // ShadowRule*
+	RuleID    int64  `json:"rule_id,omitempty"` // keyword_reactions.id для бан-слов
+	Pattern   string `json:"pattern"`           // паттерн, слово словаря или тип контента
+	Action    string `json:"action"`            // что было бы сделано: delete, warn, ban, ...
+}
+
+// Record сохраняет срабатывание в event_log и metadata сообщения.
+func (r *ShadowRepository) Record(hit ShadowHit) error {
+	data, err := json.Marshal(hit)
+	if err != nil {
+		return fmt.Errorf("marshal shadow hit: %w", err)
+	}
+
+	details := fmt.Sprintf("Shadow %s: pattern=%q, would %s (message=%d, thread=%d)",
+		hit.Rule, hit.Pattern, hit.Action, hit.MessageID, hit.ThreadID)
+	if _, err := r.db.Exec(`
+		INSERT INTO event_log (chat_id, user_id, module_name, event_type, details, metadata)
+		VALUES ($1, $2, $3, 'shadow_hit', $4, $5)
+	`, hit.ChatID, hit.UserID, hit.Module, details, data); err != nil {
+		return fmt.Errorf("log shadow hit: %w", err)
+	}
+
+	// В одном сообщении может сработать несколько правил — дописываем в массив
+	if _, err := r.db.Exec(`
+		UPDATE messages
+		SET metadata = jsonb_set(
+			COALESCE(metadata, '{}'::jsonb),
+			'{shadow}',
+			COALESCE(metadata->'shadow', '[]'::jsonb) || $3::jsonb,
+			true
+		)
+		WHERE chat_id = $1 AND message_id = $2
+	`, hit.ChatID, hit.MessageID, data); err != nil {
+		return fmt.Errorf("update shadow metadata: %w", err)
+	}
+	return nil
+}
+
+// ShadowReportRow — срабатывания одного правила за период.
+type ShadowReportRow struct {
+	Module  string
+	Rule    string
+	RuleID  int64
+	Pattern string
+	Action  string
+	Hits    int // сколько раз сработало
+	Users   int // сколько разных пользователей
+	LastHit time.Time
+}
+
+// Report возвращает срабатывания теневого режима в чате с момента since,
+// по правилам, чаще срабатывавшие — первыми.
+func (r *ShadowRepository) Report(chatID int64, since time.Time) ([]ShadowReportRow, error) {
+	rows, err := r.db.Query(`
+		SELECT module_name,
+		       COALESCE(metadata->>'rule', ''),
+		       COALESCE((metadata->>'rule_id')::BIGINT, 0),
+		       COALESCE(metadata->>'pattern', ''),
+		       COALESCE(metadata->>'action', ''),
+		       COUNT(*),
+		       COUNT(DISTINCT user_id),
+		       MAX(created_at)
+		FROM event_log
+		WHERE chat_id = $1
+		  AND event_type = 'shadow_hit'
+		  AND created_at >= $2
+		GROUP BY 1, 2, 3, 4, 5
+		ORDER BY COUNT(*) DESC, MAX(created_at) DESC
+	`, chatID, since)
+	if err != nil {
+		return nil, fmt.Errorf("get shadow report: %w", err)
+	}
+	defer rows.Close()
+
+	var report []ShadowReportRow
+	for rows.Next() {
+		var row ShadowReportRow
+		if err := rows.Scan(&row.Module, &row.Rule, &row.RuleID, &row.Pattern, &row.Action, &row.Hits, &row.Users, &row.LastHit); err != nil {
+			return nil, fmt.Errorf("scan shadow report row: %w", err)
+		}
+		report = append(report, row)
+	}
+	return report, rows.Err()
+}
@@ -0,0 +1,268 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ============================================================================
+// WarningRepository - предупреждения и лестница эскалации
+// ============================================================================
+
+// WarningRepository управляет таблицами warnings, warn_settings и warn_escalation.
+type WarningRepository struct {
+	db *sql.DB
+}
+
+// NewWarningRepository создаёт новый репозиторий предупреждений.
+func NewWarningRepository(db *sql.DB) *WarningRepository {
+	return &WarningRepository{db: db}
+}
+
+// Warning — одно предупреждение пользователя.
+type Warning struct {
+	ID        int64
+	ChatID    int64
+	UserID    int64
+	IssuedBy  int64 // 0 = бот
+	Source    string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// WarnSettings — какие нарушения выдают предупреждения автоматически.
+type WarnSettings struct {
+	AutoLimiter     bool
+	AutoProfanity   bool
+	AutoBannedWords bool
+	AutoLinks       bool
+}
+
+// EscalationStep — ступень лестницы: при WarnCount активных предупреждений применяется Action.
+type EscalationStep struct {
+	WarnCount int
+	Action    string        // mute | kick | ban
+	Duration  time.Duration // 0 = навсегда
+}
+
+// DefaultEscalation — лестница для чатов без своей политики: 3 → мут на час, 5 → бан.
+func DefaultEscalation() []EscalationStep {
+	return []EscalationStep{
+		{WarnCount: 3, Action: "mute", Duration: time.Hour},
+		{WarnCount: 5, Action: "ban"},
+	}
+}
+
+// AddWarning добавляет предупреждение и возвращает число активных предупреждений пользователя.
+// За одно сообщение (messageID != 0) из одного источника выдаётся одно предупреждение:
+// повтор (правка сообщения, параллельное срабатывание) возвращает added = false.
+// Вставка и подсчёт — в транзакции под блокировкой пользователя чата (lockUser):
+// при READ COMMITTED один запрос не видит строку параллельной транзакции,
+// и два предупреждения за разные сообщения получили бы одно и то же число.
+func (r *WarningRepository) AddWarning(chatID, userID, issuedBy int64, source, reason string, messageID int) (count int, added bool, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockUser(tx, "warnings", chatID, userID); err != nil {
+		return 0, false, err
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO warnings (chat_id, user_id, issued_by, source, reason, message_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (chat_id, message_id, source) WHERE message_id <> 0 DO NOTHING
+	`, chatID, userID, issuedBy, source, reason, messageID)
+	if err != nil {
+		return 0, false, fmt.Errorf("add warning: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return 0, false, nil
+	}
+
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM warnings
+		WHERE chat_id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, chatID, userID).Scan(&count); err != nil {
+		return 0, false, fmt.Errorf("count active warnings: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("commit warning: %w", err)
+	}
+	return count, true, nil
+}
+
+// lockUser берёт транзакционную advisory-блокировку пользователя чата в области scope:
+// параллельные транзакции с той же блокировкой выполняются по очереди,
+// блокировка снимается при commit или rollback.
+func lockUser(tx *sql.Tx, scope string, chatID, userID int64) error {
+	if _, err := tx.Exec(`
+		SELECT pg_advisory_xact_lock(hashtextextended(format('%s:%s:%s', $1::text, $2::bigint, $3::bigint), 0))
+	`, scope, chatID, userID); err != nil {
+		return fmt.Errorf("lock %s of user %d: %w", scope, userID, err)
+	}
+	return nil
+}
+
+// CountActive возвращает число активных (не снятых) предупреждений пользователя.
+func (r *WarningRepository) CountActive(chatID, userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM warnings
+		WHERE chat_id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, chatID, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count active warnings: %w", err)
+	}
+	return count, nil
+}
+
+// GetActive возвращает активные предупреждения пользователя (от старых к новым).
+func (r *WarningRepository) GetActive(chatID, userID int64) ([]Warning, error) {
+	rows, err := r.db.Query(`
+		SELECT id, chat_id, user_id, issued_by, source, COALESCE(reason, ''), created_at
+		FROM warnings
+		WHERE chat_id = $1 AND user_id = $2 AND revoked_at IS NULL
+		ORDER BY created_at
+	`, chatID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get active warnings: %w", err)
+	}
+	defer rows.Close()
+
+	var warnings []Warning
+	for rows.Next() {
+		var w Warning
+		if err := rows.Scan(&w.ID, &w.ChatID, &w.UserID, &w.IssuedBy, &w.Source, &w.Reason, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan warning: %w", err)
+		}
+		warnings = append(warnings, w)
+	}
+	return warnings, rows.Err()
+}
+
+// RevokeLast снимает последнее активное предупреждение. Возвращает false, если снимать нечего.
+func (r *WarningRepository) RevokeLast(chatID, userID, revokedBy int64) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE warnings SET revoked_at = NOW(), revoked_by = $3
+		WHERE id = (
+			SELECT id FROM warnings
+			WHERE chat_id = $1 AND user_id = $2 AND revoked_at IS NULL
+			ORDER BY created_at DESC
+			LIMIT 1
+		)
+	`, chatID, userID, revokedBy)
+	if err != nil {
+		return false, fmt.Errorf("revoke last warning: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// RevokeAll снимает все активные предупреждения пользователя. Возвращает число снятых.
+func (r *WarningRepository) RevokeAll(chatID, userID, revokedBy int64) (int, error) {
+	result, err := r.db.Exec(`
+		UPDATE warnings SET revoked_at = NOW(), revoked_by = $3
+		WHERE chat_id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, chatID, userID, revokedBy)
+	if err != nil {
+		return 0, fmt.Errorf("revoke all warnings: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return int(rows), nil
+}
+
+// GetSettings возвращает настройки автопредупреждений (по умолчанию всё выключено).
+func (r *WarningRepository) GetSettings(chatID int64) (*WarnSettings, error) {
+	s := &WarnSettings{}
+	err := r.db.QueryRow(`
+		SELECT auto_limiter, auto_profanity, auto_banned_words, auto_links
+		FROM warn_settings
+		WHERE chat_id = $1
+	`, chatID).Scan(&s.AutoLimiter, &s.AutoProfanity, &s.AutoBannedWords, &s.AutoLinks)
+	if err == sql.ErrNoRows {
+		return &WarnSettings{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get warn settings: %w", err)
+	}
+	return s, nil
+}
+
+// SetSettings сохраняет настройки автопредупреждений.
+func (r *WarningRepository) SetSettings(chatID int64, s *WarnSettings) error {
+	_, err := r.db.Exec(`
+		INSERT INTO warn_settings (chat_id, auto_limiter, auto_profanity, auto_banned_words, auto_links, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (chat_id) DO UPDATE
+		SET auto_limiter = EXCLUDED.auto_limiter,
+		    auto_profanity = EXCLUDED.auto_profanity,
+		    auto_banned_words = EXCLUDED.auto_banned_words,
+		    auto_links = EXCLUDED.auto_links,
+		    updated_at = NOW()
+	`, chatID, s.AutoLimiter, s.AutoProfanity, s.AutoBannedWords, s.AutoLinks)
+	if err != nil {
+		return fmt.Errorf("set warn settings: %w", err)
+	}
+	return nil
+}
+
+// GetEscalation возвращает лестницу чата, отсортированную по числу предупреждений.
+// Нет своих ступеней — возвращается DefaultEscalation.
+func (r *WarningRepository) GetEscalation(chatID int64) ([]EscalationStep, error) {
+	rows, err := r.db.Query(`
+		SELECT warn_count, action, duration_seconds
+		FROM warn_escalation
+		WHERE chat_id = $1
+		ORDER BY warn_count
+	`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get warn escalation: %w", err)
+	}
+	defer rows.Close()
+
+	var steps []EscalationStep
+	for rows.Next() {
+		var step EscalationStep
+		var seconds int
+		if err := rows.Scan(&step.WarnCount, &step.Action, &seconds); err != nil {
+			return nil, fmt.Errorf("scan escalation step: %w", err)
+		}
+		step.Duration = time.Duration(seconds) * time.Second
+		steps = append(steps, step)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return DefaultEscalation(), nil
+	}
+	return steps, nil
+}
+
+// SetEscalation заменяет лестницу чата целиком (в транзакции).
+// Чтобы сохранить ступени по умолчанию при изменении одной, вызывающий
+// передаёт полную лестницу (GetEscalation + правка).
+func (r *WarningRepository) SetEscalation(chatID int64, steps []EscalationStep) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM warn_escalation WHERE chat_id = $1`, chatID); err != nil {
+		return fmt.Errorf("clear warn escalation: %w", err)
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(`
+			INSERT INTO warn_escalation (chat_id, warn_count, action, duration_seconds)
+			VALUES ($1, $2, $3, $4)
+		`, chatID, step.WarnCount, step.Action, int(step.Duration/time.Second)); err != nil {
+			return fmt.Errorf("insert escalation step: %w", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 
 	"go.uber.org/zap"
@@ -44,15 +45,66 @@ func LoadConfigFromEnv() LoaderConfig {
 	}
 }
 
+// Уровни серьёзности слов словаря (profanity_dictionary.severity).
+const (
+	SeverityMild     = "mild"     // грубые, но не матерные слова
+	SeverityModerate = "moderate" // мат (по умолчанию)
+	SeveritySevere   = "severe"   // оскорбления и самые грубые формы
+)
+
+// SeverityRank возвращает порядок уровня: mild < moderate < severe.
+// Неизвестный уровень считается moderate.
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityMild:
+		return 1
+	case SeveritySevere:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// ValidSeverity — известный ли уровень серьёзности.
+func ValidSeverity(severity string) bool {
+	return severity == SeverityMild || severity == SeverityModerate || severity == SeveritySevere
+}
+
+// Entry — слово словаря. В JSON словаря элемент — строка (слово уровня moderate)
+// или объект {"pattern": "...", "severity": "severe", "regex": false}.
+type Entry struct {
+	Pattern  string `json:"pattern"`
+	Severity string `json:"severity"`
+	IsRegex  bool   `json:"regex"`
+}
+
+// UnmarshalJSON принимает и строку, и объект.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		e.Severity = SeverityModerate
+		return json.Unmarshal(data, &e.Pattern)
+	}
+	type entry Entry // без UnmarshalJSON, чтобы не уйти в рекурсию
+	var v entry
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = Entry(v)
+	if e.Severity == "" {
+		e.Severity = SeverityModerate
+	}
+	return nil
+}
+
 // loadWords загружает слова из gzip-сжатого JSON
-func loadWords(data []byte) ([]string, error) {
+func loadWords(data []byte) ([]Entry, error) {
 	gr, err := gzip.NewReader(strings.NewReader(string(data)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
 	}
 	defer gr.Close()
 
-	var words []string
+	var words []Entry
 	decoder := json.NewDecoder(gr)
 	if err := decoder.Decode(&words); err != nil {
 		return nil, fmt.Errorf("failed to decode json: %w", err)
@@ -62,7 +114,7 @@ func loadWords(data []byte) ([]string, error) {
 }
 
 // loadFromFile загружает словарь из файла
-func loadFromFile(path string) ([]string, error) {
+func loadFromFile(path string) ([]Entry, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
@@ -71,7 +123,7 @@ func loadFromFile(path string) ([]string, error) {
 }
 
 // loadFromEmbedded загружает встроенный словарь
-func loadFromEmbedded() ([]string, error) {
+func loadFromEmbedded() ([]Entry, error) {
 	return loadWords(embeddedDictionary)
 }
 
@@ -92,7 +144,7 @@ func EnsureDictionary(ctx context.Context, db *sql.DB, logger *zap.Logger) error
 	}
 
 	// Загружаем слова в зависимости от источника
-	var words []string
+	var words []Entry
 
 	switch config.Source {
 	case SourceSkip:
@@ -133,7 +185,7 @@ func EnsureDictionary(ctx context.Context, db *sql.DB, logger *zap.Logger) error
 
 	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO profanity_dictionary (pattern, is_regex, severity)
-		VALUES ($1, false, 'moderate')
+		VALUES ($1, $2, $3)
 		ON CONFLICT (pattern) DO NOTHING
 	`)
 	if err != nil {
@@ -143,12 +195,22 @@ func EnsureDictionary(ctx context.Context, db *sql.DB, logger *zap.Logger) error
 
 	inserted := 0
 	for _, word := range words {
-		if word == "" {
+		if word.Pattern == "" {
 			continue
 		}
-		result, err := stmt.ExecContext(ctx, word)
+		if !ValidSeverity(word.Severity) {
+			logger.Warn("unknown severity, using moderate", zap.String("word", word.Pattern), zap.String("severity", word.Severity))
+			word.Severity = SeverityModerate
+		}
+		if word.IsRegex {
+			if _, err := regexp.Compile(word.Pattern); err != nil {
+				logger.Warn("skipping invalid regex", zap.Error(err), zap.String("word", word.Pattern))
+				continue
+			}
+		}
+		result, err := stmt.ExecContext(ctx, word.Pattern, word.IsRegex, word.Severity)
 		if err != nil {
-			logger.Warn("failed to insert word", zap.Error(err), zap.String("word", word))
+			logger.Warn("failed to insert word", zap.Error(err), zap.String("word", word.Pattern))
 			continue
 		}
 		rows, _ := result.RowsAffected()
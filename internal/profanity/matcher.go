@@ -0,0 +1,246 @@
+package profanity
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Matcher ищет слова словаря в тексте за один проход: обычные слова —
+// автоматом Ахо-Корасик, регулярные выражения компилируются один раз при сборке.
+// Стоимость проверки сообщения зависит от длины текста, а не от размера словаря
+// (кроме регулярных выражений — их в словаре единицы).
+// Matcher неизменяем после NewMatcher и безопасен для параллельного использования.
+//
+// Слово находится, если Text.Contains нашёл бы его: ключами автомата служат
+// и слово в нижнем регистре, и нормализованное слово, оба ищутся в обоих видах
+// текста; Wildcard в тексте заменяет не больше одной буквы.
+type Matcher struct {
+	nodes    []acNode
+	entries  []Entry // обычные слова; индекс — номер в out
+	regexes  []compiledEntry
+	alphabet []rune // все буквы слов — варианты подстановки вместо Wildcard
+	maxLen   int    // длина самого длинного ключа в рунах
+}
+
+// acNode — узел автомата Ахо-Корасик.
+type acNode struct {
+	next map[rune]int32
+	fail int32   // самый длинный собственный суффикс, который есть в боре
+	dict int32   // ближайший по fail-цепочке узел с out; -1 — нет
+	out  []int32 // индексы entries, ключ которых заканчивается в этом узле
+}
+
+// compiledEntry — регулярное выражение словаря.
+type compiledEntry struct {
+	re    *regexp.Regexp
+	entry Entry
+}
+
+// Text — текст сообщения, подготовленный для поиска: в нижнем регистре и нормализованный.
+type Text struct {
+	Lower      string
+	Normalized string
+}
+
+// Prepare готовит текст для Matcher.Match и Text.Contains. Вызывается один раз на сообщение.
+func Prepare(text string) Text {
+	return Text{Lower: strings.ToLower(text), Normalized: Normalize(text)}
+}
+
+// Contains проверяет одно слово словаря — линейная проверка для небольших
+// списков (слова чата). Для общего словаря используется Matcher.
+func (t Text) Contains(e Entry) bool {
+	if e.IsRegex {
+		re, err := regexp.Compile(e.Pattern)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(t.Lower) || re.MatchString(t.Normalized)
+	}
+	lower := strings.ToLower(e.Pattern)
+	return (lower != "" && strings.Contains(t.Lower, lower)) || Contains(t.Normalized, Normalize(e.Pattern))
+}
+
+// NewMatcher собирает автомат по словам словаря. Регулярные выражения,
+// которые не компилируются, пропускаются (загрузчик словаря их не пропускает в БД).
+func NewMatcher(entries []Entry) *Matcher {
+	m := &Matcher{nodes: []acNode{{fail: 0, dict: -1}}}
+	letters := make(map[rune]bool)
+
+	for _, e := range entries {
+		if e.IsRegex {
+			re, err := regexp.Compile(e.Pattern)
+			if err != nil {
+				continue
+			}
+			m.regexes = append(m.regexes, compiledEntry{re: re, entry: e})
+			continue
+		}
+
+		idx := int32(len(m.entries))
+		m.entries = append(m.entries, e)
+		lower, normalized := strings.ToLower(e.Pattern), Normalize(e.Pattern)
+		m.insert(lower, idx)
+		if normalized != lower {
+			m.insert(normalized, idx)
+		}
+		for _, r := range normalized {
+			if r != Wildcard {
+				letters[r] = true
+			}
+		}
+	}
+
+	for r := range letters {
+		m.alphabet = append(m.alphabet, r)
+	}
+	m.build()
+	return m
+}
+
+// Len возвращает число слов в Matcher (обычных и регулярных выражений).
+func (m *Matcher) Len() int {
+	return len(m.entries) + len(m.regexes)
+}
+
+// insert добавляет ключ в бор.
+func (m *Matcher) insert(key string, idx int32) {
+	if key == "" {
+		return
+	}
+	state, n := int32(0), 0
+	for _, r := range key {
+		n++
+		next, ok := m.nodes[state].next[r]
+		if !ok {
+			next = int32(len(m.nodes))
+			m.nodes = append(m.nodes, acNode{dict: -1})
+			if m.nodes[state].next == nil {
+				m.nodes[state].next = make(map[rune]int32)
+			}
+			m.nodes[state].next[r] = next
+		}
+		state = next
+	}
+	m.nodes[state].out = append(m.nodes[state].out, idx)
+	if n > m.maxLen {
+		m.maxLen = n
+	}
+}
+
+// build вычисляет fail- и dict-ссылки обходом бора в ширину.
+func (m *Matcher) build() {
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[state].next {
+			fail := m.nodes[state].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if next, ok := m.nodes[fail].next[r]; ok && next != child {
+				fail = next
+			} else {
+				fail = 0
+			}
+			m.nodes[child].fail = fail
+			if len(m.nodes[fail].out) > 0 {
+				m.nodes[child].dict = fail
+			} else {
+				m.nodes[child].dict = m.nodes[fail].dict
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Match возвращает самое серьёзное слово словаря не ниже minSeverity, найденное в тексте.
+func (m *Matcher) Match(t Text, minSeverity string) (Entry, bool) {
+	minRank := SeverityRank(minSeverity)
+	best, bestRank := int32(-1), 0
+	maxRank := SeverityRank(SeveritySevere)
+
+	// report учитывает найденное слово; true — дальше искать незачем
+	report := func(idx int32) bool {
+		rank := SeverityRank(m.entries[idx].Severity)
+		if rank >= minRank && rank > bestRank {
+			best, bestRank = idx, rank
+		}
+		return bestRank == maxRank
+	}
+
+	if !m.search([]rune(t.Lower), report) {
+		normalized := []rune(t.Normalized)
+		if !m.search(normalized, report) {
+			m.searchWildcards(normalized, report)
+		}
+	}
+
+	var result Entry
+	if best >= 0 {
+		result = m.entries[best]
+	}
+	for _, c := range m.regexes {
+		rank := SeverityRank(c.entry.Severity)
+		if rank < minRank || rank <= bestRank {
+			continue
+		}
+		if c.re.MatchString(t.Lower) || c.re.MatchString(t.Normalized) {
+			result, bestRank = c.entry, rank
+		}
+	}
+	return result, bestRank > 0
+}
+
+// search проходит текст автоматом. Возвращает true, если report попросил остановиться.
+func (m *Matcher) search(text []rune, report func(int32) bool) bool {
+	state := int32(0)
+	for _, r := range text {
+		for state != 0 {
+			if _, ok := m.nodes[state].next[r]; ok {
+				break
+			}
+			state = m.nodes[state].fail
+		}
+		if next, ok := m.nodes[state].next[r]; ok {
+			state = next
+		}
+		for o := state; o > 0; o = m.nodes[o].dict {
+			for _, idx := range m.nodes[o].out {
+				if report(idx) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// searchWildcards проверяет замаскированные буквы («х*й»): вместо каждого
+// Wildcard по очереди подставляется каждая буква словаря в окне длиной
+// в самый длинный ключ. Остальные Wildcard в окне ничему не совпадают,
+// поэтому одно совпадение использует не больше одной маски.
+func (m *Matcher) searchWildcards(text []rune, report func(int32) bool) bool {
+	window := make([]rune, 0, 2*m.maxLen)
+	for i, r := range text {
+		if r != Wildcard {
+			continue
+		}
+		lo, hi := max(0, i-m.maxLen+1), min(len(text), i+m.maxLen)
+		window = append(window[:0], text[lo:hi]...)
+		for _, letter := range m.alphabet {
+			window[i-lo] = letter
+			if m.search(window, report) {
+				return true
+			}
+		}
+	}
+	return false
+}
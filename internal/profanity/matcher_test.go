@@ -0,0 +1,152 @@
+package profanity
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestMatcherEvasions проверяет, что Matcher находит те же обходы, что и Text.Contains
+func TestMatcherEvasions(t *testing.T) {
+	var dict []Entry
+	for _, tc := range evasions {
+		dict = append(dict, Entry{Pattern: tc.word, Severity: SeverityModerate})
+	}
+	m := NewMatcher(dict)
+
+	for _, tc := range evasions {
+		text := Prepare(tc.text)
+		if !text.Contains(Entry{Pattern: tc.word}) {
+			t.Errorf("Text.Contains(%q) = false for %q", tc.word, tc.text)
+		}
+		if _, ok := m.Match(text, SeverityMild); !ok {
+			t.Errorf("Match(%q) = false", tc.text)
+		}
+	}
+}
+
+// TestMatcherClean проверяет отсутствие ложных срабатываний
+func TestMatcherClean(t *testing.T) {
+	for _, tc := range clean {
+		m := NewMatcher([]Entry{{Pattern: tc.word, Severity: SeverityModerate}})
+		if e, ok := m.Match(Prepare(tc.text), SeverityMild); ok {
+			t.Errorf("Match(%q) = %q, want no match", tc.text, e.Pattern)
+		}
+	}
+}
+
+// TestMatcherSeverity проверяет выбор самого серьёзного слова и порог
+func TestMatcherSeverity(t *testing.T) {
+	m := NewMatcher([]Entry{
+		{Pattern: "блин", Severity: SeverityMild},
+		{Pattern: "сука", Severity: SeverityModerate},
+		{Pattern: "хуй", Severity: SeveritySevere},
+		{Pattern: `пид[оа]р`, Severity: SeveritySevere, IsRegex: true},
+		{Pattern: `([`, Severity: SeveritySevere, IsRegex: true}, // не компилируется — пропускается
+	})
+	if m.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", m.Len())
+	}
+
+	tests := []struct {
+		text        string
+		minSeverity string
+		want        string // "" — совпадения нет
+	}{
+		{"блин, сука", SeverityMild, "сука"},
+		{"блин, х у й", SeverityMild, "хуй"},
+		{"блин", SeverityMild, "блин"},
+		{"блин", SeverityModerate, ""},
+		{"сука", SeveritySevere, ""},
+		{"ну ты пидар", SeverityModerate, `пид[оа]р`},
+		{"с*ка", SeverityMild, "сука"},
+		{"обычный текст", SeverityMild, ""},
+	}
+	for _, tc := range tests {
+		e, ok := m.Match(Prepare(tc.text), tc.minSeverity)
+		if got := e.Pattern; !ok && tc.want != "" || ok && got != tc.want {
+			t.Errorf("Match(%q, %s) = %q, %v; want %q", tc.text, tc.minSeverity, got, ok, tc.want)
+		}
+	}
+}
+
+// TestMatcherOverlap проверяет слова, которые являются суффиксами и префиксами друг друга
+func TestMatcherOverlap(t *testing.T) {
+	m := NewMatcher([]Entry{
+		{Pattern: "абвгд", Severity: SeverityModerate},
+		{Pattern: "вгде", Severity: SeveritySevere},
+		{Pattern: "гд", Severity: SeverityMild},
+	})
+	if e, _ := m.Match(Prepare("абвгде"), SeverityMild); e.Pattern != "вгде" {
+		t.Errorf("Match = %q, want %q", e.Pattern, "вгде")
+	}
+	if e, _ := m.Match(Prepare("абвгж"), SeverityMild); e.Pattern != "" {
+		t.Errorf("Match = %q, want no match", e.Pattern)
+	}
+	if e, _ := m.Match(Prepare("ыгдж"), SeverityMild); e.Pattern != "гд" {
+		t.Errorf("Match = %q, want %q", e.Pattern, "гд")
+	}
+}
+
+// benchMessage — типичное сообщение чата без мата.
+const benchMessage = "Привет всем! Кто-нибудь знает, во сколько завтра встреча? " +
+	"Я скинул ссылку на документ в общий канал, посмотрите, пожалуйста, до вечера."
+
+// syntheticDictionary — n случайных слов из кириллицы длиной 4–9 букв.
+func syntheticDictionary(n int) []Entry {
+	letters := []rune("абвгдежзийклмнопрстуфхцчшщъыьэюя")
+	rnd := rand.New(rand.NewSource(int64(n)))
+	dict := make([]Entry, 0, n)
+	for i := 0; i < n; i++ {
+		word := make([]rune, 4+rnd.Intn(6))
+		for j := range word {
+			word[j] = letters[rnd.Intn(len(letters))]
+		}
+		dict = append(dict, Entry{Pattern: string(word), Severity: SeverityModerate})
+	}
+	return dict
+}
+
+// BenchmarkMatcher — стоимость проверки сообщения не растёт с размером словаря
+func BenchmarkMatcher(b *testing.B) {
+	for _, n := range []int{100, 1000, 5000, 20000} {
+		m := NewMatcher(syntheticDictionary(n))
+		b.Run(fmt.Sprintf("words=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m.Match(Prepare(benchMessage), SeverityMild)
+			}
+		})
+	}
+}
+
+// BenchmarkMatcherWildcard — сообщение с маской «*» перебирает буквы словаря в окне
+func BenchmarkMatcherWildcard(b *testing.B) {
+	message := strings.Replace(benchMessage, "встреча", "вс*реча", 1)
+	for _, n := range []int{100, 1000, 5000, 20000} {
+		m := NewMatcher(syntheticDictionary(n))
+		b.Run(fmt.Sprintf("words=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m.Match(Prepare(message), SeverityMild)
+			}
+		})
+	}
+}
+
+// BenchmarkLinear — прежняя проверка: каждое слово словаря отдельно
+func BenchmarkLinear(b *testing.B) {
+	for _, n := range []int{100, 1000, 5000, 20000} {
+		dict := syntheticDictionary(n)
+		b.Run(fmt.Sprintf("words=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				text := Prepare(benchMessage)
+				for _, e := range dict {
+					if text.Contains(e) {
+						break
+					}
+				}
+			}
+		})
+	}
+}
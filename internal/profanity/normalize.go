@@ -0,0 +1,181 @@
+package profanity
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Нормализация текста перед поиском мата.
+// Normalize приводит текст и слова словаря к одному виду, чтобы обходы
+// вида «х у й», «xуй» (латинская x), «хуууй», «х.у.й», «6лять», «ху​й»
+// совпадали со словарём обычным поиском подстроки. Маскировка «х*й»
+// сохраняется как символ Wildcard и учитывается в Contains.
+//
+// Этапы:
+//  1. нижний регистр, удаление невидимых символов (zero-width, soft hyphen,
+//     диакритика);
+//  2. разбиение на слова по пробелам, удаление разделителей внутри слова
+//     («х.у.й», «х-у-й» → «хуй»);
+//  3. склейка цепочек однобуквенных слов («х у й» → «хуй»);
+//  4. в словах с кириллицей — замена латинских двойников и цифр
+//     на кириллические буквы («xyй» → «хуй», «3аеб4л» → «заебчл»);
+//  5. схлопывание повторов («хуууй» → «хуй»).
+//
+// Латиница и цифры заменяются только в словах, где уже есть кириллица:
+// иначе английский текст («ebay») превращался бы в русский мат.
+
+// Wildcard — замаскированная буква («х*й», «б#ять»). Совпадает с любой одной буквой.
+const Wildcard = '*'
+
+// lookalikes — латинские буквы, похожие на кириллические по начертанию
+// или используемые вместо них в транслите.
+var lookalikes = map[rune]rune{
+	'a': 'а', 'b': 'б', 'c': 'с', 'd': 'д', 'e': 'е', 'h': 'н', 'i': 'и',
+	'k': 'к', 'l': 'л', 'm': 'м', 'n': 'п', 'o': 'о', 'p': 'р', 'r': 'г',
+	't': 'т', 'u': 'и', 'x': 'х', 'y': 'у', 'z': 'з',
+	// Буквы других кириллических алфавитов и ё
+	'ё': 'е', 'і': 'и', 'ї': 'и', 'ў': 'у',
+}
+
+// digits — цифры и символы, которыми пишут буквы («6лять», «п1зда», «@»).
+var digits = map[rune]rune{
+	'0': 'о', '1': 'и', '3': 'з', '4': 'ч', '6': 'б', '9': 'я', '@': 'а', '$': 'с',
+}
+
+// masks — символы, которыми закрывают букву.
+var masks = map[rune]bool{'*': true, '#': true, '%': true}
+
+// Normalize возвращает нормализованный текст: слова через один пробел.
+// Слова словаря нормализуются той же функцией.
+func Normalize(text string) string {
+	var words [][]rune
+	for _, field := range strings.Fields(strings.ToLower(text)) {
+		if w := cleanWord(field); len(w) > 0 {
+			words = append(words, w)
+		}
+	}
+	words = joinSingleLetters(words)
+
+	var b strings.Builder
+	for i, w := range words {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(string(squeeze(toCyrillic(w))))
+	}
+	return b.String()
+}
+
+// cleanWord удаляет из слова невидимые символы и разделители.
+// Маски внутри слова становятся Wildcard, по краям — удаляются («*слово*»).
+func cleanWord(field string) []rune {
+	var w []rune
+	for _, r := range field {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || digits[r] != 0:
+			w = append(w, r)
+		case masks[r]:
+			w = append(w, Wildcard)
+		}
+		// Всё остальное — разделители, zero-width (Cf) и диакритика (Mn)
+	}
+	start, end := 0, len(w)
+	for start < end && w[start] == Wildcard {
+		start++
+	}
+	for end > start && w[end-1] == Wildcard {
+		end--
+	}
+	return w[start:end]
+}
+
+// joinSingleLetters склеивает подряд идущие однобуквенные слова («х у й» → «хуй»).
+// Одиночное однобуквенное слово («и», «в») не трогается.
+func joinSingleLetters(words [][]rune) [][]rune {
+	var out [][]rune
+	for i := 0; i < len(words); {
+		j := i
+		var joined []rune
+		for j < len(words) && len(words[j]) == 1 {
+			joined = append(joined, words[j]...)
+			j++
+		}
+		if j-i >= 2 {
+			out = append(out, joined)
+			i = j
+			continue
+		}
+		out = append(out, words[i])
+		i++
+	}
+	return out
+}
+
+// toCyrillic заменяет латинские двойники и цифры, если в слове есть кириллица.
+func toCyrillic(w []rune) []rune {
+	hasCyrillic := false
+	for _, r := range w {
+		if unicode.Is(unicode.Cyrillic, r) {
+			hasCyrillic = true
+			break
+		}
+	}
+	if !hasCyrillic {
+		return w
+	}
+	for i, r := range w {
+		if c, ok := lookalikes[r]; ok {
+			w[i] = c
+		} else if c, ok := digits[r]; ok {
+			w[i] = c
+		}
+	}
+	return w
+}
+
+// squeeze схлопывает повторы одного символа («хуууй» → «хуй», «х**й» → «х*й»).
+func squeeze(w []rune) []rune {
+	out := w[:0]
+	for i, r := range w {
+		if i > 0 && r == w[i-1] {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Contains ищет нормализованное слово словаря в нормализованном тексте.
+// Wildcard в тексте совпадает с любой буквой слова, но не больше одного раза
+// на совпадение: иначе «*у*» находило бы любое слово из трёх букв.
+func Contains(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	if !strings.ContainsRune(text, Wildcard) {
+		return strings.Contains(text, word)
+	}
+
+	t, w := []rune(text), []rune(word)
+	for i := 0; i+len(w) <= len(t); i++ {
+		wildcards := 0
+		matched := true
+		for j, r := range w {
+			switch t[i+j] {
+			case r:
+			case Wildcard:
+				wildcards++
+			default:
+				matched = false
+			}
+			if !matched || wildcards > 1 {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
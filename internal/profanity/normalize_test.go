@@ -0,0 +1,93 @@
+package profanity
+
+import "testing"
+
+// evasions — реальные обходы фильтра мата: текст сообщения → слово словаря.
+var evasions = []struct {
+	text string
+	word string
+}{
+	{"хуй", "хуй"},
+	{"ХУЙ", "хуй"},
+	{"х у й", "хуй"},
+	{"х.у.й", "хуй"},
+	{"х-у-й", "хуй"},
+	{"х_у_й", "хуй"},
+	{"х. у. й.", "хуй"},
+	{"xуй", "хуй"}, // латинская x
+	{"xyй", "хуй"}, // латинские x и y
+	{"XYЙ", "хуй"}, // латиница в верхнем регистре
+	{"хуууууй", "хуй"},
+	{"ххххуууй", "хуй"},
+	{"х*й", "хуй"},
+	{"х**й", "хуй"},
+	{"х#й", "хуй"},
+	{"ху\u200bй", "хуй"}, // zero-width space
+	{"х\u00adуй", "хуй"}, // soft hyphen
+	{"ху\u0301й", "хуй"}, // комбинируемое ударение
+	{"6лять", "блять"},
+	{"бля", "бля"},
+	{"bля", "бля"},
+	{"п1зда", "пизда"},
+	{"пiзда", "пизда"}, // латинская i
+	{"пізда", "пизда"}, // украинская і
+	{"3аебал", "заебал"},
+	{"eбaть", "ебать"},    // латинские e и a
+	{"ёбаный", "ебанный"}, // ё и двойная н в словаре
+	{"ну ты и с у к а", "сука"},
+	{"сууука!!!", "сука"},
+	{"пидор@с", "пидорас"},
+}
+
+// clean — тексты, в которых мата нет, хотя нормализация их меняет.
+var clean = []struct {
+	text string
+	word string
+}{
+	{"их уйти", "хуй"},  // граница слов не склеивается
+	{"ebay", "еба"},     // чистая латиница не переводится в кириллицу
+	{"2024 год", "зо"},  // чистые цифры не переводятся в буквы
+	{"*у*", "хуй"},      // маски по краям слова убираются
+	{"б*я*ь", "блять"},  // больше одной маски на совпадение не считается
+	{"в доме", "вдоме"}, // одиночное однобуквенное слово не склеивается
+	{"страховка", "хуй"},
+}
+
+// TestNormalizeEvasions проверяет, что обходы находятся после нормализации
+func TestNormalizeEvasions(t *testing.T) {
+	for _, tc := range evasions {
+		text, word := Normalize(tc.text), Normalize(tc.word)
+		if !Contains(text, word) {
+			t.Errorf("Contains(Normalize(%q), Normalize(%q)) = false (text=%q, word=%q)", tc.text, tc.word, text, word)
+		}
+	}
+}
+
+// TestNormalizeClean проверяет отсутствие ложных срабатываний
+func TestNormalizeClean(t *testing.T) {
+	for _, tc := range clean {
+		text, word := Normalize(tc.text), Normalize(tc.word)
+		if Contains(text, word) {
+			t.Errorf("Contains(Normalize(%q), Normalize(%q)) = true (text=%q, word=%q)", tc.text, tc.word, text, word)
+		}
+	}
+}
+
+// TestNormalize проверяет результат нормализации
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Х  У  Й":     "хуй",
+		"xyй, ну!":    "хуй ну",
+		"*важно*":     "важно",
+		"hello world": "helo world",
+		"ёлка":        "елка",
+		"":            "",
+		"\u200b":      "",
+		"а б в где":   "абв где",
+	}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -43,6 +43,18 @@ CREATE TABLE chat_vips (
 
 CREATE INDEX idx_chat_vips_lookup ON chat_vips(chat_id, thread_id, user_id);
 
+-- Включение/выключение модулей per-chat/per-topic.
+-- Нет записи = модуль включён.
+CREATE TABLE chat_modules (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id BIGINT DEFAULT 0,
+    module_name VARCHAR(50) NOT NULL,
+    is_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id, module_name)
+);
+
 -- Партиционированная таблица сообщений.
 -- Партиции создаются автоматически модулем Maintenance при запуске бота.
 CREATE TABLE messages (
@@ -68,6 +80,22 @@ CREATE INDEX idx_messages_content_type ON messages(chat_id, thread_id, content_t
 CREATE INDEX idx_messages_metadata ON messages USING GIN (metadata);
 CREATE INDEX idx_messages_chat_name ON messages(chat_name);
 
+-- История правок сообщений (исходная версия — в messages).
+-- Очищается модулем Maintenance вместе с партициями messages.
+CREATE TABLE message_edits (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    thread_id BIGINT DEFAULT 0,
+    user_id BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    text TEXT,
+    caption TEXT,
+    edited_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_message_edits_message ON message_edits(chat_id, message_id, edited_at);
+CREATE INDEX idx_message_edits_edited_at ON message_edits(edited_at);
+
 -- ============================================================================
 -- Limiter Module
 -- ============================================================================
@@ -88,8 +116,11 @@ CREATE TABLE content_limits (
     limit_document INTEGER DEFAULT 0,
     limit_location INTEGER DEFAULT 0,
     limit_contact INTEGER DEFAULT 0,
+    limit_forward INTEGER DEFAULT 0,
     limit_banned_words INTEGER DEFAULT 0,
     warning_threshold INTEGER DEFAULT 2,
+    penalty VARCHAR(20) DEFAULT 'delete',  -- delete | mute_until_reset | restrict_media | warn_only
+    shadow BOOLEAN NOT NULL DEFAULT false, -- только запись срабатываний, без наказаний
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
@@ -97,6 +128,23 @@ CREATE TABLE content_limits (
 CREATE UNIQUE INDEX idx_content_limits_unique ON content_limits(chat_id, thread_id, COALESCE(user_id, -1));
 CREATE INDEX idx_content_limits_chat ON content_limits(chat_id, thread_id);
 
+-- Лимиты за окна (час, 24 часа, неделя, своё окно); дневные — в content_limits
+CREATE TABLE content_limit_windows (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id BIGINT DEFAULT 0,
+    user_id BIGINT,
+    content_type VARCHAR(20) NOT NULL,
+    window_type VARCHAR(12) NOT NULL,          -- hour | rolling24h | week | custom
+    window_seconds INTEGER NOT NULL DEFAULT 0, -- длина окна для custom
+    limit_value INTEGER NOT NULL CHECK (limit_value > 0),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_content_limit_windows_unique
+    ON content_limit_windows(chat_id, thread_id, COALESCE(user_id, -1), content_type, window_type, window_seconds);
+
 -- ============================================================================
 -- Reactions Module (включает фильтры запрещённых слов и автоответы)
 -- ============================================================================
@@ -117,6 +165,7 @@ CREATE TABLE keyword_reactions (
     delete_on_limit BOOLEAN DEFAULT FALSE,
     action VARCHAR(20) DEFAULT NULL,
     is_active BOOLEAN DEFAULT TRUE,
+    shadow BOOLEAN NOT NULL DEFAULT false,  -- фильтр только записывает срабатывания
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
@@ -170,6 +219,11 @@ CREATE TABLE profanity_settings (
     thread_id BIGINT DEFAULT 0,
     action VARCHAR(20) DEFAULT 'delete',
     warn_text TEXT,
+    min_severity VARCHAR(20) NOT NULL DEFAULT 'mild',  -- mild | moderate | severe
+    action_mild VARCHAR(20),                           -- NULL = action
+    action_moderate VARCHAR(20),
+    action_severe VARCHAR(20),
+    shadow BOOLEAN NOT NULL DEFAULT false,             -- только запись срабатываний
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW(),
     PRIMARY KEY (chat_id, thread_id)
@@ -197,6 +251,262 @@ CREATE TABLE scheduled_tasks (
 
 CREATE INDEX idx_scheduled_tasks_active ON scheduled_tasks(chat_id, thread_id, is_active);
 
+-- ============================================================================
+-- Captcha (проверка новых участников)
+-- ============================================================================
+
+CREATE TABLE captcha_settings (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(chat_id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    timeout_seconds INTEGER NOT NULL DEFAULT 120,
+    challenge_type VARCHAR(20) NOT NULL DEFAULT 'button', -- button | math
+    fail_action VARCHAR(20) NOT NULL DEFAULT 'kick',      -- kick | ban
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE captcha_challenges (
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    thread_id BIGINT DEFAULT 0,
+    message_id BIGINT DEFAULT 0,           -- сообщение бота с капчей (удаляется после решения)
+    challenge_type VARCHAR(20) NOT NULL,
+    answer VARCHAR(50) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    fail_action VARCHAR(20) NOT NULL DEFAULT 'kick',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX idx_captcha_challenges_expires ON captcha_challenges(expires_at);
+
+-- ============================================================================
+-- Warnings (предупреждения и эскалация)
+-- ============================================================================
+
+CREATE TABLE warnings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    issued_by BIGINT NOT NULL DEFAULT 0,   -- 0 = бот (автоматическое предупреждение)
+    source VARCHAR(20) NOT NULL,           -- manual | limiter | profanity | banned_words | antiflood | links | report
+    reason TEXT,
+    message_id BIGINT DEFAULT 0,           -- сообщение-нарушение (защита от повторного авто-варна на правку)
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ,
+    revoked_by BIGINT
+);
+
+CREATE INDEX idx_warnings_active ON warnings(chat_id, user_id) WHERE revoked_at IS NULL;
+CREATE UNIQUE INDEX idx_warnings_message ON warnings(chat_id, message_id, source) WHERE message_id <> 0;
+
+CREATE TABLE warn_settings (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(chat_id) ON DELETE CASCADE,
+    auto_limiter BOOLEAN NOT NULL DEFAULT FALSE,
+    auto_profanity BOOLEAN NOT NULL DEFAULT FALSE,
+    auto_banned_words BOOLEAN NOT NULL DEFAULT FALSE,
+    auto_links BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE warn_escalation (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    warn_count INTEGER NOT NULL CHECK (warn_count > 0),
+    action VARCHAR(10) NOT NULL,           -- mute | kick | ban
+    duration_seconds INTEGER NOT NULL DEFAULT 0, -- 0 = навсегда (для mute и ban)
+    PRIMARY KEY (chat_id, warn_count)
+);
+
+-- ============================================================================
+-- Punishments (мут, бан, кик)
+-- ============================================================================
+
+CREATE TABLE punishments (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    action VARCHAR(10) NOT NULL,           -- mute | ban | kick | restrict
+    content_type VARCHAR(20),              -- restrict: запрещённый тип контента
+    reason TEXT,
+    issued_by BIGINT NOT NULL DEFAULT 0,   -- 0 = бот (лестница предупреждений)
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,                -- NULL = навсегда (для kick не используется)
+    lifted_at TIMESTAMPTZ,                 -- снято воркером или /unmute, /unban
+    lifted_by BIGINT                       -- 0 = воркер по истечении срока
+);
+
+CREATE INDEX idx_punishments_expires ON punishments(expires_at) WHERE lifted_at IS NULL;
+CREATE INDEX idx_punishments_user ON punishments(chat_id, user_id);
+
+-- ============================================================================
+-- Reports (жалобы участников)
+-- ============================================================================
+
+CREATE TABLE reports (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    message_id BIGINT NOT NULL,
+    reporter_id BIGINT NOT NULL,
+    reported_user_id BIGINT NOT NULL,
+    reason TEXT,
+    message_text TEXT,                             -- начало текста (история переживает удаление сообщения)
+    status VARCHAR(10) NOT NULL DEFAULT 'open',    -- open | deleted | warned | banned | dismissed
+    notifications JSONB NOT NULL DEFAULT '[]',     -- оповещения админам: [{"chat_id", "message_id"}]
+    resolved_by BIGINT,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_reports_chat ON reports(chat_id, created_at DESC);
+CREATE INDEX idx_reports_reporter ON reports(chat_id, reporter_id, created_at DESC);
+CREATE UNIQUE INDEX idx_reports_open ON reports(chat_id, message_id) WHERE status = 'open'; -- одна открытая жалоба на сообщение
+
+CREATE TABLE report_settings (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(chat_id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    notify VARCHAR(10) NOT NULL DEFAULT 'mention', -- mention | dm | log
+    log_chat_id BIGINT,                            -- чат модерации для notify = log
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- ============================================================================
+-- Antiflood Module (порог флуда)
+-- ============================================================================
+
+CREATE TABLE antiflood_settings (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    max_messages INTEGER NOT NULL CHECK (max_messages > 0),
+    window_seconds INTEGER NOT NULL CHECK (window_seconds > 0),
+    action VARCHAR(10) NOT NULL DEFAULT 'delete',   -- delete | mute | warn
+    mute_seconds INTEGER NOT NULL DEFAULT 0,        -- длительность мута для action = mute
+    weights JSONB NOT NULL DEFAULT '{}',
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id)
+);
+
+-- ============================================================================
+-- Antiraid Module (детектор рейдов)
+-- ============================================================================
+
+CREATE TABLE raid_settings (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(chat_id) ON DELETE CASCADE,
+    auto_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    join_threshold INTEGER NOT NULL DEFAULT 10 CHECK (join_threshold > 0),
+    window_seconds INTEGER NOT NULL DEFAULT 60 CHECK (window_seconds > 0),
+    action VARCHAR(10) NOT NULL DEFAULT 'restrict',  -- restrict | kick
+    cooldown_seconds INTEGER NOT NULL DEFAULT 600 CHECK (cooldown_seconds > 0),
+    raid_until TIMESTAMPTZ,
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_raid_settings_until ON raid_settings(raid_until) WHERE raid_until IS NOT NULL;
+
+-- ============================================================================
+-- Link filter (часть модуля Reactions)
+-- ============================================================================
+
+CREATE TABLE link_filter_settings (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    action VARCHAR(20) NOT NULL DEFAULT 'delete',   -- delete | warn | delete_warn
+    mode VARCHAR(10) NOT NULL DEFAULT 'all',        -- all | deny
+    block_invites BOOLEAN NOT NULL DEFAULT TRUE,
+    block_mentions BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id)
+);
+
+CREATE TABLE link_filter_domains (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    domain VARCHAR(255) NOT NULL,                   -- example.com или @channel
+    list_type VARCHAR(5) NOT NULL CHECK (list_type IN ('allow', 'deny')),
+    created_by BIGINT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (chat_id, thread_id, domain)
+);
+
+-- ============================================================================
+-- Forward policy (часть модуля Limiter)
+-- ============================================================================
+
+CREATE TABLE forward_settings (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    policy VARCHAR(20) NOT NULL DEFAULT 'allow',   -- allow | deny | deny_channels | whitelist
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id)
+);
+
+CREATE TABLE forward_whitelist (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    source_id BIGINT NOT NULL,                     -- ID канала, группы или пользователя
+    title TEXT,
+    created_by BIGINT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id, source_id)
+);
+
+-- ============================================================================
+-- Probation (испытательный срок новичков)
+-- ============================================================================
+
+CREATE TABLE chat_members (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    message_count INTEGER NOT NULL DEFAULT 0,
+    trusted_at TIMESTAMPTZ,
+    trusted_by BIGINT,
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE TABLE probation_settings (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(chat_id) ON DELETE CASCADE,
+    duration_seconds INTEGER NOT NULL DEFAULT 86400 CHECK (duration_seconds >= 0),
+    min_messages INTEGER NOT NULL DEFAULT 0 CHECK (min_messages >= 0),
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- ============================================================================
+-- Chat state snapshots (часть модуля Scheduler)
+-- ============================================================================
+
+CREATE TABLE chat_state_snapshots (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    kind VARCHAR(20) NOT NULL,                     -- permissions | slowmode
+    data JSONB,
+    saved_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id, kind)
+);
+
+-- ============================================================================
+-- Per-chat profanity words
+-- ============================================================================
+
+CREATE TABLE profanity_chat_words (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    word VARCHAR(100) NOT NULL,
+    kind VARCHAR(10) NOT NULL,                     -- allow | add
+    severity VARCHAR(20) NOT NULL DEFAULT 'moderate',
+    created_by BIGINT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, word)
+);
+
 -- ============================================================================
 -- System tables
 -- ============================================================================
@@ -218,12 +528,13 @@ CREATE TABLE event_log (
 CREATE INDEX idx_event_log_chat ON event_log(chat_id, created_at DESC);
 CREATE INDEX idx_event_log_module ON event_log(module_name, created_at DESC);
 CREATE INDEX idx_event_log_metadata ON event_log USING GIN (metadata);
+CREATE INDEX idx_event_log_shadow ON event_log(chat_id, created_at DESC) WHERE event_type = 'shadow_hit';
 
 CREATE TABLE bot_settings (
     id SERIAL PRIMARY KEY,
     bot_version TEXT DEFAULT '1.1.1',
     timezone TEXT DEFAULT 'UTC',
-    available_modules TEXT[] DEFAULT ARRAY['core', 'limiter', 'statistics', 'reactions', 'scheduler']
+    available_modules TEXT[] DEFAULT ARRAY['core', 'limiter', 'statistics', 'reactions', 'scheduler', 'antiflood', 'probation']
 );
 
 INSERT INTO bot_settings (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,21 @@
+-- ============================================================================
+-- BMFT Migration: per-chat module state
+-- ============================================================================
+-- Включение/выключение модулей pipeline для конкретного чата или топика.
+-- Нет записи = модуль включён (поведение по умолчанию не меняется).
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS chat_modules (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id BIGINT DEFAULT 0,
+    module_name VARCHAR(50) NOT NULL,
+    is_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id, module_name)
+);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (4, 'per-chat module enable/disable (chat_modules)')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,25 @@
+-- ============================================================================
+-- BMFT Migration: edit history
+-- ============================================================================
+-- История правок сообщений. Исходная версия остаётся в messages,
+-- каждая правка — отдельная строка. Счётчик правок — metadata.statistics.edit_count.
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS message_edits (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    thread_id BIGINT DEFAULT 0,
+    user_id BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    text TEXT,
+    caption TEXT,
+    edited_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_edits_message ON message_edits(chat_id, message_id, edited_at);
+CREATE INDEX IF NOT EXISTS idx_message_edits_edited_at ON message_edits(edited_at);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (5, 'message edit history (message_edits)')
+ON CONFLICT (version) DO NOTHING;
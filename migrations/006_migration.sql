@@ -0,0 +1,38 @@
+-- ============================================================================
+-- BMFT Migration: captcha for new members
+-- ============================================================================
+-- captcha_settings — настройки капчи per-chat (нет записи = капча выключена).
+-- captcha_challenges — ожидающие проверки новички. Хранятся в БД, чтобы
+-- после перезапуска бота просроченные капчи всё равно обработались.
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS captcha_settings (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(chat_id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    timeout_seconds INTEGER NOT NULL DEFAULT 120,
+    challenge_type VARCHAR(20) NOT NULL DEFAULT 'button', -- button | math
+    fail_action VARCHAR(20) NOT NULL DEFAULT 'kick',      -- kick | ban
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS captcha_challenges (
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    thread_id BIGINT DEFAULT 0,
+    message_id BIGINT DEFAULT 0,           -- сообщение бота с капчей (удаляется после решения)
+    challenge_type VARCHAR(20) NOT NULL,
+    answer VARCHAR(50) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    fail_action VARCHAR(20) NOT NULL DEFAULT 'kick',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_captcha_challenges_expires ON captcha_challenges(expires_at);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (6, 'captcha for new members (captcha_settings, captcha_challenges)')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,45 @@
+-- ============================================================================
+-- BMFT Migration: warnings with escalation ladder
+-- ============================================================================
+-- warnings — выданные предупреждения (вручную через /warn или автоматически
+-- limiter/reactions). Снятые (/unwarn, /resetwarns) остаются в истории с revoked_at.
+-- warn_settings — какие нарушения выдают предупреждения автоматически.
+-- warn_escalation — лестница наказаний: N активных предупреждений → действие.
+-- Нет записей для чата = лестница по умолчанию (3 → мут 1ч, 5 → бан).
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS warnings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    issued_by BIGINT NOT NULL DEFAULT 0,   -- 0 = бот (автоматическое предупреждение)
+    source VARCHAR(20) NOT NULL,           -- manual | limiter | profanity | banned_words
+    reason TEXT,
+    message_id BIGINT DEFAULT 0,           -- сообщение-нарушение (защита от повторного авто-варна на правку)
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ,
+    revoked_by BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_warnings_active ON warnings(chat_id, user_id) WHERE revoked_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS warn_settings (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(chat_id) ON DELETE CASCADE,
+    auto_limiter BOOLEAN NOT NULL DEFAULT FALSE,
+    auto_profanity BOOLEAN NOT NULL DEFAULT FALSE,
+    auto_banned_words BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS warn_escalation (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    warn_count INTEGER NOT NULL CHECK (warn_count > 0),
+    action VARCHAR(10) NOT NULL,           -- mute | kick | ban
+    duration_seconds INTEGER NOT NULL DEFAULT 0, -- 0 = навсегда (для mute и ban)
+    PRIMARY KEY (chat_id, warn_count)
+);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (7, 'warnings with escalation ladder (warnings, warn_settings, warn_escalation)')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,29 @@
+-- ============================================================================
+-- BMFT Migration: punishments (mute, ban, kick) with timed expiry
+-- ============================================================================
+-- punishments — наказания, выданные командами /mute, /ban, /kick и лестницей
+-- предупреждений. Воркер модуля moderation снимает истёкшие наказания
+-- (expires_at <= NOW(), lifted_at IS NULL) — в том числе пропущенные,
+-- пока бот был выключен.
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS punishments (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    action VARCHAR(10) NOT NULL,           -- mute | ban | kick
+    reason TEXT,
+    issued_by BIGINT NOT NULL DEFAULT 0,   -- 0 = бот (лестница предупреждений)
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,                -- NULL = навсегда (для kick не используется)
+    lifted_at TIMESTAMPTZ,                 -- снято воркером или /unmute, /unban
+    lifted_by BIGINT                       -- 0 = воркер по истечении срока
+);
+
+CREATE INDEX IF NOT EXISTS idx_punishments_expires ON punishments(expires_at) WHERE lifted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_punishments_user ON punishments(chat_id, user_id);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (8, 'punishments with timed expiry')
+ON CONFLICT (version) DO NOTHING;
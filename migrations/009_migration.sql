@@ -0,0 +1,33 @@
+-- ============================================================================
+-- BMFT Migration: antiflood settings
+-- ============================================================================
+-- antiflood_settings — порог флуда per-chat и per-topic: не больше max_messages
+-- «весовых» сообщений за window_seconds. Сам подсчёт ведётся в памяти модуля
+-- antiflood, в БД хранятся только настройки (кэшируются в модуле).
+-- thread_id = 0 — настройка для всего чата, >0 — только для топика.
+-- weights — веса типов контента, например {"sticker": 3}; по умолчанию 1.
+-- ============================================================================
+
+-- antiflood — новый модуль pipeline, доступный для /enable и /disable
+UPDATE bot_settings
+SET available_modules = array_append(available_modules, 'antiflood')
+WHERE NOT ('antiflood' = ANY(available_modules));
+
+CREATE TABLE IF NOT EXISTS antiflood_settings (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    max_messages INTEGER NOT NULL CHECK (max_messages > 0),
+    window_seconds INTEGER NOT NULL CHECK (window_seconds > 0),
+    action VARCHAR(10) NOT NULL DEFAULT 'delete',   -- delete | mute | warn
+    mute_seconds INTEGER NOT NULL DEFAULT 0,        -- длительность мута для action = mute
+    weights JSONB NOT NULL DEFAULT '{}',
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id)
+);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (9, 'antiflood settings')
+ON CONFLICT (version) DO NOTHING;
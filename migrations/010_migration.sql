@@ -0,0 +1,31 @@
+-- ============================================================================
+-- BMFT Migration: content limit windows (hour, rolling 24h, week, custom)
+-- ============================================================================
+-- content_limit_windows — лимиты контента за окна, отличные от календарного дня.
+-- Дневные лимиты остаются в колонках content_limits; окна дополняют их:
+-- «5 фото в час и 20 в неделю» — две записи для photo.
+-- window_type: hour (текущий час), rolling24h (последние 24 часа),
+-- week (текущая неделя с понедельника), custom (последние window_seconds).
+-- Fallback как у content_limits: пользователь+топик → топик → пользователь+чат → чат.
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS content_limit_windows (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id BIGINT DEFAULT 0,
+    user_id BIGINT,
+    content_type VARCHAR(20) NOT NULL,
+    window_type VARCHAR(12) NOT NULL,          -- hour | rolling24h | week | custom
+    window_seconds INTEGER NOT NULL DEFAULT 0, -- длина окна для custom
+    limit_value INTEGER NOT NULL CHECK (limit_value > 0),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_content_limit_windows_unique
+    ON content_limit_windows(chat_id, thread_id, COALESCE(user_id, -1), content_type, window_type, window_seconds);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (10, 'content limit windows')
+ON CONFLICT (version) DO NOTHING;
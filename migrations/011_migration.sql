@@ -0,0 +1,17 @@
+-- ============================================================================
+-- BMFT Migration: limiter penalty policy
+-- ============================================================================
+-- content_limits.penalty — что делать при превышении лимита:
+--   delete           — удалить сообщение (как раньше)
+--   mute_until_reset — удалить и замутить до сброса окна лимита
+--   restrict_media   — удалить и запретить этот тип контента до сброса окна
+--   warn_only        — только предупредить, сообщение остаётся
+-- Политика действует для записи лимитов (чат, топик или пользователь).
+-- ============================================================================
+
+ALTER TABLE content_limits ADD COLUMN IF NOT EXISTS penalty VARCHAR(20) DEFAULT 'delete';
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (11, 'limiter penalty policy')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,28 @@
+-- ============================================================================
+-- BMFT Migration: anti-raid settings
+-- ============================================================================
+-- raid_settings — детектор рейдов per-chat: больше join_threshold вступлений
+-- за window_seconds включают режим рейда на cooldown_seconds. В режиме рейда
+-- новые участники ограничиваются (restrict) или удаляются (kick), приветствия
+-- не отправляются. raid_until — конец текущего режима рейда (NULL = выключен),
+-- переживает рестарт бота. Нет записи = автодетектор включён с порогами по умолчанию.
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS raid_settings (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(chat_id) ON DELETE CASCADE,
+    auto_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    join_threshold INTEGER NOT NULL DEFAULT 10 CHECK (join_threshold > 0),
+    window_seconds INTEGER NOT NULL DEFAULT 60 CHECK (window_seconds > 0),
+    action VARCHAR(10) NOT NULL DEFAULT 'restrict',  -- restrict | kick
+    cooldown_seconds INTEGER NOT NULL DEFAULT 600 CHECK (cooldown_seconds > 0),
+    raid_until TIMESTAMPTZ,
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_raid_settings_until ON raid_settings(raid_until) WHERE raid_until IS NOT NULL;
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (12, 'anti-raid settings')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,42 @@
+-- ============================================================================
+-- BMFT Migration: link and invite-link filter
+-- ============================================================================
+-- link_filter_settings — фильтр ссылок per-chat/per-topic (нет записи = выключен):
+--   action         — delete | warn | delete_warn (как у фильтра запрещённых слов)
+--   mode           — all: запрещены все ссылки, кроме allowlist
+--                    deny: запрещены только домены из denylist
+--   block_invites  — запрещать ссылки-приглашения t.me/+..., t.me/joinchat/...
+--   block_mentions — запрещать упоминания каналов и групп (@channel, t.me/channel)
+-- link_filter_domains — allowlist и denylist доменов (и @каналов) чата/топика.
+-- warn_settings.auto_links — автопредупреждение за запрещённую ссылку.
+-- ============================================================================
+
+ALTER TABLE warn_settings ADD COLUMN IF NOT EXISTS auto_links BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS link_filter_settings (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    action VARCHAR(20) NOT NULL DEFAULT 'delete',   -- delete | warn | delete_warn
+    mode VARCHAR(10) NOT NULL DEFAULT 'all',        -- all | deny
+    block_invites BOOLEAN NOT NULL DEFAULT TRUE,
+    block_mentions BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id)
+);
+
+CREATE TABLE IF NOT EXISTS link_filter_domains (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    domain VARCHAR(255) NOT NULL,                   -- example.com или @channel
+    list_type VARCHAR(5) NOT NULL CHECK (list_type IN ('allow', 'deny')),
+    created_by BIGINT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (chat_id, thread_id, domain)
+);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (13, 'link and invite-link filter')
+ON CONFLICT (version) DO NOTHING;
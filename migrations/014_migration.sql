@@ -0,0 +1,38 @@
+-- ============================================================================
+-- BMFT Migration: forward policy
+-- ============================================================================
+-- content_limits.limit_forward — пересылки стали отдельным типом контента
+-- (messages.content_type = 'forward'), лимитируются как остальные типы.
+-- forward_settings — политика пересылок per-chat/per-topic (нет записи = allow):
+--   allow         — пересылки разрешены
+--   deny          — запрещены все пересылки
+--   deny_channels — запрещены пересылки из каналов
+--   whitelist     — разрешены только пересылки из источников forward_whitelist
+-- forward_whitelist — разрешённые источники: каналы, группы и пользователи (source_id).
+-- ============================================================================
+
+ALTER TABLE content_limits ADD COLUMN IF NOT EXISTS limit_forward INTEGER DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS forward_settings (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    policy VARCHAR(20) NOT NULL DEFAULT 'allow',   -- allow | deny | deny_channels | whitelist
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id)
+);
+
+CREATE TABLE IF NOT EXISTS forward_whitelist (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    source_id BIGINT NOT NULL,                     -- ID канала, группы или пользователя
+    title TEXT,
+    created_by BIGINT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id, source_id)
+);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (14, 'forward policy')
+ON CONFLICT (version) DO NOTHING;
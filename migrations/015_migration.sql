@@ -0,0 +1,39 @@
+-- ============================================================================
+-- BMFT Migration: new-member probation
+-- ============================================================================
+-- chat_members — когда пользователь вступил в чат (заполняется при каждом
+-- вступлении, повторное вступление начинает испытательный срок заново).
+-- message_count — сообщения за испытательный срок, trusted_at — срок окончен
+-- (trusted_by = NULL — автоматически, иначе админ командой /trust).
+-- probation_settings — испытательный срок per-chat (нет записи = выключен):
+-- новичку можно только текст без ссылок, пока не прошло duration_seconds
+-- и он не написал min_messages сообщений (0 = условие не задано).
+-- ============================================================================
+
+-- probation — новый модуль pipeline, доступный для /enable и /disable
+UPDATE bot_settings
+SET available_modules = array_append(available_modules, 'probation')
+WHERE NOT ('probation' = ANY(available_modules));
+
+CREATE TABLE IF NOT EXISTS chat_members (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    message_count INTEGER NOT NULL DEFAULT 0,
+    trusted_at TIMESTAMPTZ,
+    trusted_by BIGINT,
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS probation_settings (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(chat_id) ON DELETE CASCADE,
+    duration_seconds INTEGER NOT NULL DEFAULT 86400 CHECK (duration_seconds >= 0),
+    min_messages INTEGER NOT NULL DEFAULT 0 CHECK (min_messages >= 0),
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (15, 'new-member probation')
+ON CONFLICT (version) DO NOTHING;
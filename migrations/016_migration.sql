@@ -0,0 +1,26 @@
+-- ============================================================================
+-- BMFT Migration: scheduled chat-state actions (night mode)
+-- ============================================================================
+-- scheduled_tasks.action_type получил действия, меняющие состояние чата:
+-- lock / unlock (права чата только на чтение и обратно), close_topic /
+-- reopen_topic, slowmode <сек>. Колонки не меняются.
+-- chat_state_snapshots — состояние до изменения планировщиком, чтобы
+-- вернуть именно его, а не права «по умолчанию»:
+--   permissions — права участников чата (JSON tele.Rights), thread_id = 0
+--   slowmode    — настройка антифлуда чата/топика до slow mode (NULL = не было)
+-- Запись существует, пока изменение действует; повторный lock её не перезаписывает.
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS chat_state_snapshots (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    kind VARCHAR(20) NOT NULL,                     -- permissions | slowmode
+    data JSONB,
+    saved_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, thread_id, kind)
+);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (16, 'scheduled chat-state actions')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,23 @@
+-- ============================================================================
+-- BMFT Migration: per-chat profanity words
+-- ============================================================================
+-- profanity_chat_words — слова чата поверх глобального profanity_dictionary:
+--   allow — исключение: слово текста, содержащее его, фильтр мата не проверяет
+--           (ложные срабатывания вроде «оскорблять» → «бля»)
+--   add   — дополнительное слово словаря только для этого чата
+-- Слово хранится в нижнем регистре, одно слово — один вид.
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS profanity_chat_words (
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    word VARCHAR(100) NOT NULL,
+    kind VARCHAR(10) NOT NULL,                     -- allow | add
+    created_by BIGINT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (chat_id, word)
+);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (17, 'per-chat profanity words')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,22 @@
+-- ============================================================================
+-- BMFT Migration: profanity severity levels
+-- ============================================================================
+-- profanity_dictionary.severity (mild | moderate | severe) теперь учитывается:
+--   profanity_settings.min_severity — минимальный уровень, на который фильтр
+--     реагирует (по умолчанию mild — все слова, как раньше)
+--   profanity_settings.action_<уровень> — своё действие для уровня,
+--     NULL — общее действие action
+--   profanity_chat_words.severity — уровень дополнительных слов чата
+-- ============================================================================
+
+ALTER TABLE profanity_settings ADD COLUMN IF NOT EXISTS min_severity VARCHAR(20) NOT NULL DEFAULT 'mild';
+ALTER TABLE profanity_settings ADD COLUMN IF NOT EXISTS action_mild VARCHAR(20);
+ALTER TABLE profanity_settings ADD COLUMN IF NOT EXISTS action_moderate VARCHAR(20);
+ALTER TABLE profanity_settings ADD COLUMN IF NOT EXISTS action_severe VARCHAR(20);
+
+ALTER TABLE profanity_chat_words ADD COLUMN IF NOT EXISTS severity VARCHAR(20) NOT NULL DEFAULT 'moderate';
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (18, 'profanity severity levels')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,22 @@
+-- ============================================================================
+-- BMFT Migration: shadow mode for filters and limits
+-- ============================================================================
+-- shadow = true — правило проверяется, срабатывание записывается в event_log
+-- (event_type = 'shadow_hit', детали в metadata) и в metadata сообщения
+-- (массив shadow), но сообщение не удаляется, предупреждений и банов нет.
+-- Отчёт по срабатываниям — /shadowreport.
+--   keyword_reactions.shadow  — фильтры запрещённых слов (/addban ... shadow)
+--   profanity_settings.shadow — фильтр мата чата/топика
+--   content_limits.shadow     — лимиты контента и лимит мата
+-- ============================================================================
+
+ALTER TABLE keyword_reactions ADD COLUMN IF NOT EXISTS shadow BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE profanity_settings ADD COLUMN IF NOT EXISTS shadow BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE content_limits ADD COLUMN IF NOT EXISTS shadow BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_event_log_shadow ON event_log(chat_id, created_at DESC) WHERE event_type = 'shadow_hit';
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (19, 'shadow mode for filters and limits')
+ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,45 @@
+-- ============================================================================
+-- BMFT Migration: member reports
+-- ============================================================================
+-- reports — жалобы участников (/report в ответ на сообщение) и их разбор.
+-- message_text — начало текста сообщения: история жалоб сохраняется и после
+-- удаления сообщения. notifications — куда отправлено оповещение админам
+-- ([{"chat_id", "message_id"}]), чтобы после разбора убрать кнопки у всех.
+-- status: open | deleted | warned | banned | dismissed.
+-- report_settings — куда оповещать админов per-chat (нет записи = включено,
+-- упоминание админов в чате): mention | dm | log (чат модерации log_chat_id).
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS reports (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL REFERENCES chats(chat_id) ON DELETE CASCADE,
+    thread_id INTEGER NOT NULL DEFAULT 0,
+    message_id BIGINT NOT NULL,
+    reporter_id BIGINT NOT NULL,
+    reported_user_id BIGINT NOT NULL,
+    reason TEXT,
+    message_text TEXT,
+    status VARCHAR(10) NOT NULL DEFAULT 'open',
+    notifications JSONB NOT NULL DEFAULT '[]',
+    resolved_by BIGINT,
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_chat ON reports(chat_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_reports_reporter ON reports(chat_id, reporter_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_reports_open ON reports(chat_id, message_id) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS report_settings (
+    chat_id BIGINT PRIMARY KEY REFERENCES chats(chat_id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    notify VARCHAR(10) NOT NULL DEFAULT 'mention',  -- mention | dm | log
+    log_chat_id BIGINT,                             -- чат модерации для notify = log
+    updated_by BIGINT,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (20, 'member reports (reports, report_settings)')
+ON CONFLICT (version) DO NOTHING;
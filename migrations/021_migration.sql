@@ -0,0 +1,25 @@
+-- ============================================================================
+-- BMFT Migration: one warning per message
+-- ============================================================================
+-- Правка сообщения повторно проходит фильтры, и два параллельных срабатывания
+-- могли выдать два предупреждения за одно нарушение (и дважды применить
+-- ступень лестницы). Уникальный индекс (chat_id, message_id, source) делает
+-- INSERT ... ON CONFLICT DO NOTHING атомарной проверкой. message_id = 0 —
+-- предупреждение без сообщения, ограничение на него не распространяется.
+-- Уже выданные дубли удаляются (остаётся первое предупреждение).
+-- ============================================================================
+
+DELETE FROM warnings w
+USING warnings d
+WHERE w.message_id <> 0
+  AND d.chat_id = w.chat_id
+  AND d.message_id = w.message_id
+  AND d.source = w.source
+  AND d.id < w.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warnings_message ON warnings(chat_id, message_id, source) WHERE message_id <> 0;
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (21, 'one warning per message (idx_warnings_message)')
+ON CONFLICT (version) DO NOTHING;
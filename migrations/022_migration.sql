@@ -0,0 +1,15 @@
+-- ============================================================================
+-- BMFT Migration: content type of restrict punishments
+-- ============================================================================
+-- Права пользователя в Telegram — одно значение на всех, поэтому при выдаче
+-- и снятии наказания бот пересчитывает их по всем действующим мутам и
+-- запретам контента. content_type — что запрещает restrict (photo, sticker, ...);
+-- для записей до этой миграции NULL — запрет текста (can_send_messages).
+-- ============================================================================
+
+ALTER TABLE punishments ADD COLUMN IF NOT EXISTS content_type VARCHAR(20);
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (22, 'content type of restrict punishments (punishments.content_type)')
+ON CONFLICT (version) DO NOTHING;
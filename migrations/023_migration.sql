@@ -0,0 +1,28 @@
+-- ============================================================================
+-- BMFT Migration: one open report per message
+-- ============================================================================
+-- Два одновременных /report на одно сообщение могли создать две открытые
+-- жалобы: проверка «уже пожаловались» и вставка шли отдельными запросами.
+-- Индекс открытых жалоб становится уникальным, вставка — ON CONFLICT DO NOTHING.
+-- Лишние открытые дубликаты (кроме самой ранней жалобы) закрываются как
+-- отклонённые, иначе уникальный индекс не создать.
+-- ============================================================================
+
+UPDATE reports r
+SET status = 'dismissed', resolved_at = NOW()
+WHERE r.status = 'open'
+  AND EXISTS (
+      SELECT 1 FROM reports o
+      WHERE o.chat_id = r.chat_id
+        AND o.message_id = r.message_id
+        AND o.status = 'open'
+        AND o.id < r.id
+  );
+
+DROP INDEX IF EXISTS idx_reports_open;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open ON reports(chat_id, message_id) WHERE status = 'open';
+
+-- Запись версии миграции
+INSERT INTO schema_migrations (version, description)
+VALUES (23, 'one open report per message (unique idx_reports_open)')
+ON CONFLICT (version) DO NOTHING;